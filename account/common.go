@@ -1,11 +1,21 @@
 package account
 
 import (
+	"errors"
 	"time"
 
 	"github.com/Adventureinc/hotel-hm-api/src/common"
 )
 
+// ErrPasswordResetRequired パスワード再設定フラグが立っているアカウントでログインしようとした
+var ErrPasswordResetRequired = errors.New("Error: パスワードの再設定が必要です。")
+
+// ErrAccountLocked ログイン失敗が続いたためアカウントもしくは接続元IPがロックされている
+var ErrAccountLocked = errors.New("Error: ログイン失敗が続いたため、一時的にログインできません。")
+
+// ErrPasswordPolicy 新しいパスワードがパスワードポリシーを満たしていない
+var ErrPasswordPolicy = errors.New("password policy violation")
+
 // HtTmHotelManager hotel managerのアカウント管理用テーブル
 type HtTmHotelManager struct {
 	HotelManagerID        int64     `gorm:"primaryKey;autoIncrement:true" json:"hotel_manager_id"`
@@ -17,6 +27,9 @@ type HtTmHotelManager struct {
 	EmailEnc              string    `json:"email_enc"`
 	UsernameEnc           string    `json:"username_enc,omitempty"`
 	PasswordEnc           string    `json:"password_enc,omitempty"`
+	PasswordHash          string    `json:"-"`
+	PasswordResetFlg      bool      `json:"password_reset_flg,omitempty"`
+	PasswordChangedAt     time.Time `gorm:"type:time" json:"password_changed_at"`
 	IsPrimary             bool      `json:"is_primary,omitempty"`
 	MasterEditFlg         bool      `json:"master_edit_flg,omitempty"`
 	SettlementNeedFlg     bool      `json:"settlement_need_flg,omitempty"`
@@ -25,7 +38,12 @@ type HtTmHotelManager struct {
 	DelFlg                bool      `json:"del_flg,omitempty"`
 	LoginedAt             time.Time `gorm:"type:time" json:"logined_at"`
 	LoginFailedNum        int       `json:"login_failed_num,omitempty"`
+	LockedUntil           time.Time `gorm:"type:time" json:"locked_until"`
+	TwoFactorRequiredFlg  bool      `json:"two_factor_required_flg,omitempty"`
+	OwnerHotelManagerID   int64     `json:"owner_hotel_manager_id,omitempty"`
 	RememberToken         string    `json:"remember_token,omitempty"`
+	Roles                 []string  `gorm:"-" json:"roles,omitempty"`
+	Permissions           []string  `gorm:"-" json:"permissions,omitempty"`
 	common.Times          `gorm:"embedded"`
 }
 
@@ -37,21 +55,106 @@ type HtTmHotelManagerTokens struct {
 	common.Times   `gorm:"embedded"`
 }
 
+// HtTmHotelManagerSessions HMアカウントのログインセッション（端末）管理用テーブル
+// リフレッシュトークンはハッシュ値のみ保持し、使用のたびに新しいものへ置き換える
+type HtTmHotelManagerSessions struct {
+	SessionID                string    `gorm:"primaryKey" json:"session_id"`
+	HotelManagerID           int64     `json:"hotel_manager_id"`
+	RefreshTokenHash         string    `json:"-"`
+	PreviousRefreshTokenHash string    `json:"-"`
+	IPAddress                string    `json:"ip_address"`
+	UserAgent                string    `json:"user_agent"`
+	ExpiresAt                time.Time `gorm:"type:time" json:"expires_at"`
+	LastUsedAt               time.Time `gorm:"type:time" json:"last_used_at"`
+	RevokedAt                time.Time `gorm:"type:time" json:"revoked_at"`
+	common.Times             `gorm:"embedded"`
+}
+
+// HtThHotelManagerRevokedTokens 失効させたアクセストークン（jti）の一覧テーブル
+type HtThHotelManagerRevokedTokens struct {
+	TokenID        string    `gorm:"primaryKey" json:"token_id"`
+	HotelManagerID int64     `json:"hotel_manager_id"`
+	ExpiresAt      time.Time `gorm:"type:time" json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// HtThHotelManagerPasswordHistories HMアカウントのパスワード変更履歴テーブル
+type HtThHotelManagerPasswordHistories struct {
+	PasswordHistoryID int64  `gorm:"primaryKey;autoIncrement:true" json:"password_history_id"`
+	HotelManagerID    int64  `json:"hotel_manager_id"`
+	PasswordHash      string `json:"-"`
+	common.Times      `gorm:"embedded"`
+}
+
+const (
+	// LoginResultSuccess ログイン成功
+	LoginResultSuccess = "success"
+	// LoginResultFailure ID・パスワード不一致
+	LoginResultFailure = "failure"
+	// LoginResultLocked ロック中のため拒否
+	LoginResultLocked = "locked"
+)
+
+// HtThHotelManagerLoginHistories HMアカウントのログイン試行履歴テーブル
+type HtThHotelManagerLoginHistories struct {
+	LoginHistoryID int64     `gorm:"primaryKey;autoIncrement:true" json:"login_history_id"`
+	HotelManagerID int64     `json:"hotel_manager_id"`
+	IPAddress      string    `json:"ip_address"`
+	UserAgent      string    `json:"user_agent"`
+	Result         string    `json:"result"`
+	AttemptedAt    time.Time `gorm:"type:time" json:"attempted_at"`
+}
+
 // ClaimParam トークンの属性情報
 type ClaimParam struct {
-	HotelManagerID int64  `json:"hotel_manager_id,omitempty"`
-	APIToken       string `json:"api_token,omitempty"`
+	HotelManagerID int64     `json:"hotel_manager_id,omitempty"`
+	APIToken       string    `json:"api_token,omitempty"`
+	SessionID      string    `json:"session_id,omitempty"`
+	TokenID        string    `json:"token_id,omitempty"`
+	ExpiresAt      time.Time `json:"-"`
 }
 
 // LoginInput Loginの入力
 type LoginInput struct {
-	Username string `validate:"required"`
-	Password string `validate:"required"`
+	Username  string `validate:"required"`
+	Password  string `validate:"required"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // TokenOutput token返却用
+// 2段階認証が必要な場合はトークンを発行せず、ChallengeTokenを返す
 type TokenOutput struct {
-	APIToken string `json:"api_token,omitempty"`
+	APIToken                    string   `json:"api_token,omitempty"`
+	RefreshToken                string   `json:"refresh_token,omitempty"`
+	ExpiresIn                   int64    `json:"expires_in,omitempty"`
+	TwoFactorRequired           bool     `json:"two_factor_required,omitempty"`
+	TwoFactorEnrollmentRequired bool     `json:"two_factor_enrollment_required,omitempty"`
+	ChallengeToken              string   `json:"challenge_token,omitempty"`
+	RecoveryCodes               []string `json:"recovery_codes,omitempty"`
+}
+
+// RefreshInput アクセストークン再発行の入力
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	IPAddress    string `json:"-"`
+	UserAgent    string `json:"-"`
+}
+
+// SessionOutput ログイン中の端末一覧の出力
+type SessionOutput struct {
+	SessionID  string    `json:"session_id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	IsCurrent  bool      `json:"is_current"`
+}
+
+// RevokeSessionInput 端末のログアウトの入力
+type RevokeSessionInput struct {
+	SessionID string `json:"session_id" param:"sessionId" validate:"required"`
 }
 
 // ChangePasswordInput パスワード変更の入力
@@ -59,6 +162,8 @@ type ChangePasswordInput struct {
 	Username    string `json:"username" validate:"required"`
 	Password    string `json:"password" validate:"required"`
 	NewPassword string `json:"new_password" validate:"required"`
+	IPAddress   string `json:"-"`
+	UserAgent   string `json:"-"`
 }
 
 // CheckConnectInput 接続ユーザ確認の入力
@@ -72,31 +177,148 @@ type IsParentAccountInput struct {
 	HotelManagerID int64 `json:"hotel_manager_id" param:"hotelManagerId"`
 }
 
+// UnlockInput アカウントロック解除の入力
+type UnlockInput struct {
+	HotelManagerID int64 `json:"hotel_manager_id" param:"hotelManagerId" validate:"required"`
+}
+
 // IAccountUsecase アカウント関連のusecaseのインターフェース
 type IAccountUsecase interface {
-	Login(LoginInput *LoginInput) (string, error)
+	Login(LoginInput *LoginInput) (*TokenOutput, error)
 	Logout(claimParam *ClaimParam) error
-	CheckToken(claimParam *ClaimParam) (string, error)
+	CheckToken(claimParam *ClaimParam) (*TokenOutput, error)
+	Refresh(request *RefreshInput) (*TokenOutput, error)
+	FetchSessions(claimParam *ClaimParam) ([]SessionOutput, error)
+	RevokeSession(claimParam *ClaimParam, sessionID string) error
 	FetchDetail(claimParam *ClaimParam) (*HtTmHotelManager, error)
 	FetchHMUserByToken(claimParam *ClaimParam) (HtTmHotelManager, error)
 	ChangePassword(request *ChangePasswordInput) error
 	IsParentAccount(hotelManagerID int64) bool
+	ForcePasswordResetUnmigrated() (int64, error)
+	Unlock(hotelManagerID int64) error
+	UnlockChild(parent *HtTmHotelManager, hotelManagerID int64) error
+	FetchLoginHistories(claimParam *ClaimParam, paging *common.Paging) ([]HtThHotelManagerLoginHistories, error)
+	FetchRoles(hmUser *HtTmHotelManager) ([]string, error)
+	HasPermission(hmUser *HtTmHotelManager, permission string) (bool, error)
+	AssignRoles(claimParam *ClaimParam, request *AssignRolesInput) error
+	VerifyTwoFactorLogin(request *TwoFactorLoginInput) (*TokenOutput, error)
+	EnrollTwoFactorByChallenge(challengeToken string) (*TwoFactorEnrollOutput, error)
+	FetchTwoFactorStatus(claimParam *ClaimParam) (*TwoFactorStatusOutput, error)
+	EnrollTwoFactor(claimParam *ClaimParam) (*TwoFactorEnrollOutput, error)
+	ActivateTwoFactor(claimParam *ClaimParam, code string) (*RecoveryCodesOutput, error)
+	DisableTwoFactor(claimParam *ClaimParam, request *DisableTwoFactorInput) error
+	RegenerateRecoveryCodes(claimParam *ClaimParam, code string) (*RecoveryCodesOutput, error)
+	UpdateTwoFactorPolicy(claimParam *ClaimParam, request *TwoFactorPolicyInput) error
+	RequestPasswordReset(request *PasswordResetRequestInput) error
+	ResetPassword(request *ResetPasswordInput) error
+	InviteStaff(claimParam *ClaimParam, request *InviteStaffInput) error
+	AcceptInvitation(request *AcceptInvitationInput) error
+	DisableStaff(claimParam *ClaimParam, hotelManagerID int64) error
+	FetchTeam(claimParam *ClaimParam) (*TeamOutput, error)
 }
 
 // IAccountRepository アカウント関連のrepositoryのインターフェース
 type IAccountRepository interface {
-	// FetchHMUserByLoginInfo ログインユーザとパスワードに合致するアカウントを1件取得
-	FetchHMUserByLoginInfo(hmUser *HtTmHotelManager) HtTmHotelManager
+	// FetchHMUserByUsername ログインユーザに合致するアカウントを1件取得
+	FetchHMUserByUsername(usernameEnc string) (HtTmHotelManager, error)
 	// FetchHMUserByToken トークンからアカウントを1件取得
 	FetchHMUserByToken(claimParam *ClaimParam) (HtTmHotelManager, error)
-	// SaveLoginInfo ログイン日時とトークンを更新
-	SaveLoginInfo(hmUser *HtTmHotelManager, claimParam *ClaimParam, newToken string) error
-	// UpdatePassword パスワードを更新
-	UpdatePassword(hotelManagerID int64, password string) error
-	// DeleteAPIToken トークン削除
+	// SaveLoginInfo ログイン日時を更新し、セッションを作成
+	SaveLoginInfo(hmUser *HtTmHotelManager, session *HtTmHotelManagerSessions) error
+	// UpdatePassword パスワードのハッシュを更新し、変更履歴を追加
+	UpdatePassword(hotelManagerID int64, passwordHash string) error
+	// MigratePassword 旧形式（暗号化）のパスワードをハッシュに置き換える
+	MigratePassword(hotelManagerID int64, passwordHash string) error
+	// FetchPasswordHistories 直近のパスワード変更履歴を指定件数取得
+	FetchPasswordHistories(hotelManagerID int64, limit int) ([]HtThHotelManagerPasswordHistories, error)
+	// FlagUnmigratedPasswordReset ハッシュ未移行のアカウントにパスワード再設定フラグを立てる
+	FlagUnmigratedPasswordReset() (int64, error)
+	// DeleteAPIToken セッション導入前に発行したトークンを削除
 	DeleteAPIToken(claimParam *ClaimParam) error
-	// FetchHMUserByPropertyID property_idでHMアカウントを1件取得
+	// FetchSession セッションIDでセッションを1件取得
+	FetchSession(sessionID string) (HtTmHotelManagerSessions, error)
+	// FetchSessionByRefreshToken 現在もしくは1つ前のリフレッシュトークンに合致するセッションを1件取得
+	FetchSessionByRefreshToken(refreshTokenHash string) (HtTmHotelManagerSessions, error)
+	// RotateRefreshToken セッションのリフレッシュトークンを置き換える
+	RotateRefreshToken(session *HtTmHotelManagerSessions, newRefreshTokenHash string) error
+	// TouchSession セッションの最終利用日時を更新
+	TouchSession(sessionID string) error
+	// FetchActiveSessions 失効していないセッションを複数件取得
+	FetchActiveSessions(hotelManagerID int64) ([]HtTmHotelManagerSessions, error)
+	// RevokeSession セッションを1件失効
+	RevokeSession(hotelManagerID int64, sessionID string) error
+	// RevokeAllSessions アカウントのセッションとトークンをすべて失効
+	RevokeAllSessions(hotelManagerID int64) error
+	// RevokeToken アクセストークンを失効リストに追加
+	RevokeToken(revokedToken *HtThHotelManagerRevokedTokens) error
+	// FetchRoles アカウントに割り当てたロールを複数件取得
+	FetchRoles(hotelManagerID int64) ([]string, error)
+	// ReplaceRoles アカウントのロールを置き換える
+	ReplaceRoles(hotelManagerID int64, roles []string) error
+	// FetchHMUserByPropertyID property_idでHMアカウントを1件取得（スタッフアカウントは除く）
 	FetchHMUserByPropertyID(propertyID int64, wholesalerID int64) (HtTmHotelManager, error)
 	// FetchOne PRIMARY KEYでアカウントを1件取得
 	FetchOne(hotelManagerID int64) (HtTmHotelManager, error)
+	// UpdateLoginFailed ログイン失敗回数とロック期限を更新
+	UpdateLoginFailed(hotelManagerID int64, loginFailedNum int, lockedUntil time.Time) error
+	// ResetLoginFailed ログイン失敗回数とロック期限をクリア
+	ResetLoginFailed(hotelManagerID int64) error
+	// CreateLoginHistory ログイン試行履歴を1件作成
+	CreateLoginHistory(history *HtThHotelManagerLoginHistories) error
+	// CountFailedLoginsByIP 指定日時以降の接続元IPごとのログイン失敗回数を取得（2段階認証の失敗を含む）
+	CountFailedLoginsByIP(ipAddress string, since time.Time) (int64, error)
+	// FetchLoginHistories アカウントのログイン試行履歴を新しい順に複数件取得
+	FetchLoginHistories(hotelManagerID int64, paging *common.Paging) ([]HtThHotelManagerLoginHistories, error)
+	// FetchTwoFactor 2段階認証の設定を取得（未登録の場合はHotelManagerIDが0）
+	FetchTwoFactor(hotelManagerID int64) (HtTmHotelManagerTwoFactors, error)
+	// SaveTwoFactorSecret 登録途中の2段階認証のシークレットを保存
+	SaveTwoFactorSecret(hotelManagerID int64, secretEnc string) error
+	// EnableTwoFactor 2段階認証を有効にし、リカバリーコードを置き換える
+	EnableTwoFactor(hotelManagerID int64, step int64, recoveryCodeHashes []string) error
+	// UpdateTwoFactorLastUsedStep 使用した認証コードのステップを記録（既に使用済みの場合はfalse）
+	UpdateTwoFactorLastUsedStep(hotelManagerID int64, step int64) (bool, error)
+	// DeleteTwoFactor 2段階認証の設定とリカバリーコードを削除
+	DeleteTwoFactor(hotelManagerID int64) error
+	// ReplaceRecoveryCodes リカバリーコードを置き換える
+	ReplaceRecoveryCodes(hotelManagerID int64, recoveryCodeHashes []string) error
+	// UseRecoveryCode 未使用のリカバリーコードを使用済みにする（該当なしの場合はfalse）
+	UseRecoveryCode(hotelManagerID int64, codeHash string) (bool, error)
+	// CountRecoveryCodes 未使用のリカバリーコードの件数
+	CountRecoveryCodes(hotelManagerID int64) (int64, error)
+	// CreateTwoFactorChallenge 認証コードの入力待ちのログインを作成
+	CreateTwoFactorChallenge(challenge *HtThHotelManagerTwoFactorChallenges) error
+	// FetchTwoFactorChallenge 認証コードの入力待ちのログインを1件取得
+	FetchTwoFactorChallenge(challengeTokenHash string) (HtThHotelManagerTwoFactorChallenges, error)
+	// UpdateTwoFactorChallengeFailed 認証コードの失敗回数を加算
+	UpdateTwoFactorChallengeFailed(challengeTokenHash string) error
+	// ConsumeTwoFactorChallenge 認証コードの入力待ちのログインを使用済みにする（既に使用済みの場合はfalse）
+	ConsumeTwoFactorChallenge(challengeTokenHash string) (bool, error)
+	// IsTwoFactorRequiredByParent 同じ取引先の親アカウントが2段階認証を必須にしているか
+	IsTwoFactorRequiredByParent(clientCompanyID int64) (bool, error)
+	// UpdateTwoFactorRequiredFlg 親アカウントの2段階認証必須設定を更新
+	UpdateTwoFactorRequiredFlg(hotelManagerID int64, required bool) error
+	// FetchHMUsersByEmail メールアドレスに合致するアカウントを複数件取得
+	FetchHMUsersByEmail(emailEnc string) ([]HtTmHotelManager, error)
+	// CountPasswordResets 指定日時以降に発行したパスワード再設定URLの件数を取得
+	CountPasswordResets(hotelManagerID int64, since time.Time) (int64, error)
+	// CreatePasswordReset パスワード再設定URLを発行（未使用の発行済みURLは無効にする）
+	CreatePasswordReset(reset *HtThHotelManagerPasswordResets) error
+	// FetchPasswordReset トークンに合致するパスワード再設定URLを1件取得
+	FetchPasswordReset(tokenHash string) (HtThHotelManagerPasswordResets, error)
+	// ResetPasswordByToken パスワード再設定URLを使用済みにし、パスワードの更新・セッションの失効・ログイン失敗回数のリセットをまとめて行う
+	ResetPasswordByToken(tokenHash string, hotelManagerID int64, passwordHash string) error
+	// CountByUsername ログインユーザに合致するアカウントの件数を取得（無効化済みを含む）
+	CountByUsername(usernameEnc string) (int64, error)
+	// FetchTeam 施設・親アカウントと、その配下のスタッフアカウントを複数件取得（無効化済みを含む）
+	FetchTeam(ownerHotelManagerID int64) ([]HtTmHotelManager, error)
+	// CreateInvitation スタッフの招待を作成
+	CreateInvitation(invitation *HtTmHotelManagerInvitations) error
+	// FetchInvitation トークンに合致する招待を1件取得
+	FetchInvitation(tokenHash string) (HtTmHotelManagerInvitations, error)
+	// FetchPendingInvitations 承諾待ちで有効期限内の招待を複数件取得
+	FetchPendingInvitations(ownerHotelManagerID int64) ([]HtTmHotelManagerInvitations, error)
+	// AcceptInvitation 招待を承諾済みにして、スタッフアカウントとロールを作成（既に承諾済みの場合はfalse）
+	AcceptInvitation(tokenHash string, hmUser *HtTmHotelManager, roles []string) (bool, error)
+	// DisableHotelManager アカウントを無効化し、セッションとトークンをすべて失効
+	DisableHotelManager(hotelManagerID int64) error
 }
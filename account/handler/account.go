@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	"github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -13,6 +17,7 @@ import (
 // AccountHandler アカウント関連の振り分け
 type AccountHandler struct {
 	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
 	ANeppanUsecase account.IAccountNeppanUsecase
 	ATemaUsecase   account.IAccountTemaUsecase
 	ARaku2Usecase  account.IAccountRaku2Usecase
@@ -22,6 +27,7 @@ type AccountHandler struct {
 func NewAccountHandler(db *gorm.DB) *AccountHandler {
 	return &AccountHandler{
 		AUsecase:       usecase.NewAccountUsecase(db),
+		OUsecase:       oUsecase.NewOwnershipUsecase(db),
 		ANeppanUsecase: usecase.NewAccountNeppanUsecase(db),
 		ATemaUsecase:   usecase.NewAccountTemaUsecase(db),
 		ARaku2Usecase:  usecase.NewAccountRaku2Usecase(db),
@@ -41,14 +47,22 @@ func (a *AccountHandler) Login(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 	utils.RequestLog(c, request)
+	request.IPAddress = c.RealIP()
+	request.UserAgent = c.Request().UserAgent()
 
-	token, err := a.AUsecase.Login(request)
+	output, err := a.AUsecase.Login(request)
 	if err != nil {
 		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrAccountLocked) {
+			return echo.NewHTTPError(http.StatusLocked, "account locked")
+		}
+		if errors.Is(err, account.ErrPasswordResetRequired) {
+			return echo.NewHTTPError(http.StatusForbidden, "password reset required")
+		}
 		return echo.ErrUnauthorized
 	}
 
-	return c.JSON(http.StatusOK, account.TokenOutput{APIToken: token})
+	return c.JSON(http.StatusOK, output)
 }
 
 // Logout ログアウト
@@ -57,7 +71,9 @@ func (a *AccountHandler) Logout(c echo.Context) error {
 	if ClaimParamErr != nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
-	a.AUsecase.Logout(claimParam)
+	if err := a.AUsecase.Logout(claimParam); err != nil {
+		c.Echo().Logger.Error(err)
+	}
 	return c.NoContent(http.StatusOK)
 }
 
@@ -69,12 +85,74 @@ func (a *AccountHandler) CheckToken(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
 
-	token, err := a.AUsecase.CheckToken(claimParam)
+	output, err := a.AUsecase.CheckToken(claimParam)
 	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
-	return c.JSON(http.StatusOK, account.TokenOutput{APIToken: token})
+	return c.JSON(http.StatusOK, output)
+}
+
+// Refresh リフレッシュトークンでアクセストークンを再発行
+// セキュリティ上、無効な問い合わせはすべてUnauthorizedで返す
+func (a *AccountHandler) Refresh(c echo.Context) error {
+	request := &account.RefreshInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	request.IPAddress = c.RealIP()
+	request.UserAgent = c.Request().UserAgent()
+
+	output, err := a.AUsecase.Refresh(request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired refresh token")
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// Sessions ログイン中の端末一覧
+func (a *AccountHandler) Sessions(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+
+	sessions, err := a.AUsecase.FetchSessions(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession 指定した端末のログアウト
+func (a *AccountHandler) RevokeSession(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.RevokeSessionInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.RevokeSession(claimParam, request.SessionID); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrNotFound
+	}
+	return c.NoContent(http.StatusOK)
 }
 
 // AccountDetail ログイン中のHMアカウント情報を取得
@@ -104,13 +182,31 @@ func (a *AccountHandler) ChangePassword(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 	utils.RequestLog(c, request)
+	request.IPAddress = c.RealIP()
+	request.UserAgent = c.Request().UserAgent()
 	if err := a.AUsecase.ChangePassword(request); err != nil {
 		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrAccountLocked) {
+			return echo.NewHTTPError(http.StatusLocked, "account locked")
+		}
+		if errors.Is(err, account.ErrPasswordPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
 		return echo.ErrUnauthorized
 	}
 	return c.NoContent(http.StatusOK)
 }
 
+// ForcePasswordReset ハッシュ未移行のアカウントにパスワード再設定を要求する（運用向け）
+func (a *AccountHandler) ForcePasswordReset(c echo.Context) error {
+	count, err := a.AUsecase.ForcePasswordResetUnmigrated()
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"count": count})
+}
+
 // CheckConnect ホールセラー接続用のユーザがあるかどうか
 func (a *AccountHandler) CheckConnect(c echo.Context) error {
 	claimParam, err := utils.GetHmUser(c)
@@ -118,7 +214,7 @@ func (a *AccountHandler) CheckConnect(c echo.Context) error {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
-	_, fErr := a.AUsecase.FetchHMUserByToken(claimParam)
+	hmUser, fErr := a.AUsecase.FetchHMUserByToken(claimParam)
 	if fErr != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -134,6 +230,11 @@ func (a *AccountHandler) CheckConnect(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := a.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch request.WholesalerID {
 	case utils.WholesalerIDTema:
 		return c.JSON(http.StatusOK, a.ATemaUsecase.FetchConnectUser(request))
@@ -161,3 +262,97 @@ func (a *AccountHandler) IsParentAccount(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, a.AUsecase.IsParentAccount(request.HotelManagerID))
 }
+
+// Unlock 親アカウントによる子アカウントのロック解除
+func (a *AccountHandler) Unlock(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := a.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.UnlockInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.UnlockChild(&hmUser, request.HotelManagerID); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// UnlockInternal 運用によるアカウントのロック解除（内部APIキーで保護）
+func (a *AccountHandler) UnlockInternal(c echo.Context) error {
+	request := &account.UnlockInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.Unlock(request.HotelManagerID); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// LoginHistories ログイン中のアカウントのログイン試行履歴
+func (a *AccountHandler) LoginHistories(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &common.Paging{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	histories, err := a.AUsecase.FetchLoginHistories(claimParam, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	return c.JSON(http.StatusOK, histories)
+}
+
+// AssignRoles 同じ施設・取引先のアカウントへのロール割り当て
+func (a *AccountHandler) AssignRoles(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.AssignRolesInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.AssignRoles(claimParam, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestPasswordReset パスワード再設定メールの送信
+// アカウントの有無を推測させないため、該当アカウントがない・送信に失敗した場合も同じ応答を返す
+func (a *AccountHandler) RequestPasswordReset(c echo.Context) error {
+	request := &account.PasswordResetRequestInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	request.IPAddress = c.RealIP()
+
+	// 送信に失敗した場合もエラーを返すとアカウントがあると分かるため、ログにのみ残す
+	if err := a.AUsecase.RequestPasswordReset(request); err != nil {
+		c.Echo().Logger.Error(err)
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ResetPassword パスワード再設定URLからの新しいパスワードの設定
+func (a *AccountHandler) ResetPassword(c echo.Context) error {
+	request := &account.ResetPasswordInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := a.AUsecase.ResetPassword(request); err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrPasswordResetInvalid) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid or expired reset token")
+		}
+		if errors.Is(err, account.ErrPasswordPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.NoContent(http.StatusOK)
+}
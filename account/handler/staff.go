@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// InviteStaff スタッフの招待
+func (a *AccountHandler) InviteStaff(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.InviteStaffInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.InviteStaff(claimParam, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// AcceptInvitation 招待の承諾とスタッフアカウントの作成
+func (a *AccountHandler) AcceptInvitation(c echo.Context) error {
+	request := &account.AcceptInvitationInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := a.AUsecase.AcceptInvitation(request); err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrInvitationInvalid) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid or expired invitation")
+		}
+		if errors.Is(err, account.ErrUsernameTaken) {
+			return echo.NewHTTPError(http.StatusConflict, "username already taken")
+		}
+		if errors.Is(err, account.ErrPasswordPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// DisableStaff スタッフアカウントの無効化
+func (a *AccountHandler) DisableStaff(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.DisableStaffInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.DisableStaff(claimParam, request.HotelManagerID); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// Team 施設・親アカウントとスタッフアカウントの一覧
+func (a *AccountHandler) Team(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+
+	output, err := a.AUsecase.FetchTeam(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, output)
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// LoginTwoFactor ログイン2段階目（認証コードもしくはリカバリーコードの確認）
+// セキュリティ上、無効な問い合わせはすべてUnauthorizedで返す
+func (a *AccountHandler) LoginTwoFactor(c echo.Context) error {
+	request := &account.TwoFactorLoginInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	request.IPAddress = c.RealIP()
+	request.UserAgent = c.Request().UserAgent()
+
+	output, err := a.AUsecase.VerifyTwoFactorLogin(request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrUnauthorized
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// EnrollTwoFactorOnLogin 2段階認証が必須で未登録の場合に、ログイン中に登録を始める
+func (a *AccountHandler) EnrollTwoFactorOnLogin(c echo.Context) error {
+	request := &account.TwoFactorChallengeInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	output, err := a.AUsecase.EnrollTwoFactorByChallenge(request.ChallengeToken)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrUnauthorized
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// TwoFactorStatus 2段階認証の設定状況
+func (a *AccountHandler) TwoFactorStatus(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+
+	output, err := a.AUsecase.FetchTwoFactorStatus(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// EnrollTwoFactor 2段階認証の登録開始
+func (a *AccountHandler) EnrollTwoFactor(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+
+	output, err := a.AUsecase.EnrollTwoFactor(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// ActivateTwoFactor 認証コードを確認して2段階認証を有効にする
+func (a *AccountHandler) ActivateTwoFactor(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.TwoFactorCodeInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	output, err := a.AUsecase.ActivateTwoFactor(claimParam, request.Code)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrTwoFactorInvalid) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid code")
+		}
+		return echo.ErrBadRequest
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// DisableTwoFactor 2段階認証の解除
+func (a *AccountHandler) DisableTwoFactor(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.DisableTwoFactorInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := a.AUsecase.DisableTwoFactor(claimParam, request); err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrTwoFactorRequired) {
+			return echo.NewHTTPError(http.StatusForbidden, "two factor authentication required")
+		}
+		if errors.Is(err, account.ErrTwoFactorInvalid) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid password or code")
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// RegenerateRecoveryCodes リカバリーコードの再発行
+func (a *AccountHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.TwoFactorCodeInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	output, err := a.AUsecase.RegenerateRecoveryCodes(claimParam, request.Code)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, account.ErrTwoFactorInvalid) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid code")
+		}
+		return echo.ErrBadRequest
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// UpdateTwoFactorPolicy 子アカウントへの2段階認証必須設定（親アカウントのみ）
+func (a *AccountHandler) UpdateTwoFactorPolicy(c echo.Context) error {
+	claimParam, ClaimParamErr := utils.GetHmUser(c)
+	if ClaimParamErr != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &account.TwoFactorPolicyInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.AUsecase.UpdateTwoFactorPolicy(claimParam, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -1,6 +1,7 @@
 package infra
 
 import (
+	"errors"
 	"time"
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
@@ -20,28 +21,43 @@ func NewAccountRepository(db *gorm.DB) account.IAccountRepository {
 	}
 }
 
-// FetchHMUserByLoginInfo ログインユーザとパスワードに合致するアカウントを1件取得
-func (a *accountRepository) FetchHMUserByLoginInfo(hmUser *account.HtTmHotelManager) account.HtTmHotelManager {
+// FetchHMUserByUsername ログインユーザに合致するアカウントを1件取得
+func (a *accountRepository) FetchHMUserByUsername(usernameEnc string) (account.HtTmHotelManager, error) {
 	result := account.HtTmHotelManager{}
-	a.db.Where("username_enc = ? AND password_enc = ? AND del_flg = 0", hmUser.UsernameEnc, hmUser.PasswordEnc).First(&result)
-	return result
+	err := a.db.
+		Where("username_enc = ? AND del_flg = 0", usernameEnc).
+		First(&result).Error
+	return result, err
 }
 
 // FetchHMUserByToken トークンからアカウントを1件取得
+// セッションが失効している、もしくはトークン自体が失効リストにある場合は取得できない
 func (a *accountRepository) FetchHMUserByToken(claimParam *account.ClaimParam) (account.HtTmHotelManager, error) {
 	result := account.HtTmHotelManager{}
+	if claimParam.SessionID == "" {
+		// セッション導入前に発行されたトークン
+		err := a.db.Table("ht_tm_hotel_managers").
+			Joins("INNER JOIN ht_tm_hotel_manager_tokens ON ht_tm_hotel_managers.hotel_manager_id = ht_tm_hotel_manager_tokens.hotel_manager_id").
+			Where("ht_tm_hotel_manager_tokens.api_token = ?", claimParam.APIToken).
+			Where("ht_tm_hotel_managers.hotel_manager_id = ? AND ht_tm_hotel_managers.del_flg = 0", claimParam.HotelManagerID).
+			First(&result).Error
+		return result, err
+	}
 	err := a.db.Table("ht_tm_hotel_managers").
-		Joins("INNER JOIN ht_tm_hotel_manager_tokens ON ht_tm_hotel_managers.hotel_manager_id = ht_tm_hotel_manager_tokens.hotel_manager_id").
-		Where("ht_tm_hotel_manager_tokens.api_token = ?", claimParam.APIToken).
+		Select("ht_tm_hotel_managers.*").
+		Joins("INNER JOIN ht_tm_hotel_manager_sessions ON ht_tm_hotel_managers.hotel_manager_id = ht_tm_hotel_manager_sessions.hotel_manager_id").
+		Where("ht_tm_hotel_manager_sessions.session_id = ?", claimParam.SessionID).
+		Where("ht_tm_hotel_manager_sessions.revoked_at IS NULL AND ht_tm_hotel_manager_sessions.expires_at > ?", time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM ht_th_hotel_manager_revoked_tokens WHERE ht_th_hotel_manager_revoked_tokens.token_id = ?)", claimParam.TokenID).
 		Where("ht_tm_hotel_managers.hotel_manager_id = ? AND ht_tm_hotel_managers.del_flg = 0", claimParam.HotelManagerID).
 		First(&result).Error
 	return result, err
 }
 
-// SaveLoginInfo ログイン日時とトークンを更新
-func (a *accountRepository) SaveLoginInfo(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, newToken string) error {
+// SaveLoginInfo ログイン日時を更新し、セッションを作成
+func (a *accountRepository) SaveLoginInfo(hmUser *account.HtTmHotelManager, session *account.HtTmHotelManagerSessions) error {
 	return a.db.Transaction(func(tx *gorm.DB) error {
-		if err := a.db.Model(account.HtTmHotelManager{}).
+		if err := tx.Model(account.HtTmHotelManager{}).
 			Where("hotel_manager_id = ?", hmUser.HotelManagerID).
 			Updates(map[string]interface{}{
 				"logined_at": hmUser.LoginedAt,
@@ -49,37 +65,182 @@ func (a *accountRepository) SaveLoginInfo(hmUser *account.HtTmHotelManager, clai
 			}).Error; err != nil {
 			return err
 		}
-		return a.db.
-			Where(account.HtTmHotelManagerTokens{HotelManagerID: claimParam.HotelManagerID, APIToken: claimParam.APIToken}).
-			Assign(account.HtTmHotelManagerTokens{
-				APIToken: newToken,
-				Times:    common.Times{UpdatedAt: time.Now()},
-			}).
-			FirstOrCreate(&account.HtTmHotelManagerTokens{}).Error
+		return tx.Create(session).Error
+	})
+}
+
+// UpdatePassword パスワードのハッシュを更新し、変更履歴を追加
+func (a *accountRepository) UpdatePassword(hotelManagerID int64, passwordHash string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		return updatePassword(tx, hotelManagerID, passwordHash, time.Now())
 	})
 }
 
-// UpdatePassword パスワードを更新
-func (a *accountRepository) UpdatePassword(hotelManagerID int64, password string) error {
+// updatePassword パスワードのハッシュを更新し、変更履歴を追加（トランザクション内で呼び出す）
+func updatePassword(tx *gorm.DB, hotelManagerID int64, passwordHash string, now time.Time) error {
+	if err := tx.Model(account.HtTmHotelManager{}).
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Updates(map[string]interface{}{
+			"password_enc":        "",
+			"password_hash":       passwordHash,
+			"password_reset_flg":  false,
+			"password_changed_at": now,
+			"updated_at":          now,
+		}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&account.HtThHotelManagerPasswordHistories{
+		HotelManagerID: hotelManagerID,
+		PasswordHash:   passwordHash,
+		Times:          common.Times{CreatedAt: now, UpdatedAt: now},
+	}).Error
+}
+
+// MigratePassword 旧形式（暗号化）のパスワードをハッシュに置き換える
+// パスワード自体は変わらないので、変更日時と再設定フラグはそのままにする
+func (a *accountRepository) MigratePassword(hotelManagerID int64, passwordHash string) error {
 	return a.db.Model(account.HtTmHotelManager{}).
 		Where("hotel_manager_id = ?", hotelManagerID).
+		Where("password_hash = ''").
 		Updates(map[string]interface{}{
-			"password_enc": password,
-			"updated_at":   time.Now(),
+			"password_enc":  "",
+			"password_hash": passwordHash,
+			"updated_at":    time.Now(),
 		}).Error
 }
 
-// DeleteAPIToken トークン削除
+// FetchPasswordHistories 直近のパスワード変更履歴を指定件数取得
+func (a *accountRepository) FetchPasswordHistories(hotelManagerID int64, limit int) ([]account.HtThHotelManagerPasswordHistories, error) {
+	result := []account.HtThHotelManagerPasswordHistories{}
+	err := a.db.
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
+
+// FlagUnmigratedPasswordReset ハッシュ未移行のアカウントにパスワード再設定フラグを立てる
+func (a *accountRepository) FlagUnmigratedPasswordReset() (int64, error) {
+	result := a.db.Model(account.HtTmHotelManager{}).
+		Where("password_hash = '' AND password_reset_flg = 0 AND del_flg = 0").
+		Updates(map[string]interface{}{
+			"password_reset_flg": true,
+			"updated_at":         time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteAPIToken セッション導入前に発行したトークンを削除
 func (a *accountRepository) DeleteAPIToken(claimParam *account.ClaimParam) error {
 	return a.db.Delete(&account.HtTmHotelManagerTokens{}, "hotel_manager_id = ? AND api_token = ?", claimParam.HotelManagerID, claimParam.APIToken).Error
 }
 
-// FetchHMUserByPropertyID property_idでHMアカウントを1件取得
+// FetchSession セッションIDでセッションを1件取得
+func (a *accountRepository) FetchSession(sessionID string) (account.HtTmHotelManagerSessions, error) {
+	result := account.HtTmHotelManagerSessions{}
+	err := a.db.
+		Where("session_id = ?", sessionID).
+		First(&result).Error
+	return result, err
+}
+
+// FetchSessionByRefreshToken 現在もしくは1つ前のリフレッシュトークンに合致するセッションを1件取得
+func (a *accountRepository) FetchSessionByRefreshToken(refreshTokenHash string) (account.HtTmHotelManagerSessions, error) {
+	result := account.HtTmHotelManagerSessions{}
+	err := a.db.
+		Where("refresh_token_hash = ? OR previous_refresh_token_hash = ?", refreshTokenHash, refreshTokenHash).
+		First(&result).Error
+	return result, err
+}
+
+// RotateRefreshToken セッションのリフレッシュトークンを置き換える
+// 同じリフレッシュトークンで同時に更新された場合は、先に更新した方だけを有効にする
+func (a *accountRepository) RotateRefreshToken(session *account.HtTmHotelManagerSessions, newRefreshTokenHash string) error {
+	result := a.db.Model(account.HtTmHotelManagerSessions{}).
+		Where("session_id = ? AND refresh_token_hash = ?", session.SessionID, session.RefreshTokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":          newRefreshTokenHash,
+			"previous_refresh_token_hash": session.RefreshTokenHash,
+			"ip_address":                  session.IPAddress,
+			"user_agent":                  session.UserAgent,
+			"last_used_at":                session.LastUsedAt,
+			"updated_at":                  time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TouchSession セッションの最終利用日時を更新
+func (a *accountRepository) TouchSession(sessionID string) error {
+	return a.db.Model(account.HtTmHotelManagerSessions{}).
+		Where("session_id = ?", sessionID).
+		Updates(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"updated_at":   time.Now(),
+		}).Error
+}
+
+// FetchActiveSessions 失効していないセッションを複数件取得
+func (a *accountRepository) FetchActiveSessions(hotelManagerID int64) ([]account.HtTmHotelManagerSessions, error) {
+	result := []account.HtTmHotelManagerSessions{}
+	err := a.db.
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Order("last_used_at DESC").
+		Find(&result).Error
+	return result, err
+}
+
+// RevokeSession セッションを1件失効
+func (a *accountRepository) RevokeSession(hotelManagerID int64, sessionID string) error {
+	result := a.db.Model(account.HtTmHotelManagerSessions{}).
+		Where("hotel_manager_id = ? AND session_id = ? AND revoked_at IS NULL", hotelManagerID, sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions アカウントのセッションとトークンをすべて失効
+func (a *accountRepository) RevokeAllSessions(hotelManagerID int64) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account.HtTmHotelManagerSessions{}).
+			Where("hotel_manager_id = ? AND revoked_at IS NULL", hotelManagerID).
+			Updates(map[string]interface{}{
+				"revoked_at": time.Now(),
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&account.HtTmHotelManagerTokens{}, "hotel_manager_id = ?", hotelManagerID).Error
+	})
+}
+
+// RevokeToken アクセストークンを失効リストに追加
+func (a *accountRepository) RevokeToken(revokedToken *account.HtThHotelManagerRevokedTokens) error {
+	return a.db.Create(revokedToken).Error
+}
+
+// FetchHMUserByPropertyID property_idでHMアカウントを1件取得（スタッフアカウントは除く）
 func (a *accountRepository) FetchHMUserByPropertyID(propertyID int64, wholesalerID int64) (account.HtTmHotelManager, error) {
 	result := account.HtTmHotelManager{}
 	err := a.db.
 		Where("property_id = ? AND del_flg = 0", propertyID).
 		Where("wholesaler_id = ?", wholesalerID).
+		Where("owner_hotel_manager_id = 0").
 		First(&result).Error
 	return result, err
 }
@@ -92,3 +253,420 @@ func (a *accountRepository) FetchOne(hotelManagerID int64) (account.HtTmHotelMan
 		First(&result).Error
 	return result, err
 }
+
+// UpdateLoginFailed ログイン失敗回数とロック期限を更新
+func (a *accountRepository) UpdateLoginFailed(hotelManagerID int64, loginFailedNum int, lockedUntil time.Time) error {
+	updates := map[string]interface{}{
+		"login_failed_num": loginFailedNum,
+		"updated_at":       time.Now(),
+	}
+	if lockedUntil.IsZero() == false {
+		updates["locked_until"] = lockedUntil
+	}
+	return a.db.Model(account.HtTmHotelManager{}).
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Updates(updates).Error
+}
+
+// ResetLoginFailed ログイン失敗回数とロック期限をクリア
+func (a *accountRepository) ResetLoginFailed(hotelManagerID int64) error {
+	return a.db.Model(account.HtTmHotelManager{}).
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Updates(map[string]interface{}{
+			"login_failed_num": 0,
+			"locked_until":     nil,
+			"updated_at":       time.Now(),
+		}).Error
+}
+
+// CreateLoginHistory ログイン試行履歴を1件作成
+func (a *accountRepository) CreateLoginHistory(history *account.HtThHotelManagerLoginHistories) error {
+	return a.db.Create(history).Error
+}
+
+// CountFailedLoginsByIP 指定日時以降の接続元IPごとのログイン失敗回数を取得（2段階認証の失敗を含む）
+func (a *accountRepository) CountFailedLoginsByIP(ipAddress string, since time.Time) (int64, error) {
+	var count int64
+	err := a.db.Model(&account.HtThHotelManagerLoginHistories{}).
+		Where("ip_address = ?", ipAddress).
+		Where("result IN ?", []string{account.LoginResultFailure, account.LoginResultTwoFactorFailure}).
+		Where("attempted_at >= ?", since).
+		Count(&count).Error
+	return count, err
+}
+
+// FetchLoginHistories アカウントのログイン試行履歴を新しい順に複数件取得
+func (a *accountRepository) FetchLoginHistories(hotelManagerID int64, paging *common.Paging) ([]account.HtThHotelManagerLoginHistories, error) {
+	result := []account.HtThHotelManagerLoginHistories{}
+	query := a.db.
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Order("attempted_at DESC")
+	if paging.Limit > 0 {
+		query = query.Limit(paging.Limit).Offset(paging.Offset)
+	}
+	err := query.Find(&result).Error
+	return result, err
+}
+
+// FetchRoles アカウントに割り当てたロールを複数件取得
+func (a *accountRepository) FetchRoles(hotelManagerID int64) ([]string, error) {
+	result := []string{}
+	err := a.db.Model(&account.HtTmHotelManagerRoles{}).
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Pluck("role", &result).Error
+	return result, err
+}
+
+// ReplaceRoles アカウントのロールを置き換える
+func (a *accountRepository) ReplaceRoles(hotelManagerID int64, roles []string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&account.HtTmHotelManagerRoles{}, "hotel_manager_id = ?", hotelManagerID).Error; err != nil {
+			return err
+		}
+		insertData := []account.HtTmHotelManagerRoles{}
+		for _, role := range roles {
+			insertData = append(insertData, account.HtTmHotelManagerRoles{
+				HotelManagerID: hotelManagerID,
+				Role:           role,
+				Times:          common.Times{CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			})
+		}
+		return tx.Create(&insertData).Error
+	})
+}
+
+// FetchTwoFactor 2段階認証の設定を取得（未登録の場合はHotelManagerIDが0）
+func (a *accountRepository) FetchTwoFactor(hotelManagerID int64) (account.HtTmHotelManagerTwoFactors, error) {
+	result := account.HtTmHotelManagerTwoFactors{}
+	err := a.db.
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Limit(1).
+		Find(&result).Error
+	return result, err
+}
+
+// SaveTwoFactorSecret 登録途中の2段階認証のシークレットを保存
+func (a *accountRepository) SaveTwoFactorSecret(hotelManagerID int64, secretEnc string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&account.HtTmHotelManagerTwoFactors{}, "hotel_manager_id = ? AND enabled_flg = 0", hotelManagerID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&account.HtTmHotelManagerTwoFactors{
+			HotelManagerID: hotelManagerID,
+			SecretEnc:      secretEnc,
+			Times:          common.Times{CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		}).Error
+	})
+}
+
+// EnableTwoFactor 2段階認証を有効にし、リカバリーコードを置き換える
+func (a *accountRepository) EnableTwoFactor(hotelManagerID int64, step int64, recoveryCodeHashes []string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&account.HtTmHotelManagerTwoFactors{}).
+			Where("hotel_manager_id = ? AND enabled_flg = 0", hotelManagerID).
+			Updates(map[string]interface{}{
+				"enabled_flg":    true,
+				"enabled_at":     time.Now(),
+				"last_used_step": step,
+				"updated_at":     time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, hotelManagerID, recoveryCodeHashes)
+	})
+}
+
+// UpdateTwoFactorLastUsedStep 使用した認証コードのステップを記録（既に使用済みの場合はfalse）
+func (a *accountRepository) UpdateTwoFactorLastUsedStep(hotelManagerID int64, step int64) (bool, error) {
+	result := a.db.Model(&account.HtTmHotelManagerTwoFactors{}).
+		Where("hotel_manager_id = ? AND last_used_step < ?", hotelManagerID, step).
+		Updates(map[string]interface{}{
+			"last_used_step": step,
+			"updated_at":     time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteTwoFactor 2段階認証の設定とリカバリーコードを削除
+func (a *accountRepository) DeleteTwoFactor(hotelManagerID int64) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&account.HtTmHotelManagerRecoveryCodes{}, "hotel_manager_id = ?", hotelManagerID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&account.HtTmHotelManagerTwoFactors{}, "hotel_manager_id = ?", hotelManagerID).Error
+	})
+}
+
+// ReplaceRecoveryCodes リカバリーコードを置き換える
+func (a *accountRepository) ReplaceRecoveryCodes(hotelManagerID int64, recoveryCodeHashes []string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, hotelManagerID, recoveryCodeHashes)
+	})
+}
+
+// replaceRecoveryCodes トランザクション内でリカバリーコードを置き換える
+func replaceRecoveryCodes(tx *gorm.DB, hotelManagerID int64, recoveryCodeHashes []string) error {
+	if err := tx.Delete(&account.HtTmHotelManagerRecoveryCodes{}, "hotel_manager_id = ?", hotelManagerID).Error; err != nil {
+		return err
+	}
+	insertData := []account.HtTmHotelManagerRecoveryCodes{}
+	for _, codeHash := range recoveryCodeHashes {
+		insertData = append(insertData, account.HtTmHotelManagerRecoveryCodes{
+			HotelManagerID: hotelManagerID,
+			CodeHash:       codeHash,
+			Times:          common.Times{CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		})
+	}
+	return tx.Create(&insertData).Error
+}
+
+// UseRecoveryCode 未使用のリカバリーコードを使用済みにする（該当なしの場合はfalse）
+func (a *accountRepository) UseRecoveryCode(hotelManagerID int64, codeHash string) (bool, error) {
+	result := a.db.Model(&account.HtTmHotelManagerRecoveryCodes{}).
+		Where("hotel_manager_id = ? AND code_hash = ? AND used_at IS NULL", hotelManagerID, codeHash).
+		Limit(1).
+		Updates(map[string]interface{}{
+			"used_at":    time.Now(),
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes 未使用のリカバリーコードの件数
+func (a *accountRepository) CountRecoveryCodes(hotelManagerID int64) (int64, error) {
+	var count int64
+	err := a.db.Model(&account.HtTmHotelManagerRecoveryCodes{}).
+		Where("hotel_manager_id = ? AND used_at IS NULL", hotelManagerID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateTwoFactorChallenge 認証コードの入力待ちのログインを作成
+func (a *accountRepository) CreateTwoFactorChallenge(challenge *account.HtThHotelManagerTwoFactorChallenges) error {
+	return a.db.Create(challenge).Error
+}
+
+// FetchTwoFactorChallenge 認証コードの入力待ちのログインを1件取得
+func (a *accountRepository) FetchTwoFactorChallenge(challengeTokenHash string) (account.HtThHotelManagerTwoFactorChallenges, error) {
+	result := account.HtThHotelManagerTwoFactorChallenges{}
+	err := a.db.
+		Where("challenge_token_hash = ?", challengeTokenHash).
+		First(&result).Error
+	return result, err
+}
+
+// UpdateTwoFactorChallengeFailed 認証コードの失敗回数を加算
+func (a *accountRepository) UpdateTwoFactorChallengeFailed(challengeTokenHash string) error {
+	return a.db.Model(&account.HtThHotelManagerTwoFactorChallenges{}).
+		Where("challenge_token_hash = ?", challengeTokenHash).
+		Update("failed_num", gorm.Expr("failed_num + 1")).Error
+}
+
+// ConsumeTwoFactorChallenge 認証コードの入力待ちのログインを使用済みにする（既に使用済みの場合はfalse）
+func (a *accountRepository) ConsumeTwoFactorChallenge(challengeTokenHash string) (bool, error) {
+	result := a.db.Model(&account.HtThHotelManagerTwoFactorChallenges{}).
+		Where("challenge_token_hash = ? AND used_at IS NULL", challengeTokenHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// IsTwoFactorRequiredByParent 同じ取引先の親アカウントが2段階認証を必須にしているか
+func (a *accountRepository) IsTwoFactorRequiredByParent(clientCompanyID int64) (bool, error) {
+	var count int64
+	err := a.db.Model(&account.HtTmHotelManager{}).
+		Where("client_company_id = ? AND property_id = 0 AND del_flg = 0", clientCompanyID).
+		Where("two_factor_required_flg = 1").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateTwoFactorRequiredFlg 親アカウントの2段階認証必須設定を更新
+func (a *accountRepository) UpdateTwoFactorRequiredFlg(hotelManagerID int64, required bool) error {
+	return a.db.Model(&account.HtTmHotelManager{}).
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Updates(map[string]interface{}{
+			"two_factor_required_flg": required,
+			"updated_at":              time.Now(),
+		}).Error
+}
+
+// FetchHMUsersByEmail メールアドレスに合致するアカウントを複数件取得
+func (a *accountRepository) FetchHMUsersByEmail(emailEnc string) ([]account.HtTmHotelManager, error) {
+	result := []account.HtTmHotelManager{}
+	err := a.db.
+		Where("email_enc = ? AND del_flg = 0", emailEnc).
+		Find(&result).Error
+	return result, err
+}
+
+// CountPasswordResets 指定日時以降に発行したパスワード再設定URLの件数を取得
+func (a *accountRepository) CountPasswordResets(hotelManagerID int64, since time.Time) (int64, error) {
+	var count int64
+	err := a.db.Model(&account.HtThHotelManagerPasswordResets{}).
+		Where("hotel_manager_id = ? AND created_at >= ?", hotelManagerID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CreatePasswordReset パスワード再設定URLを発行（未使用の発行済みURLは無効にする）
+func (a *accountRepository) CreatePasswordReset(reset *account.HtThHotelManagerPasswordResets) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account.HtThHotelManagerPasswordResets{}).
+			Where("hotel_manager_id = ? AND used_at IS NULL", reset.HotelManagerID).
+			Update("expires_at", reset.CreatedAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+// FetchPasswordReset トークンに合致するパスワード再設定URLを1件取得
+func (a *accountRepository) FetchPasswordReset(tokenHash string) (account.HtThHotelManagerPasswordResets, error) {
+	result := account.HtThHotelManagerPasswordResets{}
+	err := a.db.
+		Where("token_hash = ?", tokenHash).
+		First(&result).Error
+	return result, err
+}
+
+// ResetPasswordByToken パスワード再設定URLを使用済みにし、パスワードの更新・セッションの失効・ログイン失敗回数のリセットをまとめて行う
+// 使用済み・期限切れのURLの場合は account.ErrPasswordResetInvalid を返し、何も更新しない
+func (a *accountRepository) ResetPasswordByToken(tokenHash string, hotelManagerID int64, passwordHash string) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		consumed := tx.Model(&account.HtThHotelManagerPasswordResets{}).
+			Where("token_hash = ? AND hotel_manager_id = ?", tokenHash, hotelManagerID).
+			Where("used_at IS NULL AND expires_at > ?", now).
+			Update("used_at", now)
+		if consumed.Error != nil {
+			return consumed.Error
+		}
+		// 同じURLで同時に再設定された場合は、先に更新した方だけを有効にする
+		if consumed.RowsAffected == 0 {
+			return account.ErrPasswordResetInvalid
+		}
+		if err := updatePassword(tx, hotelManagerID, passwordHash, now); err != nil {
+			return err
+		}
+		if err := tx.Model(account.HtTmHotelManagerSessions{}).
+			Where("hotel_manager_id = ? AND revoked_at IS NULL", hotelManagerID).
+			Updates(map[string]interface{}{
+				"revoked_at": now,
+				"updated_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&account.HtTmHotelManagerTokens{}, "hotel_manager_id = ?", hotelManagerID).Error; err != nil {
+			return err
+		}
+		return tx.Model(account.HtTmHotelManager{}).
+			Where("hotel_manager_id = ?", hotelManagerID).
+			Updates(map[string]interface{}{
+				"login_failed_num": 0,
+				"locked_until":     nil,
+				"updated_at":       now,
+			}).Error
+	})
+}
+
+// CountByUsername ログインユーザに合致するアカウントの件数を取得（無効化済みを含む）
+func (a *accountRepository) CountByUsername(usernameEnc string) (int64, error) {
+	var count int64
+	err := a.db.Model(&account.HtTmHotelManager{}).
+		Where("username_enc = ?", usernameEnc).
+		Count(&count).Error
+	return count, err
+}
+
+// FetchTeam 施設・親アカウントと、その配下のスタッフアカウントを複数件取得（無効化済みを含む）
+func (a *accountRepository) FetchTeam(ownerHotelManagerID int64) ([]account.HtTmHotelManager, error) {
+	result := []account.HtTmHotelManager{}
+	err := a.db.
+		Where("hotel_manager_id = ? OR owner_hotel_manager_id = ?", ownerHotelManagerID, ownerHotelManagerID).
+		Order("hotel_manager_id").
+		Find(&result).Error
+	return result, err
+}
+
+// CreateInvitation スタッフの招待を作成
+func (a *accountRepository) CreateInvitation(invitation *account.HtTmHotelManagerInvitations) error {
+	return a.db.Create(invitation).Error
+}
+
+// FetchInvitation トークンに合致する招待を1件取得
+func (a *accountRepository) FetchInvitation(tokenHash string) (account.HtTmHotelManagerInvitations, error) {
+	result := account.HtTmHotelManagerInvitations{}
+	err := a.db.
+		Where("token_hash = ?", tokenHash).
+		First(&result).Error
+	return result, err
+}
+
+// FetchPendingInvitations 承諾待ちで有効期限内の招待を複数件取得
+func (a *accountRepository) FetchPendingInvitations(ownerHotelManagerID int64) ([]account.HtTmHotelManagerInvitations, error) {
+	result := []account.HtTmHotelManagerInvitations{}
+	err := a.db.
+		Where("owner_hotel_manager_id = ? AND accepted_at IS NULL", ownerHotelManagerID).
+		Where("expires_at > ?", time.Now()).
+		Order("invitation_id").
+		Find(&result).Error
+	return result, err
+}
+
+// AcceptInvitation 招待を承諾済みにして、スタッフアカウントとロールを作成（既に承諾済みの場合はfalse）
+func (a *accountRepository) AcceptInvitation(tokenHash string, hmUser *account.HtTmHotelManager, roles []string) (bool, error) {
+	accepted := false
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(hmUser).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&account.HtTmHotelManagerInvitations{}).
+			Where("token_hash = ? AND accepted_at IS NULL", tokenHash).
+			Updates(map[string]interface{}{
+				"accepted_at":               time.Now(),
+				"accepted_hotel_manager_id": hmUser.HotelManagerID,
+				"updated_at":                time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 同時に承諾された場合は、作成したアカウントごと取り消す
+			return gorm.ErrRecordNotFound
+		}
+		insertData := []account.HtTmHotelManagerRoles{}
+		for _, role := range roles {
+			insertData = append(insertData, account.HtTmHotelManagerRoles{
+				HotelManagerID: hmUser.HotelManagerID,
+				Role:           role,
+				Times:          common.Times{CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			})
+		}
+		if err := tx.Create(&insertData).Error; err != nil {
+			return err
+		}
+		accepted = true
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return accepted, err
+}
+
+// DisableHotelManager アカウントを無効化し、セッションとトークンをすべて失効
+func (a *accountRepository) DisableHotelManager(hotelManagerID int64) error {
+	if err := a.db.Model(&account.HtTmHotelManager{}).
+		Where("hotel_manager_id = ?", hotelManagerID).
+		Updates(map[string]interface{}{
+			"del_flg":    true,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+	return a.RevokeAllSessions(hotelManagerID)
+}
@@ -0,0 +1,41 @@
+package account
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPasswordResetInvalid パスワード再設定のトークンが正しくないか、有効期限が切れている
+var ErrPasswordResetInvalid = errors.New("Error: パスワード再設定のURLが正しくないか、有効期限が切れています。")
+
+const (
+	// PasswordResetTTL パスワード再設定URLの有効期限
+	PasswordResetTTL = 30 * time.Minute
+	// PasswordResetRequestLimit 1アカウントあたり、PasswordResetRequestWindowの間に送信する再設定メールの上限
+	PasswordResetRequestLimit = 3
+	// PasswordResetRequestWindow 再設定メールの送信回数を数える期間
+	PasswordResetRequestWindow = time.Hour
+)
+
+// HtThHotelManagerPasswordResets パスワード再設定URLの発行履歴テーブル（トークンはハッシュ値のみ保持）
+type HtThHotelManagerPasswordResets struct {
+	TokenHash      string    `gorm:"primaryKey" json:"-"`
+	HotelManagerID int64     `json:"hotel_manager_id"`
+	IPAddress      string    `json:"ip_address"`
+	ExpiresAt      time.Time `gorm:"type:time" json:"expires_at"`
+	UsedAt         time.Time `gorm:"type:time" json:"used_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PasswordResetRequestInput パスワード再設定メール送信の入力。ログインユーザかメールアドレスのどちらかを指定する
+type PasswordResetRequestInput struct {
+	Username  string `json:"username" validate:"required_without=Email"`
+	Email     string `json:"email" validate:"omitempty,email"`
+	IPAddress string `json:"-"`
+}
+
+// ResetPasswordInput パスワード再設定の入力
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
@@ -0,0 +1,100 @@
+package account
+
+import (
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+const (
+	// RoleViewer 閲覧のみ
+	RoleViewer = "viewer"
+	// RoleInventoryEditor 在庫・料金・販売停止・予約の操作
+	RoleInventoryEditor = "inventory_editor"
+	// RoleContentEditor 施設・部屋・プラン・画像・キャンセルポリシーの編集
+	RoleContentEditor = "content_editor"
+	// RoleFinanceApprover 精算書の承認・口座情報の編集
+	RoleFinanceApprover = "finance_approver"
+	// RoleAdmin すべての操作とアカウントの権限設定
+	RoleAdmin = "admin"
+
+	// PermissionView 各種情報の閲覧
+	PermissionView = "view"
+	// PermissionBookingEdit 予約のキャンセル・NoShow
+	PermissionBookingEdit = "booking.edit"
+	// PermissionInventoryEdit 在庫・料金・販売停止の編集
+	PermissionInventoryEdit = "inventory.edit"
+	// PermissionContentEdit 施設・部屋・プラン・画像・キャンセルポリシーの編集
+	PermissionContentEdit = "content.edit"
+	// PermissionSettlementApprove 精算書の承認
+	PermissionSettlementApprove = "settlement.approve"
+	// PermissionSettlementAccountEdit 精算口座情報の編集
+	PermissionSettlementAccountEdit = "settlement.account.edit"
+	// PermissionAccountAdmin アカウントの権限設定
+	PermissionAccountAdmin = "account.admin"
+)
+
+// RolePermissions ロールごとに付与される権限
+var RolePermissions = map[string][]string{
+	RoleViewer:          {PermissionView},
+	RoleInventoryEditor: {PermissionView, PermissionInventoryEdit, PermissionBookingEdit},
+	RoleContentEditor:   {PermissionView, PermissionContentEdit},
+	RoleFinanceApprover: {PermissionView, PermissionSettlementApprove, PermissionSettlementAccountEdit},
+	RoleAdmin: {
+		PermissionView,
+		PermissionBookingEdit,
+		PermissionInventoryEdit,
+		PermissionContentEdit,
+		PermissionSettlementApprove,
+		PermissionSettlementAccountEdit,
+		PermissionAccountAdmin,
+	},
+}
+
+// HtTmHotelManagerRoles HMアカウントに割り当てたロールのテーブル
+type HtTmHotelManagerRoles struct {
+	HotelManagerRoleID int64  `gorm:"primaryKey;autoIncrement:true" json:"hotel_manager_role_id"`
+	HotelManagerID     int64  `json:"hotel_manager_id"`
+	Role               string `json:"role"`
+	common.Times       `gorm:"embedded"`
+}
+
+// AssignRolesInput ロール割り当ての入力
+type AssignRolesInput struct {
+	HotelManagerID int64    `json:"hotel_manager_id" param:"hotelManagerId" validate:"required"`
+	Roles          []string `json:"roles" validate:"required,min=1,dive,oneof=viewer inventory_editor content_editor finance_approver admin"`
+}
+
+// DefaultRoles ロール未割り当てのアカウントに適用するロール
+// ロール導入前のフラグ（IsPrimary, MasterEditFlg, SettlementNeedFlg）から読み替える
+// スタッフアカウントは招待時にロールを割り当てるため、未割り当ての場合は閲覧のみとする
+func DefaultRoles(hmUser *HtTmHotelManager) []string {
+	if hmUser.OwnerHotelManagerID != 0 {
+		return []string{RoleViewer}
+	}
+	if hmUser.IsPrimary || hmUser.PropertyID == 0 {
+		return []string{RoleAdmin}
+	}
+	roles := []string{RoleViewer}
+	if hmUser.MasterEditFlg {
+		roles = append(roles, RoleInventoryEditor, RoleContentEditor)
+	}
+	if hmUser.SettlementNeedFlg {
+		roles = append(roles, RoleFinanceApprover)
+	}
+	return roles
+}
+
+// PermissionsOf ロールから権限の一覧を作成（重複なし）
+func PermissionsOf(roles []string) []string {
+	permissions := []string{}
+	exists := map[string]bool{}
+	for _, role := range roles {
+		for _, permission := range RolePermissions[role] {
+			if exists[permission] {
+				continue
+			}
+			exists[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,85 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+// ErrInvitationInvalid 招待のトークンが正しくないか、有効期限が切れている
+var ErrInvitationInvalid = errors.New("Error: 招待URLが正しくないか、有効期限が切れています。")
+
+// ErrUsernameTaken 指定したログインユーザは既に使われている
+var ErrUsernameTaken = errors.New("Error: このユーザーIDは既に使用されています。")
+
+// InvitationTTL スタッフ招待URLの有効期限
+const InvitationTTL = 72 * time.Hour
+
+// HtTmHotelManagerInvitations スタッフアカウントの招待テーブル（トークンはハッシュ値のみ保持）
+type HtTmHotelManagerInvitations struct {
+	InvitationID           int64     `gorm:"primaryKey;autoIncrement:true" json:"invitation_id"`
+	OwnerHotelManagerID    int64     `json:"owner_hotel_manager_id"`
+	InvitedBy              int64     `json:"invited_by"`
+	EmailEnc               string    `json:"-"`
+	Roles                  string    `json:"roles"`
+	TokenHash              string    `json:"-"`
+	ExpiresAt              time.Time `gorm:"type:time" json:"expires_at"`
+	AcceptedAt             time.Time `gorm:"type:time" json:"accepted_at"`
+	AcceptedHotelManagerID int64     `json:"accepted_hotel_manager_id"`
+	common.Times           `gorm:"embedded"`
+}
+
+// InviteStaffInput スタッフ招待の入力
+type InviteStaffInput struct {
+	Email string   `json:"email" validate:"required,email"`
+	Roles []string `json:"roles" validate:"required,min=1,dive,oneof=viewer inventory_editor content_editor finance_approver admin"`
+}
+
+// AcceptInvitationInput 招待を受けてスタッフアカウントを作成する入力
+type AcceptInvitationInput struct {
+	Token     string `json:"token" validate:"required"`
+	Username  string `json:"username" validate:"required"`
+	Password  string `json:"password" validate:"required"`
+	FirstName string `json:"first_name" validate:"required"`
+	LastName  string `json:"last_name" validate:"required"`
+}
+
+// DisableStaffInput スタッフアカウント無効化の入力
+type DisableStaffInput struct {
+	HotelManagerID int64 `json:"hotel_manager_id" param:"hotelManagerId" validate:"required"`
+}
+
+// TeamMemberOutput チームのアカウント一覧の出力
+type TeamMemberOutput struct {
+	HotelManagerID int64     `json:"hotel_manager_id"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	Roles          []string  `json:"roles"`
+	IsOwner        bool      `json:"is_owner"`
+	Disabled       bool      `json:"disabled"`
+	LoginedAt      time.Time `json:"logined_at"`
+}
+
+// InvitationOutput 承諾待ちの招待の出力
+type InvitationOutput struct {
+	InvitationID int64     `json:"invitation_id"`
+	Email        string    `json:"email"`
+	Roles        []string  `json:"roles"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// TeamOutput チーム一覧の出力
+type TeamOutput struct {
+	Members     []TeamMemberOutput `json:"members"`
+	Invitations []InvitationOutput `json:"invitations"`
+}
+
+// TeamOwnerID スタッフアカウントの場合は招待元、それ以外は自身のアカウントID
+func (h *HtTmHotelManager) TeamOwnerID() int64 {
+	if h.OwnerHotelManagerID != 0 {
+		return h.OwnerHotelManagerID
+	}
+	return h.HotelManagerID
+}
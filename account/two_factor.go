@@ -0,0 +1,107 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+// ErrTwoFactorInvalid 2段階認証のコードもしくはチャレンジが正しくない
+var ErrTwoFactorInvalid = errors.New("Error: 認証コードが正しくないか、有効期限が切れています。")
+
+// ErrTwoFactorRequired 親アカウントの設定により2段階認証を無効にできない
+var ErrTwoFactorRequired = errors.New("Error: 2段階認証の利用が必須になっています。")
+
+const (
+	// TwoFactorChallengeTTL パスワード認証後、認証コードを入力するまでの有効期限
+	TwoFactorChallengeTTL = 5 * time.Minute
+	// TwoFactorChallengeMaxFailures 1回のチャレンジで認証コードを間違えられる回数
+	TwoFactorChallengeMaxFailures = 5
+	// RecoveryCodeCount 発行するリカバリーコードの件数
+	RecoveryCodeCount = 10
+
+	// LoginResultTwoFactorFailure 認証コード不一致
+	LoginResultTwoFactorFailure = "two_factor_failure"
+)
+
+// HtTmHotelManagerTwoFactors HMアカウントの2段階認証（TOTP）設定テーブル
+// 登録途中（認証コード未確認）の間は EnabledFlg を立てない
+type HtTmHotelManagerTwoFactors struct {
+	HotelManagerID int64     `gorm:"primaryKey" json:"hotel_manager_id"`
+	SecretEnc      string    `json:"-"`
+	EnabledFlg     bool      `json:"enabled_flg"`
+	EnabledAt      time.Time `gorm:"type:time" json:"enabled_at"`
+	LastUsedStep   int64     `json:"-"`
+	common.Times   `gorm:"embedded"`
+}
+
+// HtTmHotelManagerRecoveryCodes 2段階認証のリカバリーコードテーブル（ハッシュ値のみ保持）
+type HtTmHotelManagerRecoveryCodes struct {
+	RecoveryCodeID int64     `gorm:"primaryKey;autoIncrement:true" json:"recovery_code_id"`
+	HotelManagerID int64     `json:"hotel_manager_id"`
+	CodeHash       string    `json:"-"`
+	UsedAt         time.Time `gorm:"type:time" json:"used_at"`
+	common.Times   `gorm:"embedded"`
+}
+
+// HtThHotelManagerTwoFactorChallenges パスワード認証済みで、認証コードの入力待ちのログインテーブル
+type HtThHotelManagerTwoFactorChallenges struct {
+	ChallengeTokenHash string    `gorm:"primaryKey" json:"-"`
+	HotelManagerID     int64     `json:"hotel_manager_id"`
+	IPAddress          string    `json:"ip_address"`
+	UserAgent          string    `json:"user_agent"`
+	FailedNum          int       `json:"failed_num"`
+	ExpiresAt          time.Time `gorm:"type:time" json:"expires_at"`
+	UsedAt             time.Time `gorm:"type:time" json:"used_at"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// TwoFactorLoginInput ログイン2段階目の入力。認証コードかリカバリーコードのどちらかを指定する
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code"`
+	IPAddress      string `json:"-"`
+	UserAgent      string `json:"-"`
+}
+
+// TwoFactorChallengeInput ログイン中に2段階認証を登録する際の入力
+type TwoFactorChallengeInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+// TwoFactorCodeInput 認証コードの入力
+type TwoFactorCodeInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// DisableTwoFactorInput 2段階認証解除の入力
+type DisableTwoFactorInput struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// TwoFactorPolicyInput 子アカウントへの2段階認証必須設定の入力
+type TwoFactorPolicyInput struct {
+	Required bool `json:"required"`
+}
+
+// TwoFactorEnrollOutput 2段階認証登録開始の出力
+type TwoFactorEnrollOutput struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesOutput リカバリーコードの出力（発行時の1回のみ平文で返す）
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatusOutput 2段階認証の設定状況の出力
+type TwoFactorStatusOutput struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RemainingRecoveryCodes int64 `json:"remaining_recovery_codes"`
+}
@@ -6,77 +6,248 @@ import (
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	"github.com/Adventureinc/hotel-hm-api/src/account/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+	mailInfra "github.com/Adventureinc/hotel-hm-api/src/common/mail/infra"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"gorm.io/gorm"
 )
 
+const (
+	// accountLockThreshold アカウントをロックし始めるログイン失敗回数
+	accountLockThreshold = 5
+	// accountLockBaseDuration 初回ロック時のロック時間
+	accountLockBaseDuration = 5 * time.Minute
+	// accountLockMaxDuration ロック時間の上限
+	accountLockMaxDuration = 24 * time.Hour
+	// ipLockThreshold 接続元IPをロックするログイン失敗回数
+	ipLockThreshold = 20
+	// ipLockWindow 接続元IPのログイン失敗回数を数える期間
+	ipLockWindow = 15 * time.Minute
+)
+
 type accountUsecase struct {
 	ARepository account.IAccountRepository
+	MailSender  mail.ISender
 }
 
 // NewAccountUsecase インスタンス生成
 func NewAccountUsecase(db *gorm.DB) account.IAccountUsecase {
 	return &accountUsecase{
 		ARepository: infra.NewAccountRepository(db),
+		MailSender:  mailInfra.NewMailSender(),
 	}
 }
 
 // Login ログイン時のID＆PWチェックとトークン発行
-func (a *accountUsecase) Login(LoginInput *account.LoginInput) (string, error) {
-	usernameEnc, eErr := utils.Encrypt(LoginInput.Username)
-	if eErr != nil {
-		return "", eErr
+func (a *accountUsecase) Login(LoginInput *account.LoginInput) (*account.TokenOutput, error) {
+	hmUser, authErr := a.authenticate(LoginInput.Username, LoginInput.Password, LoginInput.IPAddress, LoginInput.UserAgent)
+	if authErr != nil {
+		return nil, authErr
 	}
-	passwordEnc, eErr := utils.Encrypt(LoginInput.Password)
-	if eErr != nil {
-		return "", eErr
+	// ハッシュ未移行のまま再設定を求められているアカウントは、パスワード変更を済ませるまでログインさせない
+	if hmUser.PasswordResetFlg {
+		return nil, account.ErrPasswordResetRequired
+	}
+	// 2段階認証を有効にしている、もしくは親アカウントから必須にされている場合は認証コードの入力待ちにする
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := a.isTwoFactorRequired(&hmUser)
+	if err != nil {
+		return nil, err
+	}
+	// 失敗回数は、認証コードの確認が済むまでクリアしない（パスワードを知っていれば認証コードを何度でも試せてしまうため）
+	if twoFactor.EnabledFlg || required {
+		return a.startTwoFactorChallenge(&hmUser, LoginInput.IPAddress, LoginInput.UserAgent, twoFactor.EnabledFlg == false)
 	}
+	if err := a.clearLoginFailed(&hmUser); err != nil {
+		return nil, err
+	}
+	return a.startSession(&hmUser, LoginInput.IPAddress, LoginInput.UserAgent)
+}
 
-	hmUser := a.ARepository.FetchHMUserByLoginInfo(&account.HtTmHotelManager{
-		UsernameEnc: usernameEnc,
-		PasswordEnc: passwordEnc,
+// Logout ログアウト処理。セッションとアクセストークンを失効させる
+func (a *accountUsecase) Logout(claimParam *account.ClaimParam) error {
+	if claimParam.SessionID == "" {
+		return a.ARepository.DeleteAPIToken(claimParam)
+	}
+	if err := a.ARepository.RevokeSession(claimParam.HotelManagerID, claimParam.SessionID); err != nil {
+		return err
+	}
+	return a.ARepository.RevokeToken(&account.HtThHotelManagerRevokedTokens{
+		TokenID:        claimParam.TokenID,
+		HotelManagerID: claimParam.HotelManagerID,
+		ExpiresAt:      claimParam.ExpiresAt,
+		CreatedAt:      time.Now(),
 	})
-	if hmUser.HotelManagerID == 0 {
-		return "", fmt.Errorf("Error: %s", "ユーザーIDもしくはパスワードが正しくありません。")
+}
+
+// CheckToken 正しいトークンか確認し、同じセッションのアクセストークンを再度生成する
+// リフレッシュトークンなしで再発行できるため、アクセストークンの有効期限はセッションの有効期限（ログイン時から延長しない）までとする
+// セッション導入前のトークンの場合は、新しいセッションを作成してリフレッシュトークンも返す
+func (a *accountUsecase) CheckToken(claimParam *account.ClaimParam) (*account.TokenOutput, error) {
+	fetchedHmUser, fetchErr := a.ARepository.FetchHMUserByToken(claimParam)
+	if fetchErr != nil {
+		return nil, fetchErr
 	}
 
-	token, tokenErr := utils.GenerateToken(hmUser.HotelManagerID)
-	if tokenErr != nil {
-		return "", tokenErr
+	if claimParam.SessionID == "" {
+		output, err := a.startSession(&fetchedHmUser, "", "")
+		if err != nil {
+			return nil, err
+		}
+		if err := a.ARepository.DeleteAPIToken(claimParam); err != nil {
+			return nil, err
+		}
+		return output, nil
 	}
 
-	claimParam := &account.ClaimParam{
-		HotelManagerID: hmUser.HotelManagerID,
-		APIToken:       token,
+	session, sErr := a.ARepository.FetchSession(claimParam.SessionID)
+	if sErr != nil {
+		return nil, sErr
 	}
-	hmUser.LoginedAt = time.Now()
-	if DBErr := a.ARepository.SaveLoginInfo(&hmUser, claimParam, token); DBErr != nil {
-		return "", DBErr
+	newToken, tokenErr := utils.GenerateTokenUntil(fetchedHmUser.HotelManagerID, claimParam.SessionID, session.ExpiresAt)
+	if tokenErr != nil {
+		return nil, tokenErr
 	}
-	return token, nil
+	if DBErr := a.ARepository.TouchSession(claimParam.SessionID); DBErr != nil {
+		return nil, DBErr
+	}
+	return &account.TokenOutput{
+		APIToken:  newToken,
+		ExpiresIn: accessTokenExpiresIn(session.ExpiresAt),
+	}, nil
 }
 
-// Logout ログアウト処理。トークンを削除するだけ
-func (a *accountUsecase) Logout(claimParam *account.ClaimParam) error {
-	return a.ARepository.DeleteAPIToken(claimParam)
+// accessTokenExpiresIn セッションの有効期限までに切り詰めたアクセストークンの有効期間（秒）
+func accessTokenExpiresIn(sessionExpiresAt time.Time) int64 {
+	ttl := utils.AccessTokenTTL()
+	if remaining := time.Until(sessionExpiresAt); remaining < ttl {
+		ttl = remaining
+	}
+	return int64(ttl.Seconds())
 }
 
-// CheckToken 正しいトークンか確認し、トークン再度生成する
-func (a *accountUsecase) CheckToken(claimParam *account.ClaimParam) (string, error) {
-	fetchedHmUser, fetchErr := a.ARepository.FetchHMUserByToken(claimParam)
+// Refresh リフレッシュトークンを検証し、アクセストークンとリフレッシュトークンを再発行する
+// セッションの有効期限はログイン時から延長しない
+func (a *accountUsecase) Refresh(request *account.RefreshInput) (*account.TokenOutput, error) {
+	invalidErr := fmt.Errorf("Error: %s", "リフレッシュトークンが不正です。")
+	refreshTokenHash := utils.HashToken(request.RefreshToken)
+	session, fetchErr := a.ARepository.FetchSessionByRefreshToken(refreshTokenHash)
 	if fetchErr != nil {
-		return "", fetchErr
+		return nil, invalidErr
+	}
+	// 使用済みのリフレッシュトークンが再度使われた場合は漏えいとみなし、セッションごと失効させる
+	if session.RefreshTokenHash != refreshTokenHash {
+		if session.RevokedAt.IsZero() {
+			if err := a.ARepository.RevokeSession(session.HotelManagerID, session.SessionID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, invalidErr
+	}
+	now := time.Now()
+	if session.RevokedAt.IsZero() == false || now.After(session.ExpiresAt) {
+		return nil, invalidErr
+	}
+	hmUser, hmErr := a.ARepository.FetchOne(session.HotelManagerID)
+	if hmErr != nil {
+		return nil, invalidErr
+	}
+
+	newRefreshToken, rErr := utils.GenerateRandomToken(32)
+	if rErr != nil {
+		return nil, rErr
+	}
+	session.IPAddress = request.IPAddress
+	session.UserAgent = request.UserAgent
+	session.LastUsedAt = now
+	if err := a.ARepository.RotateRefreshToken(&session, utils.HashToken(newRefreshToken)); err != nil {
+		return nil, invalidErr
+	}
+
+	newToken, tokenErr := utils.GenerateTokenUntil(hmUser.HotelManagerID, session.SessionID, session.ExpiresAt)
+	if tokenErr != nil {
+		return nil, tokenErr
+	}
+	return &account.TokenOutput{
+		APIToken:     newToken,
+		RefreshToken: newRefreshToken,
+		ExpiresIn:    accessTokenExpiresIn(session.ExpiresAt),
+	}, nil
+}
+
+// FetchSessions ログイン中の端末一覧
+func (a *accountUsecase) FetchSessions(claimParam *account.ClaimParam) ([]account.SessionOutput, error) {
+	res := []account.SessionOutput{}
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return res, err
+	}
+	sessions, sErr := a.ARepository.FetchActiveSessions(hmUser.HotelManagerID)
+	if sErr != nil {
+		return res, sErr
+	}
+	for _, session := range sessions {
+		res = append(res, account.SessionOutput{
+			SessionID:  session.SessionID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			IsCurrent:  session.SessionID == claimParam.SessionID,
+		})
 	}
+	return res, nil
+}
 
-	newToken, tokenErr := utils.GenerateToken(fetchedHmUser.HotelManagerID)
+// RevokeSession 指定した端末をログアウトさせる
+func (a *accountUsecase) RevokeSession(claimParam *account.ClaimParam, sessionID string) error {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return err
+	}
+	return a.ARepository.RevokeSession(hmUser.HotelManagerID, sessionID)
+}
+
+// startSession セッションを作成し、アクセストークンとリフレッシュトークンを発行
+func (a *accountUsecase) startSession(hmUser *account.HtTmHotelManager, ipAddress string, userAgent string) (*account.TokenOutput, error) {
+	sessionID, sErr := utils.GenerateRandomToken(16)
+	if sErr != nil {
+		return nil, sErr
+	}
+	refreshToken, rErr := utils.GenerateRandomToken(32)
+	if rErr != nil {
+		return nil, rErr
+	}
+	token, tokenErr := utils.GenerateToken(hmUser.HotelManagerID, sessionID)
 	if tokenErr != nil {
-		return "", tokenErr
+		return nil, tokenErr
 	}
 
-	if DBErr := a.ARepository.SaveLoginInfo(&fetchedHmUser, claimParam, newToken); DBErr != nil {
-		return "", DBErr
+	now := time.Now()
+	session := &account.HtTmHotelManagerSessions{
+		SessionID:        sessionID,
+		HotelManagerID:   hmUser.HotelManagerID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		IPAddress:        ipAddress,
+		UserAgent:        userAgent,
+		ExpiresAt:        now.Add(utils.RefreshTokenTTL()),
+		LastUsedAt:       now,
+		Times:            common.Times{CreatedAt: now, UpdatedAt: now},
+	}
+	hmUser.LoginedAt = now
+	if DBErr := a.ARepository.SaveLoginInfo(hmUser, session); DBErr != nil {
+		return nil, DBErr
 	}
-	return newToken, nil
+	return &account.TokenOutput{
+		APIToken:     token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
 }
 
 // FetchDetail HMアカウント情報の取得
@@ -106,32 +277,217 @@ func (a *accountUsecase) FetchDetail(claimParam *account.ClaimParam) (*account.H
 	fetchedHmUser.EmailEnc = email
 	fetchedHmUser.UsernameEnc = username
 	fetchedHmUser.PasswordEnc = ""
+	fetchedHmUser.PasswordHash = ""
+
+	// 画面側で操作の出し分けができるよう、ロールと権限も返す
+	roles, rErr := a.FetchRoles(&fetchedHmUser)
+	if rErr != nil {
+		return &account.HtTmHotelManager{}, rErr
+	}
+	fetchedHmUser.Roles = roles
+	fetchedHmUser.Permissions = account.PermissionsOf(roles)
 	return &fetchedHmUser, nil
 }
 
 // ChangePassword パスワード変更
 func (a *accountUsecase) ChangePassword(request *account.ChangePasswordInput) error {
-	usernameEnc, eErr := utils.Encrypt(request.Username)
-	if eErr != nil {
-		return eErr
+	hmUser, authErr := a.authenticate(request.Username, request.Password, request.IPAddress, request.UserAgent)
+	if authErr != nil {
+		return authErr
+	}
+	if err := a.clearLoginFailed(&hmUser); err != nil {
+		return err
+	}
+	if request.NewPassword == request.Password {
+		return fmt.Errorf("%w: %s", account.ErrPasswordPolicy, "現在と同じパスワードは設定できません。")
+	}
+	if err := a.validateNewPassword(&hmUser, request.NewPassword); err != nil {
+		return err
+	}
+
+	newPasswordHash, hashErr := utils.HashPassword(request.NewPassword)
+	if hashErr != nil {
+		return hashErr
+	}
+	if err := a.ARepository.UpdatePassword(hmUser.HotelManagerID, newPasswordHash); err != nil {
+		return err
+	}
+	// パスワード変更前に発行したトークンはすべて失効させる
+	return a.ARepository.RevokeAllSessions(hmUser.HotelManagerID)
+}
+
+// validateNewPassword 新しいパスワードがパスワードポリシーを満たすか確認する
+// 現在のパスワードと直近の変更履歴に含まれるパスワードは再利用させない
+func (a *accountUsecase) validateNewPassword(hmUser *account.HtTmHotelManager, newPassword string) error {
+	if err := utils.ValidatePasswordPolicy(newPassword); err != nil {
+		return fmt.Errorf("%w: %s", account.ErrPasswordPolicy, err)
+	}
+	if utils.ComparePassword(hmUser.PasswordHash, newPassword) {
+		return fmt.Errorf("%w: %s", account.ErrPasswordPolicy, "現在と同じパスワードは設定できません。")
+	}
+	histories, hErr := a.ARepository.FetchPasswordHistories(hmUser.HotelManagerID, utils.PasswordHistoryCount)
+	if hErr != nil {
+		return hErr
+	}
+	for _, history := range histories {
+		if utils.ComparePassword(history.PasswordHash, newPassword) {
+			return fmt.Errorf("%w: 過去%d回以内に使用したパスワードは設定できません。", account.ErrPasswordPolicy, utils.PasswordHistoryCount)
+		}
+	}
+	return nil
+}
+
+// authenticate ログインユーザとパスワードを照合してアカウントを取得
+// アカウント・接続元IPごとのロックを確認し、試行結果を履歴に残す
+// 失敗回数のクリアは呼び出し元で行う（2段階認証の場合は認証コードを確認した後）
+// 旧形式（暗号化）のパスワードで照合できた場合は、その場でハッシュに置き換える
+func (a *accountUsecase) authenticate(username string, password string, ipAddress string, userAgent string) (account.HtTmHotelManager, error) {
+	invalidErr := fmt.Errorf("Error: %s", "ユーザーIDもしくはパスワードが正しくありません。")
+	now := time.Now()
+	history := &account.HtThHotelManagerLoginHistories{
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		AttemptedAt: now,
+	}
+
+	// 接続元IP単位のロック（アカウントを変えながらの総当たり対策）
+	ipLocked, cErr := a.isIPLocked(ipAddress, now)
+	if cErr != nil {
+		return account.HtTmHotelManager{}, cErr
+	}
+	if ipLocked {
+		history.Result = account.LoginResultLocked
+		if err := a.ARepository.CreateLoginHistory(history); err != nil {
+			return account.HtTmHotelManager{}, err
+		}
+		return account.HtTmHotelManager{}, account.ErrAccountLocked
 	}
-	passwordEnc, eErr := utils.Encrypt(request.Password)
+
+	usernameEnc, eErr := utils.Encrypt(username)
 	if eErr != nil {
-		return eErr
+		return account.HtTmHotelManager{}, eErr
 	}
-	hmUser := a.ARepository.FetchHMUserByLoginInfo(&account.HtTmHotelManager{
-		UsernameEnc: usernameEnc,
-		PasswordEnc: passwordEnc,
-	})
-	if hmUser.HotelManagerID == 0 {
-		return fmt.Errorf("Error: %s", "ユーザーIDもしくはパスワードが正しくありません。")
+	hmUser, fetchErr := a.ARepository.FetchHMUserByUsername(usernameEnc)
+	if fetchErr != nil || hmUser.HotelManagerID == 0 {
+		history.Result = account.LoginResultFailure
+		if err := a.ARepository.CreateLoginHistory(history); err != nil {
+			return account.HtTmHotelManager{}, err
+		}
+		return account.HtTmHotelManager{}, invalidErr
 	}
+	history.HotelManagerID = hmUser.HotelManagerID
 
-	newPasswordEnc, eErr := utils.Encrypt(request.NewPassword)
+	// アカウント単位のロック。ロック中はパスワードの照合自体を行わない
+	if now.Before(hmUser.LockedUntil) {
+		history.Result = account.LoginResultLocked
+		if err := a.ARepository.CreateLoginHistory(history); err != nil {
+			return account.HtTmHotelManager{}, err
+		}
+		return account.HtTmHotelManager{}, account.ErrAccountLocked
+	}
+
+	matched, vErr := a.verifyPassword(&hmUser, password)
+	if vErr != nil {
+		return account.HtTmHotelManager{}, vErr
+	}
+	if matched == false {
+		if err := a.recordLoginFailed(&hmUser, now); err != nil {
+			return account.HtTmHotelManager{}, err
+		}
+		history.Result = account.LoginResultFailure
+		if err := a.ARepository.CreateLoginHistory(history); err != nil {
+			return account.HtTmHotelManager{}, err
+		}
+		return account.HtTmHotelManager{}, invalidErr
+	}
+
+	history.Result = account.LoginResultSuccess
+	if err := a.ARepository.CreateLoginHistory(history); err != nil {
+		return account.HtTmHotelManager{}, err
+	}
+	return hmUser, nil
+}
+
+// verifyPassword アカウントのパスワードと照合する
+// 旧形式（暗号化）のパスワードで照合できた場合は、その場でハッシュに置き換える
+func (a *accountUsecase) verifyPassword(hmUser *account.HtTmHotelManager, password string) (bool, error) {
+	if hmUser.PasswordHash != "" {
+		return utils.ComparePassword(hmUser.PasswordHash, password), nil
+	}
+
+	passwordEnc, eErr := utils.Encrypt(password)
 	if eErr != nil {
-		return eErr
+		return false, eErr
+	}
+	if hmUser.PasswordEnc == "" || hmUser.PasswordEnc != passwordEnc {
+		return false, nil
+	}
+	passwordHash, hashErr := utils.HashPassword(password)
+	if hashErr != nil {
+		return false, hashErr
+	}
+	if err := a.ARepository.MigratePassword(hmUser.HotelManagerID, passwordHash); err != nil {
+		return false, err
+	}
+	hmUser.PasswordEnc = ""
+	hmUser.PasswordHash = passwordHash
+	return true, nil
+}
+
+// isIPLocked 接続元IPが、直近のログイン失敗（2段階認証の失敗を含む）の回数によりロックされているか
+func (a *accountUsecase) isIPLocked(ipAddress string, now time.Time) (bool, error) {
+	if ipAddress == "" {
+		return false, nil
+	}
+	failedCount, err := a.ARepository.CountFailedLoginsByIP(ipAddress, now.Add(-ipLockWindow))
+	if err != nil {
+		return false, err
 	}
-	return a.ARepository.UpdatePassword(hmUser.HotelManagerID, newPasswordEnc)
+	return failedCount >= ipLockThreshold, nil
+}
+
+// recordLoginFailed ログイン失敗回数を加算し、閾値に達した場合はアカウントをロックする
+func (a *accountUsecase) recordLoginFailed(hmUser *account.HtTmHotelManager, now time.Time) error {
+	loginFailedNum := hmUser.LoginFailedNum + 1
+	var lockedUntil time.Time
+	if lockDuration := accountLockDuration(loginFailedNum); lockDuration > 0 {
+		lockedUntil = now.Add(lockDuration)
+	}
+	if err := a.ARepository.UpdateLoginFailed(hmUser.HotelManagerID, loginFailedNum, lockedUntil); err != nil {
+		return err
+	}
+	hmUser.LoginFailedNum = loginFailedNum
+	hmUser.LockedUntil = lockedUntil
+	return nil
+}
+
+// clearLoginFailed ログインに成功したアカウントの失敗回数とロック期限をクリア
+func (a *accountUsecase) clearLoginFailed(hmUser *account.HtTmHotelManager) error {
+	if hmUser.LoginFailedNum == 0 && hmUser.LockedUntil.IsZero() {
+		return nil
+	}
+	if err := a.ARepository.ResetLoginFailed(hmUser.HotelManagerID); err != nil {
+		return err
+	}
+	hmUser.LoginFailedNum = 0
+	hmUser.LockedUntil = time.Time{}
+	return nil
+}
+
+// accountLockDuration ログイン失敗回数に応じたアカウントのロック時間
+// 閾値に達した後は、失敗するたびにロック時間を倍にしていく
+func accountLockDuration(loginFailedNum int) time.Duration {
+	if loginFailedNum < accountLockThreshold {
+		return 0
+	}
+	duration := accountLockBaseDuration
+	for i := accountLockThreshold; i < loginFailedNum; i++ {
+		duration *= 2
+		if duration >= accountLockMaxDuration {
+			return accountLockMaxDuration
+		}
+	}
+	return duration
 }
 
 // FetchHMUser トークンに紐づくHMアカウントを取得
@@ -147,3 +503,103 @@ func (a *accountUsecase) IsParentAccount(hotelManagerID int64) bool {
 	}
 	return hmUser.PropertyID == 0
 }
+
+// ForcePasswordResetUnmigrated ハッシュ未移行のアカウントすべてにパスワード再設定を要求する
+func (a *accountUsecase) ForcePasswordResetUnmigrated() (int64, error) {
+	return a.ARepository.FlagUnmigratedPasswordReset()
+}
+
+// Unlock アカウントのロックを解除する（運用向け）
+func (a *accountUsecase) Unlock(hotelManagerID int64) error {
+	if _, err := a.ARepository.FetchOne(hotelManagerID); err != nil {
+		return err
+	}
+	return a.ARepository.ResetLoginFailed(hotelManagerID)
+}
+
+// UnlockChild 親アカウントから、同じ取引先の子アカウントのロックを解除する
+func (a *accountUsecase) UnlockChild(parent *account.HtTmHotelManager, hotelManagerID int64) error {
+	if parent.PropertyID != 0 {
+		return fmt.Errorf("Error: %s", "親アカウントではありません。")
+	}
+	child, err := a.ARepository.FetchOne(hotelManagerID)
+	if err != nil {
+		return err
+	}
+	if child.ClientCompanyID == 0 || child.ClientCompanyID != parent.ClientCompanyID {
+		return fmt.Errorf("Error: %s", "ロック解除の権限がありません。")
+	}
+	return a.ARepository.ResetLoginFailed(hotelManagerID)
+}
+
+// FetchLoginHistories ログイン中のアカウントのログイン試行履歴を取得
+func (a *accountUsecase) FetchLoginHistories(claimParam *account.ClaimParam, paging *common.Paging) ([]account.HtThHotelManagerLoginHistories, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return []account.HtThHotelManagerLoginHistories{}, err
+	}
+	return a.ARepository.FetchLoginHistories(hmUser.HotelManagerID, paging)
+}
+
+// FetchRoles アカウントのロールを取得。未割り当ての場合は従来のフラグから読み替える
+func (a *accountUsecase) FetchRoles(hmUser *account.HtTmHotelManager) ([]string, error) {
+	roles, err := a.ARepository.FetchRoles(hmUser.HotelManagerID)
+	if err != nil {
+		return []string{}, err
+	}
+	if len(roles) == 0 {
+		return account.DefaultRoles(hmUser), nil
+	}
+	return roles, nil
+}
+
+// HasPermission アカウントが指定の権限を持っているか
+func (a *accountUsecase) HasPermission(hmUser *account.HtTmHotelManager, permission string) (bool, error) {
+	roles, err := a.FetchRoles(hmUser)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range account.PermissionsOf(roles) {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AssignRoles 管理者権限を持つアカウントから、同じ施設（親アカウントは同じ取引先）のアカウントへロールを割り当てる
+func (a *accountUsecase) AssignRoles(claimParam *account.ClaimParam, request *account.AssignRolesInput) error {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return err
+	}
+	isAdmin, pErr := a.HasPermission(&hmUser, account.PermissionAccountAdmin)
+	if pErr != nil {
+		return pErr
+	}
+	if isAdmin == false {
+		return fmt.Errorf("Error: %s", "権限設定の権限がありません。")
+	}
+	// 自分自身の管理者権限を外して、誰も権限設定できなくなるのを防ぐ
+	if hmUser.HotelManagerID == request.HotelManagerID {
+		return fmt.Errorf("Error: %s", "自分自身のロールは変更できません。")
+	}
+	target, tErr := a.ARepository.FetchOne(request.HotelManagerID)
+	if tErr != nil {
+		return tErr
+	}
+	// 取引先内の他の施設のアカウントへ割り当てられるのは親アカウントのみ
+	// 施設のアカウントからは同じ施設のアカウントにのみ割り当てられる
+	if hmUser.WholesalerID == utils.WholesalerIDParent {
+		if target.ClientCompanyID == 0 || target.ClientCompanyID != hmUser.ClientCompanyID {
+			return fmt.Errorf("Error: %s", "権限設定の権限がありません。")
+		}
+	} else if target.PropertyID == 0 || target.PropertyID != hmUser.PropertyID {
+		return fmt.Errorf("Error: %s", "権限設定の権限がありません。")
+	}
+	// スタッフアカウントから招待元アカウントのロールは変更させない
+	if hmUser.OwnerHotelManagerID != 0 && target.HotelManagerID == hmUser.OwnerHotelManagerID {
+		return fmt.Errorf("Error: %s", "招待元アカウントのロールは変更できません。")
+	}
+	return a.ARepository.ReplaceRoles(target.HotelManagerID, request.Roles)
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// RequestPasswordReset ログインユーザもしくはメールアドレスに合致するアカウントへ、パスワード再設定メールを送信する
+// アカウントの有無を推測させないため、該当なし・送信回数超過の場合もエラーにしない
+// 送信に失敗した場合のエラーはログ出力用で、handlerは成功時と同じ応答を返す
+func (a *accountUsecase) RequestPasswordReset(request *account.PasswordResetRequestInput) error {
+	hmUsers := []account.HtTmHotelManager{}
+	if request.Username != "" {
+		usernameEnc, err := utils.Encrypt(request.Username)
+		if err != nil {
+			return err
+		}
+		hmUser, err := a.ARepository.FetchHMUserByUsername(usernameEnc)
+		if err == nil {
+			hmUsers = append(hmUsers, hmUser)
+		}
+	} else {
+		emailEnc, err := utils.Encrypt(request.Email)
+		if err != nil {
+			return err
+		}
+		fetched, err := a.ARepository.FetchHMUsersByEmail(emailEnc)
+		if err != nil {
+			return err
+		}
+		hmUsers = fetched
+	}
+
+	// 1件の送信に失敗しても、他のアカウントへの送信は続ける
+	var sendErr error
+	for _, hmUser := range hmUsers {
+		if err := a.sendPasswordResetMail(&hmUser, request.IPAddress); err != nil {
+			sendErr = err
+		}
+	}
+	return sendErr
+}
+
+// ResetPassword パスワード再設定URLのトークンを確認して新しいパスワードを設定する
+// 再設定前に発行したトークンはすべて失効させ、ログイン失敗によるロックも解除する
+func (a *accountUsecase) ResetPassword(request *account.ResetPasswordInput) error {
+	tokenHash := utils.HashToken(request.Token)
+	reset, err := a.ARepository.FetchPasswordReset(tokenHash)
+	if err != nil {
+		return account.ErrPasswordResetInvalid
+	}
+	if reset.UsedAt.IsZero() == false || reset.ExpiresAt.Before(time.Now()) {
+		return account.ErrPasswordResetInvalid
+	}
+	hmUser, err := a.ARepository.FetchOne(reset.HotelManagerID)
+	if err != nil || hmUser.DelFlg {
+		return account.ErrPasswordResetInvalid
+	}
+	if err := a.validateNewPassword(&hmUser, request.NewPassword); err != nil {
+		return err
+	}
+
+	newPasswordHash, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		return err
+	}
+	// 同じURLで2回再設定させないよう、URLの使用とパスワードの更新は1つのトランザクションで行う
+	return a.ARepository.ResetPasswordByToken(tokenHash, hmUser.HotelManagerID, newPasswordHash)
+}
+
+// sendPasswordResetMail パスワード再設定URLを発行してメールで送信する
+func (a *accountUsecase) sendPasswordResetMail(hmUser *account.HtTmHotelManager, ipAddress string) error {
+	if hmUser.EmailEnc == "" {
+		return nil
+	}
+	now := time.Now()
+	count, err := a.ARepository.CountPasswordResets(hmUser.HotelManagerID, now.Add(-account.PasswordResetRequestWindow))
+	if err != nil {
+		return err
+	}
+	if count >= account.PasswordResetRequestLimit {
+		return nil
+	}
+	email, err := utils.Decrypt(hmUser.EmailEnc)
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := a.ARepository.CreatePasswordReset(&account.HtThHotelManagerPasswordResets{
+		TokenHash:      utils.HashToken(token),
+		HotelManagerID: hmUser.HotelManagerID,
+		IPAddress:      ipAddress,
+		ExpiresAt:      now.Add(account.PasswordResetTTL),
+		CreatedAt:      now,
+	}); err != nil {
+		return err
+	}
+
+	resetURL := os.Getenv("PASSWORD_RESET_URL") + "?token=" + url.QueryEscape(token)
+	return a.MailSender.Send(&mail.Message{
+		To:      []string{email},
+		Subject: "【Hotel Manager】パスワード再設定のご案内",
+		Body: fmt.Sprintf("パスワード再設定のご依頼を受け付けました。\n"+
+			"以下のURLから%d分以内に新しいパスワードを設定してください。\n\n%s\n\n"+
+			"お心当たりのない場合は、このメールを破棄してください。\n",
+			int(account.PasswordResetTTL.Minutes()), resetURL),
+	})
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// InviteStaff 同じ施設・親アカウント配下にスタッフを招待し、招待メールを送信する
+func (a *accountUsecase) InviteStaff(claimParam *account.ClaimParam, request *account.InviteStaffInput) error {
+	hmUser, err := a.fetchAccountAdmin(claimParam)
+	if err != nil {
+		return err
+	}
+	emailEnc, err := utils.Encrypt(request.Email)
+	if err != nil {
+		return err
+	}
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	invitation := &account.HtTmHotelManagerInvitations{
+		OwnerHotelManagerID: hmUser.TeamOwnerID(),
+		InvitedBy:           hmUser.HotelManagerID,
+		EmailEnc:            emailEnc,
+		Roles:               strings.Join(request.Roles, ","),
+		TokenHash:           utils.HashToken(token),
+		ExpiresAt:           now.Add(account.InvitationTTL),
+		Times:               common.Times{CreatedAt: now, UpdatedAt: now},
+	}
+	if err := a.ARepository.CreateInvitation(invitation); err != nil {
+		return err
+	}
+
+	invitationURL := os.Getenv("STAFF_INVITATION_URL") + "?token=" + url.QueryEscape(token)
+	return a.MailSender.Send(&mail.Message{
+		To:      []string{request.Email},
+		Subject: "【Hotel Manager】アカウント作成のご案内",
+		Body: fmt.Sprintf("Hotel Managerのスタッフアカウントに招待されました。\n"+
+			"以下のURLから%d時間以内にユーザーIDとパスワードを設定してください。\n\n%s\n\n"+
+			"お心当たりのない場合は、このメールを破棄してください。\n",
+			int(account.InvitationTTL.Hours()), invitationURL),
+	})
+}
+
+// AcceptInvitation 招待を承諾し、招待元と同じ施設・取引先のスタッフアカウントを作成する
+func (a *accountUsecase) AcceptInvitation(request *account.AcceptInvitationInput) error {
+	tokenHash := utils.HashToken(request.Token)
+	invitation, err := a.ARepository.FetchInvitation(tokenHash)
+	if err != nil {
+		return account.ErrInvitationInvalid
+	}
+	if invitation.AcceptedAt.IsZero() == false || invitation.ExpiresAt.Before(time.Now()) {
+		return account.ErrInvitationInvalid
+	}
+	owner, err := a.ARepository.FetchOne(invitation.OwnerHotelManagerID)
+	if err != nil || owner.DelFlg {
+		return account.ErrInvitationInvalid
+	}
+
+	usernameEnc, err := utils.Encrypt(request.Username)
+	if err != nil {
+		return err
+	}
+	count, err := a.ARepository.CountByUsername(usernameEnc)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return account.ErrUsernameTaken
+	}
+	if err := utils.ValidatePasswordPolicy(request.Password); err != nil {
+		return fmt.Errorf("%w: %s", account.ErrPasswordPolicy, err)
+	}
+	passwordHash, err := utils.HashPassword(request.Password)
+	if err != nil {
+		return err
+	}
+	firstNameEnc, err := utils.Encrypt(request.FirstName)
+	if err != nil {
+		return err
+	}
+	lastNameEnc, err := utils.Encrypt(request.LastName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	staff := &account.HtTmHotelManager{
+		ClientCompanyID:     owner.ClientCompanyID,
+		PropertyID:          owner.PropertyID,
+		WholesalerID:        owner.WholesalerID,
+		FirstNameEnc:        firstNameEnc,
+		LastNameEnc:         lastNameEnc,
+		EmailEnc:            invitation.EmailEnc,
+		UsernameEnc:         usernameEnc,
+		PasswordHash:        passwordHash,
+		PasswordChangedAt:   now,
+		OwnerHotelManagerID: owner.HotelManagerID,
+		Times:               common.Times{CreatedAt: now, UpdatedAt: now},
+	}
+	accepted, err := a.ARepository.AcceptInvitation(tokenHash, staff, strings.Split(invitation.Roles, ","))
+	if err != nil {
+		return err
+	}
+	if accepted == false {
+		return account.ErrInvitationInvalid
+	}
+	return nil
+}
+
+// DisableStaff 同じ施設・親アカウント配下のスタッフアカウントを無効化する
+func (a *accountUsecase) DisableStaff(claimParam *account.ClaimParam, hotelManagerID int64) error {
+	hmUser, err := a.fetchAccountAdmin(claimParam)
+	if err != nil {
+		return err
+	}
+	if hotelManagerID == hmUser.HotelManagerID {
+		return fmt.Errorf("Error: %s", "自身のアカウントは無効化できません。")
+	}
+	staff, err := a.ARepository.FetchOne(hotelManagerID)
+	if err != nil {
+		return err
+	}
+	if staff.OwnerHotelManagerID == 0 || staff.OwnerHotelManagerID != hmUser.TeamOwnerID() {
+		return fmt.Errorf("Error: %s", "無効化できるのは同じチームのスタッフアカウントのみです。")
+	}
+	return a.ARepository.DisableHotelManager(staff.HotelManagerID)
+}
+
+// FetchTeam 施設・親アカウントとスタッフアカウントの一覧、承諾待ちの招待を取得する
+func (a *accountUsecase) FetchTeam(claimParam *account.ClaimParam) (*account.TeamOutput, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return nil, err
+	}
+	members, err := a.ARepository.FetchTeam(hmUser.TeamOwnerID())
+	if err != nil {
+		return nil, err
+	}
+	output := &account.TeamOutput{
+		Members:     []account.TeamMemberOutput{},
+		Invitations: []account.InvitationOutput{},
+	}
+	for _, member := range members {
+		roles, err := a.FetchRoles(&member)
+		if err != nil {
+			return nil, err
+		}
+		firstName, err := utils.Decrypt(member.FirstNameEnc)
+		if err != nil {
+			return nil, err
+		}
+		lastName, err := utils.Decrypt(member.LastNameEnc)
+		if err != nil {
+			return nil, err
+		}
+		email, err := utils.Decrypt(member.EmailEnc)
+		if err != nil {
+			return nil, err
+		}
+		output.Members = append(output.Members, account.TeamMemberOutput{
+			HotelManagerID: member.HotelManagerID,
+			FirstName:      firstName,
+			LastName:       lastName,
+			Email:          email,
+			Roles:          roles,
+			IsOwner:        member.OwnerHotelManagerID == 0,
+			Disabled:       member.DelFlg,
+			LoginedAt:      member.LoginedAt,
+		})
+	}
+
+	invitations, err := a.ARepository.FetchPendingInvitations(hmUser.TeamOwnerID())
+	if err != nil {
+		return nil, err
+	}
+	for _, invitation := range invitations {
+		email, err := utils.Decrypt(invitation.EmailEnc)
+		if err != nil {
+			return nil, err
+		}
+		output.Invitations = append(output.Invitations, account.InvitationOutput{
+			InvitationID: invitation.InvitationID,
+			Email:        email,
+			Roles:        strings.Split(invitation.Roles, ","),
+			ExpiresAt:    invitation.ExpiresAt,
+		})
+	}
+	return output, nil
+}
+
+// fetchAccountAdmin ログイン中のアカウントを取得し、アカウントの権限設定の権限があるか確認する
+func (a *accountUsecase) fetchAccountAdmin(claimParam *account.ClaimParam) (account.HtTmHotelManager, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return hmUser, err
+	}
+	isAdmin, err := a.HasPermission(&hmUser, account.PermissionAccountAdmin)
+	if err != nil {
+		return hmUser, err
+	}
+	if isAdmin == false {
+		return hmUser, fmt.Errorf("Error: %s", "権限設定の権限がありません。")
+	}
+	return hmUser, nil
+}
@@ -0,0 +1,369 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// isTwoFactorRequired 親アカウントの設定で2段階認証が必須になっているか
+func (a *accountUsecase) isTwoFactorRequired(hmUser *account.HtTmHotelManager) (bool, error) {
+	if hmUser.PropertyID == 0 || hmUser.ClientCompanyID == 0 {
+		return false, nil
+	}
+	return a.ARepository.IsTwoFactorRequiredByParent(hmUser.ClientCompanyID)
+}
+
+// startTwoFactorChallenge パスワード認証済みのログインを、認証コードの入力待ちにする
+func (a *accountUsecase) startTwoFactorChallenge(hmUser *account.HtTmHotelManager, ipAddress string, userAgent string, enrollmentRequired bool) (*account.TokenOutput, error) {
+	challengeToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := a.ARepository.CreateTwoFactorChallenge(&account.HtThHotelManagerTwoFactorChallenges{
+		ChallengeTokenHash: utils.HashToken(challengeToken),
+		HotelManagerID:     hmUser.HotelManagerID,
+		IPAddress:          ipAddress,
+		UserAgent:          userAgent,
+		ExpiresAt:          now.Add(account.TwoFactorChallengeTTL),
+		CreatedAt:          now,
+	}); err != nil {
+		return nil, err
+	}
+	return &account.TokenOutput{
+		TwoFactorRequired:           true,
+		TwoFactorEnrollmentRequired: enrollmentRequired,
+		ChallengeToken:              challengeToken,
+	}, nil
+}
+
+// fetchValidChallenge 有効期限内・未使用・失敗回数上限未満のチャレンジのアカウントを取得
+// アカウントがロック中の場合はチャレンジを使わせない
+func (a *accountUsecase) fetchValidChallenge(challengeToken string) (account.HtTmHotelManager, error) {
+	challenge, err := a.ARepository.FetchTwoFactorChallenge(utils.HashToken(challengeToken))
+	if err != nil {
+		return account.HtTmHotelManager{}, account.ErrTwoFactorInvalid
+	}
+	now := time.Now()
+	if challenge.UsedAt.IsZero() == false ||
+		challenge.ExpiresAt.Before(now) ||
+		challenge.FailedNum >= account.TwoFactorChallengeMaxFailures {
+		return account.HtTmHotelManager{}, account.ErrTwoFactorInvalid
+	}
+	hmUser, err := a.ARepository.FetchOne(challenge.HotelManagerID)
+	if err != nil {
+		return hmUser, err
+	}
+	if now.Before(hmUser.LockedUntil) {
+		return hmUser, account.ErrAccountLocked
+	}
+	return hmUser, nil
+}
+
+// VerifyTwoFactorLogin ログイン2段階目。認証コードもしくはリカバリーコードを確認してトークンを発行する
+// 2段階認証の登録途中であれば、認証コードの確認をもって有効にし、リカバリーコードもあわせて返す
+// 認証コードの失敗はパスワードの失敗と同じく、アカウント・接続元IPのロックの回数に含める
+func (a *accountUsecase) VerifyTwoFactorLogin(request *account.TwoFactorLoginInput) (*account.TokenOutput, error) {
+	ipLocked, err := a.isIPLocked(request.IPAddress, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if ipLocked {
+		return nil, account.ErrAccountLocked
+	}
+	hmUser, err := a.fetchValidChallenge(request.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	verified := false
+	if twoFactor.EnabledFlg {
+		verified, err = a.verifySecondFactor(&twoFactor, request.Code, request.RecoveryCode)
+	} else if twoFactor.HotelManagerID != 0 && request.Code != "" {
+		recoveryCodes, verified, err = a.activate(&twoFactor, request.Code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	challengeTokenHash := utils.HashToken(request.ChallengeToken)
+	if verified == false {
+		if err := a.ARepository.UpdateTwoFactorChallengeFailed(challengeTokenHash); err != nil {
+			return nil, err
+		}
+		if err := a.recordLoginFailed(&hmUser, time.Now()); err != nil {
+			return nil, err
+		}
+		if err := a.ARepository.CreateLoginHistory(&account.HtThHotelManagerLoginHistories{
+			HotelManagerID: hmUser.HotelManagerID,
+			IPAddress:      request.IPAddress,
+			UserAgent:      request.UserAgent,
+			Result:         account.LoginResultTwoFactorFailure,
+			AttemptedAt:    time.Now(),
+		}); err != nil {
+			return nil, err
+		}
+		return nil, account.ErrTwoFactorInvalid
+	}
+
+	// 同じチャレンジで2回トークンを発行しない
+	consumed, err := a.ARepository.ConsumeTwoFactorChallenge(challengeTokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if consumed == false {
+		return nil, account.ErrTwoFactorInvalid
+	}
+	if err := a.clearLoginFailed(&hmUser); err != nil {
+		return nil, err
+	}
+	output, err := a.startSession(&hmUser, request.IPAddress, request.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	output.RecoveryCodes = recoveryCodes
+	return output, nil
+}
+
+// EnrollTwoFactorByChallenge 2段階認証が必須で未登録のアカウントが、ログイン中に登録を始める
+func (a *accountUsecase) EnrollTwoFactorByChallenge(challengeToken string) (*account.TwoFactorEnrollOutput, error) {
+	hmUser, err := a.fetchValidChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return a.enroll(&hmUser)
+}
+
+// FetchTwoFactorStatus 2段階認証の設定状況
+func (a *accountUsecase) FetchTwoFactorStatus(claimParam *account.ClaimParam) (*account.TwoFactorStatusOutput, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := a.isTwoFactorRequired(&hmUser)
+	if err != nil {
+		return nil, err
+	}
+	remaining, err := a.ARepository.CountRecoveryCodes(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+	return &account.TwoFactorStatusOutput{
+		Enabled:                twoFactor.EnabledFlg,
+		Required:               required,
+		RemainingRecoveryCodes: remaining,
+	}, nil
+}
+
+// EnrollTwoFactor ログイン中のアカウントで2段階認証の登録を始める
+func (a *accountUsecase) EnrollTwoFactor(claimParam *account.ClaimParam) (*account.TwoFactorEnrollOutput, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return nil, err
+	}
+	return a.enroll(&hmUser)
+}
+
+// ActivateTwoFactor 認証アプリに表示されたコードを確認して2段階認証を有効にする
+func (a *accountUsecase) ActivateTwoFactor(claimParam *account.ClaimParam, code string) (*account.RecoveryCodesOutput, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.HotelManagerID == 0 || twoFactor.EnabledFlg {
+		return nil, fmt.Errorf("Error: %s", "2段階認証の登録が開始されていません。")
+	}
+	recoveryCodes, verified, err := a.activate(&twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+	if verified == false {
+		return nil, account.ErrTwoFactorInvalid
+	}
+	return &account.RecoveryCodesOutput{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableTwoFactor パスワードと認証コードを確認して2段階認証を解除する
+func (a *accountUsecase) DisableTwoFactor(claimParam *account.ClaimParam, request *account.DisableTwoFactorInput) error {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return err
+	}
+	required, err := a.isTwoFactorRequired(&hmUser)
+	if err != nil {
+		return err
+	}
+	if required {
+		return account.ErrTwoFactorRequired
+	}
+	passwordOK, err := a.verifyPassword(&hmUser, request.Password)
+	if err != nil {
+		return err
+	}
+	if passwordOK == false {
+		return account.ErrTwoFactorInvalid
+	}
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return err
+	}
+	if twoFactor.EnabledFlg == false {
+		return a.ARepository.DeleteTwoFactor(hmUser.HotelManagerID)
+	}
+	verified, err := a.verifySecondFactor(&twoFactor, request.Code, request.RecoveryCode)
+	if err != nil {
+		return err
+	}
+	if verified == false {
+		return account.ErrTwoFactorInvalid
+	}
+	return a.ARepository.DeleteTwoFactor(hmUser.HotelManagerID)
+}
+
+// RegenerateRecoveryCodes 認証コードを確認してリカバリーコードを再発行する（以前のコードは使えなくなる）
+func (a *accountUsecase) RegenerateRecoveryCodes(claimParam *account.ClaimParam, code string) (*account.RecoveryCodesOutput, error) {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.EnabledFlg == false {
+		return nil, fmt.Errorf("Error: %s", "2段階認証が有効になっていません。")
+	}
+	verified, err := a.verifySecondFactor(&twoFactor, code, "")
+	if err != nil {
+		return nil, err
+	}
+	if verified == false {
+		return nil, account.ErrTwoFactorInvalid
+	}
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ARepository.ReplaceRecoveryCodes(hmUser.HotelManagerID, hashes); err != nil {
+		return nil, err
+	}
+	return &account.RecoveryCodesOutput{RecoveryCodes: recoveryCodes}, nil
+}
+
+// UpdateTwoFactorPolicy 親アカウントから、同じ取引先の子アカウントに2段階認証を必須にする
+func (a *accountUsecase) UpdateTwoFactorPolicy(claimParam *account.ClaimParam, request *account.TwoFactorPolicyInput) error {
+	hmUser, err := a.ARepository.FetchHMUserByToken(claimParam)
+	if err != nil {
+		return err
+	}
+	if hmUser.PropertyID != 0 {
+		return fmt.Errorf("Error: %s", "親アカウントではありません。")
+	}
+	isAdmin, err := a.HasPermission(&hmUser, account.PermissionAccountAdmin)
+	if err != nil {
+		return err
+	}
+	if isAdmin == false {
+		return fmt.Errorf("Error: %s", "権限設定の権限がありません。")
+	}
+	return a.ARepository.UpdateTwoFactorRequiredFlg(hmUser.HotelManagerID, request.Required)
+}
+
+// enroll 2段階認証のシークレットを発行し、登録途中として保存する
+func (a *accountUsecase) enroll(hmUser *account.HtTmHotelManager) (*account.TwoFactorEnrollOutput, error) {
+	twoFactor, err := a.ARepository.FetchTwoFactor(hmUser.HotelManagerID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor.EnabledFlg {
+		return nil, fmt.Errorf("Error: %s", "2段階認証は既に有効になっています。")
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	secretEnc, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.ARepository.SaveTwoFactorSecret(hmUser.HotelManagerID, secretEnc); err != nil {
+		return nil, err
+	}
+	username, err := utils.Decrypt(hmUser.UsernameEnc)
+	if err != nil {
+		return nil, err
+	}
+	return &account.TwoFactorEnrollOutput{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(username, secret),
+	}, nil
+}
+
+// activate 登録途中のシークレットで認証コードを確認し、2段階認証を有効にしてリカバリーコードを発行する
+func (a *accountUsecase) activate(twoFactor *account.HtTmHotelManagerTwoFactors, code string) ([]string, bool, error) {
+	secret, err := utils.DecryptSecret(twoFactor.SecretEnc)
+	if err != nil {
+		return nil, false, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if ok == false {
+		return nil, false, nil
+	}
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := a.ARepository.EnableTwoFactor(twoFactor.HotelManagerID, step, hashes); err != nil {
+		return nil, false, err
+	}
+	return recoveryCodes, true, nil
+}
+
+// verifySecondFactor 有効な2段階認証に対して、認証コードもしくはリカバリーコードを確認する
+// 一度使った認証コード・リカバリーコードは再利用できない
+func (a *accountUsecase) verifySecondFactor(twoFactor *account.HtTmHotelManagerTwoFactors, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		secret, err := utils.DecryptSecret(twoFactor.SecretEnc)
+		if err != nil {
+			return false, err
+		}
+		step, ok := utils.ValidateTOTP(secret, code, time.Now())
+		if ok == false || step <= twoFactor.LastUsedStep {
+			return false, nil
+		}
+		return a.ARepository.UpdateTwoFactorLastUsedStep(twoFactor.HotelManagerID, step)
+	}
+	if recoveryCode != "" {
+		return a.ARepository.UseRecoveryCode(twoFactor.HotelManagerID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	}
+	return false, nil
+}
+
+// generateRecoveryCodes リカバリーコードと保存用のハッシュ値を生成
+func generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes, err := utils.GenerateRecoveryCodes(account.RecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := []string{}
+	for _, code := range recoveryCodes {
+		hashes = append(hashes, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	}
+	return recoveryCodes, hashes, nil
+}
@@ -0,0 +1,205 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// ErrAnalyticsRange 集計期間の指定が正しくない
+var ErrAnalyticsRange = errors.New("Error: 集計期間が正しくありません。")
+
+const (
+	// GranularityDay 日別
+	GranularityDay = "day"
+	// GranularityMonth 月別
+	GranularityMonth = "month"
+
+	// GroupByRoomType 部屋タイプ別
+	GroupByRoomType = "room_type"
+	// GroupByPlan プラン別（プランには販売可能室数がないため、稼働率・RevPARは0）
+	GroupByPlan = "plan"
+	// GroupByWholesaler 卸別
+	GroupByWholesaler = "wholesaler"
+
+	// MaxRangeDays 1回に集計できる日数
+	MaxRangeDays = 731
+	// DefaultSummaryMaxAge 集計結果を使う期間（過ぎたものは集計し直す）
+	DefaultSummaryMaxAge = time.Hour
+	// RefreshPastDays バックグラウンドで集計し直す期間（今日より前の日数）
+	RefreshPastDays = 31
+	// RefreshFutureDays バックグラウンドで集計し直す期間（今日以降の日数）
+	RefreshFutureDays = 365
+	// RefreshBatchSize 1回の処理で集計し直す施設数
+	RefreshBatchSize = 20
+	// DefaultAnalyticsWorkerInterval 古くなった集計結果を確認する間隔
+	DefaultAnalyticsWorkerInterval = 10 * time.Minute
+)
+
+// WholesalerNames 卸の表記
+var WholesalerNames = map[int64]string{
+	utils.WholesalerIDTl:     "TLリンカーン",
+	utils.WholesalerIDTema:   "手間いらず",
+	utils.WholesalerIDNeppan: "ねっぱん",
+	utils.WholesalerIDDirect: "直仕入れ",
+	utils.WholesalerIDRaku2:  "らく通",
+}
+
+// HasInventory 販売可能室数を集計している卸か（手間いらずは在庫を連携先で管理しているため集計していない）
+func HasInventory(wholesalerID int64) bool {
+	return wholesalerID != utils.WholesalerIDTema
+}
+
+// HtThAnalyticsDailySales 日別の販売実績の集計テーブル（卸・部屋タイプ・プランごと）
+type HtThAnalyticsDailySales struct {
+	PropertyID   int64     `gorm:"primaryKey" json:"property_id"`
+	UseDate      time.Time `gorm:"primaryKey" json:"use_date"`
+	WholesalerID int64     `gorm:"primaryKey" json:"wholesaler_id"`
+	RoomTypeID   int64     `gorm:"primaryKey" json:"room_type_id"`
+	PlanID       int64     `gorm:"primaryKey" json:"plan_id"`
+	// RoomNights 宿泊する部屋・泊の数（キャンセル・NoShowを除く）
+	RoomNights int `json:"room_nights"`
+	// Revenue 宿泊する部屋・泊の料金の合計（税込）
+	Revenue            int64 `json:"revenue"`
+	CanceledRoomNights int   `json:"canceled_room_nights"`
+	NoShowRoomNights   int   `json:"no_show_room_nights"`
+	common.Times       `gorm:"embedded"`
+}
+
+// HtThAnalyticsDailyInventories 日別の販売可能室数の集計テーブル（卸・部屋タイプごと）
+// 手間いらずは在庫を連携先で管理しているため含まない
+type HtThAnalyticsDailyInventories struct {
+	PropertyID   int64     `gorm:"primaryKey" json:"property_id"`
+	UseDate      time.Time `gorm:"primaryKey" json:"use_date"`
+	WholesalerID int64     `gorm:"primaryKey" json:"wholesaler_id"`
+	RoomTypeID   int64     `gorm:"primaryKey" json:"room_type_id"`
+	RoomCount    int       `json:"room_count"`
+	common.Times `gorm:"embedded"`
+}
+
+// HtThAnalyticsRefreshes 施設・日付ごとの集計日時
+type HtThAnalyticsRefreshes struct {
+	PropertyID  int64     `gorm:"primaryKey" json:"property_id"`
+	UseDate     time.Time `gorm:"primaryKey" json:"use_date"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+}
+
+// ReportInput 稼働率・ADR・RevPARの集計の入力
+type ReportInput struct {
+	PropertyID int64  `json:"property_id" query:"property_id" validate:"required"`
+	From       string `json:"from" query:"from" validate:"required,datetime=2006-01-02"`
+	To         string `json:"to" query:"to" validate:"required,datetime=2006-01-02"`
+	// Granularity 集計単位（未指定の場合は日別）
+	Granularity string `json:"granularity" query:"granularity" validate:"omitempty,oneof=day month"`
+	// GroupBy 内訳（未指定の場合は施設全体）
+	GroupBy string `json:"group_by" query:"group_by" validate:"omitempty,oneof=room_type plan wholesaler"`
+	// Refresh 集計結果を使わずに集計し直す
+	Refresh bool `json:"refresh" query:"refresh"`
+}
+
+// ReportOutput 稼働率・ADR・RevPARの集計の出力
+type ReportOutput struct {
+	PropertyID  int64  `json:"property_id"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Granularity string `json:"granularity"`
+	GroupBy     string `json:"group_by"`
+	// RefreshedAt 集計日時（期間内で最も古いもの）
+	RefreshedAt time.Time   `json:"refreshed_at"`
+	Total       Metrics     `json:"total"`
+	Rows        []ReportRow `json:"rows"`
+}
+
+// ReportRow 期間・内訳ごとの集計結果
+type ReportRow struct {
+	// Period 日別は YYYY-MM-DD、月別は YYYY-MM
+	Period       string `json:"period"`
+	WholesalerID int64  `json:"wholesaler_id,omitempty"`
+	RoomTypeID   int64  `json:"room_type_id,omitempty"`
+	PlanID       int64  `json:"plan_id,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Metrics
+}
+
+// Metrics 集計値
+type Metrics struct {
+	// RoomCount 販売可能室数（延べ）
+	RoomCount          int   `json:"room_count"`
+	RoomNights         int   `json:"room_nights"`
+	Revenue            int64 `json:"revenue"`
+	CanceledRoomNights int   `json:"canceled_room_nights"`
+	NoShowRoomNights   int   `json:"no_show_room_nights"`
+	// InventoryRoomNights 販売可能室数を集計している卸の販売室数（稼働率・RevPARの計算に使う）
+	InventoryRoomNights int `json:"inventory_room_nights"`
+	// InventoryRevenue 販売可能室数を集計している卸の売上
+	InventoryRevenue int64 `json:"inventory_revenue"`
+	// Occupancy 稼働率（%）。販売可能室数を集計していない卸（手間いらず）の販売室数は含めない
+	Occupancy float64 `json:"occupancy"`
+	// ADR 客室平均単価（売上÷販売室数）
+	ADR float64 `json:"adr"`
+	// RevPAR 販売可能客室あたり売上（売上÷販売可能室数）。販売可能室数を集計していない卸の売上は含めない
+	RevPAR float64 `json:"revpar"`
+}
+
+// AddSales 販売実績を加算
+func (m *Metrics) AddSales(sales *HtThAnalyticsDailySales) {
+	m.RoomNights += sales.RoomNights
+	m.Revenue += sales.Revenue
+	m.CanceledRoomNights += sales.CanceledRoomNights
+	m.NoShowRoomNights += sales.NoShowRoomNights
+	if HasInventory(sales.WholesalerID) {
+		m.InventoryRoomNights += sales.RoomNights
+		m.InventoryRevenue += sales.Revenue
+	}
+}
+
+// Calculate 加算した値から稼働率・ADR・RevPARを計算（小数第2位まで）
+func (m *Metrics) Calculate() {
+	m.Occupancy, m.ADR, m.RevPAR = 0, 0, 0
+	if m.RoomNights > 0 {
+		m.ADR = round2(float64(m.Revenue) / float64(m.RoomNights))
+	}
+	if m.RoomCount > 0 {
+		m.Occupancy = round2(float64(m.InventoryRoomNights) * 100 / float64(m.RoomCount))
+		m.RevPAR = round2(float64(m.InventoryRevenue) / float64(m.RoomCount))
+	}
+}
+
+// round2 小数第2位で四捨五入
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// IAnalyticsUsecase 売上分析関連のusecaseのインターフェース
+type IAnalyticsUsecase interface {
+	Report(req ReportInput) (*ReportOutput, error)
+	Pickup(req PickupInput) (*PickupOutput, error)
+	RefreshStale() (int, error)
+}
+
+// IAnalyticsRepository 売上分析関連のrepositoryのインターフェース
+type IAnalyticsRepository interface {
+	// AggregateSales 予約の料金情報から日別の販売実績を集計
+	AggregateSales(propertyID int64, from string, to string) ([]HtThAnalyticsDailySales, error)
+	// AggregateInventories 在庫から日別の販売可能室数を集計
+	AggregateInventories(propertyID int64, from string, to string) ([]HtThAnalyticsDailyInventories, error)
+	// ReplaceSummaries 期間内の集計結果を入れ替え、集計日時を記録
+	ReplaceSummaries(propertyID int64, from string, to string, sales []HtThAnalyticsDailySales, inventories []HtThAnalyticsDailyInventories, refreshes []HtThAnalyticsRefreshes) error
+	// FetchRefreshes 期間内の集計日時を取得
+	FetchRefreshes(propertyID int64, from string, to string) ([]HtThAnalyticsRefreshes, error)
+	// FetchSales 期間内の販売実績の集計結果を取得
+	FetchSales(propertyID int64, from string, to string) ([]HtThAnalyticsDailySales, error)
+	// FetchInventories 期間内の販売可能室数の集計結果を取得
+	FetchInventories(propertyID int64, from string, to string) ([]HtThAnalyticsDailyInventories, error)
+	// FetchStalePropertyIDs 期間内に、指定日時より前に集計した日がある施設を取得
+	FetchStalePropertyIDs(from string, to string, before time.Time, limit int) ([]int64, error)
+	// FetchPickupBookings 宿泊日の期間内の予約ごと・宿泊日ごとの部屋数と売上を取得（キャンセル済みを含む）
+	FetchPickupBookings(propertyID int64, from string, to string) ([]PickupBooking, error)
+	// FetchRoomTypeNames 卸の部屋タイプ名を取得（部屋タイプID→名前）
+	FetchRoomTypeNames(wholesalerID int64, roomTypeIDs []int64) (map[int64]string, error)
+	// FetchPlanNames 卸のプラン名を取得（プランID→名前）
+	FetchPlanNames(wholesalerID int64, planIDs []int64) (map[int64]string, error)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+	"github.com/Adventureinc/hotel-hm-api/src/analytics/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// AnalyticsHandler 売上分析関連の振り分け
+type AnalyticsHandler struct {
+	AnUsecase analytics.IAnalyticsUsecase
+	AUsecase  account.IAccountUsecase
+	OUsecase  ownership.IOwnershipUsecase
+}
+
+// NewAnalyticsHandler インスタンス生成
+func NewAnalyticsHandler(db *gorm.DB) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		AnUsecase: usecase.NewAnalyticsUsecase(db),
+		AUsecase:  aUsecase.NewAccountUsecase(db),
+		OUsecase:  oUsecase.NewOwnershipUsecase(db),
+	}
+}
+
+// Report 稼働率・ADR・RevPARなどの集計
+func (a *AnalyticsHandler) Report(c echo.Context) error {
+	hmUser, err := a.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &analytics.ReportInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	report, err := a.AnUsecase.Report(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, analytics.ErrAnalyticsRange) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// Pickup ブッキングペース・ピックアップ（前年同日との比較）
+func (a *AnalyticsHandler) Pickup(c echo.Context) error {
+	hmUser, err := a.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &analytics.PickupInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	pickup, err := a.AnUsecase.Pickup(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, analytics.ErrAnalyticsRange) || errors.Is(err, analytics.ErrPickupSnapshot) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, pickup)
+}
+
+// getHmUser トークンからHMアカウント情報を取得
+func (a *AnalyticsHandler) getHmUser(c echo.Context) (account.HtTmHotelManager, error) {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return account.HtTmHotelManager{}, err
+	}
+	return a.AUsecase.FetchHMUserByToken(claimParam)
+}
@@ -0,0 +1,236 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// wholesalerTables 卸ごとの在庫・部屋タイプ・プランのテーブル
+type wholesalerTables struct {
+	stock    string
+	roomType string
+	plan     string
+}
+
+// tables 在庫を持つ卸のテーブル（手間いらずは在庫を連携先で管理しているため含まない）
+var tables = map[int64]wholesalerTables{
+	utils.WholesalerIDTl:     {"ht_tm_stock_tls", "ht_tm_room_type_tls", "ht_tm_plan_tls"},
+	utils.WholesalerIDNeppan: {"ht_tm_stock_neppans", "ht_tm_room_type_neppans", "ht_tm_plan_neppans"},
+	utils.WholesalerIDDirect: {"ht_tm_stock_directs", "ht_tm_room_type_directs", "ht_tm_plan_directs"},
+	utils.WholesalerIDRaku2:  {"ht_tm_stock_raku2s", "ht_tm_room_type_raku2s", "ht_tm_plan_raku2s"},
+}
+
+// revenueColumn 1泊分の料金（税込）。大人料金は人数分の合計、子供料金は1人あたりの金額で登録されている
+const revenueColumn = "prices.price_in_tax" +
+	" + prices.child_price1_in_tax * prices.child_1_person" +
+	" + prices.child_price2_in_tax * prices.child_2_person" +
+	" + prices.child_price3_in_tax * prices.child_3_person" +
+	" + prices.child_price4_in_tax * prices.child_4_person" +
+	" + prices.child_price5_in_tax * prices.child_5_person" +
+	" + prices.child_price6_in_tax * prices.child_6_person"
+
+// pickupCancelFlgColumn 予約全体もしくは一部キャンセルでキャンセルされた部屋・泊か
+const pickupCancelFlgColumn = "(applications.cancel_flg = 1 OR prices.cancel_flg = 1)"
+
+// pickupCanceledDtColumn 部屋・泊がキャンセルされた日時（予約全体のキャンセルを優先する）
+const pickupCanceledDtColumn = "CASE WHEN applications.cancel_flg = 1 THEN applications.canceled_dt ELSE prices.canceled_dt END"
+
+// analyticsRepository 売上分析関連repository
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsRepository インスタンス生成
+func NewAnalyticsRepository(db *gorm.DB) analytics.IAnalyticsRepository {
+	return &analyticsRepository{
+		db: db,
+	}
+}
+
+// AggregateSales 予約の料金情報から日別の販売実績を集計
+// NoShowはキャンセル扱いで登録されるため、キャンセルの数には含めない。一部キャンセルした部屋・泊はキャンセルの数に含める
+func (a *analyticsRepository) AggregateSales(propertyID int64, from string, to string) ([]analytics.HtThAnalyticsDailySales, error) {
+	targetWholesalers := []int{utils.WholesalerIDTl, utils.WholesalerIDTema, utils.WholesalerIDNeppan, utils.WholesalerIDDirect, utils.WholesalerIDRaku2}
+	result := []analytics.HtThAnalyticsDailySales{}
+	err := a.db.
+		Table("ht_th_booking_prices AS prices").
+		Select("applications.property_id, DATE(prices.use_date) AS use_date, applications.wholesaler_id, prices.room_type_id, prices.plan_id, "+
+			"SUM(CASE WHEN applications.cancel_flg = 0 AND prices.cancel_flg = 0 THEN 1 ELSE 0 END) AS room_nights, "+
+			"SUM(CASE WHEN applications.cancel_flg = 0 AND prices.cancel_flg = 0 THEN "+revenueColumn+" ELSE 0 END) AS revenue, "+
+			"SUM(CASE WHEN (applications.cancel_flg = 1 AND applications.noshow_flg = 0) OR (applications.cancel_flg = 0 AND prices.cancel_flg = 1) THEN 1 ELSE 0 END) AS canceled_room_nights, "+
+			"SUM(CASE WHEN applications.noshow_flg = 1 THEN 1 ELSE 0 END) AS no_show_room_nights").
+		Joins("INNER JOIN ht_th_applications AS applications ON prices.cm_application_id = applications.cm_application_id").
+		Where("applications.property_id = ?", propertyID).
+		Where("applications.wholesaler_id IN ?", targetWholesalers).
+		Where("prices.use_date >= ?", from).
+		Where("prices.use_date < DATE_ADD(?, INTERVAL 1 DAY)", to).
+		Group("applications.property_id, DATE(prices.use_date), applications.wholesaler_id, prices.room_type_id, prices.plan_id").
+		Scan(&result).Error
+	return result, err
+}
+
+// AggregateInventories 在庫から日別の販売可能室数を集計
+func (a *analyticsRepository) AggregateInventories(propertyID int64, from string, to string) ([]analytics.HtThAnalyticsDailyInventories, error) {
+	result := []analytics.HtThAnalyticsDailyInventories{}
+	for wholesalerID, t := range tables {
+		rows := []analytics.HtThAnalyticsDailyInventories{}
+		err := a.db.
+			Table(t.stock+" AS stock").
+			Select("room.property_id, DATE(stock.use_date) AS use_date, ? AS wholesaler_id, stock.room_type_id, SUM(stock.room_count) AS room_count", wholesalerID).
+			Joins("INNER JOIN "+t.roomType+" AS room ON stock.room_type_id = room.room_type_id").
+			Where("room.property_id = ?", propertyID).
+			Where("room.is_delete = 0").
+			Where("stock.use_date >= ?", from).
+			Where("stock.use_date < DATE_ADD(?, INTERVAL 1 DAY)", to).
+			Group("room.property_id, DATE(stock.use_date), stock.room_type_id").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rows...)
+	}
+	return result, nil
+}
+
+// ReplaceSummaries 期間内の集計結果を入れ替え、集計日時を記録
+func (a *analyticsRepository) ReplaceSummaries(propertyID int64, from string, to string, sales []analytics.HtThAnalyticsDailySales, inventories []analytics.HtThAnalyticsDailyInventories, refreshes []analytics.HtThAnalyticsRefreshes) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("property_id = ?", propertyID).
+			Where("use_date BETWEEN ? AND ?", from, to).
+			Delete(&analytics.HtThAnalyticsDailySales{}).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Where("property_id = ?", propertyID).
+			Where("use_date BETWEEN ? AND ?", from, to).
+			Delete(&analytics.HtThAnalyticsDailyInventories{}).Error; err != nil {
+			return err
+		}
+		if len(sales) > 0 {
+			if err := tx.Create(&sales).Error; err != nil {
+				return err
+			}
+		}
+		if len(inventories) > 0 {
+			if err := tx.Create(&inventories).Error; err != nil {
+				return err
+			}
+		}
+		if len(refreshes) == 0 {
+			return nil
+		}
+		return tx.
+			Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"refreshed_at"})}).
+			Create(&refreshes).Error
+	})
+}
+
+// FetchRefreshes 期間内の集計日時を取得
+func (a *analyticsRepository) FetchRefreshes(propertyID int64, from string, to string) ([]analytics.HtThAnalyticsRefreshes, error) {
+	result := []analytics.HtThAnalyticsRefreshes{}
+	err := a.db.
+		Model(&analytics.HtThAnalyticsRefreshes{}).
+		Where("property_id = ?", propertyID).
+		Where("use_date BETWEEN ? AND ?", from, to).
+		Find(&result).Error
+	return result, err
+}
+
+// FetchSales 期間内の販売実績の集計結果を取得
+func (a *analyticsRepository) FetchSales(propertyID int64, from string, to string) ([]analytics.HtThAnalyticsDailySales, error) {
+	result := []analytics.HtThAnalyticsDailySales{}
+	err := a.db.
+		Model(&analytics.HtThAnalyticsDailySales{}).
+		Where("property_id = ?", propertyID).
+		Where("use_date BETWEEN ? AND ?", from, to).
+		Order("use_date").
+		Find(&result).Error
+	return result, err
+}
+
+// FetchInventories 期間内の販売可能室数の集計結果を取得
+func (a *analyticsRepository) FetchInventories(propertyID int64, from string, to string) ([]analytics.HtThAnalyticsDailyInventories, error) {
+	result := []analytics.HtThAnalyticsDailyInventories{}
+	err := a.db.
+		Model(&analytics.HtThAnalyticsDailyInventories{}).
+		Where("property_id = ?", propertyID).
+		Where("use_date BETWEEN ? AND ?", from, to).
+		Order("use_date").
+		Find(&result).Error
+	return result, err
+}
+
+// FetchStalePropertyIDs 期間内に、指定日時より前に集計した日がある施設を取得（古いものから）
+func (a *analyticsRepository) FetchStalePropertyIDs(from string, to string, before time.Time, limit int) ([]int64, error) {
+	result := []int64{}
+	err := a.db.
+		Model(&analytics.HtThAnalyticsRefreshes{}).
+		Select("property_id").
+		Where("use_date BETWEEN ? AND ?", from, to).
+		Where("refreshed_at < ?", before).
+		Group("property_id").
+		Order("MIN(refreshed_at)").
+		Limit(limit).
+		Pluck("property_id", &result).Error
+	return result, err
+}
+
+// FetchPickupBookings 宿泊日の期間内の予約ごと・宿泊日ごとの部屋数と売上を取得（キャンセル済みを含む）
+// 一部キャンセルした部屋・泊は、一部キャンセルした日時にキャンセルされたものとして返す
+func (a *analyticsRepository) FetchPickupBookings(propertyID int64, from string, to string) ([]analytics.PickupBooking, error) {
+	targetWholesalers := []int{utils.WholesalerIDTl, utils.WholesalerIDTema, utils.WholesalerIDNeppan, utils.WholesalerIDDirect, utils.WholesalerIDRaku2}
+	result := []analytics.PickupBooking{}
+	err := a.db.
+		Table("ht_th_booking_prices AS prices").
+		Select("DATE(prices.use_date) AS use_date, applications.created_at, "+pickupCancelFlgColumn+" AS cancel_flg, "+pickupCanceledDtColumn+" AS canceled_dt, "+
+			"COUNT(*) AS rooms, SUM("+revenueColumn+") AS revenue").
+		Joins("INNER JOIN ht_th_applications AS applications ON prices.cm_application_id = applications.cm_application_id").
+		Where("applications.property_id = ?", propertyID).
+		Where("applications.wholesaler_id IN ?", targetWholesalers).
+		Where("prices.use_date >= ?", from).
+		Where("prices.use_date < DATE_ADD(?, INTERVAL 1 DAY)", to).
+		Group("applications.cm_application_id, DATE(prices.use_date), applications.created_at, " + pickupCancelFlgColumn + ", " + pickupCanceledDtColumn).
+		Scan(&result).Error
+	return result, err
+}
+
+// FetchRoomTypeNames 卸の部屋タイプ名を取得（部屋タイプID→名前）
+func (a *analyticsRepository) FetchRoomTypeNames(wholesalerID int64, roomTypeIDs []int64) (map[int64]string, error) {
+	t, ok := tables[wholesalerID]
+	if !ok || len(roomTypeIDs) == 0 {
+		return map[int64]string{}, nil
+	}
+	return a.fetchNames(t.roomType, "room_type_id", roomTypeIDs)
+}
+
+// FetchPlanNames 卸のプラン名を取得（プランID→名前）
+func (a *analyticsRepository) FetchPlanNames(wholesalerID int64, planIDs []int64) (map[int64]string, error) {
+	t, ok := tables[wholesalerID]
+	if !ok || len(planIDs) == 0 {
+		return map[int64]string{}, nil
+	}
+	return a.fetchNames(t.plan, "plan_id", planIDs)
+}
+
+// fetchNames テーブルのIDと名前の対応を取得
+func (a *analyticsRepository) fetchNames(table string, idColumn string, ids []int64) (map[int64]string, error) {
+	rows := []struct {
+		ID   int64
+		Name string
+	}{}
+	err := a.db.
+		Table(table).
+		Select(idColumn+" AS id, name").
+		Where(idColumn+" IN ?", ids).
+		Scan(&rows).Error
+	result := map[int64]string{}
+	for _, row := range rows {
+		result[row.ID] = row.Name
+	}
+	return result, err
+}
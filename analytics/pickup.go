@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPickupSnapshot ピックアップの基準日が今日より後
+var ErrPickupSnapshot = errors.New("Error: ピックアップの基準日には今日以前の日付を指定してください。")
+
+const (
+	// MaxPickupRangeDays 1回に集計できる宿泊日の日数
+	MaxPickupRangeDays = 93
+	// DefaultPickupSnapshotDays 基準日を指定しない場合に、何日前を基準日とするか
+	DefaultPickupSnapshotDays = 7
+)
+
+// DefaultPickupLeadDays 到着の何日前時点の予約数を出すか（指定しない場合）
+var DefaultPickupLeadDays = []int{0, 7, 14, 30, 60, 90}
+
+// PickupInput ブッキングペース・ピックアップの入力
+type PickupInput struct {
+	PropertyID int64 `json:"property_id" query:"property_id" validate:"required"`
+	// From・To 宿泊日の期間
+	From string `json:"from" query:"from" validate:"required,datetime=2006-01-02"`
+	To   string `json:"to" query:"to" validate:"required,datetime=2006-01-02"`
+	// SnapshotDate ピックアップの基準日（この日の終わり時点からの増減を出す。未指定の場合は7日前）
+	SnapshotDate string `json:"snapshot_date" query:"snapshot_date" validate:"omitempty,datetime=2006-01-02"`
+	// LeadDays 到着の何日前時点の予約数を出すか
+	LeadDays []int `json:"lead_days" query:"lead_days" validate:"omitempty,max=12,dive,min=0,max=365"`
+}
+
+// PickupOutput ブッキングペース・ピックアップの出力
+type PickupOutput struct {
+	PropertyID   int64       `json:"property_id"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	SnapshotDate string      `json:"snapshot_date"`
+	LeadDays     []int       `json:"lead_days"`
+	AsOf         time.Time   `json:"as_of"`
+	Rows         []PickupRow `json:"rows"`
+}
+
+// PickupRow 宿泊日ごとの予約状況
+type PickupRow struct {
+	StayDate string `json:"stay_date"`
+	// OnTheBooks 現在の予約数
+	OnTheBooks PickupMetrics `json:"on_the_books"`
+	// AtSnapshot 基準日の終わり時点の予約数
+	AtSnapshot PickupMetrics `json:"at_snapshot"`
+	// Pickup 基準日からの増減
+	Pickup   PickupMetrics  `json:"pickup"`
+	Pace     []PacePoint    `json:"pace"`
+	LastYear PickupLastYear `json:"last_year"`
+}
+
+// PickupLastYear 前年同日（1年前の同じ日付）の予約状況
+type PickupLastYear struct {
+	StayDate string `json:"stay_date"`
+	// Actual 現在の予約数（宿泊日を過ぎていれば最終的な実績）
+	Actual PickupMetrics `json:"actual"`
+	// AtSnapshot 基準日の1年前の終わり時点の予約数
+	AtSnapshot PickupMetrics `json:"at_snapshot"`
+	Pace       []PacePoint   `json:"pace"`
+}
+
+// PacePoint 到着のN日前の終わり時点の予約数（まだその日になっていない場合はnull）
+type PacePoint struct {
+	DaysBefore int            `json:"days_before"`
+	OnTheBooks *PickupMetrics `json:"on_the_books"`
+}
+
+// PickupMetrics 部屋・泊の数と売上（税込）
+type PickupMetrics struct {
+	Rooms   int   `json:"rooms"`
+	Revenue int64 `json:"revenue"`
+}
+
+// Sub 差分
+func (p PickupMetrics) Sub(other PickupMetrics) PickupMetrics {
+	return PickupMetrics{Rooms: p.Rooms - other.Rooms, Revenue: p.Revenue - other.Revenue}
+}
+
+// PickupBooking 予約・宿泊日ごとの部屋数と売上
+// 一部キャンセルや変更で削除された料金情報は含まれないため、その部屋・泊は過去の時点でも数えない
+type PickupBooking struct {
+	UseDate    time.Time  `json:"use_date"`
+	CreatedAt  time.Time  `json:"created_at"`
+	CancelFlg  bool       `json:"cancel_flg"`
+	CanceledDt *time.Time `json:"canceled_dt"`
+	Rooms      int        `json:"rooms"`
+	Revenue    int64      `json:"revenue"`
+}
+
+// OnTheBooksAt 指定日時より前に予約され、その時点でキャンセルされていないか
+// キャンセル日時が分からないキャンセル済みの予約は、どの時点でも数えない
+func (p *PickupBooking) OnTheBooksAt(t time.Time) bool {
+	if !p.CreatedAt.Before(t) {
+		return false
+	}
+	if !p.CancelFlg {
+		return true
+	}
+	return p.CanceledDt != nil && !p.CanceledDt.IsZero() && !p.CanceledDt.Before(t)
+}
@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+	"github.com/Adventureinc/hotel-hm-api/src/analytics/infra"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	dateFormat  = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+// analyticsUsecase 売上分析関連usecase
+type analyticsUsecase struct {
+	AnRepository analytics.IAnalyticsRepository
+}
+
+// NewAnalyticsUsecase インスタンス生成
+func NewAnalyticsUsecase(db *gorm.DB) analytics.IAnalyticsUsecase {
+	return &analyticsUsecase{
+		AnRepository: infra.NewAnalyticsRepository(db),
+	}
+}
+
+// Report 期間内の稼働率・ADR・RevPAR・販売室数・キャンセル・NoShowを日別・月別に集計
+// 集計結果がない日・古くなった日がある場合は、期間全体を集計し直してから返す
+func (a *analyticsUsecase) Report(req analytics.ReportInput) (*analytics.ReportOutput, error) {
+	days, err := rangeDays(req.From, req.To, analytics.MaxRangeDays)
+	if err != nil {
+		return nil, err
+	}
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = analytics.GranularityDay
+	}
+
+	now := time.Now()
+	refreshes, err := a.AnRepository.FetchRefreshes(req.PropertyID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	refreshedAt := oldestRefresh(refreshes)
+	if req.Refresh || len(refreshes) < len(days) || refreshedAt.Before(now.Add(-SummaryMaxAge())) {
+		if err := a.refresh(req.PropertyID, days, now); err != nil {
+			return nil, err
+		}
+		refreshedAt = now
+	}
+
+	sales, err := a.AnRepository.FetchSales(req.PropertyID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	inventories, err := a.AnRepository.FetchInventories(req.PropertyID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	total, rows := buildReport(days, granularity, req.GroupBy, sales, inventories)
+	if err := a.fillNames(req.GroupBy, rows); err != nil {
+		return nil, err
+	}
+	return &analytics.ReportOutput{
+		PropertyID:  req.PropertyID,
+		From:        req.From,
+		To:          req.To,
+		Granularity: granularity,
+		GroupBy:     req.GroupBy,
+		RefreshedAt: refreshedAt,
+		Total:       total,
+		Rows:        rows,
+	}, nil
+}
+
+// RefreshStale 集計し直す期間（今日の前後）に古くなった集計結果がある施設を集計し直し、件数を返す
+// 一度も集計していない施設は、最初に集計結果を取得した時に集計する
+func (a *analyticsUsecase) RefreshStale() (int, error) {
+	now := time.Now()
+	from := now.AddDate(0, 0, -analytics.RefreshPastDays).Format(dateFormat)
+	to := now.AddDate(0, 0, analytics.RefreshFutureDays).Format(dateFormat)
+	days, err := rangeDays(from, to, analytics.MaxRangeDays)
+	if err != nil {
+		return 0, err
+	}
+	propertyIDs, err := a.AnRepository.FetchStalePropertyIDs(from, to, now.Add(-SummaryMaxAge()), analytics.RefreshBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, propertyID := range propertyIDs {
+		if err := a.refresh(propertyID, days, now); err != nil {
+			return i, err
+		}
+	}
+	return len(propertyIDs), nil
+}
+
+// refresh 予約の料金情報・在庫から期間内を集計し直す
+func (a *analyticsUsecase) refresh(propertyID int64, days []string, now time.Time) error {
+	from, to := days[0], days[len(days)-1]
+	sales, err := a.AnRepository.AggregateSales(propertyID, from, to)
+	if err != nil {
+		return err
+	}
+	inventories, err := a.AnRepository.AggregateInventories(propertyID, from, to)
+	if err != nil {
+		return err
+	}
+	refreshes := []analytics.HtThAnalyticsRefreshes{}
+	for _, day := range days {
+		useDate, _ := time.ParseInLocation(dateFormat, day, time.Local)
+		refreshes = append(refreshes, analytics.HtThAnalyticsRefreshes{PropertyID: propertyID, UseDate: useDate, RefreshedAt: now})
+	}
+	return a.AnRepository.ReplaceSummaries(propertyID, from, to, sales, inventories, refreshes)
+}
+
+// fillNames 内訳の卸・部屋タイプ・プランの名前を設定
+func (a *analyticsUsecase) fillNames(groupBy string, rows []analytics.ReportRow) error {
+	if groupBy == analytics.GroupByWholesaler {
+		for i := range rows {
+			rows[i].Name = analytics.WholesalerNames[rows[i].WholesalerID]
+		}
+		return nil
+	}
+	if groupBy != analytics.GroupByRoomType && groupBy != analytics.GroupByPlan {
+		return nil
+	}
+	ids := map[int64][]int64{}
+	for _, row := range rows {
+		id := row.RoomTypeID
+		if groupBy == analytics.GroupByPlan {
+			id = row.PlanID
+		}
+		ids[row.WholesalerID] = append(ids[row.WholesalerID], id)
+	}
+	names := map[int64]map[int64]string{}
+	for wholesalerID, wholesalerIDs := range ids {
+		var err error
+		if groupBy == analytics.GroupByPlan {
+			names[wholesalerID], err = a.AnRepository.FetchPlanNames(wholesalerID, wholesalerIDs)
+		} else {
+			names[wholesalerID], err = a.AnRepository.FetchRoomTypeNames(wholesalerID, wholesalerIDs)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for i := range rows {
+		id := rows[i].RoomTypeID
+		if groupBy == analytics.GroupByPlan {
+			id = rows[i].PlanID
+		}
+		rows[i].Name = names[rows[i].WholesalerID][id]
+	}
+	return nil
+}
+
+// reportKey 集計結果の行のキー（期間と内訳）
+type reportKey struct {
+	period       string
+	wholesalerID int64
+	roomTypeID   int64
+	planID       int64
+}
+
+// buildReport 日別の集計結果を期間・内訳ごとにまとめる
+// 内訳を指定しない場合は、実績のない日・月も行に含める
+func buildReport(days []string, granularity string, groupBy string, sales []analytics.HtThAnalyticsDailySales, inventories []analytics.HtThAnalyticsDailyInventories) (analytics.Metrics, []analytics.ReportRow) {
+	period := func(useDate time.Time) string {
+		if granularity == analytics.GranularityMonth {
+			return useDate.Format(monthFormat)
+		}
+		return useDate.Format(dateFormat)
+	}
+	key := func(useDate time.Time, wholesalerID int64, roomTypeID int64, planID int64) reportKey {
+		k := reportKey{period: period(useDate)}
+		switch groupBy {
+		case analytics.GroupByWholesaler:
+			k.wholesalerID = wholesalerID
+		case analytics.GroupByRoomType:
+			k.wholesalerID, k.roomTypeID = wholesalerID, roomTypeID
+		case analytics.GroupByPlan:
+			k.wholesalerID, k.roomTypeID, k.planID = wholesalerID, roomTypeID, planID
+		}
+		return k
+	}
+
+	total := analytics.Metrics{}
+	metrics := map[reportKey]*analytics.Metrics{}
+	at := func(k reportKey) *analytics.Metrics {
+		if _, ok := metrics[k]; !ok {
+			metrics[k] = &analytics.Metrics{}
+		}
+		return metrics[k]
+	}
+	if groupBy == "" {
+		for _, day := range days {
+			useDate, _ := time.Parse(dateFormat, day)
+			at(key(useDate, 0, 0, 0))
+		}
+	}
+	for i := range sales {
+		total.AddSales(&sales[i])
+		at(key(sales[i].UseDate, sales[i].WholesalerID, sales[i].RoomTypeID, sales[i].PlanID)).AddSales(&sales[i])
+	}
+	for _, inventory := range inventories {
+		total.RoomCount += inventory.RoomCount
+		// プランには販売可能室数がない
+		if groupBy != analytics.GroupByPlan {
+			at(key(inventory.UseDate, inventory.WholesalerID, inventory.RoomTypeID, 0)).RoomCount += inventory.RoomCount
+		}
+	}
+	total.Calculate()
+
+	rows := []analytics.ReportRow{}
+	for k, m := range metrics {
+		m.Calculate()
+		rows = append(rows, analytics.ReportRow{
+			Period:       k.period,
+			WholesalerID: k.wholesalerID,
+			RoomTypeID:   k.roomTypeID,
+			PlanID:       k.planID,
+			Metrics:      *m,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Period != rows[j].Period {
+			return rows[i].Period < rows[j].Period
+		}
+		if rows[i].WholesalerID != rows[j].WholesalerID {
+			return rows[i].WholesalerID < rows[j].WholesalerID
+		}
+		if rows[i].RoomTypeID != rows[j].RoomTypeID {
+			return rows[i].RoomTypeID < rows[j].RoomTypeID
+		}
+		return rows[i].PlanID < rows[j].PlanID
+	})
+	return total, rows
+}
+
+// rangeDays 期間内の日付（開始日・終了日を含む）。maxDaysを超える期間はエラー
+func rangeDays(from string, to string, maxDays int) ([]string, error) {
+	start, err := time.Parse(dateFormat, from)
+	if err != nil {
+		return nil, analytics.ErrAnalyticsRange
+	}
+	end, err := time.Parse(dateFormat, to)
+	if err != nil || end.Before(start) {
+		return nil, analytics.ErrAnalyticsRange
+	}
+	result := []string{}
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		if len(result) == maxDays {
+			return nil, analytics.ErrAnalyticsRange
+		}
+		result = append(result, date.Format(dateFormat))
+	}
+	return result, nil
+}
+
+// oldestRefresh 最も古い集計日時（集計結果がない場合はゼロ値）
+func oldestRefresh(refreshes []analytics.HtThAnalyticsRefreshes) time.Time {
+	result := time.Time{}
+	for _, refresh := range refreshes {
+		if result.IsZero() || refresh.RefreshedAt.Before(result) {
+			result = refresh.RefreshedAt
+		}
+	}
+	return result
+}
+
+// SummaryMaxAge 集計結果を使う期間。ANALYTICS_SUMMARY_MAX_AGE（未設定の場合は DefaultSummaryMaxAge）
+func SummaryMaxAge() time.Duration {
+	maxAge, err := time.ParseDuration(os.Getenv("ANALYTICS_SUMMARY_MAX_AGE"))
+	if err != nil || maxAge <= 0 {
+		return analytics.DefaultSummaryMaxAge
+	}
+	return maxAge
+}
+
+// AnalyticsWorkerInterval 古くなった集計結果を確認する間隔。ANALYTICS_WORKER_INTERVAL（未設定の場合は DefaultAnalyticsWorkerInterval）
+func AnalyticsWorkerInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("ANALYTICS_WORKER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return analytics.DefaultAnalyticsWorkerInterval
+	}
+	return interval
+}
+
+// RunAnalyticsWorker ctxが終了するまで、interval毎に古くなった集計結果を集計し直す
+func RunAnalyticsWorker(ctx context.Context, anUsecase analytics.IAnalyticsUsecase, interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := anUsecase.RefreshStale(); err != nil {
+			logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
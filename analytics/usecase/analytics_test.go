@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+)
+
+func Test_buildReport(t *testing.T) {
+	day1 := time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	days := []string{"2021-03-31", "2021-04-01", "2021-04-02"}
+	sales := []analytics.HtThAnalyticsDailySales{
+		{UseDate: day1, WholesalerID: 7, RoomTypeID: 10, PlanID: 100, RoomNights: 3, Revenue: 30000, CanceledRoomNights: 1},
+		{UseDate: day1, WholesalerID: 7, RoomTypeID: 10, PlanID: 101, RoomNights: 1, Revenue: 14000},
+		{UseDate: day2, WholesalerID: 7, RoomTypeID: 11, PlanID: 110, RoomNights: 2, Revenue: 16000, NoShowRoomNights: 1},
+	}
+	inventories := []analytics.HtThAnalyticsDailyInventories{
+		{UseDate: day1, WholesalerID: 7, RoomTypeID: 10, RoomCount: 5},
+		{UseDate: day1, WholesalerID: 7, RoomTypeID: 11, RoomCount: 5},
+		{UseDate: day2, WholesalerID: 7, RoomTypeID: 10, RoomCount: 5},
+		{UseDate: day2, WholesalerID: 7, RoomTypeID: 11, RoomCount: 5},
+	}
+
+	t.Run("期間全体の稼働率・ADR・RevPARを計算", func(t *testing.T) {
+		total, rows := buildReport(days, analytics.GranularityDay, "", sales, inventories)
+		if total.RoomCount != 20 || total.RoomNights != 6 || total.Revenue != 60000 || total.CanceledRoomNights != 1 || total.NoShowRoomNights != 1 {
+			t.Fatalf("合計が一致しません。%+v", total)
+		}
+		if total.Occupancy != 30 || total.ADR != 10000 || total.RevPAR != 3000 {
+			t.Fatalf("稼働率・ADR・RevPARが一致しません。%+v", total)
+		}
+		// 実績のない日も含める
+		if len(rows) != 3 || rows[0].Period != "2021-03-31" || rows[2].Period != "2021-04-02" || rows[2].RoomCount != 0 {
+			t.Fatalf("日別の行が一致しません。%+v", rows)
+		}
+		if rows[0].Occupancy != 40 || rows[0].ADR != 11000 || rows[0].RevPAR != 4400 {
+			t.Fatalf("日別の集計値が一致しません。%+v", rows[0])
+		}
+	})
+
+	t.Run("月別・部屋タイプ別にまとめる", func(t *testing.T) {
+		_, rows := buildReport(days, analytics.GranularityMonth, analytics.GroupByRoomType, sales, inventories)
+		if len(rows) != 4 {
+			t.Fatalf("行数が一致しません。%+v", rows)
+		}
+		march := rows[0]
+		if march.Period != "2021-03" || march.RoomTypeID != 10 || march.RoomNights != 4 || march.RoomCount != 5 || march.Occupancy != 80 {
+			t.Fatalf("3月の部屋タイプ10の集計値が一致しません。%+v", march)
+		}
+		// 販売実績がなくても販売可能室数があれば行を作る
+		if rows[1].RoomTypeID != 11 || rows[1].RoomNights != 0 || rows[1].RoomCount != 5 {
+			t.Fatalf("3月の部屋タイプ11の集計値が一致しません。%+v", rows[1])
+		}
+	})
+
+	t.Run("プラン別は販売可能室数を持たない", func(t *testing.T) {
+		total, rows := buildReport(days, analytics.GranularityMonth, analytics.GroupByPlan, sales, inventories)
+		if len(rows) != 3 || total.RoomCount != 20 {
+			t.Fatalf("集計結果が一致しません。%+v %+v", total, rows)
+		}
+		for _, row := range rows {
+			if row.RoomCount != 0 || row.Occupancy != 0 || row.RevPAR != 0 || row.ADR == 0 {
+				t.Fatalf("プラン別の集計値が一致しません。%+v", row)
+			}
+		}
+	})
+
+	t.Run("手間いらずの販売室数は稼働率・RevPARに含めない", func(t *testing.T) {
+		withTema := append(sales, analytics.HtThAnalyticsDailySales{UseDate: day1, WholesalerID: 4, RoomTypeID: 20, PlanID: 200, RoomNights: 4, Revenue: 40000})
+		total, _ := buildReport(days, analytics.GranularityDay, "", withTema, inventories)
+		if total.RoomNights != 10 || total.Revenue != 100000 || total.ADR != 10000 {
+			t.Fatalf("販売室数・売上・ADRが一致しません。%+v", total)
+		}
+		if total.Occupancy != 30 || total.RevPAR != 3000 {
+			t.Fatalf("稼働率・RevPARが一致しません。%+v", total)
+		}
+	})
+}
+
+func Test_rangeDays(t *testing.T) {
+	if days, err := rangeDays("2021-02-27", "2021-03-01", analytics.MaxRangeDays); err != nil || len(days) != 3 || days[2] != "2021-03-01" {
+		t.Fatalf("期間内の日付が一致しません。%v %v", days, err)
+	}
+	if _, err := rangeDays("2021-03-02", "2021-03-01", analytics.MaxRangeDays); err != analytics.ErrAnalyticsRange {
+		t.Fatalf("開始日が終了日より後でもエラーになりません。%v", err)
+	}
+	if _, err := rangeDays("2020-01-01", "2022-01-02", analytics.MaxRangeDays); err != analytics.ErrAnalyticsRange {
+		t.Fatalf("集計できる日数を超えてもエラーになりません。%v", err)
+	}
+}
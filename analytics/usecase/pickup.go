@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+)
+
+// Pickup 宿泊日ごとに、現在の予約数・基準日からのピックアップ・到着N日前時点の予約数（ブッキングペース）を前年同日と並べて集計
+// 予約日時とキャンセル日時から各時点の予約数を求めるため、集計結果のテーブルは使わない
+func (a *analyticsUsecase) Pickup(req analytics.PickupInput) (*analytics.PickupOutput, error) {
+	days, err := rangeDays(req.From, req.To, analytics.MaxPickupRangeDays)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	snapshotDate := req.SnapshotDate
+	if snapshotDate == "" {
+		snapshotDate = now.AddDate(0, 0, -analytics.DefaultPickupSnapshotDays).Format(dateFormat)
+	}
+	if snapshotDate > now.Format(dateFormat) {
+		return nil, analytics.ErrPickupSnapshot
+	}
+	leadDays := append([]int{}, req.LeadDays...)
+	if len(leadDays) == 0 {
+		leadDays = append(leadDays, analytics.DefaultPickupLeadDays...)
+	}
+	sort.Ints(leadDays)
+
+	bookings, err := a.AnRepository.FetchPickupBookings(req.PropertyID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	lastYearBookings, err := a.AnRepository.FetchPickupBookings(req.PropertyID, lastYear(req.From), lastYear(req.To))
+	if err != nil {
+		return nil, err
+	}
+	return &analytics.PickupOutput{
+		PropertyID:   req.PropertyID,
+		From:         req.From,
+		To:           req.To,
+		SnapshotDate: snapshotDate,
+		LeadDays:     leadDays,
+		AsOf:         now,
+		Rows:         buildPickup(days, snapshotDate, leadDays, now, append(bookings, lastYearBookings...)),
+	}, nil
+}
+
+// buildPickup 宿泊日ごとの予約状況を作成（bookingsには前年同日の予約も含める）
+func buildPickup(days []string, snapshotDate string, leadDays []int, now time.Time, bookings []analytics.PickupBooking) []analytics.PickupRow {
+	byDate := map[string][]analytics.PickupBooking{}
+	for _, booking := range bookings {
+		useDate := booking.UseDate.Format(dateFormat)
+		byDate[useDate] = append(byDate[useDate], booking)
+	}
+	onTheBooks := func(date string, at time.Time) analytics.PickupMetrics {
+		result := analytics.PickupMetrics{}
+		for _, booking := range byDate[date] {
+			if booking.OnTheBooksAt(at) {
+				result.Rooms += booking.Rooms
+				result.Revenue += booking.Revenue
+			}
+		}
+		return result
+	}
+	pace := func(date string) []analytics.PacePoint {
+		result := []analytics.PacePoint{}
+		for _, daysBefore := range leadDays {
+			point := analytics.PacePoint{DaysBefore: daysBefore}
+			if at := endOfDay(date, -daysBefore); !at.After(now) {
+				metrics := onTheBooks(date, at)
+				point.OnTheBooks = &metrics
+			}
+			result = append(result, point)
+		}
+		return result
+	}
+
+	snapshotAt := endOfDay(snapshotDate, 0)
+	rows := []analytics.PickupRow{}
+	for _, day := range days {
+		row := analytics.PickupRow{
+			StayDate:   day,
+			OnTheBooks: onTheBooks(day, now),
+			AtSnapshot: onTheBooks(day, snapshotAt),
+			Pace:       pace(day),
+		}
+		row.Pickup = row.OnTheBooks.Sub(row.AtSnapshot)
+		lastYearDay := lastYear(day)
+		row.LastYear = analytics.PickupLastYear{
+			StayDate:   lastYearDay,
+			Actual:     onTheBooks(lastYearDay, now),
+			AtSnapshot: onTheBooks(lastYearDay, snapshotAt.AddDate(-1, 0, 0)),
+			Pace:       pace(lastYearDay),
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// endOfDay 日付からoffset日ずらした日の終わり（翌日の0時）
+func endOfDay(date string, offset int) time.Time {
+	day, _ := time.ParseInLocation(dateFormat, date, time.Local)
+	return day.AddDate(0, 0, offset+1)
+}
+
+// lastYear 1年前の同じ日付（前年に同じ日付がない2/29は3/1）
+func lastYear(date string) string {
+	day, err := time.Parse(dateFormat, date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(-1, 0, 0).Format(dateFormat)
+}
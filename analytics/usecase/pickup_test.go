@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+)
+
+func Test_buildPickup(t *testing.T) {
+	at := func(date string, hour int) time.Time {
+		day, _ := time.ParseInLocation(dateFormat, date, time.Local)
+		return day.Add(time.Duration(hour) * time.Hour)
+	}
+	canceledAt := at("2021-03-25", 9)
+	now := at("2021-03-28", 12)
+	stay := at("2021-04-10", 0)
+	lastYearStay := at("2020-04-10", 0)
+	bookings := []analytics.PickupBooking{
+		// 到着30日以上前の予約
+		{UseDate: stay, CreatedAt: at("2021-03-01", 10), Rooms: 2, Revenue: 20000},
+		// 基準日の後の予約
+		{UseDate: stay, CreatedAt: at("2021-03-27", 10), Rooms: 1, Revenue: 12000},
+		// 基準日の前に予約し、基準日の後にキャンセル
+		{UseDate: stay, CreatedAt: at("2021-03-10", 10), CancelFlg: true, CanceledDt: &canceledAt, Rooms: 1, Revenue: 9000},
+		// キャンセル日時が分からないキャンセル
+		{UseDate: stay, CreatedAt: at("2021-03-02", 10), CancelFlg: true, Rooms: 5, Revenue: 50000},
+		// 前年同日
+		{UseDate: lastYearStay, CreatedAt: at("2020-03-01", 10), Rooms: 3, Revenue: 27000},
+		{UseDate: lastYearStay, CreatedAt: at("2020-04-05", 10), Rooms: 1, Revenue: 10000},
+	}
+
+	rows := buildPickup([]string{"2021-04-10"}, "2021-03-21", []int{0, 7, 14, 30}, now, bookings)
+	if len(rows) != 1 {
+		t.Fatalf("行数が一致しません。%+v", rows)
+	}
+	row := rows[0]
+	if row.OnTheBooks != (analytics.PickupMetrics{Rooms: 3, Revenue: 32000}) {
+		t.Fatalf("現在の予約数が一致しません。%+v", row.OnTheBooks)
+	}
+	if row.AtSnapshot != (analytics.PickupMetrics{Rooms: 3, Revenue: 29000}) {
+		t.Fatalf("基準日時点の予約数が一致しません。%+v", row.AtSnapshot)
+	}
+	if row.Pickup != (analytics.PickupMetrics{Rooms: 0, Revenue: 3000}) {
+		t.Fatalf("ピックアップが一致しません。%+v", row.Pickup)
+	}
+	// 到着0日前・7日前はまだその日になっていない、14日前（3/27）は当日の予約を含む、30日前（3/11）はキャンセル前
+	if row.Pace[0].OnTheBooks != nil || row.Pace[1].OnTheBooks != nil {
+		t.Fatalf("まだ来ていない時点の予約数が設定されています。%+v", row.Pace)
+	}
+	if *row.Pace[2].OnTheBooks != (analytics.PickupMetrics{Rooms: 3, Revenue: 32000}) || *row.Pace[3].OnTheBooks != (analytics.PickupMetrics{Rooms: 3, Revenue: 29000}) {
+		t.Fatalf("ブッキングペースが一致しません。%+v %+v", row.Pace[2].OnTheBooks, row.Pace[3].OnTheBooks)
+	}
+
+	lastYear := row.LastYear
+	if lastYear.StayDate != "2020-04-10" || lastYear.Actual.Rooms != 4 || lastYear.AtSnapshot.Rooms != 3 {
+		t.Fatalf("前年同日の予約数が一致しません。%+v", lastYear)
+	}
+	if *lastYear.Pace[0].OnTheBooks != (analytics.PickupMetrics{Rooms: 4, Revenue: 37000}) || lastYear.Pace[1].OnTheBooks.Rooms != 3 {
+		t.Fatalf("前年同日のブッキングペースが一致しません。%+v", lastYear.Pace)
+	}
+}
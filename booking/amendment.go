@@ -0,0 +1,163 @@
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+var (
+	// ErrAmendWholesaler 変更できるのは直仕入れの予約のみ
+	ErrAmendWholesaler = errors.New("Error: 直仕入れ以外の予約は変更できません。")
+	// ErrAmendStarted チェックイン日を過ぎた予約、もしくは過去の日付への変更
+	ErrAmendStarted = errors.New("Error: チェックイン日を過ぎた予約や、過去の日付には変更できません。")
+	// ErrAmendRooms 予約のすべての部屋を1件ずつ指定していない
+	ErrAmendRooms = errors.New("Error: 予約のすべての部屋を1件ずつ指定してください。")
+	// ErrAmendConflict 変更中に、他の操作で予約が変更された
+	ErrAmendConflict = errors.New("Error: 予約が他の操作で変更されました。もう一度お試しください。")
+	// ErrAmendPartialCanceled 一部キャンセルした予約の変更
+	ErrAmendPartialCanceled = errors.New("Error: 一部キャンセルした予約は変更できません。")
+)
+
+// AmendInput 予約変更の入力
+// 宿泊日は予約全体で変更し、部屋ごとにプラン（部屋タイプ）と人数を指定する
+type AmendInput struct {
+	CmApplicationID int64            `json:"cm_application_id" validate:"required"`
+	Arrival         string           `json:"arrival" validate:"required,datetime=2006-01-02"`
+	Departure       string           `json:"departure" validate:"required,datetime=2006-01-02"`
+	Rooms           []AmendRoomInput `json:"rooms" validate:"required,min=1,dive"`
+	// QuoteOnly 料金の見積もりのみ行い、予約は変更しない
+	QuoteOnly bool `json:"quote_only"`
+}
+
+// AmendRoomInput 予約変更の部屋ごとの入力
+type AmendRoomInput struct {
+	HtThBookingRoomID int64 `json:"ht_th_booking_room_id" validate:"required"`
+	BookingRoomInput
+}
+
+// AmendOutput 予約変更の出力
+type AmendOutput struct {
+	CmApplicationID int64 `json:"cm_application_id"`
+	// Version 変更後の版（見積もりのみの場合は0）
+	Version             int               `json:"version"`
+	Arrival             string            `json:"arrival"`
+	Departure           string            `json:"departure"`
+	Stays               int               `json:"stays"`
+	TotalPayInTaxBefore float32           `json:"total_pay_in_tax_before"`
+	TotalPayInTaxAfter  float32           `json:"total_pay_in_tax_after"`
+	Rooms               []QuoteRoomOutput `json:"rooms"`
+	// Notified 宿泊者へ変更を通知できたか
+	Notified bool `json:"notified"`
+}
+
+// HtThBookingAmendments 予約変更の履歴テーブル（変更前・変更後の予約内容を版ごとに保存する）
+type HtThBookingAmendments struct {
+	BookingAmendmentID  int64   `gorm:"primaryKey;autoIncrement:true" json:"booking_amendment_id"`
+	CmApplicationID     int64   `json:"cm_application_id"`
+	Version             int     `json:"version"`
+	HotelManagerID      int64   `json:"hotel_manager_id"`
+	TotalPayInTaxBefore float32 `json:"total_pay_in_tax_before"`
+	TotalPayInTaxAfter  float32 `json:"total_pay_in_tax_after"`
+	// BeforeJSON 変更前の予約内容（AmendmentSnapshot）
+	BeforeJSON string `json:"before_json"`
+	// AfterJSON 変更後の予約内容（AmendmentSnapshot）
+	AfterJSON    string    `json:"after_json"`
+	NotifiedAt   time.Time `gorm:"type:time" json:"notified_at"`
+	NotifyError  string    `json:"notify_error"`
+	common.Times `gorm:"embedded"`
+}
+
+// AmendmentSnapshot 予約変更の前後の予約内容
+type AmendmentSnapshot struct {
+	Arrival       string              `json:"arrival"`
+	Departure     string              `json:"departure"`
+	Stays         int                 `json:"stays"`
+	TotalPayExTax float32             `json:"total_pay_ex_tax"`
+	TotalPayInTax float32             `json:"total_pay_in_tax"`
+	NightlyJSON   string              `json:"nightly_json"`
+	Rooms         []HtThBookingRooms  `json:"rooms"`
+	Prices        []HtThBookingPrices `json:"prices"`
+}
+
+// AmendmentOutput 予約変更の履歴の出力
+type AmendmentOutput struct {
+	BookingAmendmentID int64            `json:"booking_amendment_id"`
+	Version            int              `json:"version"`
+	HotelManagerID     int64            `json:"hotel_manager_id"`
+	Before             AmendmentVersion `json:"before"`
+	After              AmendmentVersion `json:"after"`
+	Notified           bool             `json:"notified"`
+	AmendedAt          time.Time        `json:"amended_at"`
+}
+
+// AmendmentVersion 予約変更の履歴の、ある版の宿泊日と料金
+type AmendmentVersion struct {
+	Arrival       string           `json:"arrival"`
+	Departure     string           `json:"departure"`
+	Stays         int              `json:"stays"`
+	TotalPayInTax float32          `json:"total_pay_in_tax"`
+	Nights        []RoomNightPrice `json:"nights"`
+}
+
+// NewAmendmentOutput 予約変更の履歴から出力を生成
+func NewAmendmentOutput(amendment *HtThBookingAmendments) (*AmendmentOutput, error) {
+	result := &AmendmentOutput{
+		BookingAmendmentID: amendment.BookingAmendmentID,
+		Version:            amendment.Version,
+		HotelManagerID:     amendment.HotelManagerID,
+		Notified:           amendment.NotifiedAt.IsZero() == false,
+		AmendedAt:          amendment.CreatedAt,
+	}
+	for _, v := range []struct {
+		snapshotJSON string
+		version      *AmendmentVersion
+	}{
+		{amendment.BeforeJSON, &result.Before},
+		{amendment.AfterJSON, &result.After},
+	} {
+		snapshot := AmendmentSnapshot{}
+		if err := json.Unmarshal([]byte(v.snapshotJSON), &snapshot); err != nil {
+			return nil, err
+		}
+		*v.version = AmendmentVersion{
+			Arrival:       snapshot.Arrival,
+			Departure:     snapshot.Departure,
+			Stays:         snapshot.Stays,
+			TotalPayInTax: snapshot.TotalPayInTax,
+			Nights:        []RoomNightPrice{},
+		}
+		for _, price := range snapshot.Prices {
+			v.version.Nights = append(v.version.Nights, price.NightPrice())
+		}
+	}
+	return result, nil
+}
+
+// AmendmentApply 予約変更で更新する内容（repositoryで1つのトランザクションとして更新する）
+type AmendmentApply struct {
+	// OldPriceIDs 削除する変更前の料金情報
+	OldPriceIDs []int64
+	// Prices 登録する変更後の料金情報
+	Prices []HtThBookingPrices
+	// Rooms 更新する部屋情報（人数・部屋名・プラン名）
+	Rooms []HtThBookingRooms
+	// Application 予約情報の更新内容
+	Application map[string]interface{}
+	// Stocks 直仕入れの在庫の増減
+	Stocks []StockChange
+	// Amendment 予約変更の履歴（版はrepositoryで採番する）
+	Amendment *HtThBookingAmendments
+}
+
+// IBookingAmendmentRepository 予約変更関連のrepositoryのインターフェース
+type IBookingAmendmentRepository interface {
+	// ApplyAmendment 料金情報の入れ替え・部屋と予約情報の更新・在庫の移動・履歴の登録をまとめて行う
+	ApplyAmendment(apply *AmendmentApply) error
+	// UpdateAmendmentNotification 宿泊者への通知結果を更新
+	UpdateAmendmentNotification(bookingAmendmentID int64, notifiedAt time.Time, notifyError string) error
+	// FetchAmendments 予約IDに基づく予約変更の履歴を取得
+	FetchAmendments(cmApplicationID int64) ([]HtThBookingAmendments, error)
+}
@@ -0,0 +1,229 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+var (
+	// ErrWebhookRejected 施設のWebhookが通知を受け付けなかった（再送しても結果が変わらない）
+	ErrWebhookRejected = errors.New("Error: 施設のWebhookで通知を受け付けられませんでした。")
+	// ErrNotificationDisabled 送信までに施設の予約通知が無効になった、または送信先が設定から外れた
+	ErrNotificationDisabled = errors.New("Error: 施設の予約通知の送信先として設定されていません。")
+)
+
+const (
+	// NotificationEventCreated 新規予約
+	NotificationEventCreated = "created"
+	// NotificationEventCanceled キャンセル
+	NotificationEventCanceled = "canceled"
+	// NotificationEventNoShow NoShow
+	NotificationEventNoShow = "no_show"
+
+	// NotificationChannelEmail メール
+	NotificationChannelEmail = "email"
+	// NotificationChannelWebhook Webhook
+	NotificationChannelWebhook = "webhook"
+
+	// NotificationStatusPending 送信待ち（再送待ちを含む）
+	NotificationStatusPending = "pending"
+	// NotificationStatusProcessing 送信中
+	NotificationStatusProcessing = "processing"
+	// NotificationStatusDelivered 送信済み
+	NotificationStatusDelivered = "delivered"
+	// NotificationStatusFailed 再送しても成功しないため、送信を打ち切った
+	NotificationStatusFailed = "failed"
+
+	// NotificationMaxAttempts 1件の通知の最大送信回数
+	NotificationMaxAttempts = 6
+	// NotificationRetryBaseInterval 再送間隔の初期値（送信のたびに倍にする）
+	NotificationRetryBaseInterval = time.Minute
+	// NotificationRetryMaxInterval 再送間隔の上限
+	NotificationRetryMaxInterval = time.Hour
+	// NotificationLease 送信中のまま処理が止まった場合に、再び送信対象とするまでの時間
+	NotificationLease = 5 * time.Minute
+	// NotificationDispatchBatchSize 1回の処理で送信する件数
+	NotificationDispatchBatchSize = 50
+	// NotificationScanBatchSize 1回の処理で確認する予約の件数
+	NotificationScanBatchSize = 200
+	// NotificationScanLease 新しい予約・キャンセルの確認中に処理が止まった場合に、他の処理が確認を始められるまでの時間
+	NotificationScanLease = 5 * time.Minute
+	// DefaultNotificationWorkerInterval 新しい予約・送信待ちの通知を確認する間隔
+	DefaultNotificationWorkerInterval = 30 * time.Second
+
+	// HeaderNotificationEvent Webhookで通知の種類を渡すヘッダー
+	HeaderNotificationEvent = "X-Hm-Event"
+)
+
+// HtTmBookingNotificationSettings 施設ごとの予約通知の設定テーブル
+// Webhookの署名に平文が必要なため、シークレットは暗号化して保持する
+type HtTmBookingNotificationSettings struct {
+	PropertyID int64 `gorm:"primaryKey" json:"property_id"`
+	// Emails 通知先のメールアドレス（カンマ区切り）
+	Emails           string `json:"emails"`
+	LangCd           string `json:"lang_cd"`
+	WebhookURL       string `json:"webhook_url"`
+	WebhookSecretEnc string `json:"-"`
+	Enabled          bool   `json:"enabled"`
+	common.Times     `gorm:"embedded"`
+}
+
+// HtThBookingNotifications 予約通知の送信履歴テーブル（送信待ちの通知を保存しておき、順に送信する）
+// 同じ予約・種類・元の履歴・送信先の通知は1件のみ登録する
+// EventSourceIDは通知の元になった履歴のID（NoShowはNoShowの履歴ID、新規予約・キャンセルは0）。NoShowを取り消して再び登録した場合も通知するため
+type HtThBookingNotifications struct {
+	BookingNotificationID int64     `gorm:"primaryKey;autoIncrement:true" json:"booking_notification_id"`
+	CmApplicationID       int64     `gorm:"uniqueIndex:uq_booking_notifications" json:"cm_application_id"`
+	PropertyID            int64     `json:"property_id"`
+	Event                 string    `gorm:"uniqueIndex:uq_booking_notifications" json:"event"`
+	EventSourceID         int64     `gorm:"uniqueIndex:uq_booking_notifications" json:"event_source_id"`
+	Channel               string    `json:"channel"`
+	Recipient             string    `gorm:"uniqueIndex:uq_booking_notifications" json:"recipient"`
+	Status                string    `json:"status"`
+	Attempts              int       `json:"attempts"`
+	NextAttemptAt         time.Time `gorm:"type:time" json:"next_attempt_at"`
+	LastError             string    `json:"last_error"`
+	DeliveredAt           time.Time `gorm:"type:time" json:"delivered_at"`
+	common.Times          `gorm:"embedded"`
+}
+
+// IdempotencyKey 施設のWebhookが同じ通知の再送を見分けるためのキー
+func (h *HtThBookingNotifications) IdempotencyKey() string {
+	return fmt.Sprintf("hm-notification-%d", h.BookingNotificationID)
+}
+
+// NotificationRetryInterval n回目の送信に失敗した後の再送間隔
+func NotificationRetryInterval(attempts int) time.Duration {
+	return retryInterval(NotificationRetryBaseInterval, NotificationRetryMaxInterval, attempts)
+}
+
+// HtThBookingNotificationCursors 新しい予約・キャンセルをどこまで確認したかを保持するテーブル（1行のみ）
+// キャンセルは同じ日時の予約があるため、キャンセル日時とht_th_application_idの組で確認位置を保持する
+// ScanLockedUntilまでは、確認中の処理以外は確認しない（複数のインスタンスで同時に確認しないため）
+type HtThBookingNotificationCursors struct {
+	CursorID                  int64     `gorm:"primaryKey" json:"cursor_id"`
+	LastApplicationID         int64     `json:"last_application_id"`
+	LastCanceledAt            time.Time `gorm:"type:time" json:"last_canceled_at"`
+	LastCanceledApplicationID int64     `json:"last_canceled_application_id"`
+	ScanLockedUntil           time.Time `gorm:"type:time" json:"scan_locked_until"`
+	common.Times              `gorm:"embedded"`
+}
+
+// NotificationSettingInput 予約通知の設定取得の入力
+type NotificationSettingInput struct {
+	PropertyID int64 `json:"property_id" query:"property_id" validate:"required"`
+}
+
+// SaveNotificationSettingInput 予約通知の設定保存の入力
+type SaveNotificationSettingInput struct {
+	PropertyID int64    `json:"property_id" validate:"required"`
+	Emails     []string `json:"emails" validate:"max=5,dive,email,max=256"`
+	LangCd     string   `json:"lang_cd" validate:"required,oneof=ja-JP en-US"`
+	WebhookURL string   `json:"webhook_url" validate:"omitempty,url,startswith=https://,max=512"`
+	Enabled    bool     `json:"enabled"`
+	// RegenerateSecret Webhookの署名シークレットを発行し直す
+	RegenerateSecret bool `json:"regenerate_secret"`
+}
+
+// NotificationSettingOutput 予約通知の設定の出力
+type NotificationSettingOutput struct {
+	PropertyID int64    `json:"property_id"`
+	Emails     []string `json:"emails"`
+	LangCd     string   `json:"lang_cd"`
+	WebhookURL string   `json:"webhook_url"`
+	Enabled    bool     `json:"enabled"`
+	// WebhookSecret 署名シークレット（発行した時の1回のみ返す）
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+}
+
+// NotificationLogInput 予約通知の送信履歴の入力
+type NotificationLogInput struct {
+	PropertyID      int64 `json:"property_id" query:"property_id" validate:"required"`
+	CmApplicationID int64 `json:"cm_application_id" query:"cm_application_id"`
+	Limit           int   `json:"limit" query:"limit" validate:"min=0,max=500"`
+}
+
+// NotificationLogOutput 予約通知の送信履歴の出力
+type NotificationLogOutput struct {
+	BookingNotificationID int64     `json:"booking_notification_id"`
+	CmApplicationID       int64     `json:"cm_application_id"`
+	Event                 string    `json:"event"`
+	Channel               string    `json:"channel"`
+	Recipient             string    `json:"recipient"`
+	Status                string    `json:"status"`
+	Attempts              int       `json:"attempts"`
+	LastError             string    `json:"last_error,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	DeliveredAt           time.Time `json:"delivered_at"`
+}
+
+// NewNotificationLogOutput 予約通知の送信履歴の出力を生成
+func NewNotificationLogOutput(notification *HtThBookingNotifications) NotificationLogOutput {
+	return NotificationLogOutput{
+		BookingNotificationID: notification.BookingNotificationID,
+		CmApplicationID:       notification.CmApplicationID,
+		Event:                 notification.Event,
+		Channel:               notification.Channel,
+		Recipient:             notification.Recipient,
+		Status:                notification.Status,
+		Attempts:              notification.Attempts,
+		LastError:             notification.LastError,
+		CreatedAt:             notification.CreatedAt,
+		DeliveredAt:           notification.DeliveredAt,
+	}
+}
+
+// WebhookPayload Webhookで送信する内容
+type WebhookPayload struct {
+	Event           string  `json:"event"`
+	CmApplicationID int64   `json:"cm_application_id"`
+	PropertyID      int64   `json:"property_id"`
+	WholesalerID    int64   `json:"wholesaler_id"`
+	GuestName       string  `json:"guest_name"`
+	Arrival         string  `json:"arrival"`
+	Departure       string  `json:"departure"`
+	Stays           int     `json:"stays"`
+	RoomNum         int     `json:"room_num"`
+	TotalPayInTax   float32 `json:"total_pay_in_tax"`
+	CancelFee       float32 `json:"cancel_fee"`
+	NoshowFee       float32 `json:"noshow_fee"`
+}
+
+// IBookingNotificationRepository 予約通知関連のrepositoryのインターフェース
+type IBookingNotificationRepository interface {
+	// FetchNotificationSetting 施設の予約通知の設定を取得（ない場合はnil）
+	FetchNotificationSetting(propertyID int64) (*HtTmBookingNotificationSettings, error)
+	// SaveNotificationSetting 施設の予約通知の設定を登録・更新
+	SaveNotificationSetting(setting *HtTmBookingNotificationSettings) error
+	// EnqueueNotifications 通知を送信待ちとして登録（登録済みの通知は無視する）
+	EnqueueNotifications(notifications []HtThBookingNotifications) error
+	// FetchDueNotifications 送信時刻を過ぎた送信待ち・送信中の通知を取得
+	FetchDueNotifications(now time.Time, limit int) ([]HtThBookingNotifications, error)
+	// ClaimNotification 送信する通知を確保（他の処理が確保済みの場合はfalse）
+	ClaimNotification(bookingNotificationID int64, now time.Time, leaseUntil time.Time) (bool, error)
+	// UpdateNotification 通知の送信状況を更新
+	UpdateNotification(bookingNotificationID int64, values map[string]interface{}) error
+	// FetchNotifications 施設の通知の送信履歴を新しい順に取得
+	FetchNotifications(propertyID int64, cmApplicationID int64, limit int) ([]HtThBookingNotifications, error)
+	// FetchNotificationCursor 新しい予約・キャンセルの確認位置を取得（ない場合はnil）
+	FetchNotificationCursor() (*HtThBookingNotificationCursors, error)
+	// ClaimNotificationCursor 新しい予約・キャンセルの確認を確保（他の処理が確認中の場合はfalse）
+	ClaimNotificationCursor(now time.Time, leaseUntil time.Time) (bool, error)
+	// SaveNotificationCursor 新しい予約・キャンセルの確認位置を保存
+	SaveNotificationCursor(cursor *HtThBookingNotificationCursors) error
+	// FetchLastApplicationID 最新の予約のht_th_application_idを取得
+	FetchLastApplicationID() (int64, error)
+	// FetchApplicationsAfter ht_th_application_idが指定より大きい予約を取得
+	FetchApplicationsAfter(htThApplicationID int64, limit int) ([]HtThApplications, error)
+	// FetchCanceledApplicationsAfter キャンセル日時・ht_th_application_idの組が指定より後の予約を取得
+	FetchCanceledApplicationsAfter(canceledAt time.Time, htThApplicationID int64, limit int) ([]HtThApplications, error)
+}
+
+// IBookingWebhook 施設のWebhookへの送信のインターフェース
+type IBookingWebhook interface {
+	// Post 署名を付けて送信（再送しても結果が変わらない場合は ErrWebhookRejected を返す）
+	Post(url string, secret string, event string, idempotencyKey string, body []byte) error
+}
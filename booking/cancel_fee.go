@@ -0,0 +1,33 @@
+package booking
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+)
+
+var (
+	// ErrInvalidCancelAt キャンセル日時の形式が正しくない
+	ErrInvalidCancelAt = errors.New("Error: キャンセル日時の形式が正しくありません。")
+	// ErrCancelFeeExceedsPolicy 入力されたキャンセル料がキャンセルポリシーによるキャンセル料を超えている
+	ErrCancelFeeExceedsPolicy = errors.New("Error: キャンセル料がキャンセルポリシーによるキャンセル料を超えています。")
+)
+
+// CancelFeeInput キャンセル料計算の入力
+type CancelFeeInput struct {
+	CmApplicationID int64 `json:"cm_application_id" param:"cmApplicationId" validate:"required"`
+	// CancelAt キャンセル日時（RFC3339）。指定がない場合は現在時刻
+	CancelAt string `json:"cancel_at" query:"cancel_at"`
+	Noshow   uint8  `json:"noshow" query:"noshow" validate:"omitempty,oneof=0 1"`
+}
+
+// CancelFeeOutput キャンセル料計算の出力
+type CancelFeeOutput struct {
+	CmApplicationID int64                  `json:"cm_application_id"`
+	CancelAt        time.Time              `json:"cancel_at"`
+	TimeZone        string                 `json:"time_zone"`
+	TotalPayInTax   int                    `json:"total_pay_in_tax"`
+	Fee             int                    `json:"fee"`
+	Items           []cancelPolicy.FeeItem `json:"items"`
+}
@@ -0,0 +1,127 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+var (
+	// ErrCancelRejected adminのキャンセルAPIがキャンセルを受け付けなかった（再送しても結果が変わらない）
+	ErrCancelRejected = errors.New("Error: adminでキャンセルを受け付けられませんでした。")
+	// ErrAlreadyCanceled キャンセル済みの予約
+	ErrAlreadyCanceled = errors.New("Error: キャンセル済みの予約です。")
+)
+
+const (
+	// CancelStatusPending 送信待ち（再送待ちを含む）
+	CancelStatusPending = "pending"
+	// CancelStatusProcessing 送信中
+	CancelStatusProcessing = "processing"
+	// CancelStatusCompleted adminでキャンセル済み
+	CancelStatusCompleted = "completed"
+	// CancelStatusFailed 再送しても成功しないため、送信を打ち切った
+	CancelStatusFailed = "failed"
+
+	// CancelMaxAttempts adminのキャンセルAPIへの最大送信回数
+	CancelMaxAttempts = 8
+	// CancelRetryBaseInterval 再送間隔の初期値（送信のたびに倍にする）
+	CancelRetryBaseInterval = 30 * time.Second
+	// CancelRetryMaxInterval 再送間隔の上限
+	CancelRetryMaxInterval = time.Hour
+	// CancelLease 送信中のまま処理が止まった場合に、再び送信対象とするまでの時間
+	CancelLease = 5 * time.Minute
+	// CancelDispatchBatchSize 1回の処理で送信する件数
+	CancelDispatchBatchSize = 50
+	// DefaultCancelWorkerInterval 送信待ちを確認する間隔
+	DefaultCancelWorkerInterval = 10 * time.Second
+)
+
+// HtThBookingCancelRequests adminへのキャンセル依頼のテーブル（送信待ちのキャンセルを保存しておき、順に送信する）
+// BookingPartialCancelIDは、一部キャンセルの依頼の場合は一部キャンセルの履歴ID（予約全体のキャンセルは0）
+type HtThBookingCancelRequests struct {
+	BookingCancelRequestID int64     `gorm:"primaryKey;autoIncrement:true" json:"booking_cancel_request_id"`
+	CmApplicationID        int64     `json:"cm_application_id"`
+	BookingPartialCancelID int64     `json:"booking_partial_cancel_id"`
+	HotelManagerID         int64     `json:"hotel_manager_id"`
+	CancelFee              int64     `json:"cancel_fee"`
+	Noshow                 uint8     `json:"noshow"`
+	Status                 string    `json:"status"`
+	Attempts               int       `json:"attempts"`
+	NextAttemptAt          time.Time `gorm:"type:time" json:"next_attempt_at"`
+	LastError              string    `json:"last_error"`
+	CompletedAt            time.Time `gorm:"type:time" json:"completed_at"`
+	common.Times           `gorm:"embedded"`
+}
+
+// IdempotencyKey adminが同じキャンセル依頼の再送を見分けるためのキー
+func (h *HtThBookingCancelRequests) IdempotencyKey() string {
+	return fmt.Sprintf("hm-cancel-%d", h.BookingCancelRequestID)
+}
+
+// CancelRetryInterval n回目の送信に失敗した後の再送間隔
+func CancelRetryInterval(attempts int) time.Duration {
+	return retryInterval(CancelRetryBaseInterval, CancelRetryMaxInterval, attempts)
+}
+
+// retryInterval n回目の送信に失敗した後の再送間隔（初期値から送信のたびに倍にし、上限で止める）
+func retryInterval(base time.Duration, max time.Duration, attempts int) time.Duration {
+	interval := base
+	for i := 1; i < attempts; i++ {
+		interval *= 2
+		if interval >= max {
+			return max
+		}
+	}
+	return interval
+}
+
+// CancelRequestOutput キャンセル依頼の状況の出力
+type CancelRequestOutput struct {
+	BookingCancelRequestID int64     `json:"booking_cancel_request_id"`
+	CmApplicationID        int64     `json:"cm_application_id"`
+	BookingPartialCancelID int64     `json:"booking_partial_cancel_id,omitempty"`
+	HotelManagerID         int64     `json:"hotel_manager_id"`
+	CancelFee              int64     `json:"cancel_fee"`
+	Noshow                 uint8     `json:"noshow"`
+	Status                 string    `json:"status"`
+	Attempts               int       `json:"attempts"`
+	LastError              string    `json:"last_error,omitempty"`
+	RequestedAt            time.Time `json:"requested_at"`
+	CompletedAt            time.Time `json:"completed_at"`
+}
+
+// NewCancelRequestOutput キャンセル依頼の状況の出力を生成
+func NewCancelRequestOutput(cancelRequest *HtThBookingCancelRequests) *CancelRequestOutput {
+	return &CancelRequestOutput{
+		BookingCancelRequestID: cancelRequest.BookingCancelRequestID,
+		CmApplicationID:        cancelRequest.CmApplicationID,
+		BookingPartialCancelID: cancelRequest.BookingPartialCancelID,
+		HotelManagerID:         cancelRequest.HotelManagerID,
+		CancelFee:              cancelRequest.CancelFee,
+		Noshow:                 cancelRequest.Noshow,
+		Status:                 cancelRequest.Status,
+		Attempts:               cancelRequest.Attempts,
+		LastError:              cancelRequest.LastError,
+		RequestedAt:            cancelRequest.CreatedAt,
+		CompletedAt:            cancelRequest.CompletedAt,
+	}
+}
+
+// IBookingCancelRepository adminへのキャンセル依頼関連のrepositoryのインターフェース
+type IBookingCancelRepository interface {
+	// CreateCancelRequest キャンセル依頼を登録
+	CreateCancelRequest(cancelRequest *HtThBookingCancelRequests) error
+	// FetchActiveCancelRequest 予約IDに基づく送信待ち・送信中の予約全体のキャンセル依頼を取得（ない場合はnil）
+	FetchActiveCancelRequest(cmApplicationID int64) (*HtThBookingCancelRequests, error)
+	// FetchLatestCancelRequest 予約IDに基づく最新の予約全体のキャンセル依頼を取得（ない場合はnil）
+	FetchLatestCancelRequest(cmApplicationID int64) (*HtThBookingCancelRequests, error)
+	// FetchDueCancelRequests 送信時刻を過ぎた送信待ち・送信中のキャンセル依頼を取得（includePartialがfalseの場合は予約全体のキャンセルのみ）
+	FetchDueCancelRequests(now time.Time, limit int, includePartial bool) ([]HtThBookingCancelRequests, error)
+	// ClaimCancelRequest 送信するキャンセル依頼を確保（他の処理が確保済みの場合はfalse）
+	ClaimCancelRequest(bookingCancelRequestID int64, now time.Time, leaseUntil time.Time) (bool, error)
+	// UpdateCancelRequest キャンセル依頼の状況を更新
+	UpdateCancelRequest(bookingCancelRequestID int64, values map[string]interface{}) error
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/infra"
+	"github.com/joho/godotenv"
+)
+
+// 部屋と紐づいていない過去の予約の料金情報を、推定できるものだけ部屋と紐づける
+func main() {
+	batchSize := flag.Int("batch", 500, "1回のクエリで処理する予約の件数")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Fatal(".env not loaded")
+	}
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		loc = time.FixedZone("Asia/Tokyo", 9*60*60)
+	}
+	time.Local = loc
+
+	hotelDB, err := infra.DBCon()
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := usecase.NewBookingUsecase(hotelDB).BackfillRoomPriceLinks(*batchSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("checked: %d, linked: %d, skipped: %d", result.Checked, result.Linked, len(result.SkippedIDs))
+	for _, id := range result.SkippedIDs {
+		log.Printf("skipped cm_application_id: %d", id)
+	}
+}
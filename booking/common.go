@@ -1,9 +1,11 @@
 package booking
 
 import (
+	"io"
 	"time"
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
 	"github.com/Adventureinc/hotel-hm-api/src/common"
 )
 
@@ -89,6 +91,7 @@ type HtThApplications struct {
 type HtThBookingPrices struct {
 	HtThBookingPriceID int64     `json:"ht_th_booking_price_id"`
 	CmApplicationID    int64     `json:"cm_application_id"`
+	HtThBookingRoomID  int64     `json:"ht_th_booking_room_id"`
 	UseDate            time.Time `json:"use_date"`
 	RoomTypeID         int64     `json:"room_type_id"`
 	PlanID             int64     `json:"plan_id"`
@@ -113,7 +116,21 @@ type HtThBookingPrices struct {
 	ChildPrice5InTax   int       `json:"child_price5_in_tax" gorm:"column:child_price5_in_tax"`
 	ChildPrice6        int       `json:"child_price6" gorm:"column:child_price6"`
 	ChildPrice6InTax   int       `json:"child_price6_in_tax" gorm:"column:child_price6_in_tax"`
-	common.Times       `gorm:"embedded"`
+	// CancelFlg 一部キャンセルでキャンセルした部屋・泊（売上の集計のため削除せずに残す）
+	CancelFlg    bool      `json:"cancel_flg"`
+	CanceledDt   time.Time `gorm:"type:time" json:"canceled_dt"`
+	common.Times `gorm:"embedded"`
+}
+
+// AmountInTax その日の料金（税込）。大人料金は人数分の合計、子供料金は1人あたりの金額で登録されている
+func (h *HtThBookingPrices) AmountInTax() int {
+	return h.PriceInTax +
+		h.ChildPrice1InTax*h.Child1Person +
+		h.ChildPrice2InTax*h.Child2Person +
+		h.ChildPrice3InTax*h.Child3Person +
+		h.ChildPrice4InTax*h.Child4Person +
+		h.ChildPrice5InTax*h.Child5Person +
+		h.ChildPrice6InTax*h.Child6Person
 }
 
 // HtThBookingRooms 予約時の部屋情報テーブル
@@ -165,24 +182,34 @@ type SearchInput struct {
 	GivenNameEncList  []string `json:"given_name_enc_list"`  /*暗号化した予約者名*/
 	Phone             string   `json:"phone"`                /*暗号化前の電話番号*/
 	PhoneEnc          string   `json:"phone_enc"`            /*暗号化した電話番号*/
-	Status            uint8    `json:"status"`
+	Status            uint8    `json:"status" validate:"omitempty,oneof=1 2 3 4 5"`
+	ContactStatuses   []int    `json:"contact_statuses" validate:"omitempty,dive,oneof=0 1 2 3"`
+	IsMisuse          *bool    `json:"is_misuse"`
+	MemoKeyword       string   `json:"memo_keyword" validate:"max=100"` /*メモの部分一致*/
+	Sort              string   `json:"sort" validate:"omitempty,oneof=checkin created amount"`
+	Order             string   `json:"order" validate:"omitempty,oneof=asc desc"`
+	Cursor            string   `json:"cursor"` /*前ページの next_cursor。指定時は offset より優先*/
+	common.Paging
 }
 
 // SearchDBOutput 予約検索のDB出力
 type SearchDBOutput struct {
-	CmApplicationID int64   `json:"cm_application_id"`
-	CancelFlg       bool    `json:"cancel_flg"`
-	NoshowFlg       bool    `json:"noshow_flg"`
-	ApplicationCd   string  `json:"application_cd"`
-	TourID          int64   `json:"tour_id"`
-	Arrival         string  `json:"arrival"`
-	Departure       string  `json:"departure"`
-	GivenNameEnc    string  `json:"given_name_enc"`
-	FamilyNameEnc   string  `json:"family_name_enc"`
-	PhoneEnc        string  `json:"phone_enc"`
-	TotalPayInTax   float32 `json:"total_pay_in_tax"`
-	PaymentLimitDt  string  `json:"payment_limit_dt"`
-	PaymentCount    int     `json:"payment_count"`
+	CmApplicationID int64     `json:"cm_application_id"`
+	CancelFlg       bool      `json:"cancel_flg"`
+	NoshowFlg       bool      `json:"noshow_flg"`
+	ApplicationCd   string    `json:"application_cd"`
+	TourID          int64     `json:"tour_id"`
+	Arrival         string    `json:"arrival"`
+	Departure       string    `json:"departure"`
+	GivenNameEnc    string    `json:"given_name_enc"`
+	FamilyNameEnc   string    `json:"family_name_enc"`
+	PhoneEnc        string    `json:"phone_enc"`
+	TotalPayInTax   float32   `json:"total_pay_in_tax"`
+	PaymentLimitDt  string    `json:"payment_limit_dt"`
+	PaymentCount    int       `json:"payment_count"`
+	ContactStatus   int       `json:"contact_status"`
+	IsMisuse        int       `json:"is_misuse"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 // SearchOutput 予約検索の出力
@@ -197,6 +224,15 @@ type SearchOutput struct {
 	FamilyName      string  `json:"family_name"`
 	Phone           string  `json:"phone"`
 	TotalPayInTax   float32 `json:"total_pay_in_tax"`
+	ContactStatus   int     `json:"contact_status"`
+	IsMisuse        bool    `json:"is_misuse"`
+}
+
+// SearchListOutput 予約検索のページング付き出力
+type SearchListOutput struct {
+	Total      int64          `json:"total"`
+	Bookings   []SearchOutput `json:"bookings"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
 
 // DownloadInput CSV変換・DLする予約情報取得の入力
@@ -313,6 +349,11 @@ type DetailOutput struct {
 	SalePrice                   float32                `json:"sale_price"`
 	CancelFee                   float32                `json:"cancel_fee"`
 	CancelFeeSuggest            float32                `json:"cancel_fee_suggest"`
+	CancelFeeItems              []cancelPolicy.FeeItem `json:"cancel_fee_items"`
+	CancelRequest               *CancelRequestOutput   `json:"cancel_request,omitempty"`
+	PartialCancels              []PartialCancelOutput  `json:"partial_cancels"`
+	Amendments                  []AmendmentOutput      `json:"amendments"`
+	NoShowHistories             []HtThBookingNoShowHistories `json:"no_show_histories"`
 	CancelFlg                   bool                   `json:"cancel_flg"`
 	CanceledDt                  time.Time              `gorm:"type:time" json:"canceled_dt"`
 	NoshowFlg                   bool                   `json:"noshow_flg"`
@@ -368,21 +409,23 @@ type PersonPrice struct {
 
 // DetailRoomAndPlan 予約詳細情報の部屋・プラン
 type DetailRoomAndPlan struct {
-	RoomID         string `json:"room_id"`
-	RoomName       string `json:"room_name"`
-	PlanName       string `json:"plan_name"`
-	FamilyName     string `json:"family_name"`
-	GivenName      string `json:"given_name"`
-	NumberOfChilds int    `json:"number_of_childs"`
-	NumberOfAdults int    `json:"number_of_adults"`
-	ChildAges      string `json:"child_ages"`
-	Person         int    `json:"person"`
-	Child1Person   int    `json:"child_1_person"`
-	Child2Person   int    `json:"child_2_person"`
-	Child3Person   int    `json:"child_3_person"`
-	Child4Person   int    `json:"child_4_person"`
-	Child5Person   int    `json:"child_5_person"`
-	Child6Person   int    `json:"child_6_person"`
+	HtThBookingRoomID int64            `json:"ht_th_booking_room_id"`
+	RoomID            string           `json:"room_id"`
+	RoomName          string           `json:"room_name"`
+	PlanName          string           `json:"plan_name"`
+	FamilyName        string           `json:"family_name"`
+	GivenName         string           `json:"given_name"`
+	NumberOfChilds    int              `json:"number_of_childs"`
+	NumberOfAdults    int              `json:"number_of_adults"`
+	ChildAges         string           `json:"child_ages"`
+	Person            int              `json:"person"`
+	Child1Person      int              `json:"child_1_person"`
+	Child2Person      int              `json:"child_2_person"`
+	Child3Person      int              `json:"child_3_person"`
+	Child4Person      int              `json:"child_4_person"`
+	Child5Person      int              `json:"child_5_person"`
+	Child6Person      int              `json:"child_6_person"`
+	Nights            []RoomNightPrice `json:"nights"`
 }
 
 // CancelInput 予約キャンセルの入力
@@ -400,24 +443,46 @@ type CancelPolicy struct {
 }
 
 // NoShowInput NoShowの入力
+// NoShowを取り消す（noshow_flgがfalse）場合は理由が必須
 type NoShowInput struct {
-	CmApplicationID int64 `json:"cm_application_id" validate:"required"`
-	NoshowFlg       bool  `json:"noshow_flg"`
+	CmApplicationID int64  `json:"cm_application_id" validate:"required"`
+	NoshowFlg       bool   `json:"noshow_flg"`
+	Reason          string `json:"reason" validate:"max=500"`
 }
 
 // IBookingUsecase 予約関連のusecaseのインターフェース
 type IBookingUsecase interface {
-	SearchBookings(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req SearchInput) ([]SearchOutput, error)
+	SearchBookings(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req SearchInput) (*SearchListOutput, error)
 	BookingDownloads(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req DownloadInput) ([]BookingDownloadOutput, error)
 	DetailBooking(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req DetailInput) (*DetailOutput, error)
-	CancelBooking(req CancelInput) (bool, error)
-	UpdateNoShow(req *NoShowInput) error
+	CancelBooking(hmUser *account.HtTmHotelManager, req CancelInput) (*CancelRequestOutput, error)
+	PartialCancelBooking(hmUser *account.HtTmHotelManager, req PartialCancelInput) (*PartialCancelOutput, error)
+	AmendBooking(hmUser *account.HtTmHotelManager, req AmendInput) (*AmendOutput, error)
+	CreateManualBooking(hmUser *account.HtTmHotelManager, req ManualBookingInput) (*ManualBookingOutput, error)
+	FetchNotificationSetting(req NotificationSettingInput) (*NotificationSettingOutput, error)
+	SaveNotificationSetting(req SaveNotificationSettingInput) (*NotificationSettingOutput, error)
+	FetchNotificationLogs(req NotificationLogInput) ([]NotificationLogOutput, error)
+	RoomingList(req RoomingListInput) (*RoomingListOutput, error)
+	WriteRoomingList(list *RoomingListOutput, format string, w io.Writer) error
+	FetchContact(req ContactInput) (*ContactOutput, error)
+	UpdateContactStatus(hmUser *account.HtTmHotelManager, req UpdateContactStatusInput) (*ContactOutput, error)
+	AppendMemo(hmUser *account.HtTmHotelManager, req AppendMemoInput) (*ContactOutput, error)
+	UpdateMisuse(hmUser *account.HtTmHotelManager, req UpdateMisuseInput) (*ContactOutput, error)
+	ScanBookingEvents() (int, error)
+	DispatchNotifications() (int, error)
+	UpdateNoShow(hmUser *account.HtTmHotelManager, req *NoShowInput) (*NoShowResult, error)
+	BulkNoShow(hmUser *account.HtTmHotelManager, req BulkNoShowInput) (*BulkNoShowOutput, error)
+	CalculateCancelFee(req CancelFeeInput) (*CancelFeeOutput, error)
+	DispatchCancelRequests() (int, error)
+	BackfillRoomPriceLinks(batchSize int) (*BackfillOutput, error)
 }
 
 // IBookingRepository 予約関連のrepositoryのインターフェース
 type IBookingRepository interface {
-	// FetchBookings 検索条件に基づいて予約一覧を複数件取得（hotelリポジトリ参照）
-	FetchBookings(req SearchInput) ([]SearchDBOutput, error)
+	// FetchBookings 検索条件に基づいて予約一覧をページ単位で取得（hotelリポジトリ参照）
+	FetchBookings(req SearchInput, cursor *SearchCursor) ([]SearchDBOutput, error)
+	// CountBookings 検索条件に合致する予約の総件数を取得（hotelリポジトリ参照）
+	CountBookings(req SearchInput) (int64, error)
 	// FetchDetailApplicationData 予約詳細情報を一件取得（hotelリポジトリ参照）
 	FetchDetailApplicationData(req DetailInput) (*DetailApplicationDBOutput, error)
 	// FetchBookingDownloadData 予約詳細情報を複数取得（hotelリポジトリ参照）
@@ -428,17 +493,24 @@ type IBookingRepository interface {
 	FetchBookingRoomListByApplicationID(HtThApplicationIDs []int64) ([]HtThBookingRooms, error)
 	// FetchRoomListTlsByItineraryID itinerary_idに基づく部屋・プラン情報を複数件取得
 	FetchRoomListTlsByItineraryID(ItineraryIDs []string) ([]HtTmItineraryTls, error)
-	// FetchNoShowData 予約IDに基づくNoShowのデータを１件取得
-	FetchNoShowData(CmApplicationID int64) (HtThApplications, error)
-	// UpdateNoShow ht_th_application_idに基づくデータのNoShowフラグを更新
-	UpdateNoShow(HtThApplicationID int64, noShowFlg bool, noShowFee float32) error
+	// FetchApplication 予約IDに基づく予約データを１件取得
+	FetchApplication(CmApplicationID int64) (HtThApplications, error)
 	// FetchFlashSaleData 予約IDに基づくセールデータを取得
 	FetchFlashSaleData(CmApplicationIDs []int64) ([]CmThFlashSale, error)
 	// FetchBookingPriceData 予約IDに基づく予約料金データを取得
 	FetchBookingPriceData(CmApplicationIDs []int64) ([]HtThBookingPrices, error)
+	// FetchUnlinkedApplications 部屋と紐づいていない料金情報がある予約を、予約ID順に取得
+	FetchUnlinkedApplications(afterCmApplicationID int64, limit int) ([]HtThApplications, error)
+	// LinkBookingPrices 料金情報を部屋と紐づける（料金情報ID→部屋ID）
+	LinkBookingPrices(links map[int64]int64) error
+	// FetchStayingApplications 指定日にチェックイン・滞在・チェックアウトするキャンセルされていない予約を取得
+	FetchStayingApplications(propertyID int64, date string) ([]HtThApplications, error)
 }
 
 // IBookingAPI 予約関連のAPIのインターフェース
 type IBookingAPI interface {
-	CancelBooking(cmApplicationID int64, cancelFee int64, noShow uint8) (bool, error)
+	// CancelBooking adminへキャンセル依頼（予約全体・一部）を送信
+	CancelBooking(cancelRequest *HtThBookingCancelRequests) error
+	// PartialCancelEnabled adminの一部キャンセルAPIを使えるか（使えない間は一部キャンセルの依頼を送信待ちのままにする）
+	PartialCancelEnabled() bool
 }
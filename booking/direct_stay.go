@@ -0,0 +1,58 @@
+package booking
+
+import "errors"
+
+var (
+	// ErrBookingPlan 施設のプランではないか、削除されたプラン
+	ErrBookingPlan = errors.New("Error: 指定したプランは予約できません。")
+	// ErrBookingStay プランの連泊条件を満たさない宿泊日
+	ErrBookingStay = errors.New("Error: 指定した宿泊日ではプランを予約できません。")
+	// ErrBookingOccupancy 部屋の定員外の人数か、プランで受け入れていない子供の区分
+	ErrBookingOccupancy = errors.New("Error: 指定した人数ではプランを予約できません。")
+	// ErrBookingPrice 宿泊日・人数に合う料金が設定されていない
+	ErrBookingPrice = errors.New("Error: 指定した宿泊日・人数の料金が設定されていません。")
+	// ErrBookingStock 在庫が足りない
+	ErrBookingStock = errors.New("Error: 指定した宿泊日の在庫が足りません。")
+)
+
+// BookingRoomInput 部屋ごとのプランと人数（子供は区分ごと）の入力
+type BookingRoomInput struct {
+	PlanID                            int64 `json:"plan_id" validate:"required"`
+	NumberOfAdults                    int   `json:"number_of_adults" validate:"min=1"`
+	NumberOfUpperGrades               int   `json:"number_of_upper_grades" validate:"min=0"`
+	NumberOfLowerGrades               int   `json:"number_of_lower_grades" validate:"min=0"`
+	NumberOfInfantMealsWithBedding    int   `json:"number_of_infant_meals_with_bedding" validate:"min=0"`
+	NumberOfInfantMealOnly            int   `json:"number_of_infant_meal_only" validate:"min=0"`
+	NumberOfInfantBeddingOnly         int   `json:"number_of_infant_bedding_only" validate:"min=0"`
+	NumberOfInfantMealsWithoutBedding int   `json:"number_of_infant_meals_without_bedding" validate:"min=0"`
+}
+
+// Children 子供A〜Fの人数（子供料金設定のchild_rate_type 1〜6の順）
+func (a *BookingRoomInput) Children() [6]int {
+	return [6]int{
+		a.NumberOfUpperGrades,
+		a.NumberOfLowerGrades,
+		a.NumberOfInfantMealsWithBedding,
+		a.NumberOfInfantMealOnly,
+		a.NumberOfInfantBeddingOnly,
+		a.NumberOfInfantMealsWithoutBedding,
+	}
+}
+
+// QuoteRoomOutput 見積もった部屋ごとの料金
+type QuoteRoomOutput struct {
+	HtThBookingRoomID int64            `json:"ht_th_booking_room_id"`
+	RoomTypeID        int64            `json:"room_type_id"`
+	PlanID            int64            `json:"plan_id"`
+	RoomName          string           `json:"room_name"`
+	PlanName          string           `json:"plan_name"`
+	AmountInTax       int              `json:"amount_in_tax"`
+	Nights            []RoomNightPrice `json:"nights"`
+}
+
+// StockChange 部屋タイプ・日付ごとの在庫の増減（正の値は予約数を増やして在庫を減らす）
+type StockChange struct {
+	RoomTypeID int64
+	UseDate    string
+	Delta      int
+}
@@ -0,0 +1,168 @@
+package booking
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+var (
+	// ErrUnknownExportColumn 出力できない項目が指定された
+	ErrUnknownExportColumn = errors.New("Error: 出力できない項目が指定されています。")
+	// ErrExportNotFound エクスポートが存在しないか、ダウンロード期限が切れている
+	ErrExportNotFound = errors.New("Error: エクスポートが存在しないか、ダウンロード期限が切れています。")
+	// ErrExportNotReady エクスポートのファイルを作成中
+	ErrExportNotReady = errors.New("Error: エクスポートのファイルを作成中です。")
+)
+
+const (
+	// ExportStatusProcessing ファイル作成中
+	ExportStatusProcessing = "processing"
+	// ExportStatusCompleted ファイル作成済み
+	ExportStatusCompleted = "completed"
+	// ExportStatusFailed ファイル作成失敗
+	ExportStatusFailed = "failed"
+
+	// ExportTTL バックグラウンドで作成したファイルのダウンロード期限
+	ExportTTL = 7 * 24 * time.Hour
+	// ExportBatchSize 1回のクエリで取得する予約の件数
+	ExportBatchSize = 500
+	// DefaultExportSyncMaxRows この件数以下であれば、バックグラウンドにせずその場で出力する
+	DefaultExportSyncMaxRows = 1000
+)
+
+// ExportColumn 出力項目
+type ExportColumn struct {
+	Key    string
+	Header string
+}
+
+// ExportColumns 出力できる項目と見出し。項目の指定がない場合はこの順ですべて出力する
+var ExportColumns = []ExportColumn{
+	{"cm_application_id", "予約番号"},
+	{"application_cd", "申込コード"},
+	{"created_at", "申込日時"},
+	{"status", "ステータス"},
+	{"checkin", "チェックイン"},
+	{"checkout", "チェックアウト"},
+	{"stays", "泊数"},
+	{"room_num", "部屋数"},
+	{"guest_name", "予約者名"},
+	{"phone", "電話番号"},
+	{"email", "メールアドレス"},
+	{"rooms", "部屋タイプ"},
+	{"plans", "プラン"},
+	{"guests", "人数"},
+	{"nightly_prices", "日別料金"},
+	{"total_pay_in_tax", "合計金額（税込）"},
+	{"sale_price", "割引後金額"},
+	{"discount_cash_amount", "割引額"},
+	{"cancel_fee", "キャンセル料"},
+	{"noshow_fee", "NoShow料金"},
+	{"canceled_dt", "キャンセル日時"},
+}
+
+// StatusLabels 出力する予約ステータスの表記
+var StatusLabels = map[uint8]string{
+	utils.ReserveStatusReserved: "予約済み",
+	utils.ReserveStatusCancel:   "キャンセル",
+	utils.ReserveStatusNoShow:   "NoShow",
+	utils.ReserveStatusStaying:  "宿泊中",
+	utils.ReserveStatusStayed:   "宿泊済み",
+}
+
+// HtThBookingExports バックグラウンドで作成する予約一覧ファイルのテーブル
+type HtThBookingExports struct {
+	BookingExportID int64     `gorm:"primaryKey;autoIncrement:true" json:"booking_export_id"`
+	HotelManagerID  int64     `json:"hotel_manager_id"`
+	PropertyID      int64     `json:"property_id"`
+	Format          string    `json:"format"`
+	Status          string    `json:"status"`
+	ObjectPath      string    `json:"-"`
+	RowCount        int64     `json:"row_count"`
+	ErrorMessage    string    `json:"error_message"`
+	CompletedAt     time.Time `gorm:"type:time" json:"completed_at"`
+	ExpiresAt       time.Time `gorm:"type:time" json:"expires_at"`
+	common.Times    `gorm:"embedded"`
+}
+
+// ExportInput 予約一覧ファイル出力の入力。絞り込み・並び順は予約検索と同じ（ページングは無視してすべて出力）
+type ExportInput struct {
+	SearchInput
+	Format  string   `json:"format" validate:"required,oneof=csv csv_sjis xlsx"`
+	Columns []string `json:"columns"`
+}
+
+// ExportFileInput バックグラウンドで作成したファイルの取得の入力
+type ExportFileInput struct {
+	BookingExportID int64 `json:"booking_export_id" param:"bookingExportId" validate:"required"`
+}
+
+// ExportOutput バックグラウンドでのファイル作成状況の出力
+type ExportOutput struct {
+	BookingExportID int64     `json:"booking_export_id"`
+	Status          string    `json:"status"`
+	RowCount        int64     `json:"row_count"`
+	DownloadURL     string    `json:"download_url,omitempty"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// IBookingExportUsecase 予約一覧ファイル出力関連のusecaseのインターフェース
+type IBookingExportUsecase interface {
+	// PrepareExport 出力項目を確認し、バックグラウンドで作成すべき件数か判定する
+	PrepareExport(req *ExportInput) (bool, error)
+	// Export 予約一覧をファイル形式で書き込み、出力した件数を返す
+	Export(hmUser *account.HtTmHotelManager, req *ExportInput, w io.Writer) (int64, error)
+	// StartExport バックグラウンドでのファイル作成を登録
+	StartExport(hmUser *account.HtTmHotelManager, req *ExportInput) (*HtThBookingExports, error)
+	// RunExport 登録したファイルを作成してストレージに保存
+	RunExport(hmUser *account.HtTmHotelManager, bookingExport *HtThBookingExports, req *ExportInput) error
+	// FetchExport ファイル作成状況を取得
+	FetchExport(hmUser *account.HtTmHotelManager, bookingExportID int64) (*ExportOutput, error)
+	// OpenExport 作成済みのファイルを開く
+	OpenExport(hmUser *account.HtTmHotelManager, bookingExportID int64) (io.ReadCloser, *HtThBookingExports, error)
+}
+
+// IBookingExportRepository 予約一覧ファイル出力関連のrepositoryのインターフェース
+type IBookingExportRepository interface {
+	// CreateBookingExport ファイル作成を登録
+	CreateBookingExport(bookingExport *HtThBookingExports) error
+	// UpdateBookingExport ファイル作成状況を更新
+	UpdateBookingExport(bookingExportID int64, values map[string]interface{}) error
+	// FetchBookingExport ファイル作成状況を1件取得
+	FetchBookingExport(bookingExportID int64) (*HtThBookingExports, error)
+}
+
+// IBookingExportStorage 予約一覧ファイルのstorageのインターフェース
+type IBookingExportStorage interface {
+	// NewWriter ファイルの書き込みを開始（Closeで保存が完了する）
+	NewWriter(bucketName string, objectPath string) (io.WriteCloser, error)
+	// NewReader ファイルの読み込みを開始
+	NewReader(bucketName string, objectPath string) (io.ReadCloser, error)
+}
+
+// ExportHeaders 出力する項目と見出しを返す。項目の指定がなければすべての項目
+func ExportHeaders(columns []string) ([]string, []string, error) {
+	headers := map[string]string{}
+	for _, column := range ExportColumns {
+		headers[column.Key] = column.Header
+	}
+	if len(columns) == 0 {
+		for _, column := range ExportColumns {
+			columns = append(columns, column.Key)
+		}
+	}
+	result := []string{}
+	for _, column := range columns {
+		header, ok := headers[column]
+		if !ok {
+			return nil, nil, ErrUnknownExportColumn
+		}
+		result = append(result, header)
+	}
+	return columns, result, nil
+}
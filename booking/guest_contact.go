@@ -0,0 +1,94 @@
+package booking
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// ContactStatusNone 未対応
+	ContactStatusNone = 0
+	// ContactStatusInProgress 対応中
+	ContactStatusInProgress = 1
+	// ContactStatusContacted 連絡済み
+	ContactStatusContacted = 2
+	// ContactStatusUnreachable 連絡がつかない
+	ContactStatusUnreachable = 3
+
+	// ContactHistoryStatus 連絡状況の変更
+	ContactHistoryStatus = "contact_status"
+	// ContactHistoryMemo メモの追記
+	ContactHistoryMemo = "memo"
+	// ContactHistoryMisuse 不正利用の疑いの設定・解除
+	ContactHistoryMisuse = "misuse"
+)
+
+// ContactStatusLabels 連絡状況の表示名
+var ContactStatusLabels = map[int]string{
+	ContactStatusNone:        "未対応",
+	ContactStatusInProgress:  "対応中",
+	ContactStatusContacted:   "連絡済み",
+	ContactStatusUnreachable: "連絡不可",
+}
+
+// ErrContactUnchanged 連絡状況・不正利用の疑いが現在と同じ
+var ErrContactUnchanged = errors.New("Error: 現在と同じ内容のため変更できません。")
+
+// HtThBookingContactHistories 予約の連絡状況・メモ・不正利用の疑いの変更履歴テーブル（追記のみ）
+type HtThBookingContactHistories struct {
+	BookingContactHistoryID int64  `gorm:"primaryKey;autoIncrement:true" json:"booking_contact_history_id"`
+	CmApplicationID         int64  `json:"cm_application_id"`
+	Kind                    string `json:"kind"`
+	Before                  string `json:"before"`
+	After                   string `json:"after"`
+	// Note 変更理由・追記したメモ
+	Note           string    `json:"note"`
+	HotelManagerID int64     `json:"hotel_manager_id"`
+	OperatorName   string    `json:"operator_name"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ContactInput 予約の連絡状況・メモ・不正利用の疑い取得の入力
+type ContactInput struct {
+	CmApplicationID int64 `json:"cm_application_id" param:"cmApplicationId" validate:"required"`
+}
+
+// UpdateContactStatusInput 連絡状況の変更の入力
+type UpdateContactStatusInput struct {
+	CmApplicationID int64  `json:"cm_application_id" validate:"required"`
+	ContactStatus   *int   `json:"contact_status" validate:"required,oneof=0 1 2 3"`
+	Note            string `json:"note" validate:"max=500"`
+}
+
+// AppendMemoInput メモの追記の入力
+type AppendMemoInput struct {
+	CmApplicationID int64  `json:"cm_application_id" validate:"required"`
+	Memo            string `json:"memo" validate:"required,max=1000"`
+}
+
+// UpdateMisuseInput 不正利用の疑いの設定・解除の入力
+type UpdateMisuseInput struct {
+	CmApplicationID int64  `json:"cm_application_id" validate:"required"`
+	IsMisuse        *bool  `json:"is_misuse" validate:"required"`
+	Reason          string `json:"reason" validate:"required,max=500"`
+}
+
+// ContactOutput 予約の連絡状況・メモ・不正利用の疑いの出力
+type ContactOutput struct {
+	CmApplicationID    int64                         `json:"cm_application_id"`
+	ContactStatus      int                           `json:"contact_status"`
+	ContactStatusLabel string                        `json:"contact_status_label"`
+	Memo               string                        `json:"memo"`
+	IsMisuse           bool                          `json:"is_misuse"`
+	Histories          []HtThBookingContactHistories `json:"histories"`
+}
+
+// IBookingContactRepository 予約の連絡状況・メモ・不正利用の疑い関連のrepositoryのインターフェース
+type IBookingContactRepository interface {
+	// UpdateContact 予約情報の更新と変更履歴の登録をまとめて行う
+	UpdateContact(cmApplicationID int64, values map[string]interface{}, history *HtThBookingContactHistories) error
+	// AppendMemo 予約情報のメモの末尾に1行追記し、変更履歴を登録する
+	AppendMemo(cmApplicationID int64, line string, operatorID int64, history *HtThBookingContactHistories) error
+	// FetchContactHistories 予約IDに基づく変更履歴を新しい順に取得
+	FetchContactHistories(cmApplicationID int64) ([]HtThBookingContactHistories, error)
+}
@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/booking"
 	"github.com/Adventureinc/hotel-hm-api/src/booking/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -14,15 +20,21 @@ import (
 
 // BookingHandler 予約関連の振り分け
 type BookingHandler struct {
-	BUsecase booking.IBookingUsecase
-	AUsecase account.IAccountUsecase
+	BUsecase       booking.IBookingUsecase
+	BExportUsecase booking.IBookingExportUsecase
+	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
+	AuditUsecase   audit.IAuditUsecase
 }
 
 // NewBookingHandler インスタンス生成
 func NewBookingHandler(hotelDB *gorm.DB) *BookingHandler {
 	return &BookingHandler{
-		BUsecase: usecase.NewBookingUsecase(hotelDB),
-		AUsecase: aUsecase.NewAccountUsecase(hotelDB),
+		BUsecase:       usecase.NewBookingUsecase(hotelDB),
+		BExportUsecase: usecase.NewBookingExportUsecase(hotelDB),
+		AUsecase:       aUsecase.NewAccountUsecase(hotelDB),
+		OUsecase:       oUsecase.NewOwnershipUsecase(hotelDB),
+		AuditUsecase:   auditUsecase.NewAuditUsecase(hotelDB),
 	}
 }
 
@@ -48,7 +60,20 @@ func (b *BookingHandler) Search(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 	utils.RequestLog(c, request)
-	bookings, _ := b.BUsecase.SearchBookings(&hmUser, claimParam, *request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	bookings, err := b.BUsecase.SearchBookings(&hmUser, claimParam, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrInvalidCursor) {
+			return echo.ErrBadRequest
+		}
+		return echo.ErrInternalServerError
+	}
 	return c.JSON(http.StatusOK, bookings)
 }
 
@@ -74,6 +99,12 @@ func (b *BookingHandler) Download(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	detail, err := b.BUsecase.BookingDownloads(&hmUser, claimParam, *request)
 	if err != nil {
 		c.Echo().Logger.Error(err)
@@ -104,6 +135,12 @@ func (b *BookingHandler) Detail(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	detail, err := b.BUsecase.DetailBooking(&hmUser, claimParam, *request)
 	if err != nil {
 		c.Echo().Logger.Error(err)
@@ -118,7 +155,7 @@ func (b *BookingHandler) Cancel(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
-	_, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
+	hmUser, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
 	if hmErr != nil {
 		c.Echo().Logger.Error(hmErr)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -135,15 +172,261 @@ func (b *BookingHandler) Cancel(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
-	success, err := b.BUsecase.CancelBooking(*request)
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionCancel, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	cancelRequest, err := b.BUsecase.CancelBooking(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrCancelFeeExceedsPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, booking.ErrAlreadyCanceled) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	// adminへの送信はワーカーでも行うが、待たせないようすぐに送信しておく
+	go b.processCancelRequests(c.Echo().Logger)
+	return c.JSON(http.StatusAccepted, cancelRequest)
+}
+
+// processCancelRequests 送信待ちのキャンセル依頼をadminへ送信
+// レスポンス後はechoがcontextを再利用するため、contextではなくloggerを受け取る
+func (b *BookingHandler) processCancelRequests(logger echo.Logger) {
+	if _, err := b.BUsecase.DispatchCancelRequests(); err != nil {
+		logger.Error(err)
+	}
+}
+
+// PartialCancel 予約の一部（部屋・泊）をキャンセル
+func (b *BookingHandler) PartialCancel(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
+	if hmErr != nil {
+		c.Echo().Logger.Error(hmErr)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.PartialCancelInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionPartialCancel, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	partialCancel, err := b.BUsecase.PartialCancelBooking(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrPartialCancelTarget) ||
+			errors.Is(err, booking.ErrPartialCancelAll) ||
+			errors.Is(err, booking.ErrPartialCancelStayed) ||
+			errors.Is(err, booking.ErrPartialCancelUnavailable) ||
+			errors.Is(err, booking.ErrCancelFeeExceedsPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, booking.ErrAlreadyCanceled) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, partialCancel)
+}
+
+// Amend 直仕入れの予約の宿泊日・プラン・人数を変更（見積もりのみも可）
+func (b *BookingHandler) Amend(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
+	if hmErr != nil {
+		c.Echo().Logger.Error(hmErr)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.AmendInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	// 見積もりのみの場合は予約を変更しないため、監査ログに残さない
+	if request.QuoteOnly == false {
+		trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionAmend, request)
+		if err != nil {
+			c.Echo().Logger.Error(err)
+			return echo.ErrInternalServerError
+		}
+		defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+	}
+
+	amendment, err := b.BUsecase.AmendBooking(&hmUser, *request)
 	if err != nil {
 		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrAmendWholesaler) ||
+			errors.Is(err, booking.ErrAmendStarted) ||
+			errors.Is(err, booking.ErrAmendRooms) ||
+			errors.Is(err, booking.ErrAmendPartialCanceled) ||
+			errors.Is(err, booking.ErrBookingPlan) ||
+			errors.Is(err, booking.ErrBookingStay) ||
+			errors.Is(err, booking.ErrBookingOccupancy) ||
+			errors.Is(err, booking.ErrBookingPrice) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, booking.ErrBookingStock) ||
+			errors.Is(err, booking.ErrAmendConflict) ||
+			errors.Is(err, booking.ErrAlreadyCanceled) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.ErrInternalServerError
 	}
-	if success == false {
+	return c.JSON(http.StatusOK, amendment)
+}
+
+// CreateManual 電話・直接来館などの直仕入れの予約を手入力で登録（見積もりのみも可）
+func (b *BookingHandler) CreateManual(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
+	if hmErr != nil {
+		c.Echo().Logger.Error(hmErr)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.ManualBookingInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	// 見積もりのみの場合は予約を登録しないため、監査ログに残さない
+	if request.QuoteOnly == false {
+		trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionCreate, request)
+		if err != nil {
+			c.Echo().Logger.Error(err)
+			return echo.ErrInternalServerError
+		}
+		defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+	}
+
+	manualBooking, err := b.BUsecase.CreateManualBooking(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrManualBookingWholesaler) ||
+			errors.Is(err, booking.ErrBookingPlan) ||
+			errors.Is(err, booking.ErrBookingStay) ||
+			errors.Is(err, booking.ErrBookingOccupancy) ||
+			errors.Is(err, booking.ErrBookingPrice) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, booking.ErrBookingStock) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.ErrInternalServerError
 	}
-	return c.NoContent(http.StatusOK)
+	return c.JSON(http.StatusOK, manualBooking)
+}
+
+// CancelFee キャンセルポリシーに基づくキャンセル料の内訳
+func (b *BookingHandler) CancelFee(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.CancelFeeInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	cancelFee, err := b.BUsecase.CalculateCancelFee(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrInvalidCancelAt) {
+			return echo.ErrBadRequest
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, cancelFee)
 }
 
 // NoShow 予約のNoShow(無断不泊)
@@ -152,7 +435,7 @@ func (b *BookingHandler) NoShow(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
-	_, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
+	hmUser, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
 	if hmErr != nil {
 		c.Echo().Logger.Error(hmErr)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -168,11 +451,198 @@ func (b *BookingHandler) NoShow(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
-	if err := b.BUsecase.UpdateNoShow(request); err != nil {
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	action := audit.ActionNoShow
+	if !request.NoshowFlg {
+		action = audit.ActionReverseNoShow
+	}
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, action, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	result, err := b.BUsecase.UpdateNoShow(&hmUser, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrNoShowNotAllowed) ||
+			errors.Is(err, booking.ErrNoShowDeadline) ||
+			errors.Is(err, booking.ErrNoShowReason) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, booking.ErrNoShowUnchanged) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// BulkNoShow 指定日に到着する予約の一括NoShow
+func (b *BookingHandler) BulkNoShow(c echo.Context) error {
+	hmUser, err := b.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.BulkNoShowInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionNoShow, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	output, err := b.BUsecase.BulkNoShow(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// NotificationSetting 予約通知の設定
+func (b *BookingHandler) NotificationSetting(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.NotificationSettingInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	setting, err := b.BUsecase.FetchNotificationSetting(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, setting)
+}
+
+// SaveNotificationSetting 予約通知の設定の保存（Webhookの署名シークレットは発行時のみ返す）
+func (b *BookingHandler) SaveNotificationSetting(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, hmErr := b.AUsecase.FetchHMUserByToken(claimParam)
+	if hmErr != nil {
+		c.Echo().Logger.Error(hmErr)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.SaveNotificationSettingInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBookingNotification, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	setting, err := b.BUsecase.SaveNotificationSetting(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, setting)
+}
+
+// NotificationLogs 予約通知の送信履歴
+func (b *BookingHandler) NotificationLogs(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.NotificationLogInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	logs, err := b.BUsecase.FetchNotificationLogs(*request)
+	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.ErrInternalServerError
 	}
-	return c.NoContent(http.StatusOK)
+	return c.JSON(http.StatusOK, logs)
 }
 
 // getHmUser トークンからHMアカウント情報を取得
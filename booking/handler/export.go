@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/export"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// Export 予約一覧をCSV・Excelファイルで出力
+// 件数が多い場合はバックグラウンドで作成し、作成状況を202で返す
+func (b *BookingHandler) Export(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.ExportInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	isLarge, err := b.BExportUsecase.PrepareExport(request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrUnknownExportColumn) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	if isLarge {
+		bookingExport, err := b.BExportUsecase.StartExport(&hmUser, request)
+		if err != nil {
+			c.Echo().Logger.Error(err)
+			return echo.ErrInternalServerError
+		}
+		// レスポンス後はechoがcontextを再利用するため、goroutineにはcontextを渡さない
+		requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+		go b.processExport(c.Echo().Logger, requestID, hmUser, bookingExport, request)
+		return c.JSON(http.StatusAccepted, booking.ExportOutput{
+			BookingExportID: bookingExport.BookingExportID,
+			Status:          bookingExport.Status,
+			ExpiresAt:       bookingExport.ExpiresAt,
+		})
+	}
+
+	// 途中で失敗した場合に途中までのファイルを正常に見せないよう、作成し終えてから返す
+	// バックグラウンドで作成するほどの件数ではないため、メモリに収まる
+	buf := &bytes.Buffer{}
+	if _, err := b.BExportUsecase.Export(&hmUser, request, buf); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	filename := fmt.Sprintf("bookings_%d_%s%s", request.PropertyID, time.Now().Format("20060102150405"), export.Extension(request.Format))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, export.ContentType(request.Format), buf.Bytes())
+}
+
+// ExportStatus バックグラウンドでのファイル作成状況を取得
+func (b *BookingHandler) ExportStatus(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.ExportFileInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	output, err := b.BExportUsecase.FetchExport(&hmUser, request.BookingExportID)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrExportNotFound) {
+			return echo.ErrNotFound
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// ExportDownload バックグラウンドで作成したファイルをダウンロード
+func (b *BookingHandler) ExportDownload(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.ExportFileInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	reader, bookingExport, err := b.BExportUsecase.OpenExport(&hmUser, request.BookingExportID)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrExportNotFound) {
+			return echo.ErrNotFound
+		}
+		if errors.Is(err, booking.ErrExportNotReady) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	defer reader.Close()
+
+	filename := fmt.Sprintf("bookings_%d_%s%s", bookingExport.PropertyID, bookingExport.CreatedAt.Format("20060102150405"), export.Extension(bookingExport.Format))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Stream(http.StatusOK, export.ContentType(bookingExport.Format), reader)
+}
+
+// processExport バックグラウンドでファイルを作成する
+func (b *BookingHandler) processExport(logger echo.Logger, requestID string, hmUser account.HtTmHotelManager, bookingExport *booking.HtThBookingExports, request *booking.ExportInput) {
+	if err := b.BExportUsecase.RunExport(&hmUser, bookingExport, request); err != nil {
+		logger.Errorf("booking export %d failed (request_id=%s): %v", bookingExport.BookingExportID, requestID, err)
+	}
+}
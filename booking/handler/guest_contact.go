@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// Contact 予約の連絡状況・メモ・不正利用の疑いと変更履歴
+func (b *BookingHandler) Contact(c echo.Context) error {
+	hmUser, err := b.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.ContactInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	contact, err := b.BUsecase.FetchContact(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, contact)
+}
+
+// UpdateContactStatus 予約の連絡状況を変更
+func (b *BookingHandler) UpdateContactStatus(c echo.Context) error {
+	hmUser, err := b.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.UpdateContactStatusInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionContactStatus, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	contact, err := b.BUsecase.UpdateContactStatus(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrContactUnchanged) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, contact)
+}
+
+// AppendMemo 予約のメモに追記
+func (b *BookingHandler) AppendMemo(c echo.Context) error {
+	hmUser, err := b.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.AppendMemoInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionAppendMemo, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	contact, err := b.BUsecase.AppendMemo(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, contact)
+}
+
+// UpdateMisuse 予約の不正利用の疑いを設定・解除
+func (b *BookingHandler) UpdateMisuse(c echo.Context) error {
+	hmUser, err := b.getHmUser(c)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.UpdateMisuseInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, b.AUsecase, &hmUser, account.PermissionBookingEdit); err != nil {
+		return err
+	}
+
+	trail, err := b.AuditUsecase.Begin(hmUser, audit.EntityBooking, audit.ActionMisuse, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, b.AuditUsecase, trail)
+
+	contact, err := b.BUsecase.UpdateMisuse(&hmUser, *request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		if errors.Is(err, booking.ErrContactUnchanged) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, contact)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/export"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// RoomingList 指定日の到着・出発・連泊の一覧
+// formatを指定した場合は印刷用のファイル（CSV・Excel・PDF）で出力
+func (b *BookingHandler) RoomingList(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := b.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &booking.RoomingListInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := b.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	list, err := b.BUsecase.RoomingList(*request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	if request.Format == "" {
+		return c.JSON(http.StatusOK, list)
+	}
+
+	filename := fmt.Sprintf("rooming_%d_%s%s", request.PropertyID, request.Date, export.Extension(request.Format))
+	c.Response().Header().Set(echo.HeaderContentType, export.ContentType(request.Format))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+	if err := b.BUsecase.WriteRoomingList(list, request.Format, c.Response()); err != nil {
+		// ヘッダー送信後のため、ステータスは変えられない
+		c.Echo().Logger.Error(err)
+	}
+	return nil
+}
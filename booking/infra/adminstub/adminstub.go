@@ -0,0 +1,201 @@
+package adminstub
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Call stubが受け付けたキャンセル依頼1件
+type Call struct {
+	IdempotencyKey  string
+	CmApplicationID int64
+	// BookingPartialCancelID 一部キャンセルの依頼の場合は一部キャンセルの履歴ID
+	BookingPartialCancelID int64
+	CancelFee              int64
+	Noshow                 uint8
+}
+
+// Server adminのキャンセルAPI（cancel_application_from_hm）の代わりに動かすローカル用のサーバー
+// 同じIdempotency-Keyの再送は1回目と同じ結果を返し、キャンセル済みの予約には409を返す
+type Server struct {
+	apiKey string
+
+	mu       sync.Mutex
+	failNext int
+	rejected map[int64]string
+	canceled map[int64]string
+	// partialCanceled 一部キャンセルの履歴IDごとのIdempotency-Key
+	partialCanceled map[int64]string
+	calls           []Call
+}
+
+// cancelRequest キャンセル時の送信内容
+type cancelRequest struct {
+	CmApplicationID        int64 `json:"cm_application_id"`
+	BookingPartialCancelID int64 `json:"booking_partial_cancel_id"`
+	CancelFee              int64 `json:"cancel_fee"`
+	Noshow                 uint8 `json:"noshow"`
+}
+
+// cancelResponse キャンセル時の返却値
+type cancelResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// New インスタンス生成。apiKeyと異なるAPIキーのリクエストには401を返す
+func New(apiKey string) *Server {
+	return &Server{
+		apiKey:          apiKey,
+		rejected:        map[int64]string{},
+		canceled:        map[int64]string{},
+		partialCanceled: map[int64]string{},
+	}
+}
+
+// FailNext 次のn回のリクエストに500を返す（再送の確認用）
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// Reject 予約IDのキャンセルを受け付けない（400を返す）
+func (s *Server) Reject(cmApplicationID int64, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected[cmApplicationID] = message
+}
+
+// Calls キャンセルを実行した依頼（再送・失敗したリクエストは含まない）
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call{}, s.calls...)
+}
+
+// ServeHTTP キャンセルAPIの処理
+// JSONで送信する新しいAPI・一部キャンセルAPIと、従来のGETのAPI（/cancel_application_from_hm/{予約ID}/{キャンセル料}/{APIキー}?noshow=）を受け付ける
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/cancel_application_from_hm/") {
+		s.serveLegacy(w, r)
+		return
+	}
+	partial := r.URL.Path == "/partial_cancel_application_from_hm"
+	if r.Method != http.MethodPost || (r.URL.Path != "/cancel_application_from_hm" && !partial) {
+		writeResponse(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Header.Get("X-Api-Key") != s.apiKey {
+		writeResponse(w, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	req := &cancelRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.CmApplicationID == 0 || (partial && req.BookingPartialCancelID == 0) {
+		writeResponse(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if partial {
+		s.partialCancel(w, req, r.Header.Get("Idempotency-Key"))
+		return
+	}
+	s.cancel(w, req, r.Header.Get("Idempotency-Key"))
+}
+
+// serveLegacy 従来のGETのキャンセルAPIの処理
+func (s *Server) serveLegacy(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/cancel_application_from_hm/"), "/")
+	if len(parts) != 3 {
+		writeResponse(w, http.StatusNotFound, "not found")
+		return
+	}
+	if parts[2] != s.apiKey {
+		writeResponse(w, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	cmApplicationID, idErr := strconv.ParseInt(parts[0], 10, 64)
+	cancelFee, feeErr := strconv.ParseInt(parts[1], 10, 64)
+	noshow, noshowErr := strconv.ParseUint(r.URL.Query().Get("noshow"), 10, 8)
+	if idErr != nil || feeErr != nil || noshowErr != nil || cmApplicationID == 0 {
+		writeResponse(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	s.cancel(w, &cancelRequest{CmApplicationID: cmApplicationID, CancelFee: cancelFee, Noshow: uint8(noshow)}, "")
+}
+
+// cancel キャンセルを実行する。keyは同じ依頼の再送を判定するIdempotency-Key（従来のAPIは空）
+func (s *Server) cancel(w http.ResponseWriter, req *cancelRequest, key string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failNext > 0 {
+		s.failNext--
+		writeResponse(w, http.StatusInternalServerError, "temporary error")
+		return
+	}
+	if message, ok := s.rejected[req.CmApplicationID]; ok {
+		writeResponse(w, http.StatusBadRequest, message)
+		return
+	}
+	if canceledKey, ok := s.canceled[req.CmApplicationID]; ok {
+		if key != "" && canceledKey == key {
+			writeResponse(w, http.StatusOK, "canceled")
+			return
+		}
+		writeResponse(w, http.StatusConflict, "already canceled")
+		return
+	}
+	s.canceled[req.CmApplicationID] = key
+	s.calls = append(s.calls, Call{
+		IdempotencyKey:  key,
+		CmApplicationID: req.CmApplicationID,
+		CancelFee:       req.CancelFee,
+		Noshow:          req.Noshow,
+	})
+	writeResponse(w, http.StatusOK, "canceled")
+}
+
+// partialCancel 一部キャンセルを実行する。同じ履歴IDの依頼は1回だけ実行し、キャンセル済みの予約には409を返す
+func (s *Server) partialCancel(w http.ResponseWriter, req *cancelRequest, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failNext > 0 {
+		s.failNext--
+		writeResponse(w, http.StatusInternalServerError, "temporary error")
+		return
+	}
+	if message, ok := s.rejected[req.CmApplicationID]; ok {
+		writeResponse(w, http.StatusBadRequest, message)
+		return
+	}
+	if _, ok := s.canceled[req.CmApplicationID]; ok {
+		writeResponse(w, http.StatusConflict, "already canceled")
+		return
+	}
+	if canceledKey, ok := s.partialCanceled[req.BookingPartialCancelID]; ok {
+		if canceledKey == key {
+			writeResponse(w, http.StatusOK, "canceled")
+			return
+		}
+		writeResponse(w, http.StatusConflict, "already canceled")
+		return
+	}
+	s.partialCanceled[req.BookingPartialCancelID] = key
+	s.calls = append(s.calls, Call{
+		IdempotencyKey:         key,
+		CmApplicationID:        req.CmApplicationID,
+		BookingPartialCancelID: req.BookingPartialCancelID,
+		CancelFee:              req.CancelFee,
+	})
+	writeResponse(w, http.StatusOK, "canceled")
+}
+
+// writeResponse adminのAPIと同じ形式で返却
+func writeResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&cancelResponse{Status: status, Message: message})
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking/infra/adminstub"
+)
+
+// ローカルでadminのキャンセルAPIの代わりに起動する
+// HOTEL_ADMIN_API_PREFIX に http://localhost:1324 を設定して使う
+func main() {
+	addr := os.Getenv("ADMIN_STUB_ADDR")
+	if addr == "" {
+		addr = ":1324"
+	}
+	log.Printf("admin stub listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, adminstub.New(os.Getenv("HM_API_KEY"))))
+}
@@ -1,16 +1,47 @@
 package infra
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/Adventureinc/hotel-hm-api/src/booking"
-	"github.com/Adventureinc/hotel-hm-api/src/common/infra"
 )
 
-// bookingAPI TL 予約APIクライアント
+const (
+	// adminAPITimeout adminのAPIの応答待ち時間
+	adminAPITimeout = 30 * time.Second
+	// HeaderAPIKey adminのAPIキーを渡すヘッダー
+	HeaderAPIKey = "X-Api-Key"
+	// HeaderIdempotencyKey 同じ依頼の再送であることを伝えるヘッダー
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// bookingAPI admin 予約APIクライアント
 type bookingAPI struct {
-	client *infra.APIClient
+	prefix string
+	apiKey string
+	client *http.Client
+	// jsonRequest JSONで送信する新しいキャンセルAPIを使うか（falseの場合は従来のGETのAPI）
+	jsonRequest bool
+}
+
+// cancelRequestBody キャンセル時の送信内容
+type cancelRequestBody struct {
+	CmApplicationID int64 `json:"cm_application_id"`
+	CancelFee       int64 `json:"cancel_fee"`
+	Noshow          uint8 `json:"noshow"`
+}
+
+// partialCancelRequestBody 一部キャンセル時の送信内容
+type partialCancelRequestBody struct {
+	CmApplicationID        int64 `json:"cm_application_id"`
+	BookingPartialCancelID int64 `json:"booking_partial_cancel_id"`
+	CancelFee              int64 `json:"cancel_fee"`
 }
 
 // cancelResponse キャンセル時の返却値
@@ -22,25 +53,115 @@ type cancelResponse struct {
 // NewBookingAPI インスタンス生成
 func NewBookingAPI() booking.IBookingAPI {
 	return &bookingAPI{
-		client: new(infra.APIClient),
+		prefix: os.Getenv("HOTEL_ADMIN_API_PREFIX"),
+		apiKey: os.Getenv("HM_API_KEY"),
+		client: &http.Client{Timeout: adminAPITimeout},
+		// adminが新しいAPIに対応するまでは従来のAPIを使う
+		jsonRequest: os.Getenv("HOTEL_ADMIN_CANCEL_API_JSON") == "true",
 	}
 }
 
+// PartialCancelEnabled adminの一部キャンセルAPIを使えるか（JSONで送信する新しいAPIのみ対応）
+func (a *bookingAPI) PartialCancelEnabled() bool {
+	return a.jsonRequest
+}
+
 // CancelBooking 予約キャンセルAPI（現状、adminのキャンセル処理を実行するだけ）
-func (a *bookingAPI) CancelBooking(cmApplicationID int64, cancelFee int64, noShow uint8) (bool, error) {
+// 再送しても結果が変わらない場合は booking.ErrCancelRejected を返す。adminでキャンセル済みの場合は成功として扱う
+// HOTEL_ADMIN_CANCEL_API_JSON が true の場合は、APIキー・Idempotency-KeyをヘッダーにつけてJSONで送信する
+// 一部キャンセルの依頼は、JSONで送信する一部キャンセルAPIに送信する
+func (a *bookingAPI) CancelBooking(cancelRequest *booking.HtThBookingCancelRequests) error {
+	var req *http.Request
+	var err error
+	switch {
+	case cancelRequest.BookingPartialCancelID != 0 && a.jsonRequest:
+		req, err = a.newPartialCancelRequest(cancelRequest)
+	case cancelRequest.BookingPartialCancelID != 0:
+		return fmt.Errorf("Error: %s", "adminの一部キャンセルAPIが有効になっていません。")
+	case a.jsonRequest:
+		req, err = a.newCancelRequest(cancelRequest)
+	default:
+		req, err = a.newLegacyCancelRequest(cancelRequest)
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
 	response := &cancelResponse{}
+	if resp.StatusCode < http.StatusInternalServerError {
+		if err := json.Unmarshal(respBody, response); err != nil {
+			return fmt.Errorf("Error: %s %d %s", "adminのキャンセルAPIの返却値を読み込めません。", resp.StatusCode, err)
+		}
+	}
 
-	a.client.URL = fmt.Sprintf("%s/%s/%d/%d/%s?noshow=%d",
-		os.Getenv("HOTEL_ADMIN_API_PREFIX"),
-		"cancel_application_from_hm",
-		cmApplicationID,
-		cancelFee,
-		os.Getenv("HM_API_KEY"),
-		noShow)
-	a.client.Response = response
-	if err := a.client.Get(); err != nil {
-		return false, err
+	switch {
+	case resp.StatusCode == http.StatusConflict || response.Status == http.StatusConflict:
+		return nil
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("Error: %s %d", "adminのキャンセルAPIでエラーが発生しました。", resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest, response.Status >= http.StatusBadRequest && response.Status < http.StatusInternalServerError:
+		return fmt.Errorf("%w %s", booking.ErrCancelRejected, response.Message)
+	case response.Status != http.StatusOK:
+		return fmt.Errorf("Error: %s %d %s", "adminのキャンセルAPIでエラーが発生しました。", response.Status, response.Message)
 	}
+	return nil
+}
 
-	return response.Status == 200, nil
+// newCancelRequest JSONで送信するキャンセルAPIのリクエストを作成
+func (a *bookingAPI) newCancelRequest(cancelRequest *booking.HtThBookingCancelRequests) (*http.Request, error) {
+	body, err := json.Marshal(&cancelRequestBody{
+		CmApplicationID: cancelRequest.CmApplicationID,
+		CancelFee:       cancelRequest.CancelFee,
+		Noshow:          cancelRequest.Noshow,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.newJSONRequest("/cancel_application_from_hm", body, cancelRequest)
+}
+
+// newPartialCancelRequest 一部キャンセルAPIのリクエストを作成（キャンセルする部屋・泊はadminが予約の料金情報から判断する）
+func (a *bookingAPI) newPartialCancelRequest(cancelRequest *booking.HtThBookingCancelRequests) (*http.Request, error) {
+	body, err := json.Marshal(&partialCancelRequestBody{
+		CmApplicationID:        cancelRequest.CmApplicationID,
+		BookingPartialCancelID: cancelRequest.BookingPartialCancelID,
+		CancelFee:              cancelRequest.CancelFee,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.newJSONRequest("/partial_cancel_application_from_hm", body, cancelRequest)
+}
+
+// newJSONRequest APIキー・Idempotency-KeyをヘッダーにつけたJSONのリクエストを作成
+func (a *bookingAPI) newJSONRequest(path string, body []byte, cancelRequest *booking.HtThBookingCancelRequests) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, a.prefix+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderAPIKey, a.apiKey)
+	req.Header.Set(HeaderIdempotencyKey, cancelRequest.IdempotencyKey())
+	return req, nil
+}
+
+// newLegacyCancelRequest 従来のキャンセルAPI（GET /cancel_application_from_hm/{予約ID}/{キャンセル料}/{APIキー}?noshow=）のリクエストを作成
+// Idempotency-Keyを送れないため、応答がなく再送した場合はadminでキャンセル済み（409）として成功扱いになる
+func (a *bookingAPI) newLegacyCancelRequest(cancelRequest *booking.HtThBookingCancelRequests) (*http.Request, error) {
+	return http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s/%d/%d/%s?noshow=%d",
+		a.prefix,
+		"cancel_application_from_hm",
+		cancelRequest.CmApplicationID,
+		cancelRequest.CancelFee,
+		a.apiKey,
+		cancelRequest.Noshow), nil)
 }
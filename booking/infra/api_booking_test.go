@@ -0,0 +1,115 @@
+package infra
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/booking/infra/adminstub"
+)
+
+func Test_bookingAPI_CancelBooking(t *testing.T) {
+	stub := adminstub.New("test-key")
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	api := &bookingAPI{prefix: server.URL, apiKey: "test-key", client: server.Client(), jsonRequest: true}
+
+	cancelRequest := &booking.HtThBookingCancelRequests{BookingCancelRequestID: 1, CmApplicationID: 100, CancelFee: 3000}
+
+	t.Run("adminのエラーは再送できるエラーとして返すことのテスト", func(t *testing.T) {
+		stub.FailNext(1)
+		err := api.CancelBooking(cancelRequest)
+		if err == nil || errors.Is(err, booking.ErrCancelRejected) {
+			t.Fatalf("再送できるエラーになっていません。%v", err)
+		}
+	})
+	t.Run("同じ依頼の再送は1回だけキャンセルされることのテスト", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := api.CancelBooking(cancelRequest); err != nil {
+				t.Fatal(err)
+			}
+		}
+		calls := stub.Calls()
+		if len(calls) != 1 || calls[0].IdempotencyKey != "hm-cancel-1" || calls[0].CancelFee != 3000 {
+			t.Fatalf("キャンセルの実行内容が一致しません。%+v", calls)
+		}
+	})
+	t.Run("adminでキャンセル済みの予約は成功として扱うことのテスト", func(t *testing.T) {
+		other := &booking.HtThBookingCancelRequests{BookingCancelRequestID: 2, CmApplicationID: 100}
+		if err := api.CancelBooking(other); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("adminが受け付けない場合は再送しないエラーを返すことのテスト", func(t *testing.T) {
+		stub.Reject(200, "予約が見つかりません。")
+		err := api.CancelBooking(&booking.HtThBookingCancelRequests{BookingCancelRequestID: 3, CmApplicationID: 200})
+		if !errors.Is(err, booking.ErrCancelRejected) {
+			t.Fatalf("再送しないエラーになっていません。%v", err)
+		}
+	})
+	t.Run("APIキーが違う場合は再送しないエラーを返すことのテスト", func(t *testing.T) {
+		invalid := &bookingAPI{prefix: server.URL, apiKey: "invalid", client: http.DefaultClient, jsonRequest: true}
+		err := invalid.CancelBooking(&booking.HtThBookingCancelRequests{BookingCancelRequestID: 4, CmApplicationID: 300})
+		if !errors.Is(err, booking.ErrCancelRejected) {
+			t.Fatalf("再送しないエラーになっていません。%v", err)
+		}
+	})
+}
+
+func Test_bookingAPI_CancelBooking_partial(t *testing.T) {
+	stub := adminstub.New("test-key")
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	api := &bookingAPI{prefix: server.URL, apiKey: "test-key", client: server.Client(), jsonRequest: true}
+
+	t.Run("一部キャンセルの依頼は一部キャンセルAPIに1回だけ送信されることのテスト", func(t *testing.T) {
+		cancelRequest := &booking.HtThBookingCancelRequests{BookingCancelRequestID: 1, CmApplicationID: 100, BookingPartialCancelID: 10, CancelFee: 1000}
+		for i := 0; i < 2; i++ {
+			if err := api.CancelBooking(cancelRequest); err != nil {
+				t.Fatal(err)
+			}
+		}
+		calls := stub.Calls()
+		if len(calls) != 1 || calls[0].BookingPartialCancelID != 10 || calls[0].CancelFee != 1000 {
+			t.Fatalf("一部キャンセルの実行内容が一致しません。%+v", calls)
+		}
+	})
+	t.Run("従来のAPIでは一部キャンセルを送信しないことのテスト", func(t *testing.T) {
+		legacy := &bookingAPI{prefix: server.URL, apiKey: "test-key", client: server.Client()}
+		if legacy.PartialCancelEnabled() {
+			t.Fatal("従来のAPIで一部キャンセルが有効になっています。")
+		}
+		if err := legacy.CancelBooking(&booking.HtThBookingCancelRequests{BookingCancelRequestID: 2, CmApplicationID: 200, BookingPartialCancelID: 20}); err == nil {
+			t.Fatal("エラーになっていません。")
+		}
+		if len(stub.Calls()) != 1 {
+			t.Fatalf("一部キャンセルが送信されています。%+v", stub.Calls())
+		}
+	})
+}
+
+func Test_bookingAPI_CancelBooking_legacy(t *testing.T) {
+	stub := adminstub.New("test-key")
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	api := &bookingAPI{prefix: server.URL, apiKey: "test-key", client: server.Client()}
+
+	t.Run("従来のAPIでキャンセルできることのテスト", func(t *testing.T) {
+		if err := api.CancelBooking(&booking.HtThBookingCancelRequests{BookingCancelRequestID: 1, CmApplicationID: 100, CancelFee: 3000, Noshow: 1}); err != nil {
+			t.Fatal(err)
+		}
+		calls := stub.Calls()
+		if len(calls) != 1 || calls[0].CmApplicationID != 100 || calls[0].CancelFee != 3000 || calls[0].Noshow != 1 {
+			t.Fatalf("キャンセルの実行内容が一致しません。%+v", calls)
+		}
+	})
+	t.Run("従来のAPIでもadminのエラーは再送できるエラーとして返すことのテスト", func(t *testing.T) {
+		stub.FailNext(1)
+		err := api.CancelBooking(&booking.HtThBookingCancelRequests{BookingCancelRequestID: 2, CmApplicationID: 200})
+		if err == nil || errors.Is(err, booking.ErrCancelRejected) {
+			t.Fatalf("再送できるエラーになっていません。%v", err)
+		}
+	})
+}
@@ -21,11 +21,18 @@ func NewBookingRepository(hotelDB *gorm.DB) booking.IBookingRepository {
 	}
 }
 
-// FetchBookings 検索条件に基づいて予約一覧を複数件取得（hotelリポジトリ参照）
-func (b *bookingRepository) FetchBookings(req booking.SearchInput) ([]booking.SearchDBOutput, error) {
-	targetWholesalers := []int{utils.WholesalerIDTl, utils.WholesalerIDTema, utils.WholesalerIDNeppan, utils.WholesalerIDDirect, utils.WholesalerIDRaku2}
+// searchSortColumns 予約検索の並び順の項目と列名
+var searchSortColumns = map[string]string{
+	booking.SortCheckin: "applications.arrival",
+	booking.SortCreated: "applications.created_at",
+	booking.SortAmount:  "applications.total_pay_in_tax",
+}
+
+// FetchBookings 検索条件に基づいて予約一覧をページ単位で取得（hotelリポジトリ参照）
+// cursorを指定した場合は、その予約の次から取得する
+func (b *bookingRepository) FetchBookings(req booking.SearchInput, cursor *booking.SearchCursor) ([]booking.SearchDBOutput, error) {
 	result := []booking.SearchDBOutput{}
-	query := b.hotelDB.Debug().
+	query := b.searchQuery(req).
 		Select("applications.cm_application_id",
 			"applications.total_pay_in_tax",
 			"applications.cancel_flg",
@@ -37,12 +44,81 @@ func (b *bookingRepository) FetchBookings(req booking.SearchInput) ([]booking.Se
 			"applications.given_name_enc",
 			"applications.family_name_enc",
 			"applications.phone_enc",
+			"applications.created_at",
+			"applications.contact_status",
+			"applications.is_misuse",
 			"cm_th_application.payment_limit_dt",
 			"IFNULL((SELECT count(1) FROM skyticket.cm_th_payment WHERE cm_th_payment.cm_application_id = applications.cm_application_id) ,0) AS payment_count").
+		Joins("LEFT JOIN skyticket.cm_th_application ON applications.cm_application_id = cm_th_application.cm_application_id").
+		Joins("LEFT JOIN skyticket.cm_th_organized_tours_application AS tours_application ON applications.cm_application_id = tours_application.cm_application_id")
+
+	// 並び順が同じ値の予約は予約IDの順に並べ、ページの境目で重複・欠落しないようにする
+	column := searchSortColumns[req.SortOrDefault()]
+	order := req.OrderOrDefault()
+	if cursor != nil {
+		operator := ">"
+		if order == booking.OrderDesc {
+			operator = "<"
+		}
+		query = query.Where(
+			b.hotelDB.Where(column+" "+operator+" ?", cursor.Value).
+				Or(column+" = ? AND applications.cm_application_id "+operator+" ?", cursor.Value, cursor.ID))
+	}
+	query = query.Order(column + " " + order).
+		Order("applications.cm_application_id " + order)
+	if req.Paging.Limit > 0 {
+		query = query.Limit(req.Paging.Limit)
+		if cursor == nil {
+			query = query.Offset(req.Paging.Offset)
+		}
+	}
+
+	err := query.Group(strings.Join([]string{
+		"applications.ht_th_application_id",
+		"applications.created_at",
+		"langs.name",
+		"applications.total_pay_in_tax",
+		"applications.total_pay_ex_tax",
+		"applications.cancel_flg",
+		"applications.cancel_fee",
+		"applications.noshow_flg",
+		"applications.noshow_fee",
+		"applications.canceled_dt",
+		"cm_th_application.payment_limit_dt", /*支払期限日時*/
+		"cm_th_application.total_price",      /*GoTo割引適用後代金*/
+		"applications.itinerary_id",
+		"applications.affiliate_reference_id",
+		"applications.customer_ip",
+		"applications.cm_application_id",
+		"applications.wholesaler_id",
+		"applications.arrival",
+		"applications.departure",
+		"applications.given_name_enc",
+		"applications.family_name_enc",
+		"applications.email_enc",
+		"applications.phone_enc",
+		"applications.memo",
+		"applications.contact_status",
+		"applications.is_misuse"}, ",")).
+		Find(&result).Error
+	return result, err
+}
+
+// CountBookings 検索条件に合致する予約の総件数を取得（hotelリポジトリ参照）
+func (b *bookingRepository) CountBookings(req booking.SearchInput) (int64, error) {
+	var result int64
+	err := b.searchQuery(req).
+		Select("COUNT(DISTINCT applications.ht_th_application_id)").
+		Count(&result).Error
+	return result, err
+}
+
+// searchQuery 予約検索の絞り込み条件を設定したクエリ
+func (b *bookingRepository) searchQuery(req booking.SearchInput) *gorm.DB {
+	targetWholesalers := []int{utils.WholesalerIDTl, utils.WholesalerIDTema, utils.WholesalerIDNeppan, utils.WholesalerIDDirect, utils.WholesalerIDRaku2}
+	query := b.hotelDB.
 		Table("ht_th_applications AS applications").
 		Joins("LEFT JOIN ht_tm_property_langs AS langs ON applications.property_id = langs.property_id").
-		Joins("LEFT JOIN skyticket.cm_th_application ON applications.cm_application_id = cm_th_application.cm_application_id").
-		Joins("LEFT JOIN skyticket.cm_th_organized_tours_application AS tours_application ON applications.cm_application_id = tours_application.cm_application_id").
 		Where("applications.property_id = ?", req.PropertyID).
 		Where("applications.wholesaler_id IN ?", targetWholesalers).
 		Where("langs.lang_cd = ?", "ja-JP")
@@ -102,53 +178,48 @@ func (b *bookingRepository) FetchBookings(req booking.SearchInput) ([]booking.Se
 		query = query.Where("applications.phone_enc = ?", req.PhoneEnc)
 	}
 
-	switch req.Status {
+	// 連絡状況
+	if len(req.ContactStatuses) != 0 {
+		query = query.Where("applications.contact_status IN ?", req.ContactStatuses)
+	}
+	// 不正利用の疑い
+	if req.IsMisuse != nil {
+		isMisuse := 0
+		if *req.IsMisuse {
+			isMisuse = 1
+		}
+		query = query.Where("applications.is_misuse = ?", isMisuse)
+	}
+	// メモ（部分一致）
+	if req.MemoKeyword != "" {
+		query = query.Where("applications.memo LIKE ?", "%"+likeEscaper.Replace(req.MemoKeyword)+"%")
+	}
+
+	if condition, args := statusCondition(req.Status, time.Now().Format("2006-01-02")); condition != "" {
+		query = query.Where(condition, args...)
+	}
+	return query
+}
+
+// likeEscaper LIKE の部分一致で、入力をワイルドカードとして扱わないようエスケープする
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// statusCondition 予約ステータスの絞り込み条件
+// utils.GetBookingStatus と同じく、キャンセル・NoShowフラグとチェックイン・チェックアウト日から判定する
+func statusCondition(status uint8, today string) (string, []interface{}) {
+	switch status {
 	case utils.ReserveStatusReserved:
-		query = query.Where("applications.cancel_flg = ?", 0).
-			Where("applications.departure > ?", time.Now().Format("2006-01-02")).
-			Where("applications.arrival > ?", time.Now().Format("2006-01-02"))
+		return "applications.cancel_flg = 0 AND applications.arrival > ?", []interface{}{today}
 	case utils.ReserveStatusCancel:
-		query = query.Where("applications.cancel_flg = ?", 1).
-			Where("applications.noshow_flg = ?", 0)
+		return "applications.cancel_flg = 1 AND applications.noshow_flg = 0", nil
 	case utils.ReserveStatusNoShow:
-		query = query.Where("applications.cancel_flg = ?", 1).
-			Where("applications.noshow_flg = ?", 1)
+		return "applications.cancel_flg = 1 AND applications.noshow_flg = 1", nil
 	case utils.ReserveStatusStaying:
-		query = query.Where("applications.cancel_flg = ?", 0).
-			Where("applications.departure >= ?", time.Now().Format("2006-01-02")).
-			Where("applications.arrival <= ?", time.Now().Format("2006-01-02"))
+		return "applications.cancel_flg = 0 AND applications.arrival <= ? AND applications.departure >= ?", []interface{}{today, today}
 	case utils.ReserveStatusStayed:
-		query = query.Where("applications.cancel_flg = ?", 0).
-			Where("applications.departure < ?", time.Now().Format("2006-01-02"))
+		return "applications.cancel_flg = 0 AND applications.departure < ?", []interface{}{today}
 	}
-	err := query.Group(strings.Join([]string{
-		"applications.ht_th_application_id",
-		"applications.created_at",
-		"langs.name",
-		"applications.total_pay_in_tax",
-		"applications.total_pay_ex_tax",
-		"applications.cancel_flg",
-		"applications.cancel_fee",
-		"applications.noshow_flg",
-		"applications.noshow_fee",
-		"applications.canceled_dt",
-		"cm_th_application.payment_limit_dt", /*支払期限日時*/
-		"cm_th_application.total_price",      /*GoTo割引適用後代金*/
-		"applications.itinerary_id",
-		"applications.affiliate_reference_id",
-		"applications.customer_ip",
-		"applications.cm_application_id",
-		"applications.wholesaler_id",
-		"applications.arrival",
-		"applications.departure",
-		"applications.given_name_enc",
-		"applications.family_name_enc",
-		"applications.email_enc",
-		"applications.phone_enc",
-		"applications.memo",
-		"applications.is_misuse"}, ",")).
-		Find(&result).Error
-	return result, err
+	return "", nil
 }
 
 // FetchBookingDownloadData 予約詳細情報を複数取得（hotelリポジトリ参照）
@@ -260,30 +331,16 @@ func (b *bookingRepository) FetchRoomListTlsByItineraryID(ItineraryIDs []string)
 	return result, err
 }
 
-// FetchNoShowData 予約IDに基づくNoShowのデータを１件取得
-func (b *bookingRepository) FetchNoShowData(CmApplicationID int64) (booking.HtThApplications, error) {
+// FetchApplication 予約IDに基づく予約データを１件取得
+func (b *bookingRepository) FetchApplication(CmApplicationID int64) (booking.HtThApplications, error) {
 	result := booking.HtThApplications{}
 	err := b.hotelDB.
 		Model(&booking.HtThApplications{}).
 		Where("cm_application_id = ?", CmApplicationID).
-		Where("cancel_flg = 1").
-		Where("arrival <= ?", time.Now().Format("2006-01-02")).
 		First(&result).Error
 	return result, err
 }
 
-// UpdateNoShow ht_th_application_idに基づくデータのNoShowフラグを更新
-func (b *bookingRepository) UpdateNoShow(HtThApplicationID int64, noShowFlg bool, noShowFee float32) error {
-	return b.hotelDB.
-		Model(&booking.HtThApplications{}).
-		Where("ht_th_application_id = ?", HtThApplicationID).
-		Updates(map[string]interface{}{
-			"noshow_fee": noShowFee,
-			"noshow_flg": noShowFlg,
-			"updated_at": time.Now(),
-		}).Error
-}
-
 // FetchFlashSaleData 予約IDに基づくセールデータを取得
 func (b *bookingRepository) FetchFlashSaleData(CmApplicationIDs []int64) ([]booking.CmThFlashSale, error) {
 	result := []booking.CmThFlashSale{}
@@ -294,12 +351,60 @@ func (b *bookingRepository) FetchFlashSaleData(CmApplicationIDs []int64) ([]book
 	return result, err
 }
 
-// FetchBookingPriceData 予約IDに基づく予約料金データを取得
+// FetchBookingPriceData 予約IDに基づく予約料金データを取得（一部キャンセルでキャンセルした部屋・泊は除く）
 func (b *bookingRepository) FetchBookingPriceData(CmApplicationIDs []int64) ([]booking.HtThBookingPrices, error) {
 	result := []booking.HtThBookingPrices{}
 	err := b.hotelDB.
 		Model(&booking.HtThBookingPrices{}).
 		Where("cm_application_id in ?", CmApplicationIDs).
+		Where("cancel_flg = 0").
+		Find(&result).Error
+	return result, err
+}
+
+// FetchUnlinkedApplications 部屋と紐づいていない料金情報がある予約を、予約ID順に取得
+func (b *bookingRepository) FetchUnlinkedApplications(afterCmApplicationID int64, limit int) ([]booking.HtThApplications, error) {
+	result := []booking.HtThApplications{}
+	err := b.hotelDB.
+		Model(&booking.HtThApplications{}).
+		Where("cm_application_id > ?", afterCmApplicationID).
+		Where("EXISTS (SELECT 1 FROM ht_th_booking_prices AS prices WHERE prices.cm_application_id = ht_th_applications.cm_application_id AND prices.ht_th_booking_room_id = 0 AND prices.cancel_flg = 0)").
+		Order("cm_application_id").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
+
+// LinkBookingPrices 料金情報を部屋と紐づける（料金情報ID→部屋ID）
+func (b *bookingRepository) LinkBookingPrices(links map[int64]int64) error {
+	return b.hotelDB.Transaction(func(tx *gorm.DB) error {
+		for priceID, roomID := range links {
+			err := tx.
+				Model(&booking.HtThBookingPrices{}).
+				Where("ht_th_booking_price_id = ?", priceID).
+				Where("ht_th_booking_room_id = 0").
+				Update("ht_th_booking_room_id", roomID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FetchStayingApplications 指定日にチェックイン・滞在・チェックアウトするキャンセルされていない予約を取得
+func (b *bookingRepository) FetchStayingApplications(propertyID int64, date string) ([]booking.HtThApplications, error) {
+	targetWholesalers := []int{utils.WholesalerIDTl, utils.WholesalerIDTema, utils.WholesalerIDNeppan, utils.WholesalerIDDirect, utils.WholesalerIDRaku2}
+	result := []booking.HtThApplications{}
+	err := b.hotelDB.
+		Model(&booking.HtThApplications{}).
+		Where("property_id = ?", propertyID).
+		Where("wholesaler_id IN ?", targetWholesalers).
+		Where("cancel_flg = 0").
+		Where("DATE(arrival) <= ?", date).
+		Where("DATE(departure) >= ?", date).
+		Order("arrival").
+		Order("cm_application_id").
 		Find(&result).Error
 	return result, err
 }
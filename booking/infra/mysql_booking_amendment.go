@@ -0,0 +1,165 @@
+package infra
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"gorm.io/gorm"
+)
+
+// bookingAmendmentRepository 予約変更関連repository
+type bookingAmendmentRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingAmendmentRepository インスタンス生成
+func NewBookingAmendmentRepository(db *gorm.DB) booking.IBookingAmendmentRepository {
+	return &bookingAmendmentRepository{
+		db: db,
+	}
+}
+
+// ApplyAmendment 料金情報の入れ替え・部屋と予約情報の更新・在庫の移動・履歴の登録をまとめて行う
+func (b *bookingAmendmentRepository) ApplyAmendment(apply *booking.AmendmentApply) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 先に予約情報を更新して行をロックし、同じ予約への変更・一部キャンセルと順番に処理する
+		apply.Application["updated_at"] = now
+		updated := tx.
+			Model(&booking.HtThApplications{}).
+			Where("cm_application_id = ?", apply.Amendment.CmApplicationID).
+			Where("cancel_flg = 0").
+			Updates(apply.Application)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		if updated.RowsAffected == 0 {
+			return booking.ErrAlreadyCanceled
+		}
+		// 見積もり後に一部キャンセルされた場合
+		var partialCancels int64
+		if err := tx.
+			Model(&booking.HtThBookingPartialCancels{}).
+			Where("cm_application_id = ?", apply.Amendment.CmApplicationID).
+			Count(&partialCancels).Error; err != nil {
+			return err
+		}
+		if partialCancels > 0 {
+			return booking.ErrAmendPartialCanceled
+		}
+		deleted := tx.
+			Where("ht_th_booking_price_id IN ?", apply.OldPriceIDs).
+			Delete(&booking.HtThBookingPrices{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		// 見積もり後に料金情報が変わった場合
+		if deleted.RowsAffected != int64(len(apply.OldPriceIDs)) {
+			return booking.ErrAmendConflict
+		}
+		for i := range apply.Prices {
+			apply.Prices[i].CreatedAt = now
+			apply.Prices[i].UpdatedAt = now
+		}
+		if err := tx.Create(&apply.Prices).Error; err != nil {
+			return err
+		}
+		for _, room := range apply.Rooms {
+			if err := tx.
+				Model(&booking.HtThBookingRooms{}).
+				Where("ht_th_booking_room_id = ?", room.HtThBookingRoomID).
+				Updates(map[string]interface{}{
+					"number_of_adults":                       room.NumberOfAdults,
+					"number_of_childs":                       room.NumberOfChilds,
+					"number_of_upper_grades":                 room.NumberOfUpperGrades,
+					"number_of_lower_grades":                 room.NumberOfLowerGrades,
+					"number_of_infant_meals_with_bedding":    room.NumberOfInfantMealsWithBedding,
+					"number_of_infant_meal_only":             room.NumberOfInfantMealOnly,
+					"number_of_infant_bedding_only":          room.NumberOfInfantBeddingOnly,
+					"number_of_infant_meals_without_bedding": room.NumberOfInfantMealsWithoutBedding,
+					"room_name":                              room.RoomName,
+					"plan_name":                              room.PlanName,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		if err := moveDirectStocks(tx, apply.Stocks, now); err != nil {
+			return err
+		}
+
+		var version int
+		if err := tx.
+			Model(&booking.HtThBookingAmendments{}).
+			Select("COALESCE(MAX(version), 0)").
+			Where("cm_application_id = ?", apply.Amendment.CmApplicationID).
+			Scan(&version).Error; err != nil {
+			return err
+		}
+		apply.Amendment.Version = version + 1
+		apply.Amendment.CreatedAt = now
+		apply.Amendment.UpdatedAt = now
+		return tx.Create(apply.Amendment).Error
+	})
+}
+
+// moveDirectStocks 直仕入れの在庫を増減する
+// 予約数を増やす日は在庫が足りる場合のみ更新し、デッドロックを避けるため部屋タイプ・日付順に更新する
+func moveDirectStocks(tx *gorm.DB, changes []booking.StockChange, now time.Time) error {
+	sorted := append([]booking.StockChange{}, changes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].RoomTypeID != sorted[j].RoomTypeID {
+			return sorted[i].RoomTypeID < sorted[j].RoomTypeID
+		}
+		return sorted[i].UseDate < sorted[j].UseDate
+	})
+	for _, change := range sorted {
+		if change.Delta == 0 {
+			continue
+		}
+		query := tx.
+			Table("ht_tm_stock_directs").
+			Where("room_type_id = ?", change.RoomTypeID).
+			Where("use_date = ?", change.UseDate)
+		if change.Delta > 0 {
+			query = query.Where("stock >= ?", change.Delta)
+		} else {
+			query = query.Where("booking_count >= ?", -change.Delta)
+		}
+		updated := query.Updates(map[string]interface{}{
+			"booking_count": gorm.Expr("booking_count + ?", change.Delta),
+			"stock":         gorm.Expr("stock - ?", change.Delta),
+			"updated_at":    now,
+		})
+		if updated.Error != nil {
+			return updated.Error
+		}
+		if change.Delta > 0 && updated.RowsAffected == 0 {
+			return booking.ErrBookingStock
+		}
+	}
+	return nil
+}
+
+// UpdateAmendmentNotification 宿泊者への通知結果を更新
+func (b *bookingAmendmentRepository) UpdateAmendmentNotification(bookingAmendmentID int64, notifiedAt time.Time, notifyError string) error {
+	return b.db.
+		Model(&booking.HtThBookingAmendments{}).
+		Where("booking_amendment_id = ?", bookingAmendmentID).
+		Updates(map[string]interface{}{
+			"notified_at":  notifiedAt,
+			"notify_error": notifyError,
+			"updated_at":   time.Now(),
+		}).Error
+}
+
+// FetchAmendments 予約IDに基づく予約変更の履歴を取得
+func (b *bookingAmendmentRepository) FetchAmendments(cmApplicationID int64) ([]booking.HtThBookingAmendments, error) {
+	result := []booking.HtThBookingAmendments{}
+	err := b.db.
+		Model(&booking.HtThBookingAmendments{}).
+		Where("cm_application_id = ?", cmApplicationID).
+		Order("version").
+		Find(&result).Error
+	return result, err
+}
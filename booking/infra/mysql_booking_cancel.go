@@ -0,0 +1,101 @@
+package infra
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"gorm.io/gorm"
+)
+
+// bookingCancelRepository adminへのキャンセル依頼関連repository
+type bookingCancelRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingCancelRepository インスタンス生成
+func NewBookingCancelRepository(db *gorm.DB) booking.IBookingCancelRepository {
+	return &bookingCancelRepository{
+		db: db,
+	}
+}
+
+// CreateCancelRequest キャンセル依頼を登録
+func (b *bookingCancelRepository) CreateCancelRequest(cancelRequest *booking.HtThBookingCancelRequests) error {
+	return b.db.Create(cancelRequest).Error
+}
+
+// FetchActiveCancelRequest 予約IDに基づく送信待ち・送信中の予約全体のキャンセル依頼を取得（ない場合はnil）
+func (b *bookingCancelRepository) FetchActiveCancelRequest(cmApplicationID int64) (*booking.HtThBookingCancelRequests, error) {
+	return b.fetchCancelRequest(b.db.
+		Where("cm_application_id = ?", cmApplicationID).
+		Where("booking_partial_cancel_id = 0").
+		Where("status IN ?", []string{booking.CancelStatusPending, booking.CancelStatusProcessing}))
+}
+
+// FetchLatestCancelRequest 予約IDに基づく最新の予約全体のキャンセル依頼を取得（ない場合はnil）
+func (b *bookingCancelRepository) FetchLatestCancelRequest(cmApplicationID int64) (*booking.HtThBookingCancelRequests, error) {
+	return b.fetchCancelRequest(b.db.
+		Where("cm_application_id = ?", cmApplicationID).
+		Where("booking_partial_cancel_id = 0").
+		Order("booking_cancel_request_id DESC"))
+}
+
+// FetchDueCancelRequests 送信時刻を過ぎた送信待ち・送信中のキャンセル依頼を取得（includePartialがfalseの場合は予約全体のキャンセルのみ）
+// 送信中のものは、送信中のまま処理が止まって確保期限が切れたもの
+func (b *bookingCancelRepository) FetchDueCancelRequests(now time.Time, limit int, includePartial bool) ([]booking.HtThBookingCancelRequests, error) {
+	result := []booking.HtThBookingCancelRequests{}
+	query := b.db.
+		Model(&booking.HtThBookingCancelRequests{}).
+		Where("status IN ?", []string{booking.CancelStatusPending, booking.CancelStatusProcessing}).
+		Where("next_attempt_at <= ?", now)
+	if !includePartial {
+		query = query.Where("booking_partial_cancel_id = 0")
+	}
+	err := query.
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
+
+// ClaimCancelRequest 送信するキャンセル依頼を確保（他の処理が確保済みの場合はfalse）
+// 送信回数もここで数えるため、送信中に処理が止まった場合も1回として扱う
+func (b *bookingCancelRepository) ClaimCancelRequest(bookingCancelRequestID int64, now time.Time, leaseUntil time.Time) (bool, error) {
+	result := b.db.
+		Model(&booking.HtThBookingCancelRequests{}).
+		Where("booking_cancel_request_id = ?", bookingCancelRequestID).
+		Where("status IN ?", []string{booking.CancelStatusPending, booking.CancelStatusProcessing}).
+		Where("next_attempt_at <= ?", now).
+		Updates(map[string]interface{}{
+			"status":          booking.CancelStatusProcessing,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+			"updated_at":      now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateCancelRequest キャンセル依頼の状況を更新
+func (b *bookingCancelRepository) UpdateCancelRequest(bookingCancelRequestID int64, values map[string]interface{}) error {
+	values["updated_at"] = time.Now()
+	return b.db.
+		Model(&booking.HtThBookingCancelRequests{}).
+		Where("booking_cancel_request_id = ?", bookingCancelRequestID).
+		Updates(values).Error
+}
+
+// fetchCancelRequest 条件に合うキャンセル依頼を1件取得（ない場合はnil）
+func (b *bookingCancelRepository) fetchCancelRequest(query *gorm.DB) (*booking.HtThBookingCancelRequests, error) {
+	result := &booking.HtThBookingCancelRequests{}
+	err := query.
+		Model(&booking.HtThBookingCancelRequests{}).
+		First(result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"gorm.io/gorm"
+)
+
+// bookingContactRepository 予約の連絡状況・メモ・不正利用の疑い関連repository
+type bookingContactRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingContactRepository インスタンス生成
+func NewBookingContactRepository(db *gorm.DB) booking.IBookingContactRepository {
+	return &bookingContactRepository{
+		db: db,
+	}
+}
+
+// UpdateContact 予約情報の更新と変更履歴の登録をまとめて行う
+func (b *bookingContactRepository) UpdateContact(cmApplicationID int64, values map[string]interface{}, history *booking.HtThBookingContactHistories) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		values["updated_at"] = now
+		if err := tx.
+			Model(&booking.HtThApplications{}).
+			Where("cm_application_id = ?", cmApplicationID).
+			Updates(values).Error; err != nil {
+			return err
+		}
+		history.CreatedAt = now
+		return tx.Create(history).Error
+	})
+}
+
+// AppendMemo 予約情報のメモの末尾に1行追記し、変更履歴を登録する
+// 同時に追記した場合も消えないよう、現在のメモにDB上で連結する
+func (b *bookingContactRepository) AppendMemo(cmApplicationID int64, line string, operatorID int64, history *booking.HtThBookingContactHistories) error {
+	return b.UpdateContact(cmApplicationID, map[string]interface{}{
+		"memo":               gorm.Expr("IF(memo IS NULL OR memo = '', ?, CONCAT(memo, '\\n', ?))", line, line),
+		"update_operator_id": operatorID,
+	}, history)
+}
+
+// FetchContactHistories 予約IDに基づく変更履歴を新しい順に取得
+func (b *bookingContactRepository) FetchContactHistories(cmApplicationID int64) ([]booking.HtThBookingContactHistories, error) {
+	result := []booking.HtThBookingContactHistories{}
+	err := b.db.
+		Model(&booking.HtThBookingContactHistories{}).
+		Where("cm_application_id = ?", cmApplicationID).
+		Order("booking_contact_history_id DESC").
+		Find(&result).Error
+	return result, err
+}
@@ -0,0 +1,44 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"gorm.io/gorm"
+)
+
+// bookingExportRepository 予約一覧ファイル出力関連repository
+type bookingExportRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingExportRepository インスタンス生成
+func NewBookingExportRepository(db *gorm.DB) booking.IBookingExportRepository {
+	return &bookingExportRepository{
+		db: db,
+	}
+}
+
+// CreateBookingExport ファイル作成を登録
+func (b *bookingExportRepository) CreateBookingExport(bookingExport *booking.HtThBookingExports) error {
+	return b.db.Create(bookingExport).Error
+}
+
+// UpdateBookingExport ファイル作成状況を更新
+func (b *bookingExportRepository) UpdateBookingExport(bookingExportID int64, values map[string]interface{}) error {
+	values["updated_at"] = time.Now()
+	return b.db.
+		Model(&booking.HtThBookingExports{}).
+		Where("booking_export_id = ?", bookingExportID).
+		Updates(values).Error
+}
+
+// FetchBookingExport ファイル作成状況を1件取得
+func (b *bookingExportRepository) FetchBookingExport(bookingExportID int64) (*booking.HtThBookingExports, error) {
+	result := &booking.HtThBookingExports{}
+	err := b.db.
+		Model(&booking.HtThBookingExports{}).
+		Where("booking_export_id = ?", bookingExportID).
+		First(result).Error
+	return result, err
+}
@@ -0,0 +1,145 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bookingManualRepository 手入力の予約関連repository
+type bookingManualRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingManualRepository インスタンス生成
+func NewBookingManualRepository(db *gorm.DB) booking.IBookingManualRepository {
+	return &bookingManualRepository{
+		db: db,
+	}
+}
+
+// CreateManualBooking 予約IDの採番・予約情報と部屋・料金情報の登録・在庫の引当をまとめて行う
+func (b *bookingManualRepository) CreateManualBooking(create *booking.ManualBookingCreate) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := moveDirectStocks(tx, create.Stocks, now); err != nil {
+			return err
+		}
+		cmApplicationID, err := nextManualApplicationID(tx)
+		if err != nil {
+			return err
+		}
+
+		application := create.Application
+		application.CmApplicationID = cmApplicationID
+		application.CreatedAt = now
+		application.UpdatedAt = now
+		// 未設定の日時は登録しない（ゼロ値の日時は登録できないため）
+		if err := tx.
+			Omit("holded", "canceled_dt", "complete_property_arrives").
+			Create(application).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Model(&booking.HtThApplications{}).
+			Select("ht_th_application_id").
+			Where("cm_application_id = ?", cmApplicationID).
+			Scan(&application.HtThApplicationID).Error; err != nil {
+			return err
+		}
+
+		for i := range create.Rooms {
+			create.Rooms[i].HtThApplicationID = application.HtThApplicationID
+		}
+		if err := tx.Create(&create.Rooms).Error; err != nil {
+			return err
+		}
+		// 登録した順に部屋IDを振られるため、部屋ID順に取得して料金情報と紐づける
+		roomIDs := []int64{}
+		if err := tx.
+			Model(&booking.HtThBookingRooms{}).
+			Where("ht_th_application_id = ?", application.HtThApplicationID).
+			Order("ht_th_booking_room_id").
+			Pluck("ht_th_booking_room_id", &roomIDs).Error; err != nil {
+			return err
+		}
+		for i := range create.Rooms {
+			create.Rooms[i].HtThBookingRoomID = roomIDs[i]
+		}
+
+		for i := range create.Prices {
+			create.Prices[i].CmApplicationID = cmApplicationID
+			create.Prices[i].HtThBookingRoomID = roomIDs[create.PriceRoomIndexes[i]]
+			create.Prices[i].CreatedAt = now
+			create.Prices[i].UpdatedAt = now
+		}
+		return tx.Create(&create.Prices).Error
+	})
+}
+
+// CancelManualBooking 予約情報のキャンセル・在庫の戻し・キャンセルの履歴の登録をまとめて行う
+// 施設への通知は、キャンセル日時からScanBookingEventsで登録される
+func (b *bookingManualRepository) CancelManualBooking(cancel *booking.ManualBookingCancel) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		values := map[string]interface{}{
+			"cancel_flg":         true,
+			"cancel_fee":         cancel.CancelFee,
+			"canceled_dt":        now,
+			"update_operator_id": cancel.UpdateOperatorID,
+			"updated_at":         now,
+		}
+		if cancel.Noshow == 1 {
+			values["noshow_flg"] = true
+			values["noshow_fee"] = cancel.CancelFee
+		}
+		updated := tx.
+			Model(&booking.HtThApplications{}).
+			Where("cm_application_id = ?", cancel.CmApplicationID).
+			Where("cancel_flg = 0").
+			Updates(values)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		// 処理中に同じ予約がキャンセルされた場合
+		if updated.RowsAffected == 0 {
+			return booking.ErrAlreadyCanceled
+		}
+		if err := moveDirectStocks(tx, cancel.Stocks, now); err != nil {
+			return err
+		}
+		cancel.CancelRequest.NextAttemptAt = now
+		cancel.CancelRequest.CompletedAt = now
+		cancel.CancelRequest.CreatedAt = now
+		cancel.CancelRequest.UpdatedAt = now
+		return tx.Create(cancel.CancelRequest).Error
+	})
+}
+
+// nextManualApplicationID 手入力の予約の予約IDを採番
+// 採番テーブルの値が予約用の範囲より小さい場合は範囲の最小値から採番し、範囲を超えた場合はエラーにする
+func nextManualApplicationID(tx *gorm.DB) (int64, error) {
+	var result booking.HtTmManualApplicationIDs
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Table("ht_tm_manual_application_ids").
+		Select("cm_application_id").
+		First(&result).Error; err != nil {
+		return 0, err
+	}
+	cmApplicationID := result.CmApplicationID
+	if cmApplicationID < booking.ManualApplicationIDMin {
+		cmApplicationID = booking.ManualApplicationIDMin
+	}
+	if !booking.IsManualApplicationID(cmApplicationID) {
+		return 0, booking.ErrManualApplicationIDRange
+	}
+	// 次の採番用にインクリメントしておく
+	if err := tx.
+		Exec("UPDATE ht_tm_manual_application_ids SET cm_application_id = ?", cmApplicationID+1).Error; err != nil {
+		return 0, err
+	}
+	return cmApplicationID, nil
+}
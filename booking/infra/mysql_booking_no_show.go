@@ -0,0 +1,85 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bookingNoShowRepository 予約のNoShow関連repository
+type bookingNoShowRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingNoShowRepository インスタンス生成
+func NewBookingNoShowRepository(db *gorm.DB) booking.IBookingNoShowRepository {
+	return &bookingNoShowRepository{
+		db: db,
+	}
+}
+
+// FetchArrivals 施設の指定日に到着する予約（キャンセル済みを含む）を取得
+func (b *bookingNoShowRepository) FetchArrivals(propertyID int64, date string) ([]booking.HtThApplications, error) {
+	targetWholesalers := []int{utils.WholesalerIDTl, utils.WholesalerIDTema, utils.WholesalerIDNeppan, utils.WholesalerIDDirect, utils.WholesalerIDRaku2}
+	result := []booking.HtThApplications{}
+	err := b.db.
+		Model(&booking.HtThApplications{}).
+		Where("property_id = ?", propertyID).
+		Where("wholesaler_id IN ?", targetWholesalers).
+		Where("DATE(arrival) = ?", date).
+		Order("cm_application_id").
+		Find(&result).Error
+	return result, err
+}
+
+// ApplyNoShow キャンセル済みの予約のNoShowフラグ・料金の更新と履歴・施設への通知の登録をまとめて行う
+// 通知は登録した履歴のIDを元の履歴として登録する（同じ履歴の通知は無視する）
+func (b *bookingNoShowRepository) ApplyNoShow(history *booking.HtThBookingNoShowHistories, notifications []booking.HtThBookingNotifications) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		updated := tx.
+			Model(&booking.HtThApplications{}).
+			Where("cm_application_id = ?", history.CmApplicationID).
+			Where("cancel_flg = 1").
+			Where("noshow_flg = ?", !history.NoshowFlg).
+			Updates(map[string]interface{}{
+				"noshow_flg": history.NoshowFlg,
+				"noshow_fee": history.NoshowFeeAfter,
+				"updated_at": now,
+			})
+		if updated.Error != nil {
+			return updated.Error
+		}
+		// 処理中に同じ予約のNoShowが登録・取り消しされた場合
+		if updated.RowsAffected == 0 {
+			return booking.ErrNoShowUnchanged
+		}
+		history.CreatedAt = now
+		if err := tx.Create(history).Error; err != nil {
+			return err
+		}
+		if len(notifications) == 0 {
+			return nil
+		}
+		for i := range notifications {
+			notifications[i].EventSourceID = history.BookingNoShowHistoryID
+		}
+		return tx.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&notifications).Error
+	})
+}
+
+// FetchNoShowHistories 予約IDに基づくNoShowの登録・取り消しの履歴を取得
+func (b *bookingNoShowRepository) FetchNoShowHistories(cmApplicationID int64) ([]booking.HtThBookingNoShowHistories, error) {
+	result := []booking.HtThBookingNoShowHistories{}
+	err := b.db.
+		Model(&booking.HtThBookingNoShowHistories{}).
+		Where("cm_application_id = ?", cmApplicationID).
+		Order("booking_no_show_history_id").
+		Find(&result).Error
+	return result, err
+}
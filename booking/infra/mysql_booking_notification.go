@@ -0,0 +1,194 @@
+package infra
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// notificationCursorID 確認位置の行のID
+const notificationCursorID = 1
+
+// bookingNotificationRepository 予約通知関連repository
+type bookingNotificationRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingNotificationRepository インスタンス生成
+func NewBookingNotificationRepository(db *gorm.DB) booking.IBookingNotificationRepository {
+	return &bookingNotificationRepository{
+		db: db,
+	}
+}
+
+// FetchNotificationSetting 施設の予約通知の設定を取得（ない場合はnil）
+func (b *bookingNotificationRepository) FetchNotificationSetting(propertyID int64) (*booking.HtTmBookingNotificationSettings, error) {
+	result := &booking.HtTmBookingNotificationSettings{}
+	err := b.db.
+		Model(&booking.HtTmBookingNotificationSettings{}).
+		Where("property_id = ?", propertyID).
+		First(result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SaveNotificationSetting 施設の予約通知の設定を登録・更新
+func (b *bookingNotificationRepository) SaveNotificationSetting(setting *booking.HtTmBookingNotificationSettings) error {
+	return b.db.
+		Clauses(clause.OnConflict{
+			UpdateAll: true,
+		}).
+		Omit("created_at").
+		Create(setting).Error
+}
+
+// EnqueueNotifications 通知を送信待ちとして登録（登録済みの通知は無視する）
+// 同じ予約・種類・送信先の通知はユニークキーで1件に制限している
+func (b *bookingNotificationRepository) EnqueueNotifications(notifications []booking.HtThBookingNotifications) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return b.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&notifications).Error
+}
+
+// FetchDueNotifications 送信時刻を過ぎた送信待ち・送信中の通知を取得
+// 送信中のものは、送信中のまま処理が止まって確保期限が切れたもの
+func (b *bookingNotificationRepository) FetchDueNotifications(now time.Time, limit int) ([]booking.HtThBookingNotifications, error) {
+	result := []booking.HtThBookingNotifications{}
+	err := b.db.
+		Model(&booking.HtThBookingNotifications{}).
+		Where("status IN ?", []string{booking.NotificationStatusPending, booking.NotificationStatusProcessing}).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
+
+// ClaimNotification 送信する通知を確保（他の処理が確保済みの場合はfalse）
+// 送信回数もここで数えるため、送信中に処理が止まった場合も1回として扱う
+func (b *bookingNotificationRepository) ClaimNotification(bookingNotificationID int64, now time.Time, leaseUntil time.Time) (bool, error) {
+	result := b.db.
+		Model(&booking.HtThBookingNotifications{}).
+		Where("booking_notification_id = ?", bookingNotificationID).
+		Where("status IN ?", []string{booking.NotificationStatusPending, booking.NotificationStatusProcessing}).
+		Where("next_attempt_at <= ?", now).
+		Updates(map[string]interface{}{
+			"status":          booking.NotificationStatusProcessing,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+			"updated_at":      now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateNotification 通知の送信状況を更新
+func (b *bookingNotificationRepository) UpdateNotification(bookingNotificationID int64, values map[string]interface{}) error {
+	values["updated_at"] = time.Now()
+	return b.db.
+		Model(&booking.HtThBookingNotifications{}).
+		Where("booking_notification_id = ?", bookingNotificationID).
+		Updates(values).Error
+}
+
+// FetchNotifications 施設の通知の送信履歴を新しい順に取得
+func (b *bookingNotificationRepository) FetchNotifications(propertyID int64, cmApplicationID int64, limit int) ([]booking.HtThBookingNotifications, error) {
+	result := []booking.HtThBookingNotifications{}
+	query := b.db.
+		Model(&booking.HtThBookingNotifications{}).
+		Where("property_id = ?", propertyID)
+	if cmApplicationID != 0 {
+		query = query.Where("cm_application_id = ?", cmApplicationID)
+	}
+	err := query.
+		Order("booking_notification_id DESC").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
+
+// FetchNotificationCursor 新しい予約・キャンセルの確認位置を取得（ない場合はnil）
+func (b *bookingNotificationRepository) FetchNotificationCursor() (*booking.HtThBookingNotificationCursors, error) {
+	result := &booking.HtThBookingNotificationCursors{}
+	err := b.db.
+		Model(&booking.HtThBookingNotificationCursors{}).
+		Where("cursor_id = ?", notificationCursorID).
+		First(result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ClaimNotificationCursor 新しい予約・キャンセルの確認を確保（他の処理が確認中の場合はfalse）
+// 確認位置の保存で解放する。確認中に処理が止まった場合は、leaseUntilを過ぎると他の処理が確保できる
+func (b *bookingNotificationRepository) ClaimNotificationCursor(now time.Time, leaseUntil time.Time) (bool, error) {
+	result := b.db.
+		Model(&booking.HtThBookingNotificationCursors{}).
+		Where("cursor_id = ?", notificationCursorID).
+		Where("scan_locked_until <= ?", now).
+		Updates(map[string]interface{}{
+			"scan_locked_until": leaseUntil,
+			"updated_at":        now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// SaveNotificationCursor 新しい予約・キャンセルの確認位置を保存
+func (b *bookingNotificationRepository) SaveNotificationCursor(cursor *booking.HtThBookingNotificationCursors) error {
+	cursor.CursorID = notificationCursorID
+	return b.db.
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"last_application_id", "last_canceled_at", "last_canceled_application_id", "scan_locked_until", "updated_at"}),
+		}).
+		Create(cursor).Error
+}
+
+// FetchLastApplicationID 最新の予約のht_th_application_idを取得
+func (b *bookingNotificationRepository) FetchLastApplicationID() (int64, error) {
+	var result int64
+	err := b.db.
+		Model(&booking.HtThApplications{}).
+		Select("COALESCE(MAX(ht_th_application_id), 0)").
+		Scan(&result).Error
+	return result, err
+}
+
+// FetchApplicationsAfter ht_th_application_idが指定より大きい予約を取得
+func (b *bookingNotificationRepository) FetchApplicationsAfter(htThApplicationID int64, limit int) ([]booking.HtThApplications, error) {
+	result := []booking.HtThApplications{}
+	err := b.db.
+		Model(&booking.HtThApplications{}).
+		Where("ht_th_application_id > ?", htThApplicationID).
+		Order("ht_th_application_id").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
+
+// FetchCanceledApplicationsAfter キャンセル日時・ht_th_application_idの組が指定より後の予約を取得
+// 同じ日時にキャンセルされた予約は、ht_th_application_idの順に続きから取得する
+func (b *bookingNotificationRepository) FetchCanceledApplicationsAfter(canceledAt time.Time, htThApplicationID int64, limit int) ([]booking.HtThApplications, error) {
+	result := []booking.HtThApplications{}
+	err := b.db.
+		Model(&booking.HtThApplications{}).
+		Where("cancel_flg = 1").
+		Where("canceled_dt > ? OR (canceled_dt = ? AND ht_th_application_id > ?)", canceledAt, canceledAt, htThApplicationID).
+		Order("canceled_dt, ht_th_application_id").
+		Limit(limit).
+		Find(&result).Error
+	return result, err
+}
@@ -0,0 +1,115 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"gorm.io/gorm"
+)
+
+// stockTables 予約数・在庫数を持つホールセラーごとの在庫テーブル（手間いらずは在庫を連携先で管理しているため含まない）
+var stockTables = map[int64]string{
+	utils.WholesalerIDTl:     "ht_tm_stock_tls",
+	utils.WholesalerIDNeppan: "ht_tm_stock_neppans",
+	utils.WholesalerIDDirect: "ht_tm_stock_directs",
+	utils.WholesalerIDRaku2:  "ht_tm_stock_raku2s",
+}
+
+// bookingPartialCancelRepository 予約の一部キャンセル関連repository
+type bookingPartialCancelRepository struct {
+	db *gorm.DB
+}
+
+// NewBookingPartialCancelRepository インスタンス生成
+func NewBookingPartialCancelRepository(db *gorm.DB) booking.IBookingPartialCancelRepository {
+	return &bookingPartialCancelRepository{
+		db: db,
+	}
+}
+
+// ApplyPartialCancel 料金情報のキャンセル・予約情報の更新・部屋との紐づけ・在庫の戻し・履歴とadminへの依頼の登録をまとめて行う
+// 料金情報は売上の集計に残すため削除せず、キャンセル済みにする
+func (b *bookingPartialCancelRepository) ApplyPartialCancel(apply *booking.PartialCancelApply) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		canceled := tx.
+			Model(&booking.HtThBookingPrices{}).
+			Where("ht_th_booking_price_id IN ?", apply.PriceIDs).
+			Where("cancel_flg = 0").
+			Updates(map[string]interface{}{
+				"cancel_flg":  true,
+				"canceled_dt": now,
+				"updated_at":  now,
+			})
+		if canceled.Error != nil {
+			return canceled.Error
+		}
+		// 処理中に同じ部屋・泊がキャンセルされた場合
+		if canceled.RowsAffected != int64(len(apply.PriceIDs)) {
+			return booking.ErrPartialCancelTarget
+		}
+		apply.Application["updated_at"] = now
+		updated := tx.
+			Model(&booking.HtThApplications{}).
+			Where("cm_application_id = ?", apply.History.CmApplicationID).
+			Where("cancel_flg = 0").
+			Updates(apply.Application)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		// 処理中に予約全体がキャンセルされた場合
+		if updated.RowsAffected == 0 {
+			return booking.ErrAlreadyCanceled
+		}
+		for priceID, roomID := range apply.Links {
+			if err := tx.
+				Model(&booking.HtThBookingPrices{}).
+				Where("ht_th_booking_price_id = ?", priceID).
+				Where("ht_th_booking_room_id = 0").
+				Update("ht_th_booking_room_id", roomID).Error; err != nil {
+				return err
+			}
+		}
+		if table, ok := stockTables[apply.WholesalerID]; ok {
+			for _, cell := range apply.Stocks {
+				if err := tx.
+					Table(table).
+					Where("room_type_id = ?", cell.RoomTypeID).
+					Where("use_date = ?", cell.UseDate).
+					Where("booking_count > 0").
+					Updates(map[string]interface{}{
+						"booking_count": gorm.Expr("booking_count - 1"),
+						"stock":         gorm.Expr("stock + 1"),
+						"updated_at":    now,
+					}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		apply.History.CreatedAt = now
+		apply.History.UpdatedAt = now
+		if err := tx.Create(apply.History).Error; err != nil {
+			return err
+		}
+		apply.CancelRequest.BookingPartialCancelID = apply.History.BookingPartialCancelID
+		apply.CancelRequest.NextAttemptAt = now
+		if apply.CancelRequest.Status == booking.CancelStatusCompleted {
+			apply.CancelRequest.CompletedAt = now
+		}
+		apply.CancelRequest.CreatedAt = now
+		apply.CancelRequest.UpdatedAt = now
+		return tx.Create(apply.CancelRequest).Error
+	})
+}
+
+// FetchPartialCancels 予約IDに基づく一部キャンセルの履歴を取得
+func (b *bookingPartialCancelRepository) FetchPartialCancels(cmApplicationID int64) ([]booking.HtThBookingPartialCancels, error) {
+	result := []booking.HtThBookingPartialCancels{}
+	err := b.db.
+		Model(&booking.HtThBookingPartialCancels{}).
+		Where("cm_application_id = ?", cmApplicationID).
+		Order("booking_partial_cancel_id").
+		Find(&result).Error
+	return result, err
+}
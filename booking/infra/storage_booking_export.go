@@ -0,0 +1,49 @@
+package infra
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+// bookingExportStorage 予約一覧ファイル関連storage
+type bookingExportStorage struct{}
+
+// NewBookingExportStorage インスタンス生成
+func NewBookingExportStorage() booking.IBookingExportStorage {
+	return &bookingExportStorage{}
+}
+
+// NewWriter GCSへのファイルの書き込みを開始（Closeで保存が完了する）
+func (b *bookingExportStorage) NewWriter(bucketName string, objectPath string) (io.WriteCloser, error) {
+	ctx := context.Background()
+	client, err := b.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.Bucket(bucketName).Object(objectPath).NewWriter(ctx), nil
+}
+
+// NewReader GCSのファイルの読み込みを開始
+func (b *bookingExportStorage) NewReader(bucketName string, objectPath string) (io.ReadCloser, error) {
+	ctx := context.Background()
+	client, err := b.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.Bucket(bucketName).Object(objectPath).NewReader(ctx)
+}
+
+// client GCSのclient生成
+func (b *bookingExportStorage) client(ctx context.Context) (*storage.Client, error) {
+	cfg, err := google.JWTConfigFromJSON([]byte(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS_JSON")), storage.ScopeReadWrite)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewClient(ctx, option.WithTokenSource(cfg.TokenSource(ctx)))
+}
@@ -0,0 +1,160 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// webhookTimeout 施設のWebhookの応答待ち時間
+const webhookTimeout = 10 * time.Second
+
+// webhookBlockedNetworks 施設のWebhookに使えない私用・共有アドレスの範囲
+var webhookBlockedNetworks = parseCIDRs(
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"fc00::/7",
+)
+
+// bookingWebhook 施設のWebhookクライアント
+type bookingWebhook struct {
+	client *http.Client
+}
+
+// NewBookingWebhook インスタンス生成
+func NewBookingWebhook() booking.IBookingWebhook {
+	return &bookingWebhook{
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// 接続先のアドレスは名前解決した結果で確認する（プロキシ経由にはしない）
+			Transport: &http.Transport{
+				DialContext:         dialWebhook,
+				TLSHandshakeTimeout: webhookTimeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// 施設が登録したURL以外には送信しない
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Post 署名を付けて送信
+// 署名は連携APIと同じ方式（utils.RequestSignature）で、タイムスタンプ・ノンス・署名をヘッダーで渡す
+// 4xx（408・429を除く）は再送しても結果が変わらないため booking.ErrWebhookRejected を返す
+func (w *bookingWebhook) Post(webhookURL string, secret string, event string, idempotencyKey string, body []byte) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("%w %s", booking.ErrWebhookRejected, err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(booking.HeaderNotificationEvent, event)
+	req.Header.Set(HeaderIdempotencyKey, idempotencyKey)
+	req.Header.Set(credential.HeaderTimestamp, timestamp)
+	req.Header.Set(credential.HeaderNonce, nonce)
+	req.Header.Set(credential.HeaderSignature, utils.RequestSignature(secret, http.MethodPost, parsed.EscapedPath(), timestamp, nonce, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("Error: %s %d", "施設のWebhookでエラーが発生しました。", resp.StatusCode)
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		return fmt.Errorf("%w %d", booking.ErrWebhookRejected, resp.StatusCode)
+	}
+	return nil
+}
+
+// dialWebhook 名前解決したアドレスを確認してから接続する
+// 確認後に名前解決し直すと別のアドレスに接続できてしまうため（DNSリバインディング）、確認したアドレスに直接接続する
+func dialWebhook(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ipAddrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	// 1件でも内部向けのアドレスが含まれる場合は接続しない
+	for _, ipAddr := range ipAddrs {
+		if isBlockedWebhookIP(ipAddr.IP) {
+			return nil, fmt.Errorf("%w %s", booking.ErrWebhookRejected, ipAddr.IP)
+		}
+	}
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	var lastErr error
+	for _, ipAddr := range ipAddrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ipAddr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("Error: %s %s", "接続先のアドレスが見つかりません。", host)
+	}
+	return nil, lastErr
+}
+
+// isBlockedWebhookIP ループバック・私用・リンクローカル・未指定などの、施設のWebhookに使えないアドレスか
+func isBlockedWebhookIP(ip net.IP) bool {
+	if ip.IsLoopback() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return true
+	}
+	for _, network := range webhookBlockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCIDRs CIDR表記のアドレス範囲を変換
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
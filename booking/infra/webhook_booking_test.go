@@ -0,0 +1,50 @@
+package infra
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+func Test_isBlockedWebhookIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"0.0.0.0", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"203.0.113.1", false},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		if got := isBlockedWebhookIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("%s の判定が一致しません。%v", tt.ip, got)
+		}
+	}
+}
+
+func Test_bookingWebhook_Post(t *testing.T) {
+	called := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	err := NewBookingWebhook().Post(server.URL, "secret", booking.NotificationEventCreated, "key", []byte("{}"))
+	if !errors.Is(err, booking.ErrWebhookRejected) || called {
+		t.Fatalf("ループバックアドレスに送信されています。%v", err)
+	}
+}
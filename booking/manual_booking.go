@@ -0,0 +1,89 @@
+package booking
+
+import "errors"
+
+// ErrManualBookingWholesaler 手入力で予約を登録できるのは直仕入れの施設のみ
+var ErrManualBookingWholesaler = errors.New("Error: 直仕入れ以外の施設では予約を登録できません。")
+
+// ErrManualApplicationIDRange 手入力の予約の予約IDが予約用の範囲を超えた
+var ErrManualApplicationIDRange = errors.New("Error: 手入力の予約の予約IDを採番できません。")
+
+const (
+	// ManualApplicationIDMin 手入力の予約の予約IDの最小値（予約サイトの予約IDと重ならない範囲）
+	ManualApplicationIDMin int64 = 9000000000
+	// ManualApplicationIDMax 手入力の予約の予約IDの最大値
+	ManualApplicationIDMax int64 = 9999999999
+)
+
+// IsManualApplicationID 手入力で登録した予約の予約IDか
+// 手入力の予約はadminにないため、キャンセルなどはHM APIで行う
+func IsManualApplicationID(cmApplicationID int64) bool {
+	return cmApplicationID >= ManualApplicationIDMin && cmApplicationID <= ManualApplicationIDMax
+}
+
+// ManualBookingInput 電話・直接来館などの予約を手入力で登録する入力
+type ManualBookingInput struct {
+	PropertyID   int64              `json:"property_id" validate:"required"`
+	WholesalerID int64              `json:"wholesaler_id" validate:"required"`
+	Arrival      string             `json:"arrival" validate:"required,datetime=2006-01-02"`
+	Departure    string             `json:"departure" validate:"required,datetime=2006-01-02"`
+	FamilyName   string             `json:"family_name" validate:"required,max=50"`
+	GivenName    string             `json:"given_name" validate:"required,max=50"`
+	Email        string             `json:"email" validate:"omitempty,email,max=256"`
+	Phone        string             `json:"phone" validate:"required,max=20"`
+	Memo         string             `json:"memo" validate:"max=1000"`
+	Rooms        []BookingRoomInput `json:"rooms" validate:"required,min=1,max=10,dive"`
+	// QuoteOnly 料金の見積もりのみ行い、予約は登録しない
+	QuoteOnly bool `json:"quote_only"`
+}
+
+// ManualBookingOutput 手入力で登録した予約の出力
+type ManualBookingOutput struct {
+	// CmApplicationID 登録した予約の予約ID（見積もりのみの場合は0）
+	CmApplicationID int64             `json:"cm_application_id"`
+	Arrival         string            `json:"arrival"`
+	Departure       string            `json:"departure"`
+	Stays           int               `json:"stays"`
+	TotalPayInTax   float32           `json:"total_pay_in_tax"`
+	Rooms           []QuoteRoomOutput `json:"rooms"`
+}
+
+// HtTmManualApplicationIDs 手入力で登録する予約の予約IDの採番テーブル
+// 予約サイトの予約IDと重ならない範囲（ManualApplicationIDMin〜ManualApplicationIDMax）から採番する
+type HtTmManualApplicationIDs struct {
+	CmApplicationID int64 `json:"cm_application_id"`
+}
+
+// ManualBookingCreate 手入力の予約で登録する内容（repositoryで1つのトランザクションとして登録する）
+type ManualBookingCreate struct {
+	// Application 予約情報（予約IDはrepositoryで採番する）
+	Application *HtThApplications
+	// Rooms 部屋情報
+	Rooms []HtThBookingRooms
+	// Prices 料金情報
+	Prices []HtThBookingPrices
+	// PriceRoomIndexes 料金情報ごとの部屋（Roomsの添字）
+	PriceRoomIndexes []int
+	// Stocks 直仕入れの在庫の増減
+	Stocks []StockChange
+}
+
+// ManualBookingCancel 手入力の予約のキャンセルで更新する内容（repositoryで1つのトランザクションとして更新する）
+type ManualBookingCancel struct {
+	CmApplicationID  int64
+	CancelFee        int64
+	Noshow           uint8
+	UpdateOperatorID int64
+	// Stocks 直仕入れの在庫の戻し
+	Stocks []StockChange
+	// CancelRequest キャンセルの履歴（adminへは送信しないため、送信済みとして登録する）
+	CancelRequest *HtThBookingCancelRequests
+}
+
+// IBookingManualRepository 手入力の予約関連のrepositoryのインターフェース
+type IBookingManualRepository interface {
+	// CreateManualBooking 予約IDの採番・予約情報と部屋・料金情報の登録・在庫の引当をまとめて行う
+	CreateManualBooking(create *ManualBookingCreate) error
+	// CancelManualBooking 予約情報のキャンセル・在庫の戻し・キャンセルの履歴の登録をまとめて行う
+	CancelManualBooking(cancel *ManualBookingCancel) error
+}
@@ -0,0 +1,119 @@
+package booking
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+)
+
+const (
+	// NoShowBasisCanceled キャンセル日を基準に、キャンセルした月のMonthsヶ月後の月末まで
+	NoShowBasisCanceled = "canceled"
+	// NoShowBasisArrival チェックイン日を基準に、Days日後まで
+	NoShowBasisArrival = "arrival"
+
+	// NoShowResultMarked NoShowとして登録した（キャンセル済みの予約）
+	NoShowResultMarked = "no_show"
+	// NoShowResultReversed NoShowを取り消した
+	NoShowResultReversed = "reversed"
+	// NoShowResultCancelRequested NoShowとしてadminへキャンセルを依頼した（キャンセルされていない予約）
+	NoShowResultCancelRequested = "cancel_requested"
+	// NoShowResultSkipped NoShowにできなかった
+	NoShowResultSkipped = "skipped"
+)
+
+var (
+	// ErrNoShowNotAllowed NoShowにできない予約（チェックイン日前・キャンセルされていない）
+	ErrNoShowNotAllowed = errors.New("Error: NoShow可能な期間ではありません。")
+	// ErrNoShowDeadline NoShowの登録・取り消しの期限を過ぎている
+	ErrNoShowDeadline = errors.New("Error: NoShow可能日を過ぎています。")
+	// ErrNoShowUnchanged すでにNoShow・NoShowではない
+	ErrNoShowUnchanged = errors.New("Error: NoShowの状態が変わりません。")
+	// ErrNoShowReason NoShowを取り消す理由が入力されていない
+	ErrNoShowReason = errors.New("Error: NoShowを取り消す理由を入力してください。")
+	// ErrNoShowArrival 指定した日に到着する予約ではない
+	ErrNoShowArrival = errors.New("Error: 指定した日に到着する予約ではありません。")
+)
+
+// NoShowWindow NoShowを登録・取り消しできる期限のルール
+type NoShowWindow struct {
+	Basis  string `json:"basis"`
+	Months int    `json:"months"`
+	Days   int    `json:"days"`
+}
+
+// DefaultNoShowWindow ルールを設定していないホールセラーの期限（キャンセルした月の翌月末まで）
+var DefaultNoShowWindow = NoShowWindow{Basis: NoShowBasisCanceled, Months: 1}
+
+// Valid ルールとして使える設定か
+func (w NoShowWindow) Valid() bool {
+	switch w.Basis {
+	case NoShowBasisCanceled:
+		return w.Months >= 0
+	case NoShowBasisArrival:
+		return w.Days >= 0
+	}
+	return false
+}
+
+// Deadline NoShowを登録・取り消しできる期限（この時刻の前まで）
+// arrivalは施設のタイムゾーンのチェックイン日の0時。まだキャンセルされていない予約はcanceledAtに現在時刻を渡す
+func (w NoShowWindow) Deadline(arrival time.Time, canceledAt time.Time) time.Time {
+	if w.Basis == NoShowBasisArrival {
+		return arrival.AddDate(0, 0, w.Days+1)
+	}
+	return time.Date(canceledAt.Year(), canceledAt.Month(), 1, 0, 0, 0, 0, canceledAt.Location()).AddDate(0, w.Months+1, 0)
+}
+
+// HtThBookingNoShowHistories 予約のNoShowの登録・取り消しの履歴テーブル（追記のみ）
+type HtThBookingNoShowHistories struct {
+	BookingNoShowHistoryID int64   `gorm:"primaryKey;autoIncrement:true" json:"booking_no_show_history_id"`
+	CmApplicationID        int64   `json:"cm_application_id"`
+	HotelManagerID         int64   `json:"hotel_manager_id"`
+	NoshowFlg              bool    `json:"noshow_flg"`
+	NoshowFeeBefore        float32 `json:"noshow_fee_before"`
+	NoshowFeeAfter         float32 `json:"noshow_fee_after"`
+	// FeeItemsJSON NoShow料金の内訳（cancelPolicy.FeeItem）
+	FeeItemsJSON string    `json:"-"`
+	Reason       string    `json:"reason"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BulkNoShowInput 指定日に到着する予約の一括NoShowの入力
+type BulkNoShowInput struct {
+	PropertyID  int64  `json:"property_id" validate:"required"`
+	ArrivalDate string `json:"arrival_date" validate:"required,datetime=2006-01-02"`
+	// CmApplicationIDs NoShowにする予約（一度に200件まで）
+	CmApplicationIDs []int64 `json:"cm_application_ids" validate:"required,max=200"`
+}
+
+// NoShowResult 1予約分のNoShowの結果
+type NoShowResult struct {
+	CmApplicationID int64                  `json:"cm_application_id"`
+	Result          string                 `json:"result"`
+	NoshowFee       float32                `json:"noshow_fee"`
+	FeeItems        []cancelPolicy.FeeItem `json:"fee_items"`
+	Deadline        *time.Time             `json:"deadline,omitempty"`
+	CancelRequest   *CancelRequestOutput   `json:"cancel_request,omitempty"`
+	Message         string                 `json:"message,omitempty"`
+}
+
+// BulkNoShowOutput 一括NoShowの出力
+type BulkNoShowOutput struct {
+	ArrivalDate     string         `json:"arrival_date"`
+	Marked          int            `json:"marked"`
+	CancelRequested int            `json:"cancel_requested"`
+	Skipped         int            `json:"skipped"`
+	Results         []NoShowResult `json:"results"`
+}
+
+// IBookingNoShowRepository 予約のNoShow関連のrepositoryのインターフェース
+type IBookingNoShowRepository interface {
+	// FetchArrivals 施設の指定日に到着する予約（キャンセル済みを含む）を取得
+	FetchArrivals(propertyID int64, date string) ([]HtThApplications, error)
+	// ApplyNoShow キャンセル済みの予約のNoShowフラグ・料金の更新と履歴・施設への通知の登録をまとめて行う
+	ApplyNoShow(history *HtThBookingNoShowHistories, notifications []HtThBookingNotifications) error
+	// FetchNoShowHistories 予約IDに基づくNoShowの登録・取り消しの履歴を取得
+	FetchNoShowHistories(cmApplicationID int64) ([]HtThBookingNoShowHistories, error)
+}
@@ -0,0 +1,28 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_NoShowWindowDeadline(t *testing.T) {
+	arrival := time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)
+	canceledAt := time.Date(2021, 3, 9, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		window NoShowWindow
+		want   time.Time
+	}{
+		{"キャンセルした月の翌月末まで", DefaultNoShowWindow, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"キャンセルした月末まで", NoShowWindow{Basis: NoShowBasisCanceled}, time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"チェックイン日の3日後まで", NoShowWindow{Basis: NoShowBasisArrival, Days: 3}, time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"チェックイン日当日まで", NoShowWindow{Basis: NoShowBasisArrival}, time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Deadline(arrival, canceledAt); !got.Equal(tt.want) {
+				t.Fatalf("期限が一致しません。got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+var (
+	// ErrPartialCancelTarget キャンセルする部屋・泊が予約に含まれていないか、部屋との紐づけが分からない
+	ErrPartialCancelTarget = errors.New("Error: キャンセルする部屋・泊が予約に含まれていません。")
+	// ErrPartialCancelAll すべての部屋・泊を指定した（予約全体のキャンセルを使う）
+	ErrPartialCancelAll = errors.New("Error: すべての部屋・泊をキャンセルする場合は、予約のキャンセルを行ってください。")
+	// ErrPartialCancelStayed 宿泊済みの泊を指定した
+	ErrPartialCancelStayed = errors.New("Error: 宿泊済みの泊はキャンセルできません。")
+	// ErrPartialCancelUnavailable adminの一部キャンセルAPIが使えない間の、手入力以外の予約の一部キャンセル
+	ErrPartialCancelUnavailable = errors.New("Error: 現在、この予約は一部キャンセルできません。")
+)
+
+// PartialCancelInput 予約の一部キャンセルの入力
+// 部屋と泊の両方を指定した場合は、指定した部屋の指定した泊をキャンセルする
+type PartialCancelInput struct {
+	CmApplicationID    int64    `json:"cm_application_id" validate:"required"`
+	HtThBookingRoomIDs []int64  `json:"ht_th_booking_room_ids" validate:"required_without=UseDates"`
+	UseDates           []string `json:"use_dates" validate:"required_without=HtThBookingRoomIDs,dive,datetime=2006-01-02"`
+	CancelFee          int64    `json:"cancel_fee"`
+}
+
+// HtThBookingPartialCancels 予約の一部キャンセルの履歴テーブル
+type HtThBookingPartialCancels struct {
+	BookingPartialCancelID int64   `gorm:"primaryKey;autoIncrement:true" json:"booking_partial_cancel_id"`
+	CmApplicationID        int64   `json:"cm_application_id"`
+	HotelManagerID         int64   `json:"hotel_manager_id"`
+	CancelFee              int64   `json:"cancel_fee"`
+	CanceledAmountInTax    int     `json:"canceled_amount_in_tax"`
+	TotalPayInTaxBefore    float32 `json:"total_pay_in_tax_before"`
+	TotalPayInTaxAfter     float32 `json:"total_pay_in_tax_after"`
+	// PricesJSON キャンセルした料金情報（HtThBookingPrices）
+	PricesJSON string `json:"prices_json"`
+	// FeeItemsJSON キャンセル料の内訳（cancelPolicy.FeeItem）
+	FeeItemsJSON      string `json:"fee_items_json"`
+	NightlyJSONBefore string `json:"nightly_json_before"`
+	common.Times      `gorm:"embedded"`
+}
+
+// PartialCancelOutput 予約の一部キャンセルの出力
+type PartialCancelOutput struct {
+	BookingPartialCancelID int64                  `json:"booking_partial_cancel_id"`
+	CmApplicationID        int64                  `json:"cm_application_id"`
+	HotelManagerID         int64                  `json:"hotel_manager_id"`
+	CancelFee              int64                  `json:"cancel_fee"`
+	CanceledAmountInTax    int                    `json:"canceled_amount_in_tax"`
+	TotalPayInTaxBefore    float32                `json:"total_pay_in_tax_before"`
+	TotalPayInTaxAfter     float32                `json:"total_pay_in_tax_after"`
+	Nights                 []RoomNightPrice       `json:"nights"`
+	FeeItems               []cancelPolicy.FeeItem `json:"fee_items"`
+	CanceledAt             time.Time              `json:"canceled_at"`
+	CancelRequest          *CancelRequestOutput   `json:"cancel_request,omitempty"`
+}
+
+// NewPartialCancelOutput 予約の一部キャンセルの履歴から出力を生成
+func NewPartialCancelOutput(partialCancel *HtThBookingPartialCancels) (*PartialCancelOutput, error) {
+	prices := []HtThBookingPrices{}
+	if err := json.Unmarshal([]byte(partialCancel.PricesJSON), &prices); err != nil {
+		return nil, err
+	}
+	result := &PartialCancelOutput{
+		BookingPartialCancelID: partialCancel.BookingPartialCancelID,
+		CmApplicationID:        partialCancel.CmApplicationID,
+		HotelManagerID:         partialCancel.HotelManagerID,
+		CancelFee:              partialCancel.CancelFee,
+		CanceledAmountInTax:    partialCancel.CanceledAmountInTax,
+		TotalPayInTaxBefore:    partialCancel.TotalPayInTaxBefore,
+		TotalPayInTaxAfter:     partialCancel.TotalPayInTaxAfter,
+		Nights:                 []RoomNightPrice{},
+		FeeItems:               []cancelPolicy.FeeItem{},
+		CanceledAt:             partialCancel.CreatedAt,
+	}
+	for _, price := range prices {
+		result.Nights = append(result.Nights, price.NightPrice())
+	}
+	if partialCancel.FeeItemsJSON != "" {
+		if err := json.Unmarshal([]byte(partialCancel.FeeItemsJSON), &result.FeeItems); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// PartialCancelApply 一部キャンセルで更新する内容（repositoryで1つのトランザクションとして更新する）
+type PartialCancelApply struct {
+	WholesalerID int64
+	// PriceIDs キャンセル済みにする料金情報（キャンセルした部屋・泊）
+	PriceIDs []int64
+	// Stocks 在庫を戻す部屋タイプ・日付（1件につき1室）
+	Stocks []StockCell
+	// Application 予約情報の更新内容
+	Application map[string]interface{}
+	// Links 残りの料金情報と部屋の紐づけ（料金情報ID→部屋ID）
+	Links map[int64]int64
+	// History 一部キャンセルの履歴
+	History *HtThBookingPartialCancels
+	// CancelRequest adminへの一部キャンセルの依頼（履歴IDは登録時に設定する）
+	CancelRequest *HtThBookingCancelRequests
+}
+
+// StockCell 部屋タイプ・日付ごとの在庫1室
+type StockCell struct {
+	RoomTypeID int64
+	UseDate    string
+}
+
+// IBookingPartialCancelRepository 予約の一部キャンセル関連のrepositoryのインターフェース
+type IBookingPartialCancelRepository interface {
+	// ApplyPartialCancel 料金情報のキャンセル・予約情報の更新・在庫の戻し・履歴とadminへの依頼の登録をまとめて行う
+	ApplyPartialCancel(apply *PartialCancelApply) error
+	// FetchPartialCancels 予約IDに基づく一部キャンセルの履歴を取得
+	FetchPartialCancels(cmApplicationID int64) ([]HtThBookingPartialCancels, error)
+}
+
+// AmountExTax その日の料金（税抜）。大人料金は人数分の合計、子供料金は1人あたりの金額で登録されている
+func (h *HtThBookingPrices) AmountExTax() int {
+	return h.Price +
+		h.ChildPrice1*h.Child1Person +
+		h.ChildPrice2*h.Child2Person +
+		h.ChildPrice3*h.Child3Person +
+		h.ChildPrice4*h.Child4Person +
+		h.ChildPrice5*h.Child5Person +
+		h.ChildPrice6*h.Child6Person
+}
+
+// PruneNightlyJSON 予約時に保存した日別料金（NightlyJSON）から、キャンセルした分を取り除く
+// 配列の要素が泊ごと（件数＝泊数）の場合はすべての部屋がキャンセルされた泊を、部屋・泊ごと（件数＝料金情報の件数）の場合はキャンセルした部屋・泊を取り除く
+// 形式が分からない場合はそのまま返す
+func PruneNightlyJSON(nightlyJSON string, dates []string, prices []HtThBookingPrices, canceledPriceIDs map[int64]bool) string {
+	nightly := []json.RawMessage{}
+	if err := json.Unmarshal([]byte(nightlyJSON), &nightly); err != nil {
+		return nightlyJSON
+	}
+	remove := map[int]bool{}
+	switch len(nightly) {
+	case len(prices):
+		for i, price := range prices {
+			remove[i] = canceledPriceIDs[price.HtThBookingPriceID]
+		}
+	case len(dates):
+		remaining := map[string]bool{}
+		for _, price := range prices {
+			if !canceledPriceIDs[price.HtThBookingPriceID] {
+				remaining[price.UseDate.Format("2006-01-02")] = true
+			}
+		}
+		for i, date := range dates {
+			remove[i] = !remaining[date]
+		}
+	default:
+		return nightlyJSON
+	}
+	pruned := []json.RawMessage{}
+	for i, night := range nightly {
+		if !remove[i] {
+			pruned = append(pruned, night)
+		}
+	}
+	result, err := json.Marshal(pruned)
+	if err != nil {
+		return nightlyJSON
+	}
+	return string(result)
+}
@@ -0,0 +1,41 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_PruneNightlyJSON(t *testing.T) {
+	day1 := time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	dates := []string{"2021-03-10", "2021-03-11"}
+	// 2部屋2泊
+	prices := []HtThBookingPrices{
+		{HtThBookingPriceID: 1, UseDate: day1},
+		{HtThBookingPriceID: 2, UseDate: day1},
+		{HtThBookingPriceID: 3, UseDate: day2},
+		{HtThBookingPriceID: 4, UseDate: day2},
+	}
+
+	t.Run("部屋・泊ごとの形式はキャンセルした部屋・泊を取り除くことのテスト", func(t *testing.T) {
+		got := PruneNightlyJSON(`[{"p":1},{"p":2},{"p":3},{"p":4}]`, dates, prices, map[int64]bool{2: true, 4: true})
+		if got != `[{"p":1},{"p":3}]` {
+			t.Fatalf("日別料金が一致しません。%s", got)
+		}
+	})
+	t.Run("泊ごとの形式はすべての部屋がキャンセルされた泊だけを取り除くことのテスト", func(t *testing.T) {
+		got := PruneNightlyJSON(`[{"d":1},{"d":2}]`, dates, prices, map[int64]bool{2: true, 3: true, 4: true})
+		if got != `[{"d":1}]` {
+			t.Fatalf("日別料金が一致しません。%s", got)
+		}
+	})
+	t.Run("形式が分からない場合はそのまま返すことのテスト", func(t *testing.T) {
+		nightly := `[{"x":1},{"x":2},{"x":3}]`
+		if got := PruneNightlyJSON(nightly, dates, prices, map[int64]bool{1: true}); got != nightly {
+			t.Fatalf("日別料金が変更されています。%s", got)
+		}
+		if got := PruneNightlyJSON("", dates, prices, map[int64]bool{1: true}); got != "" {
+			t.Fatalf("日別料金が変更されています。%s", got)
+		}
+	})
+}
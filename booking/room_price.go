@@ -0,0 +1,207 @@
+package booking
+
+import "sort"
+
+const (
+	// PriceLineAdult 大人料金
+	PriceLineAdult = "adult"
+	// PriceLineChildA 子供料金A（小学生高学年）
+	PriceLineChildA = "child_a"
+	// PriceLineChildB 子供料金B（小学生低学年）
+	PriceLineChildB = "child_b"
+	// PriceLineChildC 子供料金C（幼児 食事・布団あり）
+	PriceLineChildC = "child_c"
+	// PriceLineChildD 子供料金D（幼児 食事のみ）
+	PriceLineChildD = "child_d"
+	// PriceLineChildE 子供料金E（幼児 布団のみ）
+	PriceLineChildE = "child_e"
+	// PriceLineChildF 子供料金F（幼児 食事・布団なし）
+	PriceLineChildF = "child_f"
+)
+
+// RoomPriceLine 1部屋1泊分の料金の明細1行（大人・子供A〜F）
+type RoomPriceLine struct {
+	Type   string `json:"type"`
+	Person int    `json:"person"`
+	// UnitPriceInTax 1人あたりの料金（税込）。大人料金は人数分の合計で登録されているため0
+	UnitPriceInTax int `json:"unit_price_in_tax,omitempty"`
+	AmountInTax    int `json:"amount_in_tax"`
+}
+
+// RoomNightPrice 1部屋1泊分の料金
+type RoomNightPrice struct {
+	UseDate     string          `json:"use_date"`
+	RoomTypeID  int64           `json:"room_type_id"`
+	PlanID      int64           `json:"plan_id"`
+	AmountInTax int             `json:"amount_in_tax"`
+	Lines       []RoomPriceLine `json:"lines"`
+}
+
+// BackfillOutput 料金情報と部屋の紐づけの補完結果
+type BackfillOutput struct {
+	// Checked 確認した予約の件数
+	Checked int `json:"checked"`
+	// Linked 紐づけた予約の件数
+	Linked int `json:"linked"`
+	// SkippedIDs 紐づけを推定できなかった予約ID
+	SkippedIDs []int64 `json:"skipped_ids"`
+}
+
+// occupancy 大人・子供A〜Fの人数
+type occupancy [7]int
+
+// occupancy 予約時の部屋の人数内訳
+func (h *HtThBookingRooms) occupancy() occupancy {
+	return occupancy{
+		h.NumberOfAdults,
+		h.NumberOfUpperGrades,
+		h.NumberOfLowerGrades,
+		h.NumberOfInfantMealsWithBedding,
+		h.NumberOfInfantMealOnly,
+		h.NumberOfInfantBeddingOnly,
+		h.NumberOfInfantMealsWithoutBedding,
+	}
+}
+
+// occupancy 料金情報の人数内訳
+func (h *HtThBookingPrices) occupancy() occupancy {
+	return occupancy{h.Person, h.Child1Person, h.Child2Person, h.Child3Person, h.Child4Person, h.Child5Person, h.Child6Person}
+}
+
+// NightPrice 1部屋1泊分の料金と明細（人数が0の子供料金は含まない）
+func (h *HtThBookingPrices) NightPrice() RoomNightPrice {
+	result := RoomNightPrice{
+		UseDate:     h.UseDate.Format("2006-01-02"),
+		RoomTypeID:  h.RoomTypeID,
+		PlanID:      h.PlanID,
+		AmountInTax: h.AmountInTax(),
+		Lines:       []RoomPriceLine{{Type: PriceLineAdult, Person: h.Person, AmountInTax: h.PriceInTax}},
+	}
+	children := []struct {
+		lineType string
+		person   int
+		price    int
+	}{
+		{PriceLineChildA, h.Child1Person, h.ChildPrice1InTax},
+		{PriceLineChildB, h.Child2Person, h.ChildPrice2InTax},
+		{PriceLineChildC, h.Child3Person, h.ChildPrice3InTax},
+		{PriceLineChildD, h.Child4Person, h.ChildPrice4InTax},
+		{PriceLineChildE, h.Child5Person, h.ChildPrice5InTax},
+		{PriceLineChildF, h.Child6Person, h.ChildPrice6InTax},
+	}
+	for _, child := range children {
+		if child.person == 0 {
+			continue
+		}
+		result.Lines = append(result.Lines, RoomPriceLine{
+			Type:           child.lineType,
+			Person:         child.person,
+			UnitPriceInTax: child.price,
+			AmountInTax:    child.price * child.person,
+		})
+	}
+	return result
+}
+
+// RoomIndexes 料金情報の各行が何部屋目（bookingRoomsの添字）のものかを返す。どの部屋か分からない行は-1
+// 部屋IDで紐づいていない予約は InferRoomLinks で推定し、推定できない場合は「同じ日のi件目 ＝ i部屋目」とみなす
+func RoomIndexes(bookingRooms []HtThBookingRooms, bookingPrices []HtThBookingPrices) []int {
+	roomIndexes := map[int64]int{}
+	for i, bookingRoom := range bookingRooms {
+		roomIndexes[bookingRoom.HtThBookingRoomID] = i
+	}
+	links, ok := InferRoomLinks(bookingRooms, bookingPrices)
+	result := make([]int, len(bookingPrices))
+	dateIndexes := map[string]int{}
+	for i, bookingPrice := range bookingPrices {
+		useDate := bookingPrice.UseDate.Format("2006-01-02")
+		result[i] = dateIndexes[useDate]
+		dateIndexes[useDate]++
+		roomID := bookingPrice.HtThBookingRoomID
+		if roomID == 0 && ok {
+			roomID = links[bookingPrice.HtThBookingPriceID]
+		}
+		if roomIndex, linked := roomIndexes[roomID]; linked {
+			result[i] = roomIndex
+		} else if roomID != 0 || result[i] >= len(bookingRooms) {
+			result[i] = -1
+		}
+	}
+	return result
+}
+
+// InferRoomLinks 部屋IDで紐づいていない料金情報について、部屋との紐づけを推定する（料金情報ID→部屋ID）
+// 日ごとの料金情報の件数が部屋数と一致し、部屋が1つの場合か、人数内訳で部屋が一意に決まる場合のみ推定できる
+// 人数内訳が同じ部屋が複数ある場合は、その部屋同士の同じ日の料金がすべて同じとき（どちらに紐づけても結果が変わらないとき）に限る
+func InferRoomLinks(bookingRooms []HtThBookingRooms, bookingPrices []HtThBookingPrices) (map[int64]int64, bool) {
+	if len(bookingRooms) == 0 {
+		return nil, false
+	}
+	rooms := append([]HtThBookingRooms{}, bookingRooms...)
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].HtThBookingRoomID < rooms[j].HtThBookingRoomID })
+
+	// 日ごとの料金情報（登録順）
+	dates := []string{}
+	pricesByDate := map[string][]HtThBookingPrices{}
+	for _, bookingPrice := range bookingPrices {
+		if bookingPrice.HtThBookingRoomID != 0 {
+			continue
+		}
+		useDate := bookingPrice.UseDate.Format("2006-01-02")
+		if _, ok := pricesByDate[useDate]; !ok {
+			dates = append(dates, useDate)
+		}
+		pricesByDate[useDate] = append(pricesByDate[useDate], bookingPrice)
+	}
+	if len(dates) == 0 {
+		return nil, false
+	}
+
+	roomsByOccupancy := map[occupancy][]int64{}
+	for _, room := range rooms {
+		roomsByOccupancy[room.occupancy()] = append(roomsByOccupancy[room.occupancy()], room.HtThBookingRoomID)
+	}
+	links := map[int64]int64{}
+	for _, useDate := range dates {
+		prices := pricesByDate[useDate]
+		sort.SliceStable(prices, func(i, j int) bool { return prices[i].HtThBookingPriceID < prices[j].HtThBookingPriceID })
+		if len(prices) != len(rooms) {
+			return nil, false
+		}
+		if len(rooms) == 1 {
+			links[prices[0].HtThBookingPriceID] = rooms[0].HtThBookingRoomID
+			continue
+		}
+		pricesByOccupancy := map[occupancy][]HtThBookingPrices{}
+		for _, price := range prices {
+			pricesByOccupancy[price.occupancy()] = append(pricesByOccupancy[price.occupancy()], price)
+		}
+		for key, roomIDs := range roomsByOccupancy {
+			samePrices := pricesByOccupancy[key]
+			if len(samePrices) != len(roomIDs) {
+				return nil, false
+			}
+			for i, price := range samePrices {
+				if !samePrices[0].interchangeable(&price) {
+					return nil, false
+				}
+				links[price.HtThBookingPriceID] = roomIDs[i]
+			}
+		}
+	}
+	return links, true
+}
+
+// interchangeable 部屋タイプ・プラン・金額がすべて同じ料金情報か
+func (h *HtThBookingPrices) interchangeable(other *HtThBookingPrices) bool {
+	a, b := h.NightPrice(), other.NightPrice()
+	if a.RoomTypeID != b.RoomTypeID || a.PlanID != b.PlanID || len(a.Lines) != len(b.Lines) {
+		return false
+	}
+	for i := range a.Lines {
+		if a.Lines[i] != b.Lines[i] {
+			return false
+		}
+	}
+	return true
+}
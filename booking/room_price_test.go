@@ -0,0 +1,60 @@
+package booking
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_InferRoomLinks(t *testing.T) {
+	day1 := time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	rooms := []HtThBookingRooms{
+		{HtThBookingRoomID: 11, NumberOfAdults: 2, NumberOfUpperGrades: 1},
+		{HtThBookingRoomID: 12, NumberOfAdults: 1},
+	}
+	// 2部屋目の料金が先に登録されている
+	prices := []HtThBookingPrices{
+		{HtThBookingPriceID: 1, UseDate: day1, Person: 1, PriceInTax: 8000},
+		{HtThBookingPriceID: 2, UseDate: day1, Person: 2, Child1Person: 1, PriceInTax: 16000, ChildPrice1InTax: 5000},
+		{HtThBookingPriceID: 3, UseDate: day2, Person: 1, PriceInTax: 9000},
+		{HtThBookingPriceID: 4, UseDate: day2, Person: 2, Child1Person: 1, PriceInTax: 18000, ChildPrice1InTax: 5000},
+	}
+
+	t.Run("人数内訳で部屋が決まる場合は紐づけられることのテスト", func(t *testing.T) {
+		links, ok := InferRoomLinks(rooms, prices)
+		if !ok || links[1] != 12 || links[2] != 11 || links[3] != 12 || links[4] != 11 {
+			t.Fatalf("紐づけが一致しません。%v %v", ok, links)
+		}
+		indexes := RoomIndexes(rooms, prices)
+		if indexes[0] != 1 || indexes[1] != 0 {
+			t.Fatalf("部屋の添字が一致しません。%v", indexes)
+		}
+	})
+	t.Run("人数内訳が同じで料金が違う部屋は推定しないことのテスト", func(t *testing.T) {
+		sameRooms := []HtThBookingRooms{{HtThBookingRoomID: 11, NumberOfAdults: 1}, {HtThBookingRoomID: 12, NumberOfAdults: 1}}
+		samePrices := []HtThBookingPrices{
+			{HtThBookingPriceID: 1, UseDate: day1, Person: 1, RoomTypeID: 1, PriceInTax: 8000},
+			{HtThBookingPriceID: 2, UseDate: day1, Person: 1, RoomTypeID: 2, PriceInTax: 12000},
+		}
+		if _, ok := InferRoomLinks(sameRooms, samePrices); ok {
+			t.Fatal("推定できないはずの予約が紐づけられています。")
+		}
+		// 推定できない場合は「同じ日のi件目 ＝ i部屋目」
+		if indexes := RoomIndexes(sameRooms, samePrices); indexes[0] != 0 || indexes[1] != 1 {
+			t.Fatalf("部屋の添字が一致しません。%v", indexes)
+		}
+	})
+	t.Run("部屋数と料金の件数が合わない場合は推定しないことのテスト", func(t *testing.T) {
+		if _, ok := InferRoomLinks(rooms, prices[:3]); ok {
+			t.Fatal("推定できないはずの予約が紐づけられています。")
+		}
+	})
+}
+
+func Test_NightPrice(t *testing.T) {
+	price := HtThBookingPrices{Person: 2, Child1Person: 1, Child3Person: 2, PriceInTax: 16000, ChildPrice1InTax: 5000, ChildPrice3InTax: 1000}
+	night := price.NightPrice()
+	if night.AmountInTax != 23000 || len(night.Lines) != 3 || night.Lines[2].Type != PriceLineChildC || night.Lines[2].AmountInTax != 2000 {
+		t.Fatalf("明細が一致しません。%+v", night)
+	}
+}
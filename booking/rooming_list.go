@@ -0,0 +1,122 @@
+package booking
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// RoomingKindArrival 到着（その日にチェックイン）
+	RoomingKindArrival = "arrival"
+	// RoomingKindDeparture 出発（その日にチェックアウト）
+	RoomingKindDeparture = "departure"
+	// RoomingKindStayOver 連泊（前日から引き続き宿泊）
+	RoomingKindStayOver = "stay_over"
+)
+
+// RoomingKinds 出力する区分の順番
+var RoomingKinds = []string{RoomingKindArrival, RoomingKindStayOver, RoomingKindDeparture}
+
+// RoomingKindLabels 区分の表記
+var RoomingKindLabels = map[string]string{
+	RoomingKindArrival:   "到着",
+	RoomingKindDeparture: "出発",
+	RoomingKindStayOver:  "連泊",
+}
+
+// RoomingListHeaders ファイル出力の見出し
+var RoomingListHeaders = []string{
+	"区分", "予約番号", "チェックイン", "チェックアウト", "泊数", "部屋タイプ", "プラン", "食事",
+	"大人", "子供A", "子供B", "子供C", "子供D", "子供E", "子供F", "子供（区分なし）",
+	"宿泊者名", "メモ",
+}
+
+// RoomingListInput 到着・出発・連泊の一覧の入力
+type RoomingListInput struct {
+	PropertyID int64  `json:"property_id" query:"property_id" validate:"required"`
+	Date       string `json:"date" query:"date" validate:"required,datetime=2006-01-02"`
+	// Kind 区分（未指定の場合はすべて）
+	Kind string `json:"kind" query:"kind" validate:"omitempty,oneof=arrival departure stay_over"`
+	// Format ファイル形式（未指定の場合はJSON）
+	Format string `json:"format" query:"format" validate:"omitempty,oneof=csv csv_sjis xlsx pdf"`
+}
+
+// RoomingListOutput 到着・出発・連泊の一覧の出力
+type RoomingListOutput struct {
+	PropertyID int64            `json:"property_id"`
+	Date       string           `json:"date"`
+	Arrivals   int              `json:"arrivals"`
+	Departures int              `json:"departures"`
+	StayOvers  int              `json:"stay_overs"`
+	Rows       []RoomingListRow `json:"rows"`
+}
+
+// RoomingListRow 一覧の1部屋分
+type RoomingListRow struct {
+	Kind            string `json:"kind"`
+	CmApplicationID int64  `json:"cm_application_id"`
+	WholesalerID    int64  `json:"wholesaler_id"`
+	// Arrival・Departure・Stays 部屋ごとの宿泊日（一部キャンセル後の泊を反映）
+	Arrival       string `json:"arrival"`
+	Departure     string `json:"departure"`
+	Stays         int    `json:"stays"`
+	RoomName      string `json:"room_name"`
+	PlanName      string `json:"plan_name"`
+	MealBreakfast bool   `json:"meal_breakfast"`
+	MealLunch     bool   `json:"meal_lunch"`
+	MealDinner    bool   `json:"meal_dinner"`
+	// NumberOfAdults・子供A〜F 大人・子供区分ごとの人数
+	NumberOfAdults                    int `json:"number_of_adults"`
+	NumberOfUpperGrades               int `json:"number_of_upper_grades"`
+	NumberOfLowerGrades               int `json:"number_of_lower_grades"`
+	NumberOfInfantMealsWithBedding    int `json:"number_of_infant_meals_with_bedding"`
+	NumberOfInfantMealOnly            int `json:"number_of_infant_meal_only"`
+	NumberOfInfantBeddingOnly         int `json:"number_of_infant_bedding_only"`
+	NumberOfInfantMealsWithoutBedding int `json:"number_of_infant_meals_without_bedding"`
+	// NumberOfChilds 子供区分のない卸の子供の人数
+	NumberOfChilds int    `json:"number_of_childs"`
+	GuestName      string `json:"guest_name"`
+	Memo           string `json:"memo"`
+}
+
+// MealLabel 食事条件の表記
+func (r *RoomingListRow) MealLabel() string {
+	meals := []string{}
+	if r.MealBreakfast {
+		meals = append(meals, "朝")
+	}
+	if r.MealLunch {
+		meals = append(meals, "昼")
+	}
+	if r.MealDinner {
+		meals = append(meals, "夕")
+	}
+	if len(meals) == 0 {
+		return "食事なし"
+	}
+	return strings.Join(meals, "・")
+}
+
+// Record ファイル出力の1行（RoomingListHeadersの順）
+func (r *RoomingListRow) Record() []string {
+	return []string{
+		RoomingKindLabels[r.Kind],
+		strconv.FormatInt(r.CmApplicationID, 10),
+		r.Arrival,
+		r.Departure,
+		strconv.Itoa(r.Stays),
+		r.RoomName,
+		r.PlanName,
+		r.MealLabel(),
+		strconv.Itoa(r.NumberOfAdults),
+		strconv.Itoa(r.NumberOfUpperGrades),
+		strconv.Itoa(r.NumberOfLowerGrades),
+		strconv.Itoa(r.NumberOfInfantMealsWithBedding),
+		strconv.Itoa(r.NumberOfInfantMealOnly),
+		strconv.Itoa(r.NumberOfInfantBeddingOnly),
+		strconv.Itoa(r.NumberOfInfantMealsWithoutBedding),
+		strconv.Itoa(r.NumberOfChilds),
+		r.GuestName,
+		r.Memo,
+	}
+}
@@ -0,0 +1,84 @@
+package booking
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	// SortCheckin チェックイン日順
+	SortCheckin = "checkin"
+	// SortCreated 申込日時順
+	SortCreated = "created"
+	// SortAmount 合計金額順
+	SortAmount = "amount"
+
+	// OrderAsc 昇順
+	OrderAsc = "asc"
+	// OrderDesc 降順
+	OrderDesc = "desc"
+)
+
+// ErrInvalidCursor 予約検索のカーソルが不正
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SearchCursor 予約検索のカーソル。前ページ最後の予約の並び順の値と予約IDを持つ
+type SearchCursor struct {
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// Encode レスポンスに含めるカーソル文字列に変換
+func (s SearchCursor) Encode() string {
+	b, _ := json.Marshal(s)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeSearchCursor リクエストのカーソル文字列を復元
+func DecodeSearchCursor(cursor string) (*SearchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	result := &SearchCursor{}
+	if err := json.Unmarshal(b, result); err != nil || result.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return result, nil
+}
+
+// SortOrDefault 並び順の項目（未指定の場合は申込日時順）
+func (s *SearchInput) SortOrDefault() string {
+	if s.Sort == "" {
+		return SortCreated
+	}
+	return s.Sort
+}
+
+// OrderOrDefault 昇順・降順（未指定の場合は降順）
+func (s *SearchInput) OrderOrDefault() string {
+	if s.Order == "" {
+		return OrderDesc
+	}
+	return s.Order
+}
+
+// CursorOf 並び順の項目に応じて、この予約の次ページ取得用カーソルを作成
+func (s *SearchDBOutput) CursorOf(sort string) SearchCursor {
+	cursor := SearchCursor{ID: s.CmApplicationID}
+	switch sort {
+	case SortCheckin:
+		cursor.Value = s.Arrival
+		if arrival, err := time.Parse(time.RFC3339, s.Arrival); err == nil {
+			cursor.Value = arrival.Format("2006-01-02")
+		}
+	case SortAmount:
+		cursor.Value = strconv.FormatFloat(float64(s.TotalPayInTax), 'f', -1, 32)
+	default:
+		cursor.Value = s.CreatedAt.Format("2006-01-02 15:04:05")
+	}
+	return cursor
+}
@@ -0,0 +1,47 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_SearchCursor(t *testing.T) {
+	t.Run("カーソル文字列から元の値に戻せることのテスト", func(t *testing.T) {
+		cursor := SearchCursor{Value: "2021-03-01 10:00:00", ID: 12345}
+		decoded, err := DecodeSearchCursor(cursor.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *decoded != cursor {
+			t.Fatalf("カーソルが一致しません。%v", decoded)
+		}
+	})
+
+	t.Run("不正なカーソルはErrInvalidCursorになることのテスト", func(t *testing.T) {
+		for _, cursor := range []string{"!!!", SearchCursor{Value: "2021-03-01"}.Encode()} {
+			if _, err := DecodeSearchCursor(cursor); errors.Is(err, ErrInvalidCursor) == false {
+				t.Fatalf("%s: エラーが一致しません。%v", cursor, err)
+			}
+		}
+	})
+
+	t.Run("並び順の項目に応じたカーソルの値になることのテスト", func(t *testing.T) {
+		row := &SearchDBOutput{
+			CmApplicationID: 1,
+			Arrival:         "2021-03-01T00:00:00+09:00",
+			TotalPayInTax:   15400.5,
+			CreatedAt:       time.Date(2021, 1, 2, 3, 4, 5, 0, time.Local),
+		}
+		expected := map[string]string{
+			SortCheckin: "2021-03-01",
+			SortAmount:  "15400.5",
+			SortCreated: "2021-01-02 03:04:05",
+		}
+		for sort, value := range expected {
+			if cursor := row.CursorOf(sort); cursor.Value != value || cursor.ID != 1 {
+				t.Fatalf("%s: カーソルが一致しません。%v", sort, cursor)
+			}
+		}
+	})
+}
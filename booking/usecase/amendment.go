@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// AmendBooking 直仕入れの予約の宿泊日・プラン（部屋タイプ）・人数を変更
+// 直仕入れの料金・子供料金設定で見積もり直し、在庫を変更前の部屋タイプ・日付から変更後へ移して、変更前後の内容を履歴に残す
+func (b *bookingUsecase) AmendBooking(hmUser *account.HtTmHotelManager, req booking.AmendInput) (*booking.AmendOutput, error) {
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if appData.WholesalerID != utils.WholesalerIDDirect {
+		return nil, booking.ErrAmendWholesaler
+	}
+	active, err := b.BCancelRepository.FetchActiveCancelRequest(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if appData.CancelFlg || active != nil {
+		return nil, booking.ErrAlreadyCanceled
+	}
+	// 一部キャンセルした部屋・泊は変更の対象にできないため、予約ごと変更を受け付けない
+	partialCancels, err := b.BPartialCancelRepository.FetchPartialCancels(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(partialCancels) > 0 {
+		return nil, booking.ErrAmendPartialCanceled
+	}
+	loc, err := b.propertyLocation(appData.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().In(loc).Format(DateFormat)
+	if firstN(appData.Arrival, len(DateFormat)) < today || req.Arrival < today {
+		return nil, booking.ErrAmendStarted
+	}
+	dates, err := stayDates(req.Arrival, req.Departure)
+	if err != nil {
+		return nil, err
+	}
+
+	bookingRooms, err := b.BRepository.FetchBookingRoomsByApplicationID(appData.HtThApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	bookingPrices, err := b.BRepository.FetchBookingPriceData([]int64{appData.CmApplicationID})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(bookingPrices, func(i, j int) bool {
+		if !bookingPrices[i].UseDate.Equal(bookingPrices[j].UseDate) {
+			return bookingPrices[i].UseDate.Before(bookingPrices[j].UseDate)
+		}
+		return bookingPrices[i].HtThBookingPriceID < bookingPrices[j].HtThBookingPriceID
+	})
+	amendRooms := map[int64]booking.BookingRoomInput{}
+	for _, room := range req.Rooms {
+		amendRooms[room.HtThBookingRoomID] = room.BookingRoomInput
+	}
+	if len(amendRooms) != len(req.Rooms) || len(amendRooms) != len(bookingRooms) {
+		return nil, booking.ErrAmendRooms
+	}
+	roomInputs := []booking.BookingRoomInput{}
+	for _, bookingRoom := range bookingRooms {
+		room, ok := amendRooms[bookingRoom.HtThBookingRoomID]
+		if !ok {
+			return nil, booking.ErrAmendRooms
+		}
+		roomInputs = append(roomInputs, room)
+	}
+
+	quote, err := b.quoteDirectStay(appData.PropertyID, appData.CmApplicationID, dates, bookingRooms, roomInputs)
+	if err != nil {
+		return nil, err
+	}
+	stocks := stockChanges(bookingPrices, quote.prices)
+	if err := b.checkDirectStocks(stocks, dates); err != nil {
+		return nil, err
+	}
+
+	oldAmount, newAmount := 0, 0
+	oldAmountExTax, newAmountExTax := 0, 0
+	for _, bookingPrice := range bookingPrices {
+		oldAmount += bookingPrice.AmountInTax()
+		oldAmountExTax += bookingPrice.AmountExTax()
+	}
+	for _, bookingPrice := range quote.prices {
+		newAmount += bookingPrice.AmountInTax()
+		newAmountExTax += bookingPrice.AmountExTax()
+	}
+	// 手数料や割引を含む合計金額のため、宿泊料金の差額のみを反映する
+	totalPayInTax := appData.TotalPayInTax + float32(newAmount-oldAmount)
+	totalPayExTax := appData.TotalPayExTax + float32(newAmountExTax-oldAmountExTax)
+	result := &booking.AmendOutput{
+		CmApplicationID:     appData.CmApplicationID,
+		Arrival:             req.Arrival,
+		Departure:           req.Departure,
+		Stays:               len(dates),
+		TotalPayInTaxBefore: appData.TotalPayInTax,
+		TotalPayInTaxAfter:  totalPayInTax,
+		Rooms:               quote.rooms,
+	}
+	if req.QuoteOnly {
+		return result, nil
+	}
+
+	nights := []booking.RoomNightPrice{}
+	for _, bookingPrice := range quote.prices {
+		nights = append(nights, bookingPrice.NightPrice())
+	}
+	nightlyJSON, err := json.Marshal(nights)
+	if err != nil {
+		return nil, err
+	}
+	before := booking.AmendmentSnapshot{
+		Arrival:       appData.Arrival,
+		Departure:     appData.Departure,
+		Stays:         appData.Stays,
+		TotalPayExTax: appData.TotalPayExTax,
+		TotalPayInTax: appData.TotalPayInTax,
+		NightlyJSON:   appData.NightlyJSON,
+		Rooms:         bookingRooms,
+		Prices:        bookingPrices,
+	}
+	after := booking.AmendmentSnapshot{
+		Arrival:       req.Arrival + suffixAfter(appData.Arrival, len(DateFormat)),
+		Departure:     req.Departure + suffixAfter(appData.Departure, len(DateFormat)),
+		Stays:         len(dates),
+		TotalPayExTax: totalPayExTax,
+		TotalPayInTax: totalPayInTax,
+		NightlyJSON:   string(nightlyJSON),
+		Rooms:         quote.bookingRooms,
+		Prices:        quote.prices,
+	}
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return nil, err
+	}
+	apply := &booking.AmendmentApply{
+		Prices: quote.prices,
+		Rooms:  quote.bookingRooms,
+		Application: map[string]interface{}{
+			"arrival":          after.Arrival,
+			"departure":        after.Departure,
+			"stays":            after.Stays,
+			"total_pay_ex_tax": after.TotalPayExTax,
+			"total_pay_in_tax": after.TotalPayInTax,
+			"nightly_json":     after.NightlyJSON,
+		},
+		Stocks: stocks,
+		Amendment: &booking.HtThBookingAmendments{
+			CmApplicationID:     appData.CmApplicationID,
+			HotelManagerID:      hmUser.HotelManagerID,
+			TotalPayInTaxBefore: appData.TotalPayInTax,
+			TotalPayInTaxAfter:  totalPayInTax,
+			BeforeJSON:          string(beforeJSON),
+			AfterJSON:           string(afterJSON),
+		},
+	}
+	for _, bookingPrice := range bookingPrices {
+		apply.OldPriceIDs = append(apply.OldPriceIDs, bookingPrice.HtThBookingPriceID)
+	}
+	if err := b.BAmendmentRepository.ApplyAmendment(apply); err != nil {
+		return nil, err
+	}
+	result.Version = apply.Amendment.Version
+
+	// 予約の変更は確定しているため、通知できなかった場合は履歴に残してエラーにしない
+	notifyErr := b.notifyAmendment(&appData, result)
+	notifyError := ""
+	notifiedAt := time.Now()
+	if notifyErr != nil {
+		notifyError = notifyErr.Error()
+		notifiedAt = time.Time{}
+	}
+	if err := b.BAmendmentRepository.UpdateAmendmentNotification(apply.Amendment.BookingAmendmentID, notifiedAt, notifyError); err != nil {
+		return nil, err
+	}
+	result.Notified = notifyErr == nil
+	return result, nil
+}
+
+// notifyAmendment 予約の変更内容を宿泊者へメールで通知（予約の言語が日本語以外の場合は英語）
+func (b *bookingUsecase) notifyAmendment(appData *booking.HtThApplications, amendment *booking.AmendOutput) error {
+	if appData.EmailEnc == "" {
+		return fmt.Errorf("Error: %s", "宿泊者のメールアドレスが登録されていません。")
+	}
+	email, err := utils.Decrypt(appData.EmailEnc)
+	if err != nil {
+		return err
+	}
+	ja := appData.LangCd == "" || strings.HasPrefix(appData.LangCd, "ja")
+	rooms := []string{}
+	for i, room := range amendment.Rooms {
+		if ja {
+			rooms = append(rooms, fmt.Sprintf("%d部屋目：%s／%s %d円", i+1, room.RoomName, room.PlanName, room.AmountInTax))
+		} else {
+			rooms = append(rooms, fmt.Sprintf("Room %d: %s / %s JPY %d", i+1, room.RoomName, room.PlanName, room.AmountInTax))
+		}
+	}
+	message := &mail.Message{To: []string{email}}
+	if ja {
+		message.Subject = "【予約変更のお知らせ】予約番号 " + strconv.FormatInt(appData.CmApplicationID, 10)
+		message.Body = fmt.Sprintf("ご予約の内容が宿泊施設により変更されました。\n\n"+
+			"予約番号：%d\nチェックイン：%s\nチェックアウト：%s（%d泊）\n%s\n\n"+
+			"合計金額：%.0f円（変更前 %.0f円）\n",
+			appData.CmApplicationID, amendment.Arrival, amendment.Departure, amendment.Stays, strings.Join(rooms, "\n"),
+			amendment.TotalPayInTaxAfter, amendment.TotalPayInTaxBefore)
+	} else {
+		message.Subject = "Your reservation has been changed (Booking No. " + strconv.FormatInt(appData.CmApplicationID, 10) + ")"
+		message.Body = fmt.Sprintf("Your reservation has been changed by the property.\n\n"+
+			"Booking No.: %d\nCheck-in: %s\nCheck-out: %s (%d nights)\n%s\n\n"+
+			"Total: JPY %.0f (before the change: JPY %.0f)\n",
+			appData.CmApplicationID, amendment.Arrival, amendment.Departure, amendment.Stays, strings.Join(rooms, "\n"),
+			amendment.TotalPayInTaxAfter, amendment.TotalPayInTaxBefore)
+	}
+	return b.MailSender.Send(message)
+}
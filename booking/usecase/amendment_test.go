@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+type amendBookingRepository struct {
+	booking.IBookingRepository
+	appData booking.HtThApplications
+}
+
+func (r *amendBookingRepository) FetchApplication(cmApplicationID int64) (booking.HtThApplications, error) {
+	return r.appData, nil
+}
+
+type amendCancelRepository struct {
+	booking.IBookingCancelRepository
+}
+
+func (r *amendCancelRepository) FetchActiveCancelRequest(cmApplicationID int64) (*booking.HtThBookingCancelRequests, error) {
+	return nil, nil
+}
+
+type amendPartialCancelRepository struct {
+	booking.IBookingPartialCancelRepository
+	partialCancels []booking.HtThBookingPartialCancels
+}
+
+func (r *amendPartialCancelRepository) FetchPartialCancels(cmApplicationID int64) ([]booking.HtThBookingPartialCancels, error) {
+	return r.partialCancels, nil
+}
+
+func Test_AmendBooking_partialCanceled(t *testing.T) {
+	b := &bookingUsecase{
+		BRepository: &amendBookingRepository{appData: booking.HtThApplications{
+			CmApplicationID: 1,
+			WholesalerID:    utils.WholesalerIDDirect,
+			Arrival:         "2099-04-01",
+			Departure:       "2099-04-03",
+		}},
+		BCancelRepository: &amendCancelRepository{},
+		BPartialCancelRepository: &amendPartialCancelRepository{partialCancels: []booking.HtThBookingPartialCancels{
+			{BookingPartialCancelID: 1, CmApplicationID: 1},
+		}},
+	}
+	req := booking.AmendInput{
+		CmApplicationID: 1,
+		Arrival:         "2099-04-01",
+		Departure:       "2099-04-03",
+		Rooms:           []booking.AmendRoomInput{{HtThBookingRoomID: 1}},
+	}
+	_, err := b.AmendBooking(&account.HtTmHotelManager{}, req)
+	if !errors.Is(err, booking.ErrAmendPartialCanceled) {
+		t.Fatalf("一部キャンセルした予約の変更がエラーになりません。%v", err)
+	}
+}
@@ -1,21 +1,27 @@
 package usecase
 
 import (
-	"encoding/json"
-	"fmt"
-	"math"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	"github.com/Adventureinc/hotel-hm-api/src/booking"
 	"github.com/Adventureinc/hotel-hm-api/src/booking/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+	cpInfra "github.com/Adventureinc/hotel-hm-api/src/cancelPolicy/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+	mailInfra "github.com/Adventureinc/hotel-hm-api/src/common/mail/infra"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/Adventureinc/hotel-hm-api/src/facility"
+	fInfra "github.com/Adventureinc/hotel-hm-api/src/facility/infra"
 	"github.com/Adventureinc/hotel-hm-api/src/plan"
 	pInfra "github.com/Adventureinc/hotel-hm-api/src/plan/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/price"
+	priceInfra "github.com/Adventureinc/hotel-hm-api/src/price/infra"
 	"github.com/Adventureinc/hotel-hm-api/src/room"
 	rInfra "github.com/Adventureinc/hotel-hm-api/src/room/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/stock"
+	sInfra "github.com/Adventureinc/hotel-hm-api/src/stock/infra"
 	"gorm.io/gorm"
 )
 
@@ -35,79 +41,88 @@ const (
 
 // bookingUsecase 　共通部分の予約関連usecase
 type bookingUsecase struct {
-	BRepository          booking.IBookingRepository
-	RoomDirectRepository room.IRoomDirectRepository
-	PlanDirectRepository plan.IPlanDirectRepository
-	RoomNeppanRepository room.IRoomNeppanRepository
-	PlanNeppanRepository plan.IPlanNeppanRepository
-	RoomRaku2Repository  room.IRoomRaku2Repository
-	PlanRaku2Repository  plan.IPlanRaku2Repository
-	RoomTemaRepository   room.IRoomTemaRepository
-	PlanTemaRepository   plan.IPlanTemaRepository
-	BAPI                 booking.IBookingAPI
+	BRepository              booking.IBookingRepository
+	BCancelRepository        booking.IBookingCancelRepository
+	BPartialCancelRepository booking.IBookingPartialCancelRepository
+	BAmendmentRepository     booking.IBookingAmendmentRepository
+	BManualRepository        booking.IBookingManualRepository
+	BNotificationRepository  booking.IBookingNotificationRepository
+	BContactRepository       booking.IBookingContactRepository
+	BNoShowRepository        booking.IBookingNoShowRepository
+	RoomDirectRepository     room.IRoomDirectRepository
+	PlanDirectRepository     plan.IPlanDirectRepository
+	PriceDirectRepository    price.IPriceDirectRepository
+	StockDirectRepository    stock.IStockDirectRepository
+	RoomNeppanRepository     room.IRoomNeppanRepository
+	PlanNeppanRepository     plan.IPlanNeppanRepository
+	RoomRaku2Repository      room.IRoomRaku2Repository
+	PlanRaku2Repository      plan.IPlanRaku2Repository
+	RoomTemaRepository       room.IRoomTemaRepository
+	PlanTemaRepository       plan.IPlanTemaRepository
+	CPRepository             cancelPolicy.ICancelPolicyCommonRepository
+	FRepository              facility.IFacilityRepository
+	BAPI                     booking.IBookingAPI
+	MailSender               mail.ISender
+	Webhook                  booking.IBookingWebhook
 }
 
 // NewBookingUsecase インスタンス生成
 func NewBookingUsecase(hotelDB *gorm.DB) booking.IBookingUsecase {
 	return &bookingUsecase{
-		BRepository:          infra.NewBookingRepository(hotelDB),
-		RoomDirectRepository: rInfra.NewRoomDirectRepository(hotelDB),
-		PlanDirectRepository: pInfra.NewPlanDirectRepository(hotelDB),
-		RoomNeppanRepository: rInfra.NewRoomNeppanRepository(hotelDB),
-		PlanNeppanRepository: pInfra.NewPlanNeppanRepository(hotelDB),
-		RoomRaku2Repository:  rInfra.NewRoomRaku2Repository(hotelDB),
-		PlanRaku2Repository:  pInfra.NewPlanRaku2Repository(hotelDB),
-		RoomTemaRepository:   rInfra.NewRoomTemaRepository(hotelDB),
-		PlanTemaRepository:   pInfra.NewPlanTemaRepository(hotelDB),
-		BAPI:                 infra.NewBookingAPI(),
+		BRepository:              infra.NewBookingRepository(hotelDB),
+		BCancelRepository:        infra.NewBookingCancelRepository(hotelDB),
+		BPartialCancelRepository: infra.NewBookingPartialCancelRepository(hotelDB),
+		BAmendmentRepository:     infra.NewBookingAmendmentRepository(hotelDB),
+		BManualRepository:        infra.NewBookingManualRepository(hotelDB),
+		BNotificationRepository:  infra.NewBookingNotificationRepository(hotelDB),
+		BContactRepository:       infra.NewBookingContactRepository(hotelDB),
+		BNoShowRepository:        infra.NewBookingNoShowRepository(hotelDB),
+		RoomDirectRepository:     rInfra.NewRoomDirectRepository(hotelDB),
+		PlanDirectRepository:     pInfra.NewPlanDirectRepository(hotelDB),
+		PriceDirectRepository:    priceInfra.NewPriceDirectRepository(hotelDB),
+		StockDirectRepository:    sInfra.NewStockDirectRepository(hotelDB),
+		RoomNeppanRepository:     rInfra.NewRoomNeppanRepository(hotelDB),
+		PlanNeppanRepository:     pInfra.NewPlanNeppanRepository(hotelDB),
+		RoomRaku2Repository:      rInfra.NewRoomRaku2Repository(hotelDB),
+		PlanRaku2Repository:      pInfra.NewPlanRaku2Repository(hotelDB),
+		RoomTemaRepository:       rInfra.NewRoomTemaRepository(hotelDB),
+		PlanTemaRepository:       pInfra.NewPlanTemaRepository(hotelDB),
+		CPRepository:             cpInfra.NewCommonCancelPolicyRepository(hotelDB),
+		FRepository:              fInfra.NewFacilityRepository(hotelDB),
+		BAPI:                     infra.NewBookingAPI(),
+		MailSender:               mailInfra.NewMailSender(),
+		Webhook:                  infra.NewBookingWebhook(),
 	}
 }
 
 // SearchBookings 予約一覧検索
-func (b *bookingUsecase) SearchBookings(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req booking.SearchInput) ([]booking.SearchOutput, error) {
-	res := []booking.SearchOutput{}
-	/*
-	* 予約者性(FamilyName),予約者名(GivenName),電話番号(Phone)は暗号化した文字列を予約テーブルに登録しているので
-	* これらを条件に取得するためには一度暗号化する。
-	 */
-	//　暗号化する予約者名(GivenNameEnc)のリストを作成
-	givenNameList := utils.UpperAndLowerStrList(req.GivenName)
-	//　暗号化する予約者性(FamilyNameEnc)のリストを作成
-	familyNameList := utils.UpperAndLowerStrList(req.FamilyName)
-
-	//　givenNameEncListを作成
-	var givenNameEncList []string
-	for _, v := range givenNameList {
-		givenNameEnc, eErr := utils.Encrypt(v)
-		if eErr != nil {
-			return res, eErr
+// 総件数と、limit指定時は次ページ取得用のカーソルを合わせて返す
+func (b *bookingUsecase) SearchBookings(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req booking.SearchInput) (*booking.SearchListOutput, error) {
+	res := &booking.SearchListOutput{Bookings: []booking.SearchOutput{}}
+	var cursor *booking.SearchCursor
+	if req.Cursor != "" {
+		decoded, err := booking.DecodeSearchCursor(req.Cursor)
+		if err != nil {
+			return res, err
 		}
-		givenNameEncList = append(givenNameEncList, givenNameEnc)
+		cursor = decoded
 	}
-	req.GivenNameEncList = givenNameEncList
-
-	//　familyNameEncListを作成
-	var familyNameEncList []string
-	for _, v := range familyNameList {
-		familyNameEnc, eErr := utils.Encrypt(v)
-		if eErr != nil {
-			return res, eErr
-		}
-		familyNameEncList = append(familyNameEncList, familyNameEnc)
+	if err := encryptSearchConditions(&req); err != nil {
+		return res, err
 	}
-	req.FamilyNameEncList = familyNameEncList
 
-	//　Phoneを暗号化
-	phoneEnc, eErr := utils.Encrypt(req.Phone)
-	if eErr != nil {
-		return res, eErr
+	total, err := b.BRepository.CountBookings(req)
+	if err != nil {
+		return res, err
 	}
-	req.PhoneEnc = phoneEnc
-
-	bookings, err := b.BRepository.FetchBookings(req)
+	res.Total = total
+	bookings, err := b.BRepository.FetchBookings(req, cursor)
 	if err != nil {
 		return res, err
 	}
+	if req.Paging.Limit > 0 && len(bookings) == req.Paging.Limit {
+		res.NextCursor = bookings[len(bookings)-1].CursorOf(req.SortOrDefault()).Encode()
+	}
 
 	var givenNames, familyNames, phones []string
 	for _, v := range bookings {
@@ -129,7 +144,7 @@ func (b *bookingUsecase) SearchBookings(hmUser *account.HtTmHotelManager, claimP
 	}
 
 	for index, v := range bookings {
-		res = append(res, booking.SearchOutput{
+		res.Bookings = append(res.Bookings, booking.SearchOutput{
 			CmApplicationID: v.CmApplicationID,
 			ApplicationCd:   v.ApplicationCd,
 			TourID:          v.TourID,
@@ -140,11 +155,55 @@ func (b *bookingUsecase) SearchBookings(hmUser *account.HtTmHotelManager, claimP
 			Phone:           phones[index],
 			TotalPayInTax:   v.TotalPayInTax,
 			Status:          utils.GetBookingStatus(v.CancelFlg, v.NoshowFlg, v.Arrival, v.Departure),
+			ContactStatus:   v.ContactStatus,
+			IsMisuse:        v.IsMisuse == 1,
 		})
 	}
 	return res, nil
 }
 
+// encryptSearchConditions 検索条件のうち、暗号化して登録している項目を暗号化する
+func encryptSearchConditions(req *booking.SearchInput) error {
+	/*
+	* 予約者性(FamilyName),予約者名(GivenName),電話番号(Phone)は暗号化した文字列を予約テーブルに登録しているので
+	* これらを条件に取得するためには一度暗号化する。
+	 */
+	//　暗号化する予約者名(GivenNameEnc)のリストを作成
+	givenNameList := utils.UpperAndLowerStrList(req.GivenName)
+	//　暗号化する予約者性(FamilyNameEnc)のリストを作成
+	familyNameList := utils.UpperAndLowerStrList(req.FamilyName)
+
+	//　givenNameEncListを作成
+	var givenNameEncList []string
+	for _, v := range givenNameList {
+		givenNameEnc, eErr := utils.Encrypt(v)
+		if eErr != nil {
+			return eErr
+		}
+		givenNameEncList = append(givenNameEncList, givenNameEnc)
+	}
+	req.GivenNameEncList = givenNameEncList
+
+	//　familyNameEncListを作成
+	var familyNameEncList []string
+	for _, v := range familyNameList {
+		familyNameEnc, eErr := utils.Encrypt(v)
+		if eErr != nil {
+			return eErr
+		}
+		familyNameEncList = append(familyNameEncList, familyNameEnc)
+	}
+	req.FamilyNameEncList = familyNameEncList
+
+	//　Phoneを暗号化
+	phoneEnc, eErr := utils.Encrypt(req.Phone)
+	if eErr != nil {
+		return eErr
+	}
+	req.PhoneEnc = phoneEnc
+	return nil
+}
+
 // DetailBooking 予約詳細情報取得
 func (b *bookingUsecase) DetailBooking(hmUser *account.HtTmHotelManager, claimParam *account.ClaimParam, req booking.DetailInput) (*booking.DetailOutput, error) {
 	response := &booking.DetailOutput{}
@@ -201,21 +260,17 @@ func (b *bookingUsecase) DetailBooking(hmUser *account.HtTmHotelManager, claimPa
 	}
 	response.PersonPrices = personPrices
 
-	// 部屋ごとの人数内訳が1日分だけ必要なため、取得した予約料金情報から宿泊初日のデータのみを切り出す
-	firstUseDate := ""
-	firstDateBookingPrices := []booking.HtThBookingPrices{}
-	for _, bookingPrice := range bookingPrices {
-		currentUseDate := bookingPrice.UseDate.Format(DateFormat)
-		if firstUseDate == "" || firstUseDate == currentUseDate {
-			firstDateBookingPrices = append(firstDateBookingPrices, bookingPrice)
-			firstUseDate = currentUseDate
-		}
-	}
-
 	bookingRooms, rErr := b.BRepository.FetchBookingRoomsByApplicationID(appData.HtThApplicationID)
 	if rErr != nil {
 		return response, rErr
 	}
+	// 料金情報を部屋ごとに分ける（部屋ごとの人数内訳は宿泊初日の料金情報を使う）
+	roomPrices := make([][]booking.HtThBookingPrices, len(bookingRooms))
+	for i, roomIndex := range booking.RoomIndexes(bookingRooms, bookingPrices) {
+		if roomIndex >= 0 {
+			roomPrices[roomIndex] = append(roomPrices[roomIndex], bookingPrices[i])
+		}
+	}
 	// 客室詳細情報を設定する
 	temp := []booking.DetailRoomAndPlan{}
 	for i, bookingRoom := range bookingRooms {
@@ -230,24 +285,30 @@ func (b *bookingUsecase) DetailBooking(hmUser *account.HtTmHotelManager, claimPa
 		}
 		// 格納する客室情報の構造体
 		detailRoomAndPlan := &booking.DetailRoomAndPlan{
-			RoomID:         bookingRoom.RoomID,
-			RoomName:       bookingRoom.RoomName,
-			PlanName:       bookingRoom.PlanName,
-			FamilyName:     roomFamilyName,
-			GivenName:      roomGivenName,
-			NumberOfAdults: bookingRoom.NumberOfAdults,
-			NumberOfChilds: bookingRoom.NumberOfChilds,
-			ChildAges:      bookingRoom.ChildAges,
+			HtThBookingRoomID: bookingRoom.HtThBookingRoomID,
+			RoomID:            bookingRoom.RoomID,
+			RoomName:          bookingRoom.RoomName,
+			PlanName:          bookingRoom.PlanName,
+			FamilyName:        roomFamilyName,
+			GivenName:         roomGivenName,
+			NumberOfAdults:    bookingRoom.NumberOfAdults,
+			NumberOfChilds:    bookingRoom.NumberOfChilds,
+			ChildAges:         bookingRoom.ChildAges,
+			Nights:            []booking.RoomNightPrice{},
 		}
-		// 部屋ごとの人数内訳をマージ（客室情報と料金情報の部屋を明示的に紐づける情報がないため、「客室情報のi部屋目 ＝ 料金情報のi部屋目」とみなす）
-		if len(firstDateBookingPrices) > i {
-			detailRoomAndPlan.Person = firstDateBookingPrices[i].Person
-			detailRoomAndPlan.Child1Person = firstDateBookingPrices[i].Child1Person
-			detailRoomAndPlan.Child2Person = firstDateBookingPrices[i].Child2Person
-			detailRoomAndPlan.Child3Person = firstDateBookingPrices[i].Child3Person
-			detailRoomAndPlan.Child4Person = firstDateBookingPrices[i].Child4Person
-			detailRoomAndPlan.Child5Person = firstDateBookingPrices[i].Child5Person
-			detailRoomAndPlan.Child6Person = firstDateBookingPrices[i].Child6Person
+		for _, roomPrice := range roomPrices[i] {
+			detailRoomAndPlan.Nights = append(detailRoomAndPlan.Nights, roomPrice.NightPrice())
+		}
+		// 部屋ごとの人数内訳をマージ
+		if len(roomPrices[i]) > 0 {
+			firstNight := roomPrices[i][0]
+			detailRoomAndPlan.Person = firstNight.Person
+			detailRoomAndPlan.Child1Person = firstNight.Child1Person
+			detailRoomAndPlan.Child2Person = firstNight.Child2Person
+			detailRoomAndPlan.Child3Person = firstNight.Child3Person
+			detailRoomAndPlan.Child4Person = firstNight.Child4Person
+			detailRoomAndPlan.Child5Person = firstNight.Child5Person
+			detailRoomAndPlan.Child6Person = firstNight.Child6Person
 		} else {
 			// 予約時に料金情報を保持しないホールセラーの場合、客室情報に保持している人数内訳をマージ
 			detailRoomAndPlan.Person = bookingRoom.NumberOfAdults
@@ -357,11 +418,54 @@ func (b *bookingUsecase) DetailBooking(hmUser *account.HtTmHotelManager, claimPa
 	}
 	response.RoomsAndPlan = temp
 
-	cancelFeeSuggest, err := SuggestCancelFee(bookingRooms, appData)
+	cancelFee, err := b.CalculateCancelFee(booking.CancelFeeInput{CmApplicationID: appData.CmApplicationID})
+	if err != nil {
+		return response, err
+	}
+	response.CancelFeeSuggest = float32(cancelFee.Fee)
+	response.CancelFeeItems = cancelFee.Items
+
+	// adminへのキャンセル依頼の状況
+	cancelRequest, err := b.BCancelRepository.FetchLatestCancelRequest(appData.CmApplicationID)
 	if err != nil {
 		return response, err
 	}
-	response.CancelFeeSuggest = cancelFeeSuggest
+	if cancelRequest != nil {
+		response.CancelRequest = booking.NewCancelRequestOutput(cancelRequest)
+	}
+
+	// 予約変更の履歴
+	amendments, err := b.BAmendmentRepository.FetchAmendments(appData.CmApplicationID)
+	if err != nil {
+		return response, err
+	}
+	response.Amendments = []booking.AmendmentOutput{}
+	for _, amendment := range amendments {
+		output, oErr := booking.NewAmendmentOutput(&amendment)
+		if oErr != nil {
+			return response, oErr
+		}
+		response.Amendments = append(response.Amendments, *output)
+	}
+
+	// 一部キャンセルの履歴
+	partialCancels, err := b.BPartialCancelRepository.FetchPartialCancels(appData.CmApplicationID)
+	if err != nil {
+		return response, err
+	}
+	response.PartialCancels = []booking.PartialCancelOutput{}
+	for _, partialCancel := range partialCancels {
+		output, oErr := booking.NewPartialCancelOutput(&partialCancel)
+		if oErr != nil {
+			return response, oErr
+		}
+		response.PartialCancels = append(response.PartialCancels, *output)
+	}
+
+	// NoShowの登録・取り消しの履歴
+	if response.NoShowHistories, err = b.BNoShowRepository.FetchNoShowHistories(appData.CmApplicationID); err != nil {
+		return response, err
+	}
 
 	// セール情報を取得
 	flashSales, fErr := b.BRepository.FetchFlashSaleData([]int64{appData.CmApplicationID})
@@ -704,6 +808,8 @@ func (b *bookingUsecase) BookingDownloads(hmUser *account.HtTmHotelManager, clai
 			NoshowFlg:          bookingDownload.NoshowFlg,
 			Arrival:            bookingDownload.Arrival,
 			Departure:          bookingDownload.Departure,
+			Stays:              bookingDownload.Stays,
+			RoomNum:            bookingDownload.RoomNum,
 			PhoneEnc:           phones[index],
 			DiscountPaymentFlg: discountPaymentFlg,
 			DiscountCashAmount: discountCashAmount,
@@ -713,80 +819,3 @@ func (b *bookingUsecase) BookingDownloads(hmUser *account.HtTmHotelManager, clai
 	}
 	return response, nil
 }
-
-// CancelBooking 予約キャンセル
-func (b *bookingUsecase) CancelBooking(req booking.CancelInput) (bool, error) {
-	return b.BAPI.CancelBooking(req.CmApplicationID, req.CancelFee, req.Noshow)
-}
-
-// UpdateNoShow NoShowフラグの更新
-func (b *bookingUsecase) UpdateNoShow(req *booking.NoShowInput) error {
-	appData, err := b.BRepository.FetchNoShowData(req.CmApplicationID)
-	if err != nil {
-		return err
-	}
-	if appData.HtThApplicationID == 0 {
-		return fmt.Errorf("Error: %s", "NoShow可能な期間ではありません。")
-	}
-	now := time.Now()
-	t := time.Date(appData.CanceledDt.Year(), appData.CanceledDt.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, 2, -1)
-	if now.After(t) {
-		return fmt.Errorf("Error: %s", "NoShow可能日を過ぎています。")
-	}
-	var noShowFee float32
-	if req.NoshowFlg == true {
-		noShowFee = appData.TotalPayInTax
-	}
-	return b.BRepository.UpdateNoShow(appData.HtThApplicationID, req.NoshowFlg, noShowFee)
-}
-
-// SuggestCancelFee キャンセル料の入力補助
-func SuggestCancelFee(bookingRooms []booking.HtThBookingRooms, appData *booking.DetailApplicationDBOutput) (float32, error) {
-	var res float32
-
-	cancelFlg := appData.CancelFlg
-	if cancelFlg == true {
-		res = 0
-		return res, nil
-	}
-
-	// 返金不可の場合は満額を返す
-	totalPayInTax := appData.TotalPayInTax
-	refundable := bookingRooms[0].Refundable
-	if refundable == false {
-		res = totalPayInTax
-		return res, nil
-	}
-
-	// キャンセルペナルティーの取得
-	// 部屋が複数の場合も、キャンセルペナルティーは同一
-	cancelPenalties := bookingRooms[0].CancelPenalties
-	// キャンセルポリシーの取得
-	jsonData := []byte(cancelPenalties)
-	var cancelPolicy []booking.CancelPolicy
-	if err := json.Unmarshal(jsonData, &cancelPolicy); err != nil {
-		return res, err
-	}
-	if len(cancelPolicy) == 0 {
-		res = 0
-		return res, nil
-	}
-
-	t := time.Now()
-	timeNow := t.Format(TimeFormat)
-	for i := 0; i < len(cancelPolicy); i++ {
-		// 該当するキャンセルポリシーがある場合
-		if (cancelPolicy[i].Start <= timeNow) && ((timeNow <= cancelPolicy[i].End) || (cancelPolicy[i].End == "")) {
-			percent := strings.TrimRight(cancelPolicy[i].Percent, "%")
-			rate, err := strconv.Atoi(percent)
-			if err != nil {
-				return res, err
-			}
-			res := float32(math.Ceil(float64(totalPayInTax * float32(rate) / 100)))
-			return res, nil
-		}
-	}
-	// 該当するキャンセルポリシーが無い場合
-	res = 0
-	return res, nil
-}
@@ -0,0 +1,431 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// notificationLogDefaultLimit 送信履歴の取得件数の既定値
+const notificationLogDefaultLimit = 100
+
+// FetchNotificationSetting 施設の予約通知の設定を取得（未設定の場合は無効の設定を返す）
+func (b *bookingUsecase) FetchNotificationSetting(req booking.NotificationSettingInput) (*booking.NotificationSettingOutput, error) {
+	setting, err := b.BNotificationRepository.FetchNotificationSetting(req.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	if setting == nil {
+		setting = &booking.HtTmBookingNotificationSettings{PropertyID: req.PropertyID, LangCd: "ja-JP"}
+	}
+	return newNotificationSettingOutput(setting), nil
+}
+
+// SaveNotificationSetting 施設の予約通知の設定を保存
+// Webhookの署名シークレットは、初めてURLを設定した時と発行し直す指定があった時に発行し、その時の出力でのみ返す
+func (b *bookingUsecase) SaveNotificationSetting(req booking.SaveNotificationSettingInput) (*booking.NotificationSettingOutput, error) {
+	current, err := b.BNotificationRepository.FetchNotificationSetting(req.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	setting := &booking.HtTmBookingNotificationSettings{
+		PropertyID: req.PropertyID,
+		Emails:     strings.Join(uniqueEmails(req.Emails), ","),
+		LangCd:     req.LangCd,
+		WebhookURL: req.WebhookURL,
+		Enabled:    req.Enabled,
+	}
+	setting.CreatedAt = now
+	setting.UpdatedAt = now
+	if current != nil {
+		setting.WebhookSecretEnc = current.WebhookSecretEnc
+	}
+
+	secret := ""
+	if setting.WebhookURL == "" {
+		setting.WebhookSecretEnc = ""
+	} else if setting.WebhookSecretEnc == "" || req.RegenerateSecret {
+		secret, err = utils.GenerateRandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		setting.WebhookSecretEnc, err = utils.EncryptSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := b.BNotificationRepository.SaveNotificationSetting(setting); err != nil {
+		return nil, err
+	}
+	result := newNotificationSettingOutput(setting)
+	result.WebhookSecret = secret
+	return result, nil
+}
+
+// FetchNotificationLogs 施設の予約通知の送信履歴を取得
+func (b *bookingUsecase) FetchNotificationLogs(req booking.NotificationLogInput) ([]booking.NotificationLogOutput, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = notificationLogDefaultLimit
+	}
+	notifications, err := b.BNotificationRepository.FetchNotifications(req.PropertyID, req.CmApplicationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := []booking.NotificationLogOutput{}
+	for i := range notifications {
+		result = append(result, booking.NewNotificationLogOutput(&notifications[i]))
+	}
+	return result, nil
+}
+
+// ScanBookingEvents 前回の確認以降の新規予約・キャンセルを確認し、通知を送信待ちとして登録した予約の件数を返す
+// 予約サイト・adminで登録・キャンセルされた予約も通知するため、予約情報から確認する
+// 初回は確認位置の記録のみ行う（過去の予約は通知しない）
+// 複数のインスタンスで同時に確認しないよう、確認位置の行を確保してから確認する（途中で失敗した場合は確保期限まで待つ）
+func (b *bookingUsecase) ScanBookingEvents() (int, error) {
+	cursor, err := b.BNotificationRepository.FetchNotificationCursor()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if cursor == nil {
+		lastApplicationID, err := b.BNotificationRepository.FetchLastApplicationID()
+		if err != nil {
+			return 0, err
+		}
+		cursor = &booking.HtThBookingNotificationCursors{LastApplicationID: lastApplicationID, LastCanceledAt: now, ScanLockedUntil: now}
+		cursor.CreatedAt = now
+		cursor.UpdatedAt = now
+		return 0, b.BNotificationRepository.SaveNotificationCursor(cursor)
+	}
+	// 他のインスタンスが確認中の場合は何もしない。確保した後に、最新の確認位置を取得し直す
+	claimed, err := b.BNotificationRepository.ClaimNotificationCursor(now, now.Add(booking.NotificationScanLease))
+	if err != nil || !claimed {
+		return 0, err
+	}
+	cursor, err = b.BNotificationRepository.FetchNotificationCursor()
+	if err != nil {
+		return 0, err
+	}
+
+	settings := map[int64]*booking.HtTmBookingNotificationSettings{}
+	scanned := 0
+	created, err := b.BNotificationRepository.FetchApplicationsAfter(cursor.LastApplicationID, booking.NotificationScanBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, appData := range created {
+		if err := b.enqueueNotifications(settings, appData.PropertyID, appData.CmApplicationID, booking.NotificationEventCreated); err != nil {
+			return scanned, err
+		}
+		cursor.LastApplicationID = appData.HtThApplicationID
+		scanned++
+	}
+	canceled, err := b.BNotificationRepository.FetchCanceledApplicationsAfter(cursor.LastCanceledAt, cursor.LastCanceledApplicationID, booking.NotificationScanBatchSize)
+	if err != nil {
+		return scanned, err
+	}
+	for _, appData := range canceled {
+		if err := b.enqueueNotifications(settings, appData.PropertyID, appData.CmApplicationID, booking.NotificationEventCanceled); err != nil {
+			return scanned, err
+		}
+		cursor.LastCanceledAt = appData.CanceledDt
+		cursor.LastCanceledApplicationID = appData.HtThApplicationID
+		scanned++
+	}
+	// 確認位置の保存と同時に確認を解放する
+	cursor.ScanLockedUntil = time.Now()
+	cursor.UpdatedAt = now
+	return scanned, b.BNotificationRepository.SaveNotificationCursor(cursor)
+}
+
+// enqueueNotifications 施設の設定に従い、送信先ごとの通知を送信待ちとして登録
+// settingsは同じ施設の設定を何度も取得しないためのキャッシュ
+func (b *bookingUsecase) enqueueNotifications(settings map[int64]*booking.HtTmBookingNotificationSettings, propertyID int64, cmApplicationID int64, event string) error {
+	setting, ok := settings[propertyID]
+	if !ok {
+		fetched, err := b.BNotificationRepository.FetchNotificationSetting(propertyID)
+		if err != nil {
+			return err
+		}
+		settings[propertyID] = fetched
+		setting = fetched
+	}
+	return b.BNotificationRepository.EnqueueNotifications(notificationsFor(setting, cmApplicationID, event, time.Now()))
+}
+
+// notificationsFor 施設の設定から、送信先ごとの通知を作成（無効の場合はなし）
+func notificationsFor(setting *booking.HtTmBookingNotificationSettings, cmApplicationID int64, event string, now time.Time) []booking.HtThBookingNotifications {
+	result := []booking.HtThBookingNotifications{}
+	if setting == nil || !setting.Enabled {
+		return result
+	}
+	add := func(channel string, recipient string) {
+		notification := booking.HtThBookingNotifications{
+			CmApplicationID: cmApplicationID,
+			PropertyID:      setting.PropertyID,
+			Event:           event,
+			Channel:         channel,
+			Recipient:       recipient,
+			Status:          booking.NotificationStatusPending,
+			NextAttemptAt:   now,
+		}
+		notification.CreatedAt = now
+		notification.UpdatedAt = now
+		result = append(result, notification)
+	}
+	for _, email := range settingEmails(setting) {
+		add(booking.NotificationChannelEmail, email)
+	}
+	if setting.WebhookURL != "" {
+		add(booking.NotificationChannelWebhook, setting.WebhookURL)
+	}
+	return result
+}
+
+// DispatchNotifications 送信時刻を過ぎた通知を送信し、送信した件数を返す
+// 失敗した通知は間隔を空けて再送し、NotificationMaxAttempts回失敗するか、再送しても成功しない場合は打ち切る
+func (b *bookingUsecase) DispatchNotifications() (int, error) {
+	now := time.Now()
+	notifications, err := b.BNotificationRepository.FetchDueNotifications(now, booking.NotificationDispatchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	dispatched := 0
+	for _, notification := range notifications {
+		claimed, err := b.BNotificationRepository.ClaimNotification(notification.BookingNotificationID, now, now.Add(booking.NotificationLease))
+		if err != nil {
+			return dispatched, err
+		}
+		if !claimed {
+			continue
+		}
+		notification.Attempts++
+		dispatched++
+		sendErr := b.sendNotification(&notification)
+		if err := b.BNotificationRepository.UpdateNotification(notification.BookingNotificationID, notificationResult(&notification, sendErr, time.Now())); err != nil {
+			return dispatched, err
+		}
+	}
+	return dispatched, nil
+}
+
+// sendNotification 通知を1件送信
+// 送信先が施設の設定から外れている場合は送信しない
+func (b *bookingUsecase) sendNotification(notification *booking.HtThBookingNotifications) error {
+	setting, err := b.BNotificationRepository.FetchNotificationSetting(notification.PropertyID)
+	if err != nil {
+		return err
+	}
+	if setting == nil || !setting.Enabled {
+		return booking.ErrNotificationDisabled
+	}
+	appData, err := b.BRepository.FetchApplication(notification.CmApplicationID)
+	if err != nil {
+		return err
+	}
+	guestName, err := guestFullName(&appData)
+	if err != nil {
+		return err
+	}
+
+	switch notification.Channel {
+	case booking.NotificationChannelEmail:
+		if !containsString(settingEmails(setting), notification.Recipient) {
+			return booking.ErrNotificationDisabled
+		}
+		return b.MailSender.Send(notificationMail(notification, &appData, guestName, setting.LangCd))
+	case booking.NotificationChannelWebhook:
+		if setting.WebhookURL != notification.Recipient || setting.WebhookSecretEnc == "" {
+			return booking.ErrNotificationDisabled
+		}
+		secret, err := utils.DecryptSecret(setting.WebhookSecretEnc)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(&booking.WebhookPayload{
+			Event:           notification.Event,
+			CmApplicationID: appData.CmApplicationID,
+			PropertyID:      appData.PropertyID,
+			WholesalerID:    appData.WholesalerID,
+			GuestName:       guestName,
+			Arrival:         appData.Arrival,
+			Departure:       appData.Departure,
+			Stays:           appData.Stays,
+			RoomNum:         appData.RoomNum,
+			TotalPayInTax:   appData.TotalPayInTax,
+			CancelFee:       appData.CancelFee,
+			NoshowFee:       appData.NoshowFee,
+		})
+		if err != nil {
+			return err
+		}
+		return b.Webhook.Post(setting.WebhookURL, secret, notification.Event, notification.IdempotencyKey(), body)
+	}
+	return fmt.Errorf("%w %s", booking.ErrNotificationDisabled, notification.Channel)
+}
+
+// notificationResult 送信結果から、通知の更新内容を作成
+func notificationResult(notification *booking.HtThBookingNotifications, sendErr error, now time.Time) map[string]interface{} {
+	if sendErr == nil {
+		return map[string]interface{}{
+			"status":       booking.NotificationStatusDelivered,
+			"last_error":   "",
+			"delivered_at": now,
+		}
+	}
+	if errors.Is(sendErr, booking.ErrWebhookRejected) ||
+		errors.Is(sendErr, booking.ErrNotificationDisabled) ||
+		notification.Attempts >= booking.NotificationMaxAttempts {
+		return map[string]interface{}{
+			"status":     booking.NotificationStatusFailed,
+			"last_error": sendErr.Error(),
+		}
+	}
+	return map[string]interface{}{
+		"status":          booking.NotificationStatusPending,
+		"last_error":      sendErr.Error(),
+		"next_attempt_at": now.Add(booking.NotificationRetryInterval(notification.Attempts)),
+	}
+}
+
+// notificationMail 施設向けの予約通知メールを作成（施設の設定が日本語以外の場合は英語）
+func notificationMail(notification *booking.HtThBookingNotifications, appData *booking.HtThApplications, guestName string, langCd string) *mail.Message {
+	message := &mail.Message{To: []string{notification.Recipient}}
+	cmApplicationID := strconv.FormatInt(appData.CmApplicationID, 10)
+	if langCd == "" || strings.HasPrefix(langCd, "ja") {
+		title, lead := "新規予約", "新しい予約が入りました。"
+		switch notification.Event {
+		case booking.NotificationEventCanceled:
+			title, lead = "キャンセル", "予約がキャンセルされました。"
+		case booking.NotificationEventNoShow:
+			title, lead = "NoShow", "予約がNoShowとして登録されました。"
+		}
+		message.Subject = "【" + title + "】予約番号 " + cmApplicationID
+		message.Body = fmt.Sprintf("%s\n\n"+
+			"予約番号：%d\n宿泊者：%s 様\nチェックイン：%s\nチェックアウト：%s（%d泊）\n部屋数：%d\n合計金額：%.0f円\n",
+			lead, appData.CmApplicationID, guestName, appData.Arrival, appData.Departure, appData.Stays, appData.RoomNum, appData.TotalPayInTax)
+		switch notification.Event {
+		case booking.NotificationEventCanceled:
+			message.Body += fmt.Sprintf("キャンセル料：%.0f円\n", appData.CancelFee)
+		case booking.NotificationEventNoShow:
+			message.Body += fmt.Sprintf("NoShow料金：%.0f円\n", appData.NoshowFee)
+		}
+		return message
+	}
+
+	title, lead := "New booking", "A new booking has been made."
+	switch notification.Event {
+	case booking.NotificationEventCanceled:
+		title, lead = "Cancellation", "A booking has been cancelled."
+	case booking.NotificationEventNoShow:
+		title, lead = "No-show", "A booking has been marked as a no-show."
+	}
+	message.Subject = "[" + title + "] Booking No. " + cmApplicationID
+	message.Body = fmt.Sprintf("%s\n\n"+
+		"Booking No.: %d\nGuest: %s\nCheck-in: %s\nCheck-out: %s (%d nights)\nRooms: %d\nTotal: JPY %.0f\n",
+		lead, appData.CmApplicationID, guestName, appData.Arrival, appData.Departure, appData.Stays, appData.RoomNum, appData.TotalPayInTax)
+	switch notification.Event {
+	case booking.NotificationEventCanceled:
+		message.Body += fmt.Sprintf("Cancellation fee: JPY %.0f\n", appData.CancelFee)
+	case booking.NotificationEventNoShow:
+		message.Body += fmt.Sprintf("No-show fee: JPY %.0f\n", appData.NoshowFee)
+	}
+	return message
+}
+
+// guestFullName 予約者の氏名（復号して姓・名の順に連結）
+func guestFullName(appData *booking.HtThApplications) (string, error) {
+	familyName, err := utils.Decrypt(appData.FamilyNameEnc)
+	if err != nil {
+		return "", err
+	}
+	givenName, err := utils.Decrypt(appData.GivenNameEnc)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(familyName + " " + givenName), nil
+}
+
+// newNotificationSettingOutput 予約通知の設定の出力を生成
+func newNotificationSettingOutput(setting *booking.HtTmBookingNotificationSettings) *booking.NotificationSettingOutput {
+	return &booking.NotificationSettingOutput{
+		PropertyID: setting.PropertyID,
+		Emails:     settingEmails(setting),
+		LangCd:     setting.LangCd,
+		WebhookURL: setting.WebhookURL,
+		Enabled:    setting.Enabled,
+	}
+}
+
+// settingEmails 設定の通知先メールアドレス
+func settingEmails(setting *booking.HtTmBookingNotificationSettings) []string {
+	if setting.Emails == "" {
+		return []string{}
+	}
+	return strings.Split(setting.Emails, ",")
+}
+
+// uniqueEmails 前後の空白を除き、重複を除いたメールアドレス
+func uniqueEmails(emails []string) []string {
+	result := []string{}
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" || containsString(result, email) {
+			continue
+		}
+		result = append(result, email)
+	}
+	return result
+}
+
+// containsString 文字列がスライスに含まれるか
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationWorkerInterval 新しい予約・送信待ちの通知を確認する間隔
+// BOOKING_NOTIFICATION_WORKER_INTERVAL（例: 30s）で変更できる
+func NotificationWorkerInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("BOOKING_NOTIFICATION_WORKER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return booking.DefaultNotificationWorkerInterval
+	}
+	return interval
+}
+
+// RunNotificationWorker ctxが終了するまで、interval毎に新しい予約・キャンセルを確認し、送信待ちの通知を送信する
+func RunNotificationWorker(ctx context.Context, bUsecase booking.IBookingUsecase, interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := bUsecase.ScanBookingEvents(); err != nil {
+			logger.Error(err)
+		}
+		if _, err := bUsecase.DispatchNotifications(); err != nil {
+			logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
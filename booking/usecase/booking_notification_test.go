@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+func Test_notificationsFor(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	setting := &booking.HtTmBookingNotificationSettings{
+		PropertyID: 100,
+		Emails:     "front@example.com,owner@example.com",
+		WebhookURL: "https://example.com/hook",
+		Enabled:    true,
+	}
+
+	t.Run("メールアドレスごととWebhookの通知を作成", func(t *testing.T) {
+		notifications := notificationsFor(setting, 123, booking.NotificationEventCreated, now)
+		if len(notifications) != 3 {
+			t.Fatalf("通知の件数が一致しません。%v", notifications)
+		}
+		channels := []string{booking.NotificationChannelEmail, booking.NotificationChannelEmail, booking.NotificationChannelWebhook}
+		recipients := []string{"front@example.com", "owner@example.com", "https://example.com/hook"}
+		for i, notification := range notifications {
+			if notification.Channel != channels[i] || notification.Recipient != recipients[i] {
+				t.Fatalf("送信先が一致しません。%v", notification)
+			}
+			if notification.PropertyID != 100 || notification.CmApplicationID != 123 || notification.Event != booking.NotificationEventCreated {
+				t.Fatalf("通知の内容が一致しません。%v", notification)
+			}
+			if notification.Status != booking.NotificationStatusPending || notification.NextAttemptAt != now {
+				t.Fatalf("送信待ちになっていません。%v", notification)
+			}
+		}
+	})
+
+	t.Run("無効・未設定の場合は通知しない", func(t *testing.T) {
+		disabled := *setting
+		disabled.Enabled = false
+		if notifications := notificationsFor(&disabled, 123, booking.NotificationEventCreated, now); len(notifications) != 0 {
+			t.Fatalf("無効の設定で通知が作成されました。%v", notifications)
+		}
+		if notifications := notificationsFor(nil, 123, booking.NotificationEventCreated, now); len(notifications) != 0 {
+			t.Fatalf("未設定の施設で通知が作成されました。%v", notifications)
+		}
+	})
+}
+
+func Test_notificationResult(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		attempts int
+		sendErr  error
+		status   string
+		retryAt  time.Time
+	}{
+		{"成功した場合は送信済み", 1, nil, booking.NotificationStatusDelivered, time.Time{}},
+		{"失敗した場合は間隔を空けて再送", 1, errors.New("Error: timeout"), booking.NotificationStatusPending, now.Add(time.Minute)},
+		{"再送のたびに間隔を倍にする", 3, errors.New("Error: timeout"), booking.NotificationStatusPending, now.Add(4 * time.Minute)},
+		{"最大送信回数に達したら打ち切り", booking.NotificationMaxAttempts, errors.New("Error: timeout"), booking.NotificationStatusFailed, time.Time{}},
+		{"Webhookが受け付けない場合は打ち切り", 1, fmt.Errorf("%w %d", booking.ErrWebhookRejected, 404), booking.NotificationStatusFailed, time.Time{}},
+		{"送信先が設定から外れた場合は打ち切り", 1, booking.ErrNotificationDisabled, booking.NotificationStatusFailed, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := notificationResult(&booking.HtThBookingNotifications{Attempts: tt.attempts}, tt.sendErr, now)
+			if values["status"] != tt.status {
+				t.Fatalf("ステータスが一致しません。%v", values)
+			}
+			if retryAt, ok := values["next_attempt_at"]; ok && retryAt != tt.retryAt {
+				t.Fatalf("再送時刻が一致しません。%v", values)
+			}
+		})
+	}
+}
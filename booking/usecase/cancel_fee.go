@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// CalculateCancelFee キャンセルポリシーに基づいて、部屋・泊ごとのキャンセル料を計算する
+func (b *bookingUsecase) CalculateCancelFee(req booking.CancelFeeInput) (*booking.CancelFeeOutput, error) {
+	cancelAt := time.Now()
+	if req.CancelAt != "" {
+		t, err := time.Parse(time.RFC3339, req.CancelAt)
+		if err != nil {
+			return nil, booking.ErrInvalidCancelAt
+		}
+		cancelAt = t
+	}
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := b.propertyLocation(appData.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	noShow := req.Noshow == 1
+
+	response := &booking.CancelFeeOutput{
+		CmApplicationID: appData.CmApplicationID,
+		CancelAt:        cancelAt.In(loc),
+		TimeZone:        loc.String(),
+		TotalPayInTax:   int(appData.TotalPayInTax),
+		Items:           []cancelPolicy.FeeItem{},
+	}
+	// キャンセル済みの予約は、NoShowの場合を除きキャンセル料の計算対象外
+	if appData.CancelFlg && !noShow {
+		return response, nil
+	}
+
+	bookingRooms, err := b.BRepository.FetchBookingRoomsByApplicationID(appData.HtThApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	bookingPrices, err := b.BRepository.FetchBookingPriceData([]int64{appData.CmApplicationID})
+	if err != nil {
+		return nil, err
+	}
+	if response.Items, err = b.cancelFeeItems(&appData, bookingRooms, bookingPrices, nil, response.CancelAt, noShow); err != nil {
+		return nil, err
+	}
+	response.Fee = sumFee(response.Items, response.TotalPayInTax)
+	return response, nil
+}
+
+// propertyLocation 施設のタイムゾーン
+func (b *bookingUsecase) propertyLocation(propertyID int64) (*time.Location, error) {
+	property, err := b.FRepository.FetchProperty(propertyID)
+	if err != nil {
+		return nil, err
+	}
+	return utils.PropertyLocation(property.BilCountryCode), nil
+}
+
+// cancelFeeItems 部屋・泊ごとのキャンセル料を計算する。targets（料金情報ID）を指定した場合は、その部屋・泊のみ計算する
+// cancelAtは施設のタイムゾーンの時刻を渡すこと
+func (b *bookingUsecase) cancelFeeItems(appData *booking.HtThApplications, bookingRooms []booking.HtThBookingRooms, bookingPrices []booking.HtThBookingPrices, targets map[int64]bool, cancelAt time.Time, noShow bool) ([]cancelPolicy.FeeItem, error) {
+	loc := cancelAt.Location()
+	arrival, err := time.ParseInLocation(DateFormat, firstN(appData.Arrival, len(DateFormat)), loc)
+	if err != nil {
+		return nil, err
+	}
+	planIDs, nights := feeNights(bookingRooms, bookingPrices, targets, loc)
+	if len(nights) == 0 && targets == nil {
+		// 料金の内訳がない予約は、合計金額をチェックイン日の1泊分として扱う
+		var planID int64
+		if len(bookingRooms) > 0 {
+			planID, _ = strconv.ParseInt(bookingRooms[0].RateID, 10, 64)
+		}
+		planIDs = []int64{planID}
+		nights = map[int64][]cancelPolicy.FeeNight{
+			planID: {{RoomIndex: 0, UseDate: arrival, Amount: int(appData.TotalPayInTax)}},
+		}
+	}
+
+	settings, err := b.planCancelSettings(int(appData.WholesalerID), appData.PropertyID, planIDs)
+	if err != nil {
+		return nil, err
+	}
+	// 返金不可で予約された場合は、ポリシーに関わらず全額
+	refundable := len(bookingRooms) == 0 || bookingRooms[0].Refundable
+	items := []cancelPolicy.FeeItem{}
+	for _, planID := range planIDs {
+		setting, ok := settings[planID]
+		switch {
+		case !refundable:
+			items = append(items, cancelPolicy.CalculateFee(&cancelPolicy.Settings{NonRefundable: 1}, planID, arrival, nights[planID], cancelAt, noShow)...)
+		case ok:
+			items = append(items, cancelPolicy.CalculateFee(setting, planID, arrival, nights[planID], cancelAt, noShow)...)
+		default:
+			// キャンセルポリシーが設定されていない場合は、予約時に保存したキャンセルペナルティーを使う
+			legacyItems, lErr := legacyCancelFee(bookingRooms, planID, arrival, nights[planID], cancelAt, noShow)
+			if lErr != nil {
+				return nil, lErr
+			}
+			items = append(items, legacyItems...)
+		}
+	}
+	return items, nil
+}
+
+// sumFee キャンセル料の内訳を合計する（maxFeeを上限とする）
+func sumFee(items []cancelPolicy.FeeItem, maxFee int) int {
+	fee := 0
+	for _, item := range items {
+		fee += item.Fee
+	}
+	if fee > maxFee {
+		return maxFee
+	}
+	return fee
+}
+
+// planCancelSettings プランごとのキャンセルポリシー。プランに割り当てがない場合は施設のデフォルトのキャンセルポリシー
+// どちらも設定されていないプランは含まない
+func (b *bookingUsecase) planCancelSettings(wholesalerID int, propertyID int64, planIDs []int64) (map[int64]*cancelPolicy.Settings, error) {
+	assigned, err := b.CPRepository.FetchAssignedPlanCancelPolicies(wholesalerID, propertyID, planIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := map[int64]*cancelPolicy.Settings{}
+	var propertySettings *cancelPolicy.Settings
+	propertyFetched := false
+	for _, planID := range planIDs {
+		if policy, ok := assigned[planID]; ok {
+			settings, pErr := cancelPolicy.ParseSettings(policy.CancelPenaltyJSON)
+			if pErr != nil {
+				return nil, pErr
+			}
+			result[planID] = settings
+			continue
+		}
+		if !propertyFetched {
+			propertyFetched = true
+			cancelPenaltyJSON, fErr := b.CPRepository.FetchPropertyCancelPenaltyJSON(wholesalerID, propertyID)
+			if fErr != nil {
+				return nil, fErr
+			}
+			if cancelPenaltyJSON != "" {
+				if propertySettings, fErr = cancelPolicy.ParseSettings(cancelPenaltyJSON); fErr != nil {
+					return nil, fErr
+				}
+			}
+		}
+		if propertySettings != nil {
+			result[planID] = propertySettings
+		}
+	}
+	return result, nil
+}
+
+// feeNights 予約料金情報をプランごとの部屋・泊に分ける（部屋が分からない料金はRoomIndexを-1とする）
+// targets（料金情報ID）を指定した場合は、その部屋・泊のみ
+func feeNights(bookingRooms []booking.HtThBookingRooms, bookingPrices []booking.HtThBookingPrices, targets map[int64]bool, loc *time.Location) ([]int64, map[int64][]cancelPolicy.FeeNight) {
+	planIDs := []int64{}
+	nights := map[int64][]cancelPolicy.FeeNight{}
+	roomIndexes := booking.RoomIndexes(bookingRooms, bookingPrices)
+	for i, bookingPrice := range bookingPrices {
+		if targets != nil && !targets[bookingPrice.HtThBookingPriceID] {
+			continue
+		}
+		t, err := time.ParseInLocation(DateFormat, bookingPrice.UseDate.Format(DateFormat), loc)
+		if err != nil {
+			continue
+		}
+		if _, ok := nights[bookingPrice.PlanID]; !ok {
+			planIDs = append(planIDs, bookingPrice.PlanID)
+		}
+		nights[bookingPrice.PlanID] = append(nights[bookingPrice.PlanID], cancelPolicy.FeeNight{
+			RoomIndex: roomIndexes[i],
+			UseDate:   t,
+			Amount:    bookingPrice.AmountInTax(),
+		})
+	}
+	return planIDs, nights
+}
+
+// legacyCancelFee 予約時に保存したキャンセルペナルティー（期間ごとの料率）からキャンセル料を計算する
+// 部屋が複数の場合も、キャンセルペナルティーは同一
+func legacyCancelFee(bookingRooms []booking.HtThBookingRooms, planID int64, arrival time.Time, nights []cancelPolicy.FeeNight, cancelAt time.Time, noShow bool) ([]cancelPolicy.FeeItem, error) {
+	daysBefore := cancelPolicy.DaysBefore(arrival, cancelAt.In(arrival.Location()))
+	rate, rule := 0, cancelPolicy.FeeRuleNone
+	if noShow {
+		rate, rule = 100, cancelPolicy.FeeRuleNoShow
+	} else if len(bookingRooms) > 0 && bookingRooms[0].CancelPenalties != "" {
+		penalties := []booking.CancelPolicy{}
+		if err := json.Unmarshal([]byte(bookingRooms[0].CancelPenalties), &penalties); err != nil {
+			return nil, err
+		}
+		for _, penalty := range penalties {
+			start, sErr := parsePenaltyTime(penalty.Start)
+			end, eErr := parsePenaltyTime(penalty.End)
+			if sErr != nil || cancelAt.Before(start) || (penalty.End != "" && (eErr != nil || cancelAt.After(end))) {
+				continue
+			}
+			percent, err := strconv.Atoi(strings.TrimRight(penalty.Percent, "%"))
+			if err != nil {
+				return nil, err
+			}
+			rate, rule = percent, cancelPolicy.FeeRuleLegacy
+			break
+		}
+	}
+	items := []cancelPolicy.FeeItem{}
+	for _, night := range nights {
+		items = append(items, cancelPolicy.NewFeeItem(night, planID, daysBefore, rate, rule))
+	}
+	return items, nil
+}
+
+// parsePenaltyTime キャンセルペナルティーの期間の日時を読み込む（タイムゾーンの指定がない場合は日本時間）
+func parsePenaltyTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(TimeFormat, value, time.Local)
+}
+
+// firstN 文字列の先頭n文字（n文字未満の場合はそのまま）
+func firstN(value string, n int) string {
+	if len(value) < n {
+		return value
+	}
+	return value[:n]
+}
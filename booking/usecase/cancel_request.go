@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/labstack/echo/v4"
+)
+
+// CancelBooking 予約キャンセル
+// キャンセル依頼を送信待ちとして登録し、adminへの送信はDispatchCancelRequestsで行う
+// 手入力の予約はadminにないため、HM APIでキャンセルする
+// 入力されたキャンセル料は、キャンセルポリシーによるキャンセル料を上限とする
+func (b *bookingUsecase) CancelBooking(hmUser *account.HtTmHotelManager, req booking.CancelInput) (*booking.CancelRequestOutput, error) {
+	// 送信待ち・送信中の依頼があれば、同じ依頼として扱う（二重送信しない）
+	active, err := b.BCancelRepository.FetchActiveCancelRequest(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return booking.NewCancelRequestOutput(active), nil
+	}
+
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if appData.CancelFlg {
+		return nil, booking.ErrAlreadyCanceled
+	}
+	cancelFee, err := b.CalculateCancelFee(booking.CancelFeeInput{CmApplicationID: req.CmApplicationID, Noshow: req.Noshow})
+	if err != nil {
+		return nil, err
+	}
+	if req.CancelFee > int64(cancelFee.Fee) {
+		return nil, booking.ErrCancelFeeExceedsPolicy
+	}
+
+	if booking.IsManualApplicationID(appData.CmApplicationID) {
+		return b.cancelManualBooking(hmUser, req)
+	}
+
+	now := time.Now()
+	cancelRequest := &booking.HtThBookingCancelRequests{
+		CmApplicationID: req.CmApplicationID,
+		HotelManagerID:  hmUser.HotelManagerID,
+		CancelFee:       req.CancelFee,
+		Noshow:          req.Noshow,
+		Status:          booking.CancelStatusPending,
+		NextAttemptAt:   now,
+	}
+	cancelRequest.CreatedAt = now
+	cancelRequest.UpdatedAt = now
+	if err := b.BCancelRepository.CreateCancelRequest(cancelRequest); err != nil {
+		return nil, err
+	}
+	return booking.NewCancelRequestOutput(cancelRequest), nil
+}
+
+// cancelManualBooking 手入力の予約をキャンセルし、引き当てた直仕入れの在庫を戻す
+// キャンセルの履歴は、adminへの依頼と同じく送信済みのキャンセル依頼として登録する
+func (b *bookingUsecase) cancelManualBooking(hmUser *account.HtTmHotelManager, req booking.CancelInput) (*booking.CancelRequestOutput, error) {
+	bookingPrices, err := b.BRepository.FetchBookingPriceData([]int64{req.CmApplicationID})
+	if err != nil {
+		return nil, err
+	}
+	cancel := &booking.ManualBookingCancel{
+		CmApplicationID:  req.CmApplicationID,
+		CancelFee:        req.CancelFee,
+		Noshow:           req.Noshow,
+		UpdateOperatorID: hmUser.HotelManagerID,
+		Stocks:           stockChanges(bookingPrices, nil),
+		CancelRequest: &booking.HtThBookingCancelRequests{
+			CmApplicationID: req.CmApplicationID,
+			HotelManagerID:  hmUser.HotelManagerID,
+			CancelFee:       req.CancelFee,
+			Noshow:          req.Noshow,
+			Status:          booking.CancelStatusCompleted,
+		},
+	}
+	if err := b.BManualRepository.CancelManualBooking(cancel); err != nil {
+		return nil, err
+	}
+	return booking.NewCancelRequestOutput(cancel.CancelRequest), nil
+}
+
+// DispatchCancelRequests 送信時刻を過ぎたキャンセル依頼（予約全体・一部）をadminへ送信し、送信した件数を返す
+// 失敗した依頼は間隔を空けて再送し、CancelMaxAttempts回失敗するか、adminが受け付けなかった場合は打ち切る
+func (b *bookingUsecase) DispatchCancelRequests() (int, error) {
+	now := time.Now()
+	cancelRequests, err := b.BCancelRepository.FetchDueCancelRequests(now, booking.CancelDispatchBatchSize, b.BAPI.PartialCancelEnabled())
+	if err != nil {
+		return 0, err
+	}
+	dispatched := 0
+	for _, cancelRequest := range cancelRequests {
+		claimed, err := b.BCancelRepository.ClaimCancelRequest(cancelRequest.BookingCancelRequestID, now, now.Add(booking.CancelLease))
+		if err != nil {
+			return dispatched, err
+		}
+		if !claimed {
+			continue
+		}
+		cancelRequest.Attempts++
+		dispatched++
+		sendErr := b.BAPI.CancelBooking(&cancelRequest)
+		if err := b.BCancelRepository.UpdateCancelRequest(cancelRequest.BookingCancelRequestID, cancelResult(&cancelRequest, sendErr, time.Now())); err != nil {
+			return dispatched, err
+		}
+	}
+	return dispatched, nil
+}
+
+// cancelResult 送信結果から、キャンセル依頼の更新内容を作成
+func cancelResult(cancelRequest *booking.HtThBookingCancelRequests, sendErr error, now time.Time) map[string]interface{} {
+	if sendErr == nil {
+		return map[string]interface{}{
+			"status":       booking.CancelStatusCompleted,
+			"last_error":   "",
+			"completed_at": now,
+		}
+	}
+	if errors.Is(sendErr, booking.ErrCancelRejected) || cancelRequest.Attempts >= booking.CancelMaxAttempts {
+		return map[string]interface{}{
+			"status":     booking.CancelStatusFailed,
+			"last_error": sendErr.Error(),
+		}
+	}
+	return map[string]interface{}{
+		"status":          booking.CancelStatusPending,
+		"last_error":      sendErr.Error(),
+		"next_attempt_at": now.Add(booking.CancelRetryInterval(cancelRequest.Attempts)),
+	}
+}
+
+// CancelWorkerInterval 送信待ちを確認する間隔
+// BOOKING_CANCEL_WORKER_INTERVAL（例: 10s）で変更できる
+func CancelWorkerInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("BOOKING_CANCEL_WORKER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return booking.DefaultCancelWorkerInterval
+	}
+	return interval
+}
+
+// RunCancelWorker ctxが終了するまで、interval毎に送信待ちのキャンセル依頼を送信する
+func RunCancelWorker(ctx context.Context, bUsecase booking.IBookingUsecase, interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := bUsecase.DispatchCancelRequests(); err != nil {
+			logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
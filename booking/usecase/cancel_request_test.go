@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+func Test_cancelResult(t *testing.T) {
+	now := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		attempts int
+		sendErr  error
+		status   string
+		retryAt  time.Time
+	}{
+		{"成功した場合はキャンセル済み", 1, nil, booking.CancelStatusCompleted, time.Time{}},
+		{"失敗した場合は間隔を空けて再送", 1, errors.New("Error: timeout"), booking.CancelStatusPending, now.Add(30 * time.Second)},
+		{"再送のたびに間隔を倍にする", 3, errors.New("Error: timeout"), booking.CancelStatusPending, now.Add(2 * time.Minute)},
+		{"最大送信回数に達したら打ち切り", booking.CancelMaxAttempts, errors.New("Error: timeout"), booking.CancelStatusFailed, time.Time{}},
+		{"adminが受け付けない場合は打ち切り", 1, fmt.Errorf("%w %s", booking.ErrCancelRejected, "予約が見つかりません。"), booking.CancelStatusFailed, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := cancelResult(&booking.HtThBookingCancelRequests{Attempts: tt.attempts}, tt.sendErr, now)
+			if values["status"] != tt.status {
+				t.Fatalf("ステータスが一致しません。%v", values)
+			}
+			if retryAt, ok := values["next_attempt_at"]; ok && retryAt != tt.retryAt {
+				t.Fatalf("再送時刻が一致しません。%v", values)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/plan"
+	"github.com/Adventureinc/hotel-hm-api/src/price"
+)
+
+// directQuote 直仕入れの料金での見積もり結果
+type directQuote struct {
+	// prices 見積もった料金情報（部屋と紐づけて登録する）
+	prices []booking.HtThBookingPrices
+	// bookingRooms 人数・部屋名・プラン名を見積もった内容にした部屋情報
+	bookingRooms []booking.HtThBookingRooms
+	// roomIndexes 料金情報ごとの部屋（bookingRoomsの添字）
+	roomIndexes []int
+	rooms       []booking.QuoteRoomOutput
+}
+
+// quoteDirectStay 宿泊日と部屋ごとのプラン・人数で、直仕入れの料金と子供料金設定から料金情報を作成
+// roomsはbookingRoomsと同じ順に指定し、新規の予約の場合は登録前の部屋情報（部屋IDは0）を渡す
+func (b *bookingUsecase) quoteDirectStay(propertyID int64, cmApplicationID int64, dates []string, bookingRooms []booking.HtThBookingRooms, rooms []booking.BookingRoomInput) (*directQuote, error) {
+	planIDs := []int64{}
+	for _, room := range rooms {
+		planIDs = append(planIDs, room.PlanID)
+	}
+	plans, err := b.PlanDirectRepository.FetchList(planIDs)
+	if err != nil {
+		return nil, err
+	}
+	planMap := map[int64]plan.HtTmPlanDirects{}
+	roomTypeIDs := []int64{}
+	for _, planData := range plans {
+		if planData.PropertyID != propertyID {
+			continue
+		}
+		planMap[planData.PlanID] = planData
+		roomTypeIDs = append(roomTypeIDs, planData.RoomTypeID)
+	}
+	roomTypes, err := b.RoomDirectRepository.FetchRoomListByRoomTypeID(roomTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+	roomTypeMap := map[int64]int{}
+	for i, roomType := range roomTypes {
+		roomTypeMap[roomType.RoomTypeID] = i
+	}
+	childRates, err := b.PriceDirectRepository.FetchChildRatesByPlanIDList(planIDs)
+	if err != nil {
+		return nil, err
+	}
+	receives := map[int64][6]bool{}
+	for _, childRate := range childRates {
+		if childRate.ChildRateType < 1 || childRate.ChildRateType > 6 {
+			continue
+		}
+		receive := receives[childRate.PlanID]
+		receive[childRate.ChildRateType-1] = childRate.Receive
+		receives[childRate.PlanID] = receive
+	}
+	prices, err := b.PriceDirectRepository.FetchAllByPlanIDList(planIDs, dates[0], dates[len(dates)-1])
+	if err != nil {
+		return nil, err
+	}
+	// プランID・日付・人数（rate_type_code）ごとの料金
+	priceMap := map[string]price.HtTmPriceDirects{}
+	for _, priceData := range prices {
+		priceMap[fmt.Sprintf("%d_%s_%s", priceData.PlanID, priceData.UseDate.Format(DateFormat), priceData.RateTypeCode)] = priceData
+	}
+
+	result := &directQuote{}
+	for roomIndex, bookingRoom := range bookingRooms {
+		room := rooms[roomIndex]
+		planData, ok := planMap[room.PlanID]
+		if !ok {
+			return nil, booking.ErrBookingPlan
+		}
+		if (planData.MinStayCategory && len(dates) < int(planData.MinStayNum)) ||
+			(planData.MaxStayCategory && len(dates) > int(planData.MaxStayNum)) {
+			return nil, booking.ErrBookingStay
+		}
+		roomTypeIndex, ok := roomTypeMap[planData.RoomTypeID]
+		if !ok {
+			return nil, booking.ErrBookingPlan
+		}
+		roomType := roomTypes[roomTypeIndex]
+		children := room.Children()
+		persons := room.NumberOfAdults
+		for i, child := range children {
+			if child > 0 && !receives[room.PlanID][i] {
+				return nil, booking.ErrBookingOccupancy
+			}
+			persons += child
+		}
+		if persons < roomType.OcuMin || persons > roomType.OcuMax {
+			return nil, booking.ErrBookingOccupancy
+		}
+
+		quotedRoom := bookingRoom
+		quotedRoom.NumberOfAdults = room.NumberOfAdults
+		quotedRoom.NumberOfChilds = persons - room.NumberOfAdults
+		quotedRoom.NumberOfUpperGrades = room.NumberOfUpperGrades
+		quotedRoom.NumberOfLowerGrades = room.NumberOfLowerGrades
+		quotedRoom.NumberOfInfantMealsWithBedding = room.NumberOfInfantMealsWithBedding
+		quotedRoom.NumberOfInfantMealOnly = room.NumberOfInfantMealOnly
+		quotedRoom.NumberOfInfantBeddingOnly = room.NumberOfInfantBeddingOnly
+		quotedRoom.NumberOfInfantMealsWithoutBedding = room.NumberOfInfantMealsWithoutBedding
+		quotedRoom.RoomName = roomType.Name
+		quotedRoom.PlanName = planData.Name
+		result.bookingRooms = append(result.bookingRooms, quotedRoom)
+
+		roomOutput := booking.QuoteRoomOutput{
+			HtThBookingRoomID: bookingRoom.HtThBookingRoomID,
+			RoomTypeID:        planData.RoomTypeID,
+			PlanID:            planData.PlanID,
+			RoomName:          roomType.Name,
+			PlanName:          planData.Name,
+			Nights:            []booking.RoomNightPrice{},
+		}
+		for _, useDate := range dates {
+			priceData, ok := priceMap[fmt.Sprintf("%d_%s_%d", room.PlanID, useDate, room.NumberOfAdults)]
+			if !ok {
+				return nil, booking.ErrBookingPrice
+			}
+			bookingPrice := directBookingPrice(&priceData, room.NumberOfAdults, children)
+			bookingPrice.CmApplicationID = cmApplicationID
+			bookingPrice.HtThBookingRoomID = bookingRoom.HtThBookingRoomID
+			bookingPrice.RoomTypeID = planData.RoomTypeID
+			result.prices = append(result.prices, bookingPrice)
+			result.roomIndexes = append(result.roomIndexes, roomIndex)
+
+			night := bookingPrice.NightPrice()
+			roomOutput.AmountInTax += night.AmountInTax
+			roomOutput.Nights = append(roomOutput.Nights, night)
+		}
+		result.rooms = append(result.rooms, roomOutput)
+	}
+	return result, nil
+}
+
+// directBookingPrice 直仕入れの料金1件から、1部屋1泊分の料金情報を作成
+// 直仕入れの料金は大人料金・子供料金とも大人の人数分を掛けて登録されているため、子供料金は大人の人数で割って1人あたりに戻す
+func directBookingPrice(priceData *price.HtTmPriceDirects, adults int, children [6]int) booking.HtThBookingPrices {
+	perPerson := func(amount int) int {
+		return amount / adults
+	}
+	return booking.HtThBookingPrices{
+		UseDate:          priceData.UseDate,
+		PlanID:           priceData.PlanID,
+		Person:           adults,
+		Child1Person:     children[0],
+		Child2Person:     children[1],
+		Child3Person:     children[2],
+		Child4Person:     children[3],
+		Child5Person:     children[4],
+		Child6Person:     children[5],
+		Price:            priceData.Price,
+		PriceInTax:       priceData.PriceInTax,
+		ChildPrice1:      perPerson(priceData.ChildPrice1),
+		ChildPrice1InTax: perPerson(priceData.ChildPrice1InTax),
+		ChildPrice2:      perPerson(priceData.ChildPrice2),
+		ChildPrice2InTax: perPerson(priceData.ChildPrice2InTax),
+		ChildPrice3:      perPerson(priceData.ChildPrice3),
+		ChildPrice3InTax: perPerson(priceData.ChildPrice3InTax),
+		ChildPrice4:      perPerson(priceData.ChildPrice4),
+		ChildPrice4InTax: perPerson(priceData.ChildPrice4InTax),
+		ChildPrice5:      perPerson(priceData.ChildPrice5),
+		ChildPrice5InTax: perPerson(priceData.ChildPrice5InTax),
+		ChildPrice6:      perPerson(priceData.ChildPrice6),
+		ChildPrice6InTax: perPerson(priceData.ChildPrice6InTax),
+	}
+}
+
+// stayDates チェックイン日からチェックアウト日の前日までの宿泊日
+func stayDates(arrival string, departure string) ([]string, error) {
+	start, err := time.Parse(DateFormat, arrival)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse(DateFormat, departure)
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, booking.ErrBookingStay
+	}
+	result := []string{}
+	for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+		result = append(result, date.Format(DateFormat))
+	}
+	return result, nil
+}
+
+// stockChanges 変更前後の料金情報から、部屋タイプ・日付ごとの在庫の増減を作成（1件につき1室）
+func stockChanges(before []booking.HtThBookingPrices, after []booking.HtThBookingPrices) []booking.StockChange {
+	deltas := map[booking.StockCell]int{}
+	for _, bookingPrice := range before {
+		deltas[booking.StockCell{RoomTypeID: bookingPrice.RoomTypeID, UseDate: bookingPrice.UseDate.Format(DateFormat)}]--
+	}
+	for _, bookingPrice := range after {
+		deltas[booking.StockCell{RoomTypeID: bookingPrice.RoomTypeID, UseDate: bookingPrice.UseDate.Format(DateFormat)}]++
+	}
+	result := []booking.StockChange{}
+	for cell, delta := range deltas {
+		if delta != 0 {
+			result = append(result, booking.StockChange{RoomTypeID: cell.RoomTypeID, UseDate: cell.UseDate, Delta: delta})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RoomTypeID != result[j].RoomTypeID {
+			return result[i].RoomTypeID < result[j].RoomTypeID
+		}
+		return result[i].UseDate < result[j].UseDate
+	})
+	return result
+}
+
+// checkDirectStocks 予約数を増やす部屋タイプ・日付に在庫があるか確認（確定時はrepositoryで改めて確認する）
+// 施設側での変更のため、売止の部屋タイプ・日付でも在庫があれば変更できる
+func (b *bookingUsecase) checkDirectStocks(changes []booking.StockChange, dates []string) error {
+	roomTypeIDs := []int64{}
+	for _, change := range changes {
+		if change.Delta > 0 {
+			roomTypeIDs = append(roomTypeIDs, change.RoomTypeID)
+		}
+	}
+	if len(roomTypeIDs) == 0 {
+		return nil
+	}
+	stocks, err := b.StockDirectRepository.FetchAllByRoomTypeIDList(roomTypeIDs, dates[0], dates[len(dates)-1])
+	if err != nil {
+		return err
+	}
+	available := map[booking.StockCell]int{}
+	for _, stockData := range stocks {
+		available[booking.StockCell{RoomTypeID: stockData.RoomTypeID, UseDate: stockData.UseDate.Format(DateFormat)}] = int(stockData.Stock)
+	}
+	for _, change := range changes {
+		if change.Delta > 0 && available[booking.StockCell{RoomTypeID: change.RoomTypeID, UseDate: change.UseDate}] < change.Delta {
+			return booking.ErrBookingStock
+		}
+	}
+	return nil
+}
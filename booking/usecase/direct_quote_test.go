@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/price"
+)
+
+func Test_directBookingPrice(t *testing.T) {
+	// 大人2名の料金。子供料金は大人の人数分を掛けて登録されている
+	priceData := &price.HtTmPriceDirects{PriceTable: price.PriceTable{
+		PlanID:           1,
+		UseDate:          time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC),
+		RateTypeCode:     "2",
+		PriceInTax:       22000,
+		ChildPrice1InTax: 14000,
+		ChildPrice3InTax: 6000,
+	}}
+	got := directBookingPrice(priceData, 2, [6]int{1, 0, 2, 0, 0, 0})
+	if got.PriceInTax != 22000 || got.ChildPrice1InTax != 7000 || got.ChildPrice3InTax != 3000 {
+		t.Fatalf("料金が一致しません。%+v", got)
+	}
+	if got.AmountInTax() != 22000+7000+3000*2 {
+		t.Fatalf("合計金額が一致しません。%d", got.AmountInTax())
+	}
+}
+
+func Test_stockChanges(t *testing.T) {
+	day1 := time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+	// 部屋タイプ1の3/10・3/11泊から、部屋タイプ1の3/11泊・部屋タイプ2の3/12泊へ変更
+	before := []booking.HtThBookingPrices{{RoomTypeID: 1, UseDate: day1}, {RoomTypeID: 1, UseDate: day2}}
+	after := []booking.HtThBookingPrices{{RoomTypeID: 1, UseDate: day2}, {RoomTypeID: 2, UseDate: day3}}
+	got := stockChanges(before, after)
+	want := []booking.StockChange{
+		{RoomTypeID: 1, UseDate: "2021-03-10", Delta: -1},
+		{RoomTypeID: 2, UseDate: "2021-03-12", Delta: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("在庫の増減が一致しません。%+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("在庫の増減が一致しません。%+v", got)
+		}
+	}
+}
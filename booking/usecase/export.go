@@ -0,0 +1,328 @@
+package usecase
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/booking/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/export"
+	"gorm.io/gorm"
+)
+
+// bookingExportUsecase 予約一覧ファイル出力関連usecase
+type bookingExportUsecase struct {
+	BUsecase          booking.IBookingUsecase
+	BRepository       booking.IBookingRepository
+	BExportRepository booking.IBookingExportRepository
+	BExportStorage    booking.IBookingExportStorage
+}
+
+// NewBookingExportUsecase インスタンス生成
+func NewBookingExportUsecase(hotelDB *gorm.DB) booking.IBookingExportUsecase {
+	return &bookingExportUsecase{
+		BUsecase:          NewBookingUsecase(hotelDB),
+		BRepository:       infra.NewBookingRepository(hotelDB),
+		BExportRepository: infra.NewBookingExportRepository(hotelDB),
+		BExportStorage:    infra.NewBookingExportStorage(),
+	}
+}
+
+// PrepareExport 出力項目を確認し、バックグラウンドで作成すべき件数か判定する
+// 件数の上限は BOOKING_EXPORT_SYNC_MAX_ROWS（未設定の場合は DefaultExportSyncMaxRows）
+func (b *bookingExportUsecase) PrepareExport(req *booking.ExportInput) (bool, error) {
+	if _, _, err := booking.ExportHeaders(req.Columns); err != nil {
+		return false, err
+	}
+	search := req.SearchInput
+	if err := encryptSearchConditions(&search); err != nil {
+		return false, err
+	}
+	total, err := b.BRepository.CountBookings(search)
+	if err != nil {
+		return false, err
+	}
+	maxRows, err := strconv.ParseInt(os.Getenv("BOOKING_EXPORT_SYNC_MAX_ROWS"), 10, 64)
+	if err != nil || maxRows <= 0 {
+		maxRows = booking.DefaultExportSyncMaxRows
+	}
+	return total > maxRows, nil
+}
+
+// Export 予約一覧をファイル形式で書き込み、出力した件数を返す
+// 件数が多くてもメモリに溜めないよう、ExportBatchSize件ずつ取得して書き込む
+func (b *bookingExportUsecase) Export(hmUser *account.HtTmHotelManager, req *booking.ExportInput, w io.Writer) (int64, error) {
+	columns, headers, err := booking.ExportHeaders(req.Columns)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := export.NewWriter(req.Format, w)
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.Write(headers); err != nil {
+		return 0, err
+	}
+
+	search := req.SearchInput
+	search.Cursor = ""
+	search.Paging = common.Paging{Limit: booking.ExportBatchSize}
+	if err := encryptSearchConditions(&search); err != nil {
+		return 0, err
+	}
+	var count int64
+	var cursor *booking.SearchCursor
+	for {
+		rows, err := b.BRepository.FetchBookings(search, cursor)
+		if err != nil {
+			return count, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		cmApplicationIDs := []int64{}
+		for _, row := range rows {
+			cmApplicationIDs = append(cmApplicationIDs, row.CmApplicationID)
+		}
+		details, err := b.BUsecase.BookingDownloads(hmUser, nil, booking.DownloadInput{
+			CmApplicationIDs: cmApplicationIDs,
+			PropertyID:       search.PropertyID,
+			WholesalerID:     search.WholesalerID,
+		})
+		if err != nil {
+			return count, err
+		}
+		bookingPrices, err := b.BRepository.FetchBookingPriceData(cmApplicationIDs)
+		if err != nil {
+			return count, err
+		}
+
+		detailsByID := map[int64]booking.BookingDownloadOutput{}
+		for _, detail := range details {
+			detailsByID[detail.CmApplicationID] = detail
+		}
+		pricesByID := map[int64][]booking.HtThBookingPrices{}
+		for _, bookingPrice := range bookingPrices {
+			pricesByID[bookingPrice.CmApplicationID] = append(pricesByID[bookingPrice.CmApplicationID], bookingPrice)
+		}
+		// 検索結果の並び順で出力する
+		for _, row := range rows {
+			detail, ok := detailsByID[row.CmApplicationID]
+			if !ok {
+				continue
+			}
+			if err := writer.Write(exportRecord(columns, &detail, pricesByID[row.CmApplicationID])); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(rows) < booking.ExportBatchSize {
+			break
+		}
+		next := rows[len(rows)-1].CursorOf(search.SortOrDefault())
+		cursor = &next
+	}
+	return count, writer.Close()
+}
+
+// StartExport バックグラウンドでのファイル作成を登録
+func (b *bookingExportUsecase) StartExport(hmUser *account.HtTmHotelManager, req *booking.ExportInput) (*booking.HtThBookingExports, error) {
+	now := time.Now()
+	bookingExport := &booking.HtThBookingExports{
+		HotelManagerID: hmUser.HotelManagerID,
+		PropertyID:     req.PropertyID,
+		Format:         req.Format,
+		Status:         booking.ExportStatusProcessing,
+		ExpiresAt:      now.Add(booking.ExportTTL),
+		Times:          common.Times{CreatedAt: now, UpdatedAt: now},
+	}
+	if err := b.BExportRepository.CreateBookingExport(bookingExport); err != nil {
+		return nil, err
+	}
+	return bookingExport, nil
+}
+
+// RunExport 登録したファイルを作成してストレージに保存し、作成状況を更新する
+func (b *bookingExportUsecase) RunExport(hmUser *account.HtTmHotelManager, bookingExport *booking.HtThBookingExports, req *booking.ExportInput) error {
+	objectPath := fmt.Sprintf("booking_exports/%d/%d%s", bookingExport.PropertyID, bookingExport.BookingExportID, export.Extension(bookingExport.Format))
+	count, err := b.writeToStorage(hmUser, objectPath, req)
+	if err != nil {
+		if uErr := b.BExportRepository.UpdateBookingExport(bookingExport.BookingExportID, map[string]interface{}{
+			"status":        booking.ExportStatusFailed,
+			"error_message": err.Error(),
+		}); uErr != nil {
+			return uErr
+		}
+		return err
+	}
+	return b.BExportRepository.UpdateBookingExport(bookingExport.BookingExportID, map[string]interface{}{
+		"status":       booking.ExportStatusCompleted,
+		"object_path":  objectPath,
+		"row_count":    count,
+		"completed_at": time.Now(),
+	})
+}
+
+// FetchExport ファイル作成状況を取得。作成を依頼したアカウント以外には見せない
+func (b *bookingExportUsecase) FetchExport(hmUser *account.HtTmHotelManager, bookingExportID int64) (*booking.ExportOutput, error) {
+	bookingExport, err := b.fetchOwnExport(hmUser, bookingExportID)
+	if err != nil {
+		return nil, err
+	}
+	output := &booking.ExportOutput{
+		BookingExportID: bookingExport.BookingExportID,
+		Status:          bookingExport.Status,
+		RowCount:        bookingExport.RowCount,
+		ExpiresAt:       bookingExport.ExpiresAt,
+	}
+	if bookingExport.Status == booking.ExportStatusCompleted {
+		output.DownloadURL = fmt.Sprintf("%s/%d/download", os.Getenv("BOOKING_EXPORT_URL"), bookingExport.BookingExportID)
+	}
+	return output, nil
+}
+
+// OpenExport 作成済みのファイルを開く
+func (b *bookingExportUsecase) OpenExport(hmUser *account.HtTmHotelManager, bookingExportID int64) (io.ReadCloser, *booking.HtThBookingExports, error) {
+	bookingExport, err := b.fetchOwnExport(hmUser, bookingExportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bookingExport.Status != booking.ExportStatusCompleted {
+		return nil, nil, booking.ErrExportNotReady
+	}
+	reader, err := b.BExportStorage.NewReader(os.Getenv("GCS_BOOKING_EXPORT_BUCKET_NAME"), bookingExport.ObjectPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, bookingExport, nil
+}
+
+// fetchOwnExport ログイン中のアカウントが作成を依頼した、ダウンロード期限内のファイル作成状況を取得
+func (b *bookingExportUsecase) fetchOwnExport(hmUser *account.HtTmHotelManager, bookingExportID int64) (*booking.HtThBookingExports, error) {
+	bookingExport, err := b.BExportRepository.FetchBookingExport(bookingExportID)
+	if err != nil {
+		return nil, booking.ErrExportNotFound
+	}
+	if bookingExport.HotelManagerID != hmUser.HotelManagerID || bookingExport.ExpiresAt.Before(time.Now()) {
+		return nil, booking.ErrExportNotFound
+	}
+	return bookingExport, nil
+}
+
+// writeToStorage ファイルを作成してストレージに書き込む
+func (b *bookingExportUsecase) writeToStorage(hmUser *account.HtTmHotelManager, objectPath string, req *booking.ExportInput) (int64, error) {
+	w, err := b.BExportStorage.NewWriter(os.Getenv("GCS_BOOKING_EXPORT_BUCKET_NAME"), objectPath)
+	if err != nil {
+		return 0, err
+	}
+	count, err := b.Export(hmUser, req, w)
+	if err != nil {
+		w.Close()
+		return count, err
+	}
+	return count, w.Close()
+}
+
+// exportRecord 予約1件分の出力項目を作成
+func exportRecord(columns []string, detail *booking.BookingDownloadOutput, bookingPrices []booking.HtThBookingPrices) []string {
+	record := []string{}
+	for _, column := range columns {
+		var value string
+		switch column {
+		case "cm_application_id":
+			value = strconv.FormatInt(detail.CmApplicationID, 10)
+		case "application_cd":
+			value = detail.ApplicationCd
+		case "created_at":
+			value = detail.CreatedAt.Format(TimeFormat)
+		case "status":
+			value = booking.StatusLabels[detail.Status]
+		case "checkin":
+			value = formatExportDate(detail.Arrival)
+		case "checkout":
+			value = formatExportDate(detail.Departure)
+		case "stays":
+			value = detail.Stays
+		case "room_num":
+			value = detail.RoomNum
+		case "guest_name":
+			// GivenNameEnc・FamilyNameEncには復号済みの値が入っている
+			value = strings.TrimSpace(detail.FamilyNameEnc + " " + detail.GivenNameEnc)
+		case "phone":
+			value = detail.PhoneEnc
+		case "email":
+			value = detail.EmailEnc
+		case "rooms", "plans", "guests":
+			names := []string{}
+			for _, roomAndPlan := range detail.RoomsAndPlan {
+				switch column {
+				case "rooms":
+					names = append(names, roomAndPlan.RoomName)
+				case "plans":
+					names = append(names, roomAndPlan.PlanName)
+				default:
+					names = append(names, fmt.Sprintf("大人%d名 子供%d名", roomAndPlan.NumberOfAdults, roomAndPlan.NumberOfChilds))
+				}
+			}
+			value = strings.Join(names, " / ")
+		case "nightly_prices":
+			value = formatNightlyPrices(bookingPrices)
+		case "total_pay_in_tax":
+			value = formatExportAmount(detail.TotalPayInTax)
+		case "sale_price":
+			value = formatExportAmount(detail.SalePrice)
+		case "discount_cash_amount":
+			value = formatExportAmount(detail.DiscountCashAmount)
+		case "cancel_fee":
+			value = formatExportAmount(detail.CancelFee)
+		case "noshow_fee":
+			value = formatExportAmount(detail.NoshowFee)
+		case "canceled_dt":
+			if detail.CancelFlg && detail.CanceledDt.IsZero() == false {
+				value = detail.CanceledDt.Format(TimeFormat)
+			}
+		}
+		record = append(record, value)
+	}
+	return record
+}
+
+// formatNightlyPrices 日別料金を「日付:金額」の形式で日付順に連結する（複数部屋の場合は日付ごとに合算）
+func formatNightlyPrices(bookingPrices []booking.HtThBookingPrices) string {
+	useDates := []string{}
+	amounts := map[string]int{}
+	for _, bookingPrice := range bookingPrices {
+		useDate := bookingPrice.UseDate.Format(DateFormat)
+		if _, ok := amounts[useDate]; !ok {
+			useDates = append(useDates, useDate)
+		}
+		amounts[useDate] += bookingPrice.AmountInTax()
+	}
+	sort.Strings(useDates)
+	result := []string{}
+	for _, useDate := range useDates {
+		result = append(result, useDate+":"+strconv.Itoa(amounts[useDate]))
+	}
+	return strings.Join(result, " / ")
+}
+
+// formatExportDate DBから取得した日付を yyyy-mm-dd 形式にする
+func formatExportDate(value string) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Format(DateFormat)
+	}
+	return value
+}
+
+// formatExportAmount 金額を出力用の文字列にする
+func formatExportAmount(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
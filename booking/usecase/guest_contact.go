@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// memoTimeFormat メモに付ける追記日時のフォーマット
+const memoTimeFormat = "2006-01-02 15:04"
+
+// FetchContact 予約の連絡状況・メモ・不正利用の疑いと変更履歴を取得
+func (b *bookingUsecase) FetchContact(req booking.ContactInput) (*booking.ContactOutput, error) {
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := b.BContactRepository.FetchContactHistories(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	return &booking.ContactOutput{
+		CmApplicationID:    appData.CmApplicationID,
+		ContactStatus:      appData.ContactStatus,
+		ContactStatusLabel: booking.ContactStatusLabels[appData.ContactStatus],
+		Memo:               appData.Memo,
+		IsMisuse:           appData.IsMisuse == 1,
+		Histories:          histories,
+	}, nil
+}
+
+// UpdateContactStatus 連絡状況を変更し、変更履歴を登録
+func (b *bookingUsecase) UpdateContactStatus(hmUser *account.HtTmHotelManager, req booking.UpdateContactStatusInput) (*booking.ContactOutput, error) {
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if appData.ContactStatus == *req.ContactStatus {
+		return nil, booking.ErrContactUnchanged
+	}
+	history, err := newContactHistory(hmUser, req.CmApplicationID, booking.ContactHistoryStatus, req.Note)
+	if err != nil {
+		return nil, err
+	}
+	history.Before = strconv.Itoa(appData.ContactStatus)
+	history.After = strconv.Itoa(*req.ContactStatus)
+	if err := b.BContactRepository.UpdateContact(req.CmApplicationID, map[string]interface{}{
+		"contact_status":     *req.ContactStatus,
+		"update_operator_id": hmUser.HotelManagerID,
+	}, history); err != nil {
+		return nil, err
+	}
+	return b.FetchContact(booking.ContactInput{CmApplicationID: req.CmApplicationID})
+}
+
+// AppendMemo 予約のメモの末尾に、追記日時と作成者を付けて追記
+// これまでのメモは書き換えない
+func (b *bookingUsecase) AppendMemo(hmUser *account.HtTmHotelManager, req booking.AppendMemoInput) (*booking.ContactOutput, error) {
+	history, err := newContactHistory(hmUser, req.CmApplicationID, booking.ContactHistoryMemo, req.Memo)
+	if err != nil {
+		return nil, err
+	}
+	line := memoLine(time.Now(), history.OperatorName, req.Memo)
+	history.After = line
+	if err := b.BContactRepository.AppendMemo(req.CmApplicationID, line, hmUser.HotelManagerID, history); err != nil {
+		return nil, err
+	}
+	return b.FetchContact(booking.ContactInput{CmApplicationID: req.CmApplicationID})
+}
+
+// UpdateMisuse 不正利用の疑いを設定・解除し、理由を変更履歴に登録
+func (b *bookingUsecase) UpdateMisuse(hmUser *account.HtTmHotelManager, req booking.UpdateMisuseInput) (*booking.ContactOutput, error) {
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	isMisuse := 0
+	if *req.IsMisuse {
+		isMisuse = 1
+	}
+	if appData.IsMisuse == isMisuse {
+		return nil, booking.ErrContactUnchanged
+	}
+	history, err := newContactHistory(hmUser, req.CmApplicationID, booking.ContactHistoryMisuse, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	history.Before = strconv.Itoa(appData.IsMisuse)
+	history.After = strconv.Itoa(isMisuse)
+	if err := b.BContactRepository.UpdateContact(req.CmApplicationID, map[string]interface{}{
+		"is_misuse":          isMisuse,
+		"update_operator_id": hmUser.HotelManagerID,
+	}, history); err != nil {
+		return nil, err
+	}
+	return b.FetchContact(booking.ContactInput{CmApplicationID: req.CmApplicationID})
+}
+
+// newContactHistory 操作したHMアカウントの名前を付けた変更履歴を作成
+func newContactHistory(hmUser *account.HtTmHotelManager, cmApplicationID int64, kind string, note string) (*booking.HtThBookingContactHistories, error) {
+	lastName, err := utils.Decrypt(hmUser.LastNameEnc)
+	if err != nil {
+		return nil, err
+	}
+	firstName, err := utils.Decrypt(hmUser.FirstNameEnc)
+	if err != nil {
+		return nil, err
+	}
+	return &booking.HtThBookingContactHistories{
+		CmApplicationID: cmApplicationID,
+		Kind:            kind,
+		Note:            note,
+		HotelManagerID:  hmUser.HotelManagerID,
+		OperatorName:    strings.TrimSpace(lastName + " " + firstName),
+	}, nil
+}
+
+// memoLine メモに追記する1行（例: [2021-04-01 10:00 山田 太郎] 到着が遅れるとの連絡あり）
+// 改行・連続した空白は1つの空白にまとめる
+func memoLine(at time.Time, author string, memo string) string {
+	memo = strings.Join(strings.Fields(memo), " ")
+	return fmt.Sprintf("[%s %s] %s", at.Format(memoTimeFormat), author, memo)
+}
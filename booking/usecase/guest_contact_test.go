@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_memoLine(t *testing.T) {
+	at := time.Date(2021, 4, 1, 10, 5, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		memo string
+		want string
+	}{
+		{"追記日時と作成者を付ける", "到着が遅れるとの連絡あり", "[2021-04-01 10:05 山田 太郎] 到着が遅れるとの連絡あり"},
+		{"改行・連続した空白は1つにまとめる", " 20時頃到着\r\n  駐車場希望 ", "[2021-04-01 10:05 山田 太郎] 20時頃到着 駐車場希望"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memoLine(at, "山田 太郎", tt.memo); got != tt.want {
+				t.Fatalf("追記する内容が一致しません。got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
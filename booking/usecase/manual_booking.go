@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+)
+
+// CreateManualBooking 電話・直接来館などで受けた直仕入れの予約を手入力で登録
+// 直仕入れの料金・子供料金設定で料金を計算し、在庫を引き当てる。登録した予約は予約サイトの予約と同じく一覧・詳細・CSVに表示される
+func (b *bookingUsecase) CreateManualBooking(hmUser *account.HtTmHotelManager, req booking.ManualBookingInput) (*booking.ManualBookingOutput, error) {
+	if req.WholesalerID != utils.WholesalerIDDirect {
+		return nil, booking.ErrManualBookingWholesaler
+	}
+	loc, err := b.propertyLocation(req.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Arrival < time.Now().In(loc).Format(DateFormat) {
+		return nil, booking.ErrBookingStay
+	}
+	dates, err := stayDates(req.Arrival, req.Departure)
+	if err != nil {
+		return nil, err
+	}
+
+	familyName := strings.TrimSpace(req.FamilyName)
+	givenName := strings.TrimSpace(req.GivenName)
+	familyNameEnc, err := utils.Encrypt(familyName)
+	if err != nil {
+		return nil, err
+	}
+	givenNameEnc, err := utils.Encrypt(givenName)
+	if err != nil {
+		return nil, err
+	}
+	bookingRooms := make([]booking.HtThBookingRooms, len(req.Rooms))
+	for i := range bookingRooms {
+		bookingRooms[i].FamilyNameEnc = familyNameEnc
+		bookingRooms[i].GivenNameEnc = givenNameEnc
+		bookingRooms[i].Refundable = true
+	}
+	quote, err := b.quoteDirectStay(req.PropertyID, 0, dates, bookingRooms, req.Rooms)
+	if err != nil {
+		return nil, err
+	}
+	stocks := stockChanges(nil, quote.prices)
+	if err := b.checkDirectStocks(stocks, dates); err != nil {
+		return nil, err
+	}
+
+	amount, amountExTax := 0, 0
+	nights := []booking.RoomNightPrice{}
+	for _, bookingPrice := range quote.prices {
+		amount += bookingPrice.AmountInTax()
+		amountExTax += bookingPrice.AmountExTax()
+		nights = append(nights, bookingPrice.NightPrice())
+	}
+	result := &booking.ManualBookingOutput{
+		Arrival:       req.Arrival,
+		Departure:     req.Departure,
+		Stays:         len(dates),
+		TotalPayInTax: float32(amount),
+		Rooms:         quote.rooms,
+	}
+	if req.QuoteOnly {
+		return result, nil
+	}
+
+	emailEnc, err := utils.Encrypt(strings.TrimSpace(req.Email))
+	if err != nil {
+		return nil, err
+	}
+	phoneEnc, err := utils.Encrypt(strings.TrimSpace(req.Phone))
+	if err != nil {
+		return nil, err
+	}
+	nightlyJSON, err := json.Marshal(nights)
+	if err != nil {
+		return nil, err
+	}
+	for i := range quote.bookingRooms {
+		quote.bookingRooms[i].RoomID = strconv.FormatInt(quote.rooms[i].RoomTypeID, 10)
+		quote.bookingRooms[i].RateID = strconv.FormatInt(quote.rooms[i].PlanID, 10)
+	}
+	create := &booking.ManualBookingCreate{
+		Application: &booking.HtThApplications{
+			WholesalerID:     utils.WholesalerIDDirect,
+			PropertyID:       req.PropertyID,
+			GivenNameEnc:     givenNameEnc,
+			FamilyNameEnc:    familyNameEnc,
+			EmailEnc:         emailEnc,
+			PhoneEnc:         phoneEnc,
+			Arrival:          req.Arrival,
+			Departure:        req.Departure,
+			Stays:            len(dates),
+			RoomNum:          len(req.Rooms),
+			TotalPayExTax:    float32(amountExTax),
+			TotalPayInTax:    float32(amount),
+			NightlyJSON:      string(nightlyJSON),
+			CurrencyID:       "JPY",
+			LangCd:           "ja-JP",
+			Memo:             req.Memo,
+			CreateOperatorID: hmUser.HotelManagerID,
+			UpdateOperatorID: hmUser.HotelManagerID,
+			Times:            common.Times{},
+		},
+		Rooms:            quote.bookingRooms,
+		Prices:           quote.prices,
+		PriceRoomIndexes: quote.roomIndexes,
+		Stocks:           stocks,
+	}
+	if err := b.BManualRepository.CreateManualBooking(create); err != nil {
+		return nil, err
+	}
+	result.CmApplicationID = create.Application.CmApplicationID
+	for i := range result.Rooms {
+		result.Rooms[i].HtThBookingRoomID = create.Rooms[i].HtThBookingRoomID
+	}
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+)
+
+// UpdateNoShow キャンセル済みの予約のNoShowの登録・取り消し
+// NoShow料金は、プランのキャンセルポリシーのNoShowの料率（CaseOfNoShow）で計算する
+func (b *bookingUsecase) UpdateNoShow(hmUser *account.HtTmHotelManager, req *booking.NoShowInput) (*booking.NoShowResult, error) {
+	if !req.NoshowFlg && strings.TrimSpace(req.Reason) == "" {
+		return nil, booking.ErrNoShowReason
+	}
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if !appData.CancelFlg {
+		return nil, booking.ErrNoShowNotAllowed
+	}
+	return b.applyNoShow(hmUser, &appData, req.NoshowFlg, req.Reason, time.Now())
+}
+
+// BulkNoShow 指定日に到着する予約をまとめてNoShowにする
+// キャンセル済みの予約はNoShowとして登録し、キャンセルされていない予約はNoShow料金をキャンセル料としてadminへキャンセルを依頼する
+// NoShowにできない予約は結果に理由を含めて飛ばす。処理済みの予約は飛ばされるため、失敗した場合はそのままやり直せる
+func (b *bookingUsecase) BulkNoShow(hmUser *account.HtTmHotelManager, req booking.BulkNoShowInput) (*booking.BulkNoShowOutput, error) {
+	arrivals, err := b.BNoShowRepository.FetchArrivals(req.PropertyID, req.ArrivalDate)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int64]*booking.HtThApplications{}
+	for i := range arrivals {
+		byID[arrivals[i].CmApplicationID] = &arrivals[i]
+	}
+
+	now := time.Now()
+	output := &booking.BulkNoShowOutput{ArrivalDate: req.ArrivalDate, Results: []booking.NoShowResult{}}
+	processed := map[int64]bool{}
+	for _, cmApplicationID := range req.CmApplicationIDs {
+		if processed[cmApplicationID] {
+			continue
+		}
+		processed[cmApplicationID] = true
+		result, err := b.bulkNoShow(hmUser, cmApplicationID, byID[cmApplicationID], now)
+		if err != nil {
+			if !skipsNoShow(err) {
+				return nil, err
+			}
+			result = &booking.NoShowResult{
+				CmApplicationID: cmApplicationID,
+				Result:          booking.NoShowResultSkipped,
+				FeeItems:        []cancelPolicy.FeeItem{},
+				Message:         err.Error(),
+			}
+		}
+		switch result.Result {
+		case booking.NoShowResultMarked:
+			output.Marked++
+		case booking.NoShowResultCancelRequested:
+			output.CancelRequested++
+		default:
+			output.Skipped++
+		}
+		output.Results = append(output.Results, *result)
+	}
+	return output, nil
+}
+
+// bulkNoShow 一括NoShowの1予約分。appDataは指定日に到着する予約（該当しない場合はnil）
+func (b *bookingUsecase) bulkNoShow(hmUser *account.HtTmHotelManager, cmApplicationID int64, appData *booking.HtThApplications, now time.Time) (*booking.NoShowResult, error) {
+	if appData == nil {
+		return nil, booking.ErrNoShowArrival
+	}
+	if appData.CancelFlg {
+		return b.applyNoShow(hmUser, appData, true, "", now)
+	}
+	result, err := b.noShowQuote(appData, true, now)
+	if err != nil {
+		return nil, err
+	}
+	// 送信待ち・送信中の依頼がある場合は、その依頼を返す
+	cancelRequest, err := b.CancelBooking(hmUser, booking.CancelInput{
+		CmApplicationID: cmApplicationID,
+		CancelFee:       int64(result.NoshowFee),
+		Noshow:          1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Result = booking.NoShowResultCancelRequested
+	result.CancelRequest = cancelRequest
+	return result, nil
+}
+
+// applyNoShow キャンセル済みの予約のNoShowを登録・取り消しし、履歴を登録する
+func (b *bookingUsecase) applyNoShow(hmUser *account.HtTmHotelManager, appData *booking.HtThApplications, noShowFlg bool, reason string, now time.Time) (*booking.NoShowResult, error) {
+	if appData.NoshowFlg == noShowFlg {
+		return nil, booking.ErrNoShowUnchanged
+	}
+	result, err := b.noShowQuote(appData, noShowFlg, now)
+	if err != nil {
+		return nil, err
+	}
+	feeItemsJSON, err := json.Marshal(result.FeeItems)
+	if err != nil {
+		return nil, err
+	}
+	// NoShowの登録の通知は、登録と同じトランザクションで送信待ちとして登録する
+	notifications := []booking.HtThBookingNotifications{}
+	if noShowFlg {
+		setting, err := b.BNotificationRepository.FetchNotificationSetting(appData.PropertyID)
+		if err != nil {
+			return nil, err
+		}
+		notifications = notificationsFor(setting, appData.CmApplicationID, booking.NotificationEventNoShow, now)
+	}
+	if err := b.BNoShowRepository.ApplyNoShow(&booking.HtThBookingNoShowHistories{
+		CmApplicationID: appData.CmApplicationID,
+		HotelManagerID:  hmUser.HotelManagerID,
+		NoshowFlg:       noShowFlg,
+		NoshowFeeBefore: appData.NoshowFee,
+		NoshowFeeAfter:  result.NoshowFee,
+		FeeItemsJSON:    string(feeItemsJSON),
+		Reason:          reason,
+	}, notifications); err != nil {
+		return nil, err
+	}
+	if !noShowFlg {
+		result.Result = booking.NoShowResultReversed
+		return result, nil
+	}
+	result.Result = booking.NoShowResultMarked
+	return result, nil
+}
+
+// noShowQuote NoShowの登録・取り消しの期限を確認し、登録する場合はNoShow料金を計算する
+// チェックイン日前の予約はNoShowにできない。期限はホールセラーごとのルール（NoShowWindowOf）による
+func (b *bookingUsecase) noShowQuote(appData *booking.HtThApplications, noShowFlg bool, now time.Time) (*booking.NoShowResult, error) {
+	loc, err := b.propertyLocation(appData.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	arrival, err := time.ParseInLocation(DateFormat, firstN(appData.Arrival, len(DateFormat)), loc)
+	if err != nil {
+		return nil, err
+	}
+	localNow := now.In(loc)
+	if localNow.Before(arrival) {
+		return nil, booking.ErrNoShowNotAllowed
+	}
+	canceledAt := localNow
+	if appData.CancelFlg && !appData.CanceledDt.IsZero() {
+		canceledAt = appData.CanceledDt.In(loc)
+	}
+	deadline := NoShowWindowOf(appData.WholesalerID).Deadline(arrival, canceledAt)
+	if !localNow.Before(deadline) {
+		return nil, booking.ErrNoShowDeadline
+	}
+
+	result := &booking.NoShowResult{
+		CmApplicationID: appData.CmApplicationID,
+		FeeItems:        []cancelPolicy.FeeItem{},
+		Deadline:        &deadline,
+	}
+	if !noShowFlg {
+		return result, nil
+	}
+	bookingRooms, err := b.BRepository.FetchBookingRoomsByApplicationID(appData.HtThApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	bookingPrices, err := b.BRepository.FetchBookingPriceData([]int64{appData.CmApplicationID})
+	if err != nil {
+		return nil, err
+	}
+	if result.FeeItems, err = b.cancelFeeItems(appData, bookingRooms, bookingPrices, nil, localNow, true); err != nil {
+		return nil, err
+	}
+	result.NoshowFee = float32(sumFee(result.FeeItems, int(appData.TotalPayInTax)))
+	return result, nil
+}
+
+// skipsNoShow 一括NoShowで、その予約を飛ばして続けるエラーか
+func skipsNoShow(err error) bool {
+	return errors.Is(err, booking.ErrNoShowArrival) ||
+		errors.Is(err, booking.ErrNoShowNotAllowed) ||
+		errors.Is(err, booking.ErrNoShowDeadline) ||
+		errors.Is(err, booking.ErrNoShowUnchanged) ||
+		errors.Is(err, booking.ErrAlreadyCanceled) ||
+		errors.Is(err, booking.ErrCancelFeeExceedsPolicy)
+}
+
+// NoShowWindowOf ホールセラーごとのNoShowの期限のルール
+// BOOKING_NOSHOW_WINDOWS（例: {"7":{"basis":"arrival","days":7}}）で変更でき、設定がないホールセラーは DefaultNoShowWindow
+func NoShowWindowOf(wholesalerID int64) booking.NoShowWindow {
+	windows := map[string]booking.NoShowWindow{}
+	if err := json.Unmarshal([]byte(os.Getenv("BOOKING_NOSHOW_WINDOWS")), &windows); err != nil {
+		return booking.DefaultNoShowWindow
+	}
+	window, ok := windows[strconv.FormatInt(wholesalerID, 10)]
+	if !ok || !window.Valid() {
+		return booking.DefaultNoShowWindow
+	}
+	return window
+}
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+func Test_NoShowWindowOf(t *testing.T) {
+	defer os.Unsetenv("BOOKING_NOSHOW_WINDOWS")
+	os.Setenv("BOOKING_NOSHOW_WINDOWS", `{"7":{"basis":"arrival","days":7},"8":{"basis":"unknown"}}`)
+
+	if got := NoShowWindowOf(7); got != (booking.NoShowWindow{Basis: booking.NoShowBasisArrival, Days: 7}) {
+		t.Fatalf("設定したルールになりません。%+v", got)
+	}
+	// 設定がない・正しくないホールセラーはデフォルト
+	if got := NoShowWindowOf(6); got != booking.DefaultNoShowWindow {
+		t.Fatalf("設定がないホールセラーがデフォルトになりません。%+v", got)
+	}
+	if got := NoShowWindowOf(8); got != booking.DefaultNoShowWindow {
+		t.Fatalf("正しくない設定がデフォルトになりません。%+v", got)
+	}
+}
@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+// PartialCancelBooking 予約の一部（部屋・泊）をキャンセル
+// キャンセル料はキャンセルした部屋・泊の分だけを計算し、入力されたキャンセル料はその金額を上限とする
+func (b *bookingUsecase) PartialCancelBooking(hmUser *account.HtTmHotelManager, req booking.PartialCancelInput) (*booking.PartialCancelOutput, error) {
+	appData, err := b.BRepository.FetchApplication(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	// 予約全体のキャンセルを依頼中の予約も、一部キャンセルはできない
+	active, err := b.BCancelRepository.FetchActiveCancelRequest(req.CmApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	if appData.CancelFlg || active != nil {
+		return nil, booking.ErrAlreadyCanceled
+	}
+	// adminへ送信できない一部キャンセルは送信待ちのまま残るため、手入力の予約以外は受け付けない
+	if !b.BAPI.PartialCancelEnabled() && !booking.IsManualApplicationID(appData.CmApplicationID) {
+		return nil, booking.ErrPartialCancelUnavailable
+	}
+	loc, err := b.propertyLocation(appData.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	bookingRooms, err := b.BRepository.FetchBookingRoomsByApplicationID(appData.HtThApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	bookingPrices, err := b.BRepository.FetchBookingPriceData([]int64{appData.CmApplicationID})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(bookingPrices, func(i, j int) bool {
+		if !bookingPrices[i].UseDate.Equal(bookingPrices[j].UseDate) {
+			return bookingPrices[i].UseDate.Before(bookingPrices[j].UseDate)
+		}
+		return bookingPrices[i].HtThBookingPriceID < bookingPrices[j].HtThBookingPriceID
+	})
+
+	priceRoomIDs := priceRoomIDs(bookingRooms, bookingPrices)
+	targets, err := partialCancelTargets(bookingRooms, bookingPrices, priceRoomIDs, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == len(bookingPrices) {
+		return nil, booking.ErrPartialCancelAll
+	}
+	now := time.Now().In(loc)
+	today := now.Format(DateFormat)
+	targetIDs := map[int64]bool{}
+	canceledAmount, canceledAmountExTax := 0, 0
+	stocks := []booking.StockCell{}
+	for _, target := range targets {
+		if target.UseDate.Format(DateFormat) < today {
+			return nil, booking.ErrPartialCancelStayed
+		}
+		targetIDs[target.HtThBookingPriceID] = true
+		canceledAmount += target.AmountInTax()
+		canceledAmountExTax += target.AmountExTax()
+		stocks = append(stocks, booking.StockCell{RoomTypeID: target.RoomTypeID, UseDate: target.UseDate.Format(DateFormat)})
+	}
+
+	feeItems, err := b.cancelFeeItems(&appData, bookingRooms, bookingPrices, targetIDs, now, false)
+	if err != nil {
+		return nil, err
+	}
+	if req.CancelFee > int64(sumFee(feeItems, canceledAmount)) {
+		return nil, booking.ErrCancelFeeExceedsPolicy
+	}
+
+	pricesJSON, err := json.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	feeItemsJSON, err := json.Marshal(feeItems)
+	if err != nil {
+		return nil, err
+	}
+	dates := []string{}
+	for _, bookingPrice := range bookingPrices {
+		if useDate := bookingPrice.UseDate.Format(DateFormat); len(dates) == 0 || dates[len(dates)-1] != useDate {
+			dates = append(dates, useDate)
+		}
+	}
+	application := remainingStay(&appData, bookingPrices, targetIDs, priceRoomIDs)
+	application["total_pay_in_tax"] = appData.TotalPayInTax - float32(canceledAmount)
+	application["total_pay_ex_tax"] = appData.TotalPayExTax - float32(canceledAmountExTax)
+	application["cancel_fee"] = appData.CancelFee + float32(req.CancelFee)
+	application["nightly_json"] = booking.PruneNightlyJSON(appData.NightlyJSON, dates, bookingPrices, targetIDs)
+
+	// 残りの料金情報は、部屋数が変わると紐づけを推定できなくなるため、推定できた紐づけを保存しておく
+	links := map[int64]int64{}
+	for _, bookingPrice := range bookingPrices {
+		if roomID, ok := priceRoomIDs[bookingPrice.HtThBookingPriceID]; ok && !targetIDs[bookingPrice.HtThBookingPriceID] && bookingPrice.HtThBookingRoomID == 0 {
+			links[bookingPrice.HtThBookingPriceID] = roomID
+		}
+	}
+	apply := &booking.PartialCancelApply{
+		WholesalerID:  appData.WholesalerID,
+		Stocks:        stocks,
+		Application:   application,
+		Links:         links,
+		CancelRequest: partialCancelRequest(hmUser, &appData, req.CancelFee),
+		History: &booking.HtThBookingPartialCancels{
+			CmApplicationID:     appData.CmApplicationID,
+			HotelManagerID:      hmUser.HotelManagerID,
+			CancelFee:           req.CancelFee,
+			CanceledAmountInTax: canceledAmount,
+			TotalPayInTaxBefore: appData.TotalPayInTax,
+			TotalPayInTaxAfter:  appData.TotalPayInTax - float32(canceledAmount),
+			PricesJSON:          string(pricesJSON),
+			FeeItemsJSON:        string(feeItemsJSON),
+			NightlyJSONBefore:   appData.NightlyJSON,
+		},
+	}
+	for id := range targetIDs {
+		apply.PriceIDs = append(apply.PriceIDs, id)
+	}
+	if err := b.BPartialCancelRepository.ApplyPartialCancel(apply); err != nil {
+		return nil, err
+	}
+	output, err := booking.NewPartialCancelOutput(apply.History)
+	if err != nil {
+		return nil, err
+	}
+	output.CancelRequest = booking.NewCancelRequestOutput(apply.CancelRequest)
+	return output, nil
+}
+
+// partialCancelRequest adminへの一部キャンセルの依頼
+// 手入力の予約はadminにないため、送信せずに送信済みとして登録する
+func partialCancelRequest(hmUser *account.HtTmHotelManager, appData *booking.HtThApplications, cancelFee int64) *booking.HtThBookingCancelRequests {
+	result := &booking.HtThBookingCancelRequests{
+		CmApplicationID: appData.CmApplicationID,
+		HotelManagerID:  hmUser.HotelManagerID,
+		CancelFee:       cancelFee,
+		Status:          booking.CancelStatusPending,
+	}
+	if booking.IsManualApplicationID(appData.CmApplicationID) {
+		result.Status = booking.CancelStatusCompleted
+	}
+	return result
+}
+
+// priceRoomIDs 料金情報ごとの部屋ID（料金情報ID→部屋ID）。部屋IDで紐づいているか、紐づけを推定できたもののみ
+func priceRoomIDs(bookingRooms []booking.HtThBookingRooms, bookingPrices []booking.HtThBookingPrices) map[int64]int64 {
+	result := map[int64]int64{}
+	links, ok := booking.InferRoomLinks(bookingRooms, bookingPrices)
+	for _, bookingPrice := range bookingPrices {
+		if bookingPrice.HtThBookingRoomID != 0 {
+			result[bookingPrice.HtThBookingPriceID] = bookingPrice.HtThBookingRoomID
+		} else if roomID, linked := links[bookingPrice.HtThBookingPriceID]; ok && linked {
+			result[bookingPrice.HtThBookingPriceID] = roomID
+		}
+	}
+	return result
+}
+
+// partialCancelTargets キャンセルする料金情報（部屋・泊）
+// 部屋を指定した場合、どの部屋の料金か分からない料金情報があればキャンセルできない
+func partialCancelTargets(bookingRooms []booking.HtThBookingRooms, bookingPrices []booking.HtThBookingPrices, priceRoomIDs map[int64]int64, req booking.PartialCancelInput) ([]booking.HtThBookingPrices, error) {
+	roomIDs := map[int64]bool{}
+	for _, bookingRoom := range bookingRooms {
+		roomIDs[bookingRoom.HtThBookingRoomID] = false
+	}
+	for _, roomID := range req.HtThBookingRoomIDs {
+		if _, ok := roomIDs[roomID]; !ok {
+			return nil, booking.ErrPartialCancelTarget
+		}
+		roomIDs[roomID] = true
+	}
+	useDates := map[string]bool{}
+	for _, useDate := range req.UseDates {
+		useDates[useDate] = false
+	}
+
+	result := []booking.HtThBookingPrices{}
+	for _, bookingPrice := range bookingPrices {
+		useDate := bookingPrice.UseDate.Format(DateFormat)
+		if _, ok := useDates[useDate]; len(useDates) > 0 && !ok {
+			continue
+		}
+		if len(req.HtThBookingRoomIDs) > 0 {
+			roomID, ok := priceRoomIDs[bookingPrice.HtThBookingPriceID]
+			if !ok {
+				return nil, booking.ErrPartialCancelTarget
+			}
+			if !roomIDs[roomID] {
+				continue
+			}
+		}
+		useDates[useDate] = true
+		result = append(result, bookingPrice)
+	}
+	// 指定した日付に、キャンセルする料金情報がない場合
+	for _, found := range useDates {
+		if !found {
+			return nil, booking.ErrPartialCancelTarget
+		}
+	}
+	if len(result) == 0 {
+		return nil, booking.ErrPartialCancelTarget
+	}
+	return result, nil
+}
+
+// remainingStay キャンセル後に残る部屋・泊から、チェックイン・チェックアウト・泊数・部屋数の更新内容を作成
+// 泊数は残りの宿泊日の数（途中の泊をキャンセルした場合は、チェックイン〜チェックアウトの日数より少なくなる）
+// 部屋数は、残りの料金情報がすべて部屋と紐づいている場合のみ更新する
+func remainingStay(appData *booking.HtThApplications, bookingPrices []booking.HtThBookingPrices, targetIDs map[int64]bool, priceRoomIDs map[int64]int64) map[string]interface{} {
+	var first, last time.Time
+	useDates := map[string]bool{}
+	roomIDs := map[int64]bool{}
+	linked := true
+	for _, bookingPrice := range bookingPrices {
+		if targetIDs[bookingPrice.HtThBookingPriceID] {
+			continue
+		}
+		if first.IsZero() || bookingPrice.UseDate.Before(first) {
+			first = bookingPrice.UseDate
+		}
+		if last.IsZero() || bookingPrice.UseDate.After(last) {
+			last = bookingPrice.UseDate
+		}
+		useDates[bookingPrice.UseDate.Format(DateFormat)] = true
+		roomID, ok := priceRoomIDs[bookingPrice.HtThBookingPriceID]
+		linked = linked && ok
+		roomIDs[roomID] = true
+	}
+	departure := last.AddDate(0, 0, 1)
+	result := map[string]interface{}{
+		// 時刻を含む形式で登録されている場合は、時刻部分をそのまま残す
+		"arrival":   first.Format(DateFormat) + suffixAfter(appData.Arrival, len(DateFormat)),
+		"departure": departure.Format(DateFormat) + suffixAfter(appData.Departure, len(DateFormat)),
+		"stays":     len(useDates),
+	}
+	if linked {
+		result["room_num"] = len(roomIDs)
+	}
+	return result
+}
+
+// suffixAfter 文字列のn文字目以降
+func suffixAfter(value string, n int) string {
+	if len(value) <= n {
+		return ""
+	}
+	return value[n:]
+}
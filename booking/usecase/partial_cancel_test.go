@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+type partialCancelAPI struct {
+	booking.IBookingAPI
+	enabled bool
+}
+
+func (a *partialCancelAPI) PartialCancelEnabled() bool {
+	return a.enabled
+}
+
+func Test_remainingStay(t *testing.T) {
+	appData := &booking.HtThApplications{Arrival: "2021-04-01", Departure: "2021-04-04"}
+	bookingPrices := []booking.HtThBookingPrices{}
+	for i := 0; i < 3; i++ {
+		bookingPrices = append(bookingPrices, booking.HtThBookingPrices{
+			HtThBookingPriceID: int64(i + 1),
+			UseDate:            time.Date(2021, 4, 1+i, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	tests := []struct {
+		name      string
+		targetIDs map[int64]bool
+		arrival   string
+		departure string
+		stays     int
+	}{
+		{"最初の泊をキャンセルした場合はチェックインを変更", map[int64]bool{1: true}, "2021-04-02", "2021-04-04", 2},
+		{"最後の泊をキャンセルした場合はチェックアウトを変更", map[int64]bool{3: true}, "2021-04-01", "2021-04-03", 2},
+		{"途中の泊をキャンセルした場合は泊数のみ変更", map[int64]bool{2: true}, "2021-04-01", "2021-04-04", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := remainingStay(appData, bookingPrices, tt.targetIDs, map[int64]int64{})
+			if result["arrival"] != tt.arrival || result["departure"] != tt.departure || result["stays"] != tt.stays {
+				t.Fatalf("更新内容が一致しません。%v", result)
+			}
+		})
+	}
+}
+
+func Test_PartialCancelBooking_unavailable(t *testing.T) {
+	// 一部キャンセルAPIが使えない場合は、部屋・料金の取得より前にエラーにする
+	b := &bookingUsecase{
+		BRepository:       &amendBookingRepository{appData: booking.HtThApplications{CmApplicationID: 1}},
+		BCancelRepository: &amendCancelRepository{},
+		BAPI:              &partialCancelAPI{enabled: false},
+	}
+	req := booking.PartialCancelInput{CmApplicationID: 1, UseDates: []string{"2099-04-01"}}
+	_, err := b.PartialCancelBooking(&account.HtTmHotelManager{}, req)
+	if !errors.Is(err, booking.ErrPartialCancelUnavailable) {
+		t.Fatalf("一部キャンセルAPIが使えない場合にエラーになりません。%v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+// BackfillRoomPriceLinks 部屋と紐づいていない過去の予約の料金情報を、推定できるものだけ部屋と紐づける
+// batchSize件ずつ予約ID順に処理し、推定できなかった予約は紐づけずに予約IDを返す
+func (b *bookingUsecase) BackfillRoomPriceLinks(batchSize int) (*booking.BackfillOutput, error) {
+	result := &booking.BackfillOutput{SkippedIDs: []int64{}}
+	var lastID int64
+	for {
+		applications, err := b.BRepository.FetchUnlinkedApplications(lastID, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(applications) == 0 {
+			return result, nil
+		}
+		lastID = applications[len(applications)-1].CmApplicationID
+
+		htThApplicationIDs := []int64{}
+		cmApplicationIDs := []int64{}
+		for _, application := range applications {
+			htThApplicationIDs = append(htThApplicationIDs, application.HtThApplicationID)
+			cmApplicationIDs = append(cmApplicationIDs, application.CmApplicationID)
+		}
+		bookingRooms, err := b.BRepository.FetchBookingRoomListByApplicationID(htThApplicationIDs)
+		if err != nil {
+			return result, err
+		}
+		bookingPrices, err := b.BRepository.FetchBookingPriceData(cmApplicationIDs)
+		if err != nil {
+			return result, err
+		}
+		roomsByApplication := map[int64][]booking.HtThBookingRooms{}
+		for _, bookingRoom := range bookingRooms {
+			roomsByApplication[bookingRoom.HtThApplicationID] = append(roomsByApplication[bookingRoom.HtThApplicationID], bookingRoom)
+		}
+		pricesByApplication := map[int64][]booking.HtThBookingPrices{}
+		for _, bookingPrice := range bookingPrices {
+			pricesByApplication[bookingPrice.CmApplicationID] = append(pricesByApplication[bookingPrice.CmApplicationID], bookingPrice)
+		}
+
+		for _, application := range applications {
+			result.Checked++
+			links, ok := booking.InferRoomLinks(roomsByApplication[application.HtThApplicationID], pricesByApplication[application.CmApplicationID])
+			if !ok {
+				result.SkippedIDs = append(result.SkippedIDs, application.CmApplicationID)
+				continue
+			}
+			if err := b.BRepository.LinkBookingPrices(links); err != nil {
+				return result, err
+			}
+			result.Linked++
+		}
+		if len(applications) < batchSize {
+			return result, nil
+		}
+	}
+}
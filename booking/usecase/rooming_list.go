@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/common/export"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/Adventureinc/hotel-hm-api/src/plan"
+)
+
+// RoomingList 指定日の到着・出発・連泊の一覧（部屋ごと）
+// 一部キャンセルした予約は、残っている部屋・泊をもとに区分を判定する
+func (b *bookingUsecase) RoomingList(req booking.RoomingListInput) (*booking.RoomingListOutput, error) {
+	applications, err := b.BRepository.FetchStayingApplications(req.PropertyID, req.Date)
+	if err != nil {
+		return nil, err
+	}
+	response := &booking.RoomingListOutput{PropertyID: req.PropertyID, Date: req.Date, Rows: []booking.RoomingListRow{}}
+	if len(applications) == 0 {
+		return response, nil
+	}
+
+	htThApplicationIDs := []int64{}
+	cmApplicationIDs := []int64{}
+	for _, appData := range applications {
+		htThApplicationIDs = append(htThApplicationIDs, appData.HtThApplicationID)
+		cmApplicationIDs = append(cmApplicationIDs, appData.CmApplicationID)
+	}
+	bookingRooms, err := b.BRepository.FetchBookingRoomListByApplicationID(htThApplicationIDs)
+	if err != nil {
+		return nil, err
+	}
+	bookingPrices, err := b.BRepository.FetchBookingPriceData(cmApplicationIDs)
+	if err != nil {
+		return nil, err
+	}
+	plans, roomNames := b.roomingMasters(applications, bookingRooms)
+
+	for i := range applications {
+		appData := &applications[i]
+		appRooms := []booking.HtThBookingRooms{}
+		for _, bookingRoom := range bookingRooms {
+			if bookingRoom.HtThApplicationID == appData.HtThApplicationID {
+				appRooms = append(appRooms, bookingRoom)
+			}
+		}
+		appPrices := []booking.HtThBookingPrices{}
+		for _, bookingPrice := range bookingPrices {
+			if bookingPrice.CmApplicationID == appData.CmApplicationID {
+				appPrices = append(appPrices, bookingPrice)
+			}
+		}
+		nights, err := roomNights(appData, appRooms, appPrices)
+		if err != nil {
+			return nil, err
+		}
+		for _, bookingRoom := range appRooms {
+			dates, ok := nights[bookingRoom.HtThBookingRoomID]
+			if !ok {
+				continue
+			}
+			kind := roomingKind(dates, req.Date)
+			if kind == "" || (req.Kind != "" && req.Kind != kind) {
+				continue
+			}
+			row, err := newRoomingListRow(appData, &bookingRoom, kind, dates, plans, roomNames)
+			if err != nil {
+				return nil, err
+			}
+			response.Rows = append(response.Rows, *row)
+		}
+	}
+
+	order := map[string]int{}
+	for i, kind := range booking.RoomingKinds {
+		order[kind] = i
+	}
+	sort.SliceStable(response.Rows, func(i, j int) bool {
+		if response.Rows[i].Kind != response.Rows[j].Kind {
+			return order[response.Rows[i].Kind] < order[response.Rows[j].Kind]
+		}
+		return response.Rows[i].CmApplicationID < response.Rows[j].CmApplicationID
+	})
+	for _, row := range response.Rows {
+		switch row.Kind {
+		case booking.RoomingKindArrival:
+			response.Arrivals++
+		case booking.RoomingKindDeparture:
+			response.Departures++
+		case booking.RoomingKindStayOver:
+			response.StayOvers++
+		}
+	}
+	return response, nil
+}
+
+// WriteRoomingList 到着・出発・連泊の一覧を印刷用のファイル形式で書き込む
+func (b *bookingUsecase) WriteRoomingList(list *booking.RoomingListOutput, format string, w io.Writer) error {
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+	export.SetTitle(writer, fmt.Sprintf("到着・連泊・出発一覧 %s（到着 %d室 / 連泊 %d室 / 出発 %d室）",
+		list.Date, list.Arrivals, list.StayOvers, list.Departures))
+	if err := writer.Write(booking.RoomingListHeaders); err != nil {
+		return err
+	}
+	for _, row := range list.Rows {
+		if err := writer.Write(row.Record()); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// roomNights 部屋ごとの宿泊日（昇順）
+// 料金情報が部屋と紐づいている予約は料金情報の日付を使い、紐づく料金情報が残っていない部屋は含めない
+// 紐づけが分からない予約は、予約のチェックイン日からチェックアウト日の前日までとする
+func roomNights(appData *booking.HtThApplications, bookingRooms []booking.HtThBookingRooms, bookingPrices []booking.HtThBookingPrices) (map[int64][]string, error) {
+	result := map[int64][]string{}
+	links := priceRoomIDs(bookingRooms, bookingPrices)
+	if len(links) == 0 {
+		dates, err := stayDates(firstN(appData.Arrival, len(DateFormat)), firstN(appData.Departure, len(DateFormat)))
+		if err != nil {
+			return nil, err
+		}
+		for _, bookingRoom := range bookingRooms {
+			result[bookingRoom.HtThBookingRoomID] = dates
+		}
+		return result, nil
+	}
+	seen := map[int64]map[string]bool{}
+	for _, bookingPrice := range bookingPrices {
+		roomID, ok := links[bookingPrice.HtThBookingPriceID]
+		if !ok {
+			continue
+		}
+		useDate := bookingPrice.UseDate.Format(DateFormat)
+		if seen[roomID] == nil {
+			seen[roomID] = map[string]bool{}
+		}
+		if seen[roomID][useDate] {
+			continue
+		}
+		seen[roomID][useDate] = true
+		result[roomID] = append(result[roomID], useDate)
+	}
+	for roomID := range result {
+		sort.Strings(result[roomID])
+	}
+	return result, nil
+}
+
+// roomingKind 宿泊日から指定日の区分を判定（到着・連泊・出発のいずれでもない場合は空文字）
+// 当日と前日の泊の有無で判定するため、途中の泊をキャンセルした部屋も正しく分類できる
+func roomingKind(dates []string, date string) string {
+	day, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return ""
+	}
+	previous := day.AddDate(0, 0, -1).Format(DateFormat)
+	staying, stayed := false, false
+	for _, d := range dates {
+		staying = staying || d == date
+		stayed = stayed || d == previous
+	}
+	switch {
+	case staying && stayed:
+		return booking.RoomingKindStayOver
+	case staying:
+		return booking.RoomingKindArrival
+	case stayed:
+		return booking.RoomingKindDeparture
+	}
+	return ""
+}
+
+// roomingMasterKey 卸ごとの部屋タイプ・プランのキー
+type roomingMasterKey struct {
+	wholesalerID int64
+	id           int64
+}
+
+// roomingMasters 予約の部屋に紐づくプラン（名前・食事条件）と部屋タイプ名を取得
+// 食事条件を持つのは直仕入れ・ねっぱん・らく通のプランのみ
+func (b *bookingUsecase) roomingMasters(applications []booking.HtThApplications, bookingRooms []booking.HtThBookingRooms) (map[roomingMasterKey]plan.PlanTable, map[roomingMasterKey]string) {
+	wholesalerIDs := map[int64]int64{}
+	for _, appData := range applications {
+		wholesalerIDs[appData.HtThApplicationID] = appData.WholesalerID
+	}
+	roomIDs := map[int64][]int64{}
+	planIDs := map[int64][]int64{}
+	for _, bookingRoom := range bookingRooms {
+		wholesalerID := wholesalerIDs[bookingRoom.HtThApplicationID]
+		roomID, _ := strconv.ParseInt(bookingRoom.RoomID, 10, 64)
+		planID, _ := strconv.ParseInt(bookingRoom.RateID, 10, 64)
+		roomIDs[wholesalerID] = append(roomIDs[wholesalerID], roomID)
+		planIDs[wholesalerID] = append(planIDs[wholesalerID], planID)
+	}
+
+	plans := map[roomingMasterKey]plan.PlanTable{}
+	roomNames := map[roomingMasterKey]string{}
+	if ids := planIDs[utils.WholesalerIDDirect]; len(ids) > 0 {
+		directPlans, _ := b.PlanDirectRepository.FetchList(ids)
+		for _, p := range directPlans {
+			plans[roomingMasterKey{utils.WholesalerIDDirect, p.PlanID}] = p.PlanTable
+		}
+		directRooms, _ := b.RoomDirectRepository.FetchRoomListByRoomTypeID(roomIDs[utils.WholesalerIDDirect])
+		for _, r := range directRooms {
+			roomNames[roomingMasterKey{utils.WholesalerIDDirect, r.RoomTypeID}] = r.Name
+		}
+	}
+	if ids := planIDs[utils.WholesalerIDNeppan]; len(ids) > 0 {
+		neppanPlans, _ := b.PlanNeppanRepository.FetchList(ids)
+		for _, p := range neppanPlans {
+			plans[roomingMasterKey{utils.WholesalerIDNeppan, p.PlanID}] = p.PlanTable
+		}
+		neppanRooms, _ := b.RoomNeppanRepository.FetchRoomListByRoomTypeID(roomIDs[utils.WholesalerIDNeppan])
+		for _, r := range neppanRooms {
+			roomNames[roomingMasterKey{utils.WholesalerIDNeppan, r.RoomTypeID}] = r.Name
+		}
+	}
+	if ids := planIDs[utils.WholesalerIDRaku2]; len(ids) > 0 {
+		raku2Plans, _ := b.PlanRaku2Repository.FetchList(ids)
+		for _, p := range raku2Plans {
+			plans[roomingMasterKey{utils.WholesalerIDRaku2, p.PlanID}] = p.PlanTable
+		}
+		raku2Rooms, _ := b.RoomRaku2Repository.FetchRoomListByRoomTypeID(roomIDs[utils.WholesalerIDRaku2])
+		for _, r := range raku2Rooms {
+			roomNames[roomingMasterKey{utils.WholesalerIDRaku2, r.RoomTypeID}] = r.Name
+		}
+	}
+	return plans, roomNames
+}
+
+// newRoomingListRow 一覧の1部屋分を作成
+// 予約時の部屋名・プラン名がない場合は、部屋タイプ・プランの名前を使う
+func newRoomingListRow(appData *booking.HtThApplications, bookingRoom *booking.HtThBookingRooms, kind string, dates []string, plans map[roomingMasterKey]plan.PlanTable, roomNames map[roomingMasterKey]string) (*booking.RoomingListRow, error) {
+	roomID, _ := strconv.ParseInt(bookingRoom.RoomID, 10, 64)
+	planID, _ := strconv.ParseInt(bookingRoom.RateID, 10, 64)
+	planData := plans[roomingMasterKey{appData.WholesalerID, planID}]
+	departure, err := time.Parse(DateFormat, dates[len(dates)-1])
+	if err != nil {
+		return nil, err
+	}
+	row := &booking.RoomingListRow{
+		Kind:                              kind,
+		CmApplicationID:                   appData.CmApplicationID,
+		WholesalerID:                      appData.WholesalerID,
+		Arrival:                           dates[0],
+		Departure:                         departure.AddDate(0, 0, 1).Format(DateFormat),
+		Stays:                             len(dates),
+		RoomName:                          bookingRoom.RoomName,
+		PlanName:                          bookingRoom.PlanName,
+		MealBreakfast:                     planData.MealConditionBreakfast,
+		MealLunch:                         planData.MealConditionLunch,
+		MealDinner:                        planData.MealConditionDinner,
+		NumberOfAdults:                    bookingRoom.NumberOfAdults,
+		NumberOfUpperGrades:               bookingRoom.NumberOfUpperGrades,
+		NumberOfLowerGrades:               bookingRoom.NumberOfLowerGrades,
+		NumberOfInfantMealsWithBedding:    bookingRoom.NumberOfInfantMealsWithBedding,
+		NumberOfInfantMealOnly:            bookingRoom.NumberOfInfantMealOnly,
+		NumberOfInfantBeddingOnly:         bookingRoom.NumberOfInfantBeddingOnly,
+		NumberOfInfantMealsWithoutBedding: bookingRoom.NumberOfInfantMealsWithoutBedding,
+		Memo:                              appData.Memo,
+	}
+	if row.RoomName == "" {
+		row.RoomName = roomNames[roomingMasterKey{appData.WholesalerID, roomID}]
+	}
+	if row.PlanName == "" {
+		row.PlanName = planData.Name
+	}
+	categorized := row.NumberOfUpperGrades + row.NumberOfLowerGrades + row.NumberOfInfantMealsWithBedding +
+		row.NumberOfInfantMealOnly + row.NumberOfInfantBeddingOnly + row.NumberOfInfantMealsWithoutBedding
+	if bookingRoom.NumberOfChilds > categorized {
+		row.NumberOfChilds = bookingRoom.NumberOfChilds - categorized
+	}
+
+	// 部屋ごとの宿泊者名がない場合は予約者名
+	familyName, err := utils.Decrypt(bookingRoom.FamilyNameEnc)
+	if err != nil {
+		return nil, err
+	}
+	givenName, err := utils.Decrypt(bookingRoom.GivenNameEnc)
+	if err != nil {
+		return nil, err
+	}
+	row.GuestName = strings.TrimSpace(familyName + " " + givenName)
+	if row.GuestName == "" {
+		if row.GuestName, err = guestFullName(appData); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+)
+
+func Test_roomingKind(t *testing.T) {
+	stay := []string{"2021-03-10", "2021-03-11", "2021-03-12"}
+	// 3/11の泊をキャンセルした部屋
+	partial := []string{"2021-03-10", "2021-03-12"}
+	tests := []struct {
+		name  string
+		dates []string
+		date  string
+		kind  string
+	}{
+		{"最初の泊は到着", stay, "2021-03-10", booking.RoomingKindArrival},
+		{"前日から引き続き泊まる日は連泊", stay, "2021-03-11", booking.RoomingKindStayOver},
+		{"最後の泊の翌日は出発", stay, "2021-03-13", booking.RoomingKindDeparture},
+		{"宿泊期間外は対象外", stay, "2021-03-14", ""},
+		{"キャンセルした泊の日は出発", partial, "2021-03-11", booking.RoomingKindDeparture},
+		{"キャンセルした泊の翌日は到着", partial, "2021-03-12", booking.RoomingKindArrival},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := roomingKind(tt.dates, tt.date); kind != tt.kind {
+				t.Fatalf("区分が一致しません。%s", kind)
+			}
+		})
+	}
+}
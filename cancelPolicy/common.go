@@ -115,4 +115,8 @@ type ICancelPolicyCommonRepository interface {
 	FindAssignedPlanCancelPolicy(propertyId int64, planId int64) (*HtThPlanCancelPolicyRelations, error)
 	UpsertPlanCancelPolicyRelation(wholesalerID int, propertyId int64, planId int64, policyId uint64) error
 	DeletePlanCancelPolicyRelation(wholesalerID int, planId int64) error
+	// FetchAssignedPlanCancelPolicies プランに割り当てられたキャンセルポリシーをプランIDごとに取得
+	FetchAssignedPlanCancelPolicies(wholesalerID int, propertyID int64, planIDs []int64) (map[int64]HtTmPlanCancelPolicies, error)
+	// FetchPropertyCancelPenaltyJSON 施設のデフォルトのキャンセルポリシーを取得（未設定の場合は空文字）
+	FetchPropertyCancelPenaltyJSON(wholesalerID int, propertyID int64) (string, error)
 }
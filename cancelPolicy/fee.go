@@ -0,0 +1,161 @@
+package cancelPolicy
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FeeRuleNonRefundable 返金不可
+	FeeRuleNonRefundable = "non_refundable"
+	// FeeRuleNoShow NoShow
+	FeeRuleNoShow = "no_show"
+	// FeeRuleToday 当日キャンセル
+	FeeRuleToday = "today"
+	// FeeRuleAdditional 日数指定のキャンセル料（AdditionalCases）
+	FeeRuleAdditional = "additional"
+	// FeeRuleStayed 宿泊済みの日（キャンセル対象外のため全額）
+	FeeRuleStayed = "stayed"
+	// FeeRuleLegacy 予約時に保存したキャンセルペナルティー（キャンセルポリシー未設定の場合）
+	FeeRuleLegacy = "legacy"
+	// FeeRuleNone キャンセル料なし
+	FeeRuleNone = "none"
+)
+
+var (
+	// quotedRate 文字列で保存されている {"Rate":"50"} 形式の料率
+	quotedRate = regexp.MustCompile("{\"Rate\":\"(\\d{1,})\"}")
+	// quotedDeposit 文字列で保存されている "Deposit":"1" 形式の値
+	quotedDeposit = regexp.MustCompile("\"Deposit\":\"(\\d{1,})\"")
+)
+
+// FeeNight キャンセル料の計算対象の1部屋1泊分
+type FeeNight struct {
+	RoomIndex int
+	UseDate   time.Time
+	Amount    int
+}
+
+// FeeItem キャンセル料の内訳1部屋1泊分
+type FeeItem struct {
+	RoomIndex  int    `json:"room_index"`
+	PlanID     int64  `json:"plan_id"`
+	UseDate    string `json:"use_date"`
+	DaysBefore int    `json:"days_before"`
+	Amount     int    `json:"amount"`
+	Rate       int    `json:"rate"`
+	Fee        int    `json:"fee"`
+	Rule       string `json:"rule"`
+}
+
+// ParseSettings 保存されているキャンセルポリシーのJSONを読み込む
+// 施設のデフォルトのキャンセルポリシーは料率が文字列で保存されていることがあるため、数値に直してから読み込む
+func ParseSettings(cancelPenaltyJSON string) (*Settings, error) {
+	cancelPenaltyJSON = quotedRate.ReplaceAllString(cancelPenaltyJSON, "{\"Rate\":$1}")
+	cancelPenaltyJSON = quotedDeposit.ReplaceAllString(cancelPenaltyJSON, "\"Deposit\":$1")
+	policy := &CancelPolicyJSON{}
+	if err := json.Unmarshal([]byte(cancelPenaltyJSON), policy); err != nil {
+		return nil, err
+	}
+	return &policy.Settings, nil
+}
+
+// RateAt チェックインのdaysBefore日前にキャンセル（noShowの場合はNoShow）したときの料率と、適用したルール
+// 日数指定のキャンセル料が重なっている場合は高い方を適用する
+func (s *Settings) RateAt(daysBefore int, noShow bool) (int, string) {
+	if s.NonRefundable == 1 {
+		return 100, FeeRuleNonRefundable
+	}
+	if noShow {
+		return capRate(int(s.CaseOfNoShow.Rate)), FeeRuleNoShow
+	}
+	if daysBefore <= 0 {
+		return capRate(int(s.CaseOfCancellationToday.Rate)), FeeRuleToday
+	}
+	rate, rule := 0, FeeRuleNone
+	for _, additionalCase := range s.AdditionalCases {
+		start, end, ok := additionalCase.AdditionalCase.dayRange()
+		if !ok || daysBefore < start || daysBefore > end {
+			continue
+		}
+		caseRate, err := strconv.Atoi(strings.TrimRight(additionalCase.AdditionalCase.Rate, "%"))
+		if err != nil || caseRate <= rate {
+			continue
+		}
+		rate, rule = capRate(caseRate), FeeRuleAdditional
+	}
+	return rate, rule
+}
+
+// dayRange 日数指定の範囲（何日前から何日前まで）。開始・終了の大小が逆に入力されていても扱えるようにする
+func (a *AdditionalCase) dayRange() (int, int, bool) {
+	start, err := strconv.Atoi(a.StartDays)
+	if err != nil {
+		return 0, 0, false
+	}
+	end := start
+	if a.EndDays != "" {
+		if end, err = strconv.Atoi(a.EndDays); err != nil {
+			return 0, 0, false
+		}
+	}
+	if start > end {
+		start, end = end, start
+	}
+	return start, end, true
+}
+
+// CalculateFee キャンセルポリシーに基づいて、部屋・泊ごとのキャンセル料を計算する
+// arrival・cancelAtは施設のタイムゾーンの時刻を渡すこと。日数はチェックイン日を基準に数え、
+// チェックイン後のキャンセルでは、宿泊済みの日は全額、残りの日は当日キャンセルの料率とする
+func CalculateFee(settings *Settings, planID int64, arrival time.Time, nights []FeeNight, cancelAt time.Time, noShow bool) []FeeItem {
+	cancelDate := dateOf(cancelAt)
+	daysBefore := DaysBefore(arrival, cancelAt)
+	items := []FeeItem{}
+	for _, night := range nights {
+		rate, rule := settings.RateAt(daysBefore, noShow)
+		if !noShow && dateOf(night.UseDate).Before(cancelDate) {
+			rate, rule = 100, FeeRuleStayed
+		}
+		items = append(items, NewFeeItem(night, planID, daysBefore, rate, rule))
+	}
+	return items
+}
+
+// DaysBefore チェックインの何日前のキャンセルか（当日は0、チェックイン後は負の値）
+func DaysBefore(arrival time.Time, cancelAt time.Time) int {
+	return int(dateOf(arrival).Sub(dateOf(cancelAt)).Hours() / 24)
+}
+
+// NewFeeItem 料率からキャンセル料の内訳を作成（1円未満は切り上げ）
+func NewFeeItem(night FeeNight, planID int64, daysBefore int, rate int, rule string) FeeItem {
+	return FeeItem{
+		RoomIndex:  night.RoomIndex,
+		PlanID:     planID,
+		UseDate:    night.UseDate.Format("2006-01-02"),
+		DaysBefore: daysBefore,
+		Amount:     night.Amount,
+		Rate:       rate,
+		Fee:        (night.Amount*rate + 99) / 100,
+		Rule:       rule,
+	}
+}
+
+// dateOf 時刻のタイムゾーンでの日付（夏時間の影響を受けないようUTCの0時で表す）
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// capRate 料率を0〜100%に収める
+func capRate(rate int) int {
+	if rate > 100 {
+		return 100
+	}
+	if rate < 0 {
+		return 0
+	}
+	return rate
+}
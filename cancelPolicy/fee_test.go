@@ -0,0 +1,67 @@
+package cancelPolicy
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseSettings(t *testing.T) {
+	t.Run("文字列で保存された料率も読み込めることのテスト", func(t *testing.T) {
+		settings, err := ParseSettings(`{"Settings":{"CaseOfCancellationToday":{"Rate":"80"},"CaseOfNoShow":{"Rate":"100"},"NonRefundable":0,"Deposit":"0",` +
+			`"AdditionalCases":[{"AdditionalCase":{"StartDays":"1","EndDays":"3","Rate":"30"}}]}}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings.CaseOfCancellationToday.Rate != 80 || settings.CaseOfNoShow.Rate != 100 || len(settings.AdditionalCases) != 1 {
+			t.Fatalf("読み込んだ内容が一致しません。%+v", settings)
+		}
+	})
+}
+
+func Test_CalculateFee(t *testing.T) {
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	arrival := time.Date(2021, 3, 10, 0, 0, 0, 0, jst)
+	nights := []FeeNight{
+		{RoomIndex: 0, UseDate: arrival, Amount: 10000},
+		{RoomIndex: 0, UseDate: arrival.AddDate(0, 0, 1), Amount: 12001},
+	}
+	settings := &Settings{
+		CaseOfCancellationToday: CaseOfCancellationToday{Rate: 80},
+		CaseOfNoShow:            CaseOfNoShow{Rate: 100},
+		AdditionalCases: []AdditionalCases{
+			{AdditionalCase: AdditionalCase{StartDays: "1", EndDays: "3", Rate: "30"}},
+			{AdditionalCase: AdditionalCase{StartDays: "7", EndDays: "2", Rate: "20"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		cancelAt time.Time
+		noShow   bool
+		rules    []string
+		fees     []int
+	}{
+		{"日数指定の範囲外はキャンセル料なし", time.Date(2021, 3, 1, 12, 0, 0, 0, jst), false, []string{FeeRuleNone, FeeRuleNone}, []int{0, 0}},
+		{"範囲が重なる場合は高い料率", time.Date(2021, 3, 8, 12, 0, 0, 0, jst), false, []string{FeeRuleAdditional, FeeRuleAdditional}, []int{3000, 3601}},
+		{"施設のタイムゾーンで当日ならば当日キャンセル", time.Date(2021, 3, 9, 15, 30, 0, 0, time.UTC), false, []string{FeeRuleToday, FeeRuleToday}, []int{8000, 9601}},
+		{"チェックイン後は宿泊済みの日が全額", time.Date(2021, 3, 11, 9, 0, 0, 0, jst), false, []string{FeeRuleStayed, FeeRuleToday}, []int{10000, 9601}},
+		{"NoShowはNoShowの料率", time.Date(2021, 3, 11, 9, 0, 0, 0, jst), true, []string{FeeRuleNoShow, FeeRuleNoShow}, []int{10000, 12001}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := CalculateFee(settings, 1, arrival, nights, tt.cancelAt.In(jst), tt.noShow)
+			for i, item := range items {
+				if item.Rule != tt.rules[i] || item.Fee != tt.fees[i] {
+					t.Fatalf("%d泊目の内訳が一致しません。%+v", i+1, item)
+				}
+			}
+		})
+	}
+
+	t.Run("返金不可は常に全額", func(t *testing.T) {
+		items := CalculateFee(&Settings{NonRefundable: 1}, 1, arrival, nights, arrival.AddDate(0, -1, 0), false)
+		if items[0].Fee != 10000 || items[1].Rule != FeeRuleNonRefundable {
+			t.Fatalf("内訳が一致しません。%+v", items)
+		}
+	})
+}
@@ -7,6 +7,11 @@ import (
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
 	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
@@ -19,6 +24,8 @@ type CancelPolicyHandler struct {
 	FDirectUsecase cancelPolicy.ICancelPolicyUsecase
 	FRaku2Usecase  cancelPolicy.ICancelPolicyUsecase
 	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
+	AuditUsecase   audit.IAuditUsecase
 }
 
 // NewCancelPolicyHandler インスタンス生成
@@ -29,6 +36,8 @@ func NewCancelPolicyHandler(db *gorm.DB) *CancelPolicyHandler {
 		FDirectUsecase: usecase.NewCancelPolicyDirectUsecase(db),
 		FRaku2Usecase:  usecase.NewCancelPolicyRaku2Usecase(db),
 		AUsecase:       aUsecase.NewAccountUsecase(db),
+		OUsecase:       oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:   auditUsecase.NewAuditUsecase(db),
 	}
 }
 
@@ -51,6 +60,11 @@ func (f *CancelPolicyHandler) List(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		cancelPolicyList, _ := f.FTlUsecase.List(request)
@@ -87,6 +101,21 @@ func (f *CancelPolicyHandler) Create(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, f.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := f.AuditUsecase.Begin(hmUser, audit.EntityCancelPolicy, audit.ActionCreate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, f.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		if err := f.FTlUsecase.Create(request); err != nil {
@@ -132,6 +161,11 @@ func (f *CancelPolicyHandler) Detail(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		cancelPolicy, err := f.FTlUsecase.Detail(request)
@@ -177,6 +211,21 @@ func (f *CancelPolicyHandler) Save(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, f.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := f.AuditUsecase.Begin(hmUser, audit.EntityCancelPolicy, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, f.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		if err := f.FTlUsecase.Save(request); err != nil {
@@ -223,6 +272,21 @@ func (f *CancelPolicyHandler) Delete(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, f.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := f.AuditUsecase.Begin(hmUser, audit.EntityCancelPolicy, audit.ActionDelete, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, f.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		if err := f.FTlUsecase.Delete(request); err != nil {
@@ -271,6 +335,11 @@ func (f *CancelPolicyHandler) FetchPlans(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		planList, _ := f.FTlUsecase.PlanList(request)
@@ -5,10 +5,19 @@ import (
 
 	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
 	cp "github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// propertyPolicyTables 施設のデフォルトのキャンセルポリシーを保存しているテーブル
+var propertyPolicyTables = map[int]string{
+	utils.WholesalerIDTl:     "ht_tm_property_tls",
+	utils.WholesalerIDDirect: "ht_tm_property_directs",
+	utils.WholesalerIDNeppan: "ht_tm_property_neppans",
+	utils.WholesalerIDRaku2:  "ht_tm_property_raku2s",
+}
+
 type CommonCancelPolicyRepository struct {
 	db *gorm.DB
 }
@@ -100,3 +109,42 @@ func (r *CommonCancelPolicyRepository) DeletePlanCancelPolicyRelation(wholesaler
 		Where("wholesaler_id = ? AND plan_id = ?", wholesalerID, planId).
 		Delete(&cancelPolicy.HtThPlanCancelPolicyRelations{}).Error
 }
+
+// FetchAssignedPlanCancelPolicies プランに割り当てられたキャンセルポリシーをプランIDごとに取得
+func (r *CommonCancelPolicyRepository) FetchAssignedPlanCancelPolicies(wholesalerID int, propertyID int64, planIDs []int64) (map[int64]cancelPolicy.HtTmPlanCancelPolicies, error) {
+	rows := []struct {
+		PlanID int64
+		cp.HtTmPlanCancelPolicies
+	}{}
+	err := r.db.
+		Table("ht_th_plan_cancel_policy_relations AS relations").
+		Select("relations.plan_id, policies.*").
+		Joins("INNER JOIN ht_tm_plan_cancel_policies AS policies ON relations.plan_cancel_policy_id = policies.plan_cancel_policy_id").
+		Where("relations.wholesaler_id = ?", wholesalerID).
+		Where("relations.property_id = ?", propertyID).
+		Where("relations.plan_id IN ?", planIDs).
+		Scan(&rows).Error
+	result := map[int64]cp.HtTmPlanCancelPolicies{}
+	for _, row := range rows {
+		result[row.PlanID] = row.HtTmPlanCancelPolicies
+	}
+	return result, err
+}
+
+// FetchPropertyCancelPenaltyJSON 施設のデフォルトのキャンセルポリシーを取得（未設定の場合は空文字）
+func (r *CommonCancelPolicyRepository) FetchPropertyCancelPenaltyJSON(wholesalerID int, propertyID int64) (string, error) {
+	table, ok := propertyPolicyTables[wholesalerID]
+	if !ok {
+		return "", nil
+	}
+	result := []string{}
+	err := r.db.
+		Table(table).
+		Where("property_id = ?", propertyID).
+		Limit(1).
+		Pluck("cancel_penalty_json", &result).Error
+	if err != nil || len(result) == 0 {
+		return "", err
+	}
+	return result[0], nil
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// EntityProperty 施設情報
+	EntityProperty = "property"
+	// EntityPlan プラン
+	EntityPlan = "plan"
+	// EntityRoomType 部屋タイプ
+	EntityRoomType = "room_type"
+	// EntityImage 画像
+	EntityImage = "image"
+	// EntityCancelPolicy キャンセルポリシー
+	EntityCancelPolicy = "cancel_policy"
+	// EntityStock 在庫
+	EntityStock = "stock"
+	// EntityPrice 料金
+	EntityPrice = "price"
+	// EntityStopSale 売止
+	EntityStopSale = "stop_sale"
+	// EntityBooking 予約
+	EntityBooking = "booking"
+	// EntityBookingNotification 予約通知の設定
+	EntityBookingNotification = "booking_notification"
+	// EntitySettlement 精算書
+	EntitySettlement = "settlement"
+	// EntitySettlementAccount 精算口座情報
+	EntitySettlementAccount = "settlement_account"
+
+	// ActionCreate 作成
+	ActionCreate = "create"
+	// ActionUpdate 更新
+	ActionUpdate = "update"
+	// ActionDelete 削除
+	ActionDelete = "delete"
+	// ActionCancel 予約のキャンセル
+	ActionCancel = "cancel"
+	// ActionPartialCancel 予約の一部（部屋・泊）のキャンセル
+	ActionPartialCancel = "partial_cancel"
+	// ActionAmend 予約の変更
+	ActionAmend = "amend"
+	// ActionNoShow 予約のNoShow
+	ActionNoShow = "no_show"
+	// ActionReverseNoShow 予約のNoShowの取り消し
+	ActionReverseNoShow = "reverse_no_show"
+	// ActionContactStatus 予約の連絡状況の変更
+	ActionContactStatus = "contact_status"
+	// ActionAppendMemo 予約のメモの追記
+	ActionAppendMemo = "append_memo"
+	// ActionMisuse 予約の不正利用の疑いの設定・解除
+	ActionMisuse = "misuse"
+	// ActionApprove 精算書の承認
+	ActionApprove = "approve"
+)
+
+// EntityKinds 変更対象のIDとして扱うリソースの種類（先頭から順に、リクエストに含まれるものを使う）
+var EntityKinds = map[string][]string{
+	EntityProperty:            {ownership.KindProperty},
+	EntityPlan:                {ownership.KindPlan},
+	EntityRoomType:            {ownership.KindRoomType},
+	EntityImage:               {ownership.KindImage},
+	EntityCancelPolicy:        {ownership.KindCancelPolicy},
+	EntityStock:               {ownership.KindRoomType},
+	EntityPrice:               {ownership.KindPlan, ownership.KindRoomType},
+	EntityStopSale:            {ownership.KindPlan, ownership.KindRoomType},
+	EntityBooking:             {ownership.KindApplication},
+	EntityBookingNotification: {ownership.KindProperty},
+	EntitySettlement:          {ownership.KindSettlement},
+	EntitySettlementAccount:   {ownership.KindSettlementAccount},
+}
+
+// SnapshotEntities 変更前後のレコードを比較できる対象
+// 日付ごとに行が分かれる在庫・料金などは、リクエスト内容を変更後として記録する
+var SnapshotEntities = map[string]bool{
+	EntityProperty:          true,
+	EntityPlan:              true,
+	EntityRoomType:          true,
+	EntityImage:             true,
+	EntityCancelPolicy:      true,
+	EntitySettlementAccount: true,
+}
+
+// ignoredColumns 差分に含めない列
+var ignoredColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// HtThHmAuditLogs HM APIからの変更履歴テーブル（追記のみ）
+type HtThHmAuditLogs struct {
+	AuditLogID          int64     `gorm:"primaryKey;autoIncrement:true" json:"audit_log_id"`
+	RequestID           string    `json:"request_id"`
+	ActorHotelManagerID int64     `json:"actor_hotel_manager_id"`
+	WholesalerID        int64     `json:"wholesaler_id"`
+	PropertyID          int64     `json:"property_id"`
+	EntityType          string    `json:"entity_type"`
+	EntityID            int64     `json:"entity_id"`
+	Action              string    `json:"action"`
+	Diff                string    `gorm:"type:json" json:"-"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// Change 1項目分の変更前後の値
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Trail 1リクエスト分の監査ログ。変更前の状態を保持し、成功した場合に記録する
+type Trail struct {
+	HmUser     account.HtTmHotelManager
+	EntityType string
+	Action     string
+	Request    interface{}
+	Kind       string
+	EntityIDs  []int64
+	PropertyID int64
+	Before     map[int64]map[string]interface{}
+}
+
+// SearchInput 監査ログ検索の入力
+type SearchInput struct {
+	PropertyID          int64  `json:"property_id" query:"property_id"`
+	EntityType          string `json:"entity_type" query:"entity_type"`
+	EntityID            int64  `json:"entity_id" query:"entity_id"`
+	Action              string `json:"action" query:"action"`
+	ActorHotelManagerID int64  `json:"actor_hotel_manager_id" query:"actor_hotel_manager_id"`
+	RequestID           string `json:"request_id" query:"request_id"`
+	From                string `json:"from" query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To                  string `json:"to" query:"to" validate:"omitempty,datetime=2006-01-02"`
+	common.Paging
+}
+
+// AuditLogOutput 監査ログ検索の出力1件
+type AuditLogOutput struct {
+	HtThHmAuditLogs
+	Diff json.RawMessage `json:"diff"`
+}
+
+// IAuditUsecase 監査ログ関連のusecaseのインターフェース
+type IAuditUsecase interface {
+	// Begin 変更対象を特定し、変更前の状態を取得する
+	Begin(hmUser account.HtTmHotelManager, entityType string, action string, request interface{}) (*Trail, error)
+	// Commit 変更後の状態と比較して監査ログを記録する
+	Commit(trail *Trail, requestID string) error
+	// Search ログイン中のアカウントが参照できる施設の監査ログを検索
+	Search(hmUser account.HtTmHotelManager, request *SearchInput) ([]AuditLogOutput, error)
+	// SearchInternal 施設を問わず監査ログを検索（サポート向け）
+	SearchInternal(request *SearchInput) ([]AuditLogOutput, error)
+}
+
+// IAuditRepository 監査ログ関連のrepositoryのインターフェース
+type IAuditRepository interface {
+	common.Repository
+	// FetchSnapshots 変更対象のレコードを列名と値のmapで複数件取得
+	FetchSnapshots(kind string, wholesalerID int64, ids []int64) (map[int64]map[string]interface{}, error)
+	// CreateAuditLogs 監査ログを複数件作成
+	CreateAuditLogs(logs []HtThHmAuditLogs) error
+	// Search 条件に合致する監査ログを新しい順に取得
+	Search(request *SearchInput) ([]HtThHmAuditLogs, error)
+}
+
+// Diff 変更前後のレコードから、値が変わった列のみを抜き出す
+func Diff(before map[string]interface{}, after map[string]interface{}) map[string]Change {
+	changes := map[string]Change{}
+	for column, afterValue := range after {
+		if ignoredColumns[column] {
+			continue
+		}
+		beforeValue, ok := before[column]
+		if ok && reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		changes[column] = Change{Before: beforeValue, After: afterValue}
+	}
+	for column, beforeValue := range before {
+		if _, ok := after[column]; ok || ignoredColumns[column] {
+			continue
+		}
+		changes[column] = Change{Before: beforeValue, After: nil}
+	}
+	return changes
+}
+
+// RecordOnSuccess レスポンスが正常終了した場合のみ監査ログを記録する
+// ハンドラーで Begin の直後に defer して使う
+//
+//	trail, _ := h.AuditUsecase.Begin(hmUser, audit.EntityPlan, audit.ActionUpdate, request)
+//	defer audit.RecordOnSuccess(c, h.AuditUsecase, trail)
+func RecordOnSuccess(c echo.Context, usecase IAuditUsecase, trail *Trail) {
+	if trail == nil || c.Response().Committed == false || c.Response().Status >= http.StatusBadRequest {
+		return
+	}
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	if err := usecase.Commit(trail, requestID); err != nil {
+		c.Echo().Logger.Error(err)
+	}
+}
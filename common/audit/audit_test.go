@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"testing"
+)
+
+func Test_Diff(t *testing.T) {
+	t.Run("値が変わった列のみ抜き出すことのテスト", func(t *testing.T) {
+		before := map[string]interface{}{"plan_id": int64(1), "plan_name": "素泊まり", "stop_sales": false, "updated_at": "2021-01-01"}
+		after := map[string]interface{}{"plan_id": int64(1), "plan_name": "素泊まり", "stop_sales": true, "updated_at": "2021-01-14"}
+		changes := Diff(before, after)
+		if len(changes) != 1 {
+			t.Fatalf("差分の件数が一致しません。%v", changes)
+		}
+		if changes["stop_sales"].Before != false || changes["stop_sales"].After != true {
+			t.Fatalf("差分の値が一致しません。%v", changes["stop_sales"])
+		}
+	})
+
+	t.Run("作成・削除されたレコードはすべての列を差分とすることのテスト", func(t *testing.T) {
+		row := map[string]interface{}{"plan_id": int64(1), "plan_name": "素泊まり"}
+		if changes := Diff(nil, row); len(changes) != 2 || changes["plan_name"].After != "素泊まり" {
+			t.Fatalf("作成時の差分が不正です。%v", changes)
+		}
+		if changes := Diff(row, nil); len(changes) != 2 || changes["plan_name"].After != nil {
+			t.Fatalf("削除時の差分が不正です。%v", changes)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// AuditHandler 監査ログ関連の振り分け
+type AuditHandler struct {
+	AuditUsecase audit.IAuditUsecase
+	AUsecase     account.IAccountUsecase
+	OUsecase     ownership.IOwnershipUsecase
+}
+
+// NewAuditHandler インスタンス生成
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{
+		AuditUsecase: usecase.NewAuditUsecase(db),
+		AUsecase:     aUsecase.NewAccountUsecase(db),
+		OUsecase:     oUsecase.NewOwnershipUsecase(db),
+	}
+}
+
+// Search 監査ログの検索
+func (a *AuditHandler) Search(c echo.Context) error {
+	claimParam, err := utils.GetHmUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	hmUser, err := a.AUsecase.FetchHMUserByToken(claimParam)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+	}
+	request := &audit.SearchInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := a.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
+	logs, err := a.AuditUsecase.Search(hmUser, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	return c.JSON(http.StatusOK, logs)
+}
+
+// SearchInternal 施設を問わない監査ログの検索（サポート向け）
+func (a *AuditHandler) SearchInternal(c echo.Context) error {
+	request := &audit.SearchInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	logs, err := a.AuditUsecase.SearchInternal(request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, logs)
+}
@@ -0,0 +1,127 @@
+package infra
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	ownershipInfra "github.com/Adventureinc/hotel-hm-api/src/common/ownership/infra"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository インスタンス生成
+func NewAuditRepository(db *gorm.DB) audit.IAuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+// TxStart トランザクションスタート
+func (a *auditRepository) TxStart() (*gorm.DB, error) {
+	tx := a.db.Begin()
+	return tx, tx.Error
+}
+
+// TxCommit トランザクションコミット
+func (a *auditRepository) TxCommit(tx *gorm.DB) error {
+	return tx.Commit().Error
+}
+
+// TxRollback トランザクション ロールバック
+func (a *auditRepository) TxRollback(tx *gorm.DB) {
+	tx.Rollback()
+}
+
+// FetchSnapshots 変更対象のレコードを列名と値のmapで複数件取得
+func (a *auditRepository) FetchSnapshots(kind string, wholesalerID int64, ids []int64) (map[int64]map[string]interface{}, error) {
+	table, column, ok := ownershipInfra.TableOf(kind, wholesalerID)
+	if !ok {
+		return nil, fmt.Errorf("Error: %s", "resource "+kind+" is not supported for this wholesaler")
+	}
+	rows := []map[string]interface{}{}
+	if err := a.db.Table(table).Where(column+" IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := map[int64]map[string]interface{}{}
+	for _, row := range rows {
+		for key, value := range row {
+			// 文字列の列はバイト列で返ってくるため、JSONで読める形にそろえる
+			if b, ok := value.([]byte); ok {
+				row[key] = string(b)
+			}
+		}
+		id, ok := toInt64(row[column])
+		if !ok {
+			continue
+		}
+		result[id] = row
+	}
+	return result, nil
+}
+
+// CreateAuditLogs 監査ログを複数件作成
+func (a *auditRepository) CreateAuditLogs(logs []audit.HtThHmAuditLogs) error {
+	return a.db.Create(&logs).Error
+}
+
+// Search 条件に合致する監査ログを新しい順に取得
+func (a *auditRepository) Search(request *audit.SearchInput) ([]audit.HtThHmAuditLogs, error) {
+	result := []audit.HtThHmAuditLogs{}
+	query := a.db.Model(&audit.HtThHmAuditLogs{})
+	if request.PropertyID != 0 {
+		query = query.Where("property_id = ?", request.PropertyID)
+	}
+	if request.EntityType != "" {
+		query = query.Where("entity_type = ?", request.EntityType)
+	}
+	if request.EntityID != 0 {
+		query = query.Where("entity_id = ?", request.EntityID)
+	}
+	if request.Action != "" {
+		query = query.Where("action = ?", request.Action)
+	}
+	if request.ActorHotelManagerID != 0 {
+		query = query.Where("actor_hotel_manager_id = ?", request.ActorHotelManagerID)
+	}
+	if request.RequestID != "" {
+		query = query.Where("request_id = ?", request.RequestID)
+	}
+	if request.From != "" {
+		query = query.Where("created_at >= ?", request.From)
+	}
+	if request.To != "" {
+		to, err := time.Parse("2006-01-02", request.To)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1).Format("2006-01-02"))
+	}
+	err := query.
+		Order("audit_log_id DESC").
+		Limit(request.Limit).
+		Offset(request.Offset).
+		Find(&result).Error
+	return result, err
+}
+
+// toInt64 主キーの値をint64で取り出す
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int32:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	}
+	return 0, false
+}
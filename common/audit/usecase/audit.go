@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	"gorm.io/gorm"
+)
+
+// searchDefaultLimit 検索の件数指定がない場合の件数
+const searchDefaultLimit = 100
+
+type auditUsecase struct {
+	ARepository audit.IAuditRepository
+}
+
+// NewAuditUsecase インスタンス生成
+func NewAuditUsecase(db *gorm.DB) audit.IAuditUsecase {
+	return &auditUsecase{
+		ARepository: infra.NewAuditRepository(db),
+	}
+}
+
+// Begin 変更対象を特定し、変更前の状態を取得する
+func (a *auditUsecase) Begin(hmUser account.HtTmHotelManager, entityType string, action string, request interface{}) (*audit.Trail, error) {
+	trail := &audit.Trail{
+		HmUser:     hmUser,
+		EntityType: entityType,
+		Action:     action,
+		Request:    request,
+		PropertyID: hmUser.PropertyID,
+	}
+	resources := ownership.ResourcesOf(request)
+	for _, kind := range audit.EntityKinds[entityType] {
+		trail.EntityIDs = idsOf(resources, kind)
+		if len(trail.EntityIDs) > 0 {
+			trail.Kind = kind
+			break
+		}
+	}
+	// 親アカウントの場合は、リクエストで指定した施設を記録する
+	if trail.PropertyID == 0 {
+		if propertyIDs := idsOf(resources, ownership.KindProperty); len(propertyIDs) > 0 {
+			trail.PropertyID = propertyIDs[0]
+		}
+	}
+
+	if audit.SnapshotEntities[entityType] && len(trail.EntityIDs) > 0 {
+		before, err := a.ARepository.FetchSnapshots(trail.Kind, hmUser.WholesalerID, trail.EntityIDs)
+		if err != nil {
+			return nil, err
+		}
+		trail.Before = before
+	}
+	return trail, nil
+}
+
+// Commit 変更後の状態と比較して監査ログを記録する
+// 変更前後を比較できない対象は、リクエスト内容を変更後として記録する
+func (a *auditUsecase) Commit(trail *audit.Trail, requestID string) error {
+	var after map[int64]map[string]interface{}
+	if trail.Before != nil {
+		fetched, err := a.ARepository.FetchSnapshots(trail.Kind, trail.HmUser.WholesalerID, trail.EntityIDs)
+		if err != nil {
+			return err
+		}
+		after = fetched
+	}
+
+	entityIDs := trail.EntityIDs
+	if len(entityIDs) == 0 {
+		entityIDs = []int64{0}
+	}
+	now := time.Now()
+	logs := []audit.HtThHmAuditLogs{}
+	for _, entityID := range entityIDs {
+		var diff interface{}
+		if trail.Before != nil {
+			diff = audit.Diff(trail.Before[entityID], after[entityID])
+		} else {
+			diff = map[string]interface{}{"request": trail.Request}
+		}
+		diffJSON, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		logs = append(logs, audit.HtThHmAuditLogs{
+			RequestID:           requestID,
+			ActorHotelManagerID: trail.HmUser.HotelManagerID,
+			WholesalerID:        trail.HmUser.WholesalerID,
+			PropertyID:          trail.PropertyID,
+			EntityType:          trail.EntityType,
+			EntityID:            entityID,
+			Action:              trail.Action,
+			Diff:                string(diffJSON),
+			CreatedAt:           now,
+		})
+	}
+	return a.ARepository.CreateAuditLogs(logs)
+}
+
+// Search ログイン中のアカウントが参照できる施設の監査ログを検索
+// 親アカウントの場合は施設の指定が必須（指定した施設の所有はハンドラーで確認済み）
+func (a *auditUsecase) Search(hmUser account.HtTmHotelManager, request *audit.SearchInput) ([]audit.AuditLogOutput, error) {
+	if hmUser.PropertyID != 0 {
+		request.PropertyID = hmUser.PropertyID
+	}
+	if request.PropertyID == 0 {
+		return nil, fmt.Errorf("Error: %s", "施設を指定してください。")
+	}
+	return a.search(request)
+}
+
+// SearchInternal 施設を問わず監査ログを検索（サポート向け）
+func (a *auditUsecase) SearchInternal(request *audit.SearchInput) ([]audit.AuditLogOutput, error) {
+	return a.search(request)
+}
+
+// search 監査ログを検索して出力用に変換
+func (a *auditUsecase) search(request *audit.SearchInput) ([]audit.AuditLogOutput, error) {
+	if request.Limit == 0 {
+		request.Limit = searchDefaultLimit
+	}
+	logs, err := a.ARepository.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	output := []audit.AuditLogOutput{}
+	for _, log := range logs {
+		output = append(output, audit.AuditLogOutput{
+			HtThHmAuditLogs: log,
+			Diff:            json.RawMessage(log.Diff),
+		})
+	}
+	return output, nil
+}
+
+// idsOf 指定した種類のリソースIDを重複なしで抜き出す
+func idsOf(resources []ownership.Resource, kind string) []int64 {
+	ids := []int64{}
+	exists := map[int64]bool{}
+	for _, resource := range resources {
+		if resource.Kind != kind || exists[resource.ID] {
+			continue
+		}
+		exists[resource.ID] = true
+		ids = append(ids, resource.ID)
+	}
+	return ids
+}
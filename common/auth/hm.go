@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// HM HMアカウントのアクセストークンを検証し、contextの"user"に格納するmiddleware
+// 署名鍵はkidで切り替えるため、HS256とRS256のトークンが混在していても検証できる
+// ログアウト・端末のログアウトで失効させたセッション・トークンもここで拒否する
+// ルーティング（app.Route）でHMアカウント向けのグループに適用する
+//
+//	例）hm := e.Group("", auth.HM(db))
+func HM(db *gorm.DB) echo.MiddlewareFunc {
+	accountUsecase := aUsecase.NewAccountUsecase(db)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+			if !strings.HasPrefix(authorization, "Bearer ") {
+				return echo.NewHTTPError(http.StatusBadRequest, "missing or malformed jwt")
+			}
+			token, err := utils.ParseToken(strings.TrimPrefix(authorization, "Bearer "))
+			if err != nil || !token.Valid {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+			}
+			c.Set("user", token)
+
+			claimParam, err := utils.GetHmUser(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+			}
+			// セッションの失効・期限切れ、トークン（jti）の失効を確認する
+			if _, err := accountUsecase.FetchHMUserByToken(claimParam); err != nil {
+				c.Echo().Logger.Error(err)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+			}
+			return next(c)
+		}
+	}
+}
@@ -1,15 +1,24 @@
 package auth
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"strconv"
 
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential"
+	cUsecase "github.com/Adventureinc/hotel-hm-api/src/common/credential/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
+// internalMaxBodyBytes 署名を確認する連携APIのリクエストサイズ上限（BulkBodyLimitの既定値と同じ）
+const internalMaxBodyBytes = 32 << 20
+
 func Internal(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// check `API_KEY` exist in request header
@@ -32,6 +41,68 @@ func Internal(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// InternalWithDB 連携APIリクエストの署名を検証するmiddleware
+// ホールセラーはキーから特定し、Wholesaler-Id ヘッダーを上書きする（送られてきたヘッダーは信用しない）
+//
+//	例）bulk.POST("/plan", h.CreateOrUpdateBulk, auth.InternalWithDB(db))
+func InternalWithDB(db *gorm.DB) echo.MiddlewareFunc {
+	credentialUsecase := cUsecase.NewCredentialUsecase(db)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// BulkBodyLimitを付けずに登録されたルートでも、署名の確認のために際限なく読み込まない
+			body, err := ioutil.ReadAll(io.LimitReader(c.Request().Body, internalMaxBodyBytes+1))
+			if err != nil {
+				c.Echo().Logger.Error(err)
+				return echo.ErrBadRequest
+			}
+			if len(body) > internalMaxBodyBytes {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			header := c.Request().Header
+			wholesalerID, err := credentialUsecase.Verify(&credential.SignedRequest{
+				KeyID:     header.Get(credential.HeaderKeyID),
+				Timestamp: header.Get(credential.HeaderTimestamp),
+				Nonce:     header.Get(credential.HeaderNonce),
+				Signature: header.Get(credential.HeaderSignature),
+				Method:    c.Request().Method,
+				Path:      c.Request().URL.RequestURI(),
+				Body:      body,
+			})
+			if err != nil {
+				c.Echo().Logger.Error(err)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+			}
+			if !containsInValidWholesalerList(int(wholesalerID)) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Unknown Wholesaler-Id")
+			}
+			// 他のホールセラーを名乗るリクエストは拒否する
+			wholesalerIDString := strconv.FormatInt(wholesalerID, 10)
+			if requested := header.Get("Wholesaler-Id"); requested != "" && requested != wholesalerIDString {
+				return echo.NewHTTPError(http.StatusForbidden, "Wholesaler-Id does not match the credential")
+			}
+			header.Set("Wholesaler-Id", wholesalerIDString)
+			c.Set("wholesalerID", wholesalerID)
+
+			return next(c)
+		}
+	}
+}
+
+// InternalAdmin 社内運用向けAPI（連携APIキーの発行・サポート向け検索等）の共有キーを確認するmiddleware
+// ホールセラーからの連携には使わない
+func InternalAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// check `API_KEY` exist in request header
+		apiKey := c.Request().Header.Get(os.Getenv("ADV_INTERNAL_API_KEY_HEADER"))
+		if os.Getenv("ADV_INTERNAL_API_KEY") == "" || apiKey != os.Getenv("ADV_INTERNAL_API_KEY") {
+			return echo.NewHTTPError(http.StatusUnauthorized, fmt.Sprintf("Invalid %s", os.Getenv("ADV_INTERNAL_API_KEY_HEADER")))
+		}
+		return next(c)
+	}
+}
+
 func containsInValidWholesalerList(wholesalerId int) bool {
 	validWholesalers := []int{
 		utils.WholesalerIDParent,
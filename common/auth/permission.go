@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Permission HMアカウントが指定の権限を持っているか確認するmiddleware
+// HM（トークン検証）の後にルートごとに適用する
+//
+//	例）settlement.PUT("/approve", h.Approve, auth.Permission(db, account.PermissionSettlementApprove))
+func Permission(db *gorm.DB, permission string) echo.MiddlewareFunc {
+	accountUsecase := aUsecase.NewAccountUsecase(db)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claimParam, err := utils.GetHmUser(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+			}
+			hmUser, err := accountUsecase.FetchHMUserByToken(claimParam)
+			if err != nil {
+				c.Echo().Logger.Error(err)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+			}
+			if err := RequirePermission(c, accountUsecase, &hmUser, permission); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequirePermission HMアカウントが指定の権限を持っているか確認し、持っていない場合は403を返す
+// 変更系のハンドラーで、所有確認（OUsecase.Authorize）の後に呼び出す
+func RequirePermission(c echo.Context, accountUsecase account.IAccountUsecase, hmUser *account.HtTmHotelManager, permission string) error {
+	allowed, err := accountUsecase.HasPermission(hmUser, permission)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	if allowed == false {
+		return echo.NewHTTPError(http.StatusForbidden, "permission denied")
+	}
+	return nil
+}
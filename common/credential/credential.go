@@ -0,0 +1,123 @@
+package credential
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+// ErrInvalidSignature 連携APIリクエストの署名・キー・タイムスタンプが正しくない
+var ErrInvalidSignature = errors.New("Error: リクエストの署名が正しくありません。")
+
+// ErrReplayedRequest 同じノンスのリクエストが既に処理されている
+var ErrReplayedRequest = errors.New("Error: 同じリクエストが既に送信されています。")
+
+const (
+	// SignatureTolerance リクエストのタイムスタンプとサーバー時刻のずれの許容範囲
+	SignatureTolerance = 5 * time.Minute
+	// DefaultRotationOverlap キー更新後、旧キーを引き続き使える期間の既定値
+	DefaultRotationOverlap = 7 * 24 * time.Hour
+	// DefaultNoncePurgeInterval 有効期限を過ぎたノンスを削除する間隔
+	DefaultNoncePurgeInterval = 10 * time.Minute
+
+	// HeaderKeyID キーIDのヘッダー
+	HeaderKeyID = "X-Api-Key-Id"
+	// HeaderTimestamp タイムスタンプ（UNIX秒）のヘッダー
+	HeaderTimestamp = "X-Api-Timestamp"
+	// HeaderNonce ノンス（リクエストごとに一意な文字列）のヘッダー
+	HeaderNonce = "X-Api-Nonce"
+	// HeaderSignature 署名のヘッダー
+	HeaderSignature = "X-Api-Signature"
+)
+
+// HtTmWholesalerAPICredentials ホールセラーごとの連携APIキーテーブル
+// 署名の検証に平文が必要なため、シークレットは暗号化して保持する
+type HtTmWholesalerAPICredentials struct {
+	KeyID        string    `gorm:"primaryKey" json:"key_id"`
+	WholesalerID int64     `json:"wholesaler_id"`
+	SecretEnc    string    `json:"-"`
+	ExpiresAt    time.Time `gorm:"type:time" json:"expires_at"`
+	RevokedAt    time.Time `gorm:"type:time" json:"revoked_at"`
+	LastUsedAt   time.Time `gorm:"type:time" json:"last_used_at"`
+	common.Times `gorm:"embedded"`
+}
+
+// HtThWholesalerAPINonces 処理済みリクエストのノンステーブル（再送攻撃の防止）
+type HtThWholesalerAPINonces struct {
+	KeyID     string    `gorm:"primaryKey" json:"key_id"`
+	Nonce     string    `gorm:"primaryKey" json:"nonce"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignedRequest 署名の検証に必要なリクエストの内容
+type SignedRequest struct {
+	KeyID     string
+	Timestamp string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+// IssueInput キー発行・更新の入力
+type IssueInput struct {
+	WholesalerID int64 `json:"wholesaler_id" param:"wholesalerId" validate:"required"`
+	// OverlapHours 更新時に旧キーを引き続き使える時間（未指定の場合は7日）
+	OverlapHours int `json:"overlap_hours" validate:"min=0"`
+}
+
+// RevokeInput キー失効の入力
+type RevokeInput struct {
+	KeyID string `json:"key_id" param:"keyId" validate:"required"`
+}
+
+// ListInput キー一覧の入力
+type ListInput struct {
+	WholesalerID int64 `json:"wholesaler_id" param:"wholesalerId" validate:"required"`
+}
+
+// IssueOutput キー発行の出力（シークレットは発行時の1回のみ返す）
+type IssueOutput struct {
+	KeyID        string `json:"key_id"`
+	Secret       string `json:"secret"`
+	WholesalerID int64  `json:"wholesaler_id"`
+}
+
+// ICredentialUsecase 連携APIキー関連のusecaseのインターフェース
+type ICredentialUsecase interface {
+	// Verify 署名を検証し、キーに紐づくホールセラーIDを返す
+	Verify(request *SignedRequest) (int64, error)
+	// Issue キーを新規発行（既存のキーはそのまま使える）
+	Issue(wholesalerID int64) (*IssueOutput, error)
+	// Rotate キーを新規発行し、既存のキーは指定期間後に失効させる
+	Rotate(wholesalerID int64, overlap time.Duration) (*IssueOutput, error)
+	// Revoke キーを即時失効
+	Revoke(keyID string) error
+	// List ホールセラーのキー一覧
+	List(wholesalerID int64) ([]HtTmWholesalerAPICredentials, error)
+	// PurgeNonces 有効期限を過ぎたノンスを削除（RunNoncePurgeWorkerで定期実行）
+	PurgeNonces() (int64, error)
+}
+
+// ICredentialRepository 連携APIキー関連のrepositoryのインターフェース
+type ICredentialRepository interface {
+	common.Repository
+	// FetchOne キーIDで連携APIキーを1件取得
+	FetchOne(keyID string) (HtTmWholesalerAPICredentials, error)
+	// FetchAll ホールセラーの連携APIキーを複数件取得
+	FetchAll(wholesalerID int64) ([]HtTmWholesalerAPICredentials, error)
+	// Create 連携APIキーを1件作成
+	Create(credential *HtTmWholesalerAPICredentials) error
+	// Rotate 連携APIキーを1件作成し、既存の有効なキーの有効期限を設定
+	Rotate(credential *HtTmWholesalerAPICredentials, expiresAt time.Time) error
+	// Revoke 連携APIキーを失効
+	Revoke(keyID string) error
+	// TouchLastUsed 最終利用日時を更新
+	TouchLastUsed(keyID string) error
+	// CreateNonce ノンスを記録（既に記録済みの場合はfalse）
+	CreateNonce(nonce *HtThWholesalerAPINonces) (bool, error)
+	// DeleteNoncesBefore 指定日時より前のノンスを削除
+	DeleteNoncesBefore(before time.Time) (int64, error)
+}
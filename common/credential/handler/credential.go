@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential"
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// CredentialHandler 連携APIキー関連の振り分け（社内運用向け）
+type CredentialHandler struct {
+	CUsecase credential.ICredentialUsecase
+}
+
+// NewCredentialHandler インスタンス生成
+func NewCredentialHandler(db *gorm.DB) *CredentialHandler {
+	return &CredentialHandler{
+		CUsecase: usecase.NewCredentialUsecase(db),
+	}
+}
+
+// List ホールセラーの連携APIキー一覧
+func (h *CredentialHandler) List(c echo.Context) error {
+	request := &credential.ListInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+
+	credentials, err := h.CUsecase.List(request.WholesalerID)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// Issue 連携APIキーの発行
+func (h *CredentialHandler) Issue(c echo.Context) error {
+	request := &credential.IssueInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	output, err := h.CUsecase.Issue(request.WholesalerID)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// Rotate 連携APIキーの更新（旧キーは指定時間後に失効）
+func (h *CredentialHandler) Rotate(c echo.Context) error {
+	request := &credential.IssueInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	overlap := credential.DefaultRotationOverlap
+	if request.OverlapHours > 0 {
+		overlap = time.Duration(request.OverlapHours) * time.Hour
+	}
+	output, err := h.CUsecase.Rotate(request.WholesalerID, overlap)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+// Revoke 連携APIキーの即時失効
+func (h *CredentialHandler) Revoke(c echo.Context) error {
+	request := &credential.RevokeInput{}
+	if err := c.Bind(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	if err := c.Validate(request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrBadRequest
+	}
+	utils.RequestLog(c, request)
+
+	if err := h.CUsecase.Revoke(request.KeyID); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// PurgeNonces 有効期限を過ぎたノンスの削除（定期実行）
+func (h *CredentialHandler) PurgeNonces(c echo.Context) error {
+	deleted, err := h.CUsecase.PurgeNonces()
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	return c.JSON(http.StatusOK, map[string]int64{"deleted": deleted})
+}
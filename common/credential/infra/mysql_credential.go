@@ -0,0 +1,106 @@
+package infra
+
+import (
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type credentialRepository struct {
+	db *gorm.DB
+}
+
+// NewCredentialRepository インスタンス生成
+func NewCredentialRepository(db *gorm.DB) credential.ICredentialRepository {
+	return &credentialRepository{
+		db: db,
+	}
+}
+
+// TxStart トランザクションスタート
+func (c *credentialRepository) TxStart() (*gorm.DB, error) {
+	tx := c.db.Begin()
+	return tx, tx.Error
+}
+
+// TxCommit トランザクションコミット
+func (c *credentialRepository) TxCommit(tx *gorm.DB) error {
+	return tx.Commit().Error
+}
+
+// TxRollback トランザクション ロールバック
+func (c *credentialRepository) TxRollback(tx *gorm.DB) {
+	tx.Rollback()
+}
+
+// FetchOne キーIDで連携APIキーを1件取得
+func (c *credentialRepository) FetchOne(keyID string) (credential.HtTmWholesalerAPICredentials, error) {
+	result := credential.HtTmWholesalerAPICredentials{}
+	err := c.db.
+		Where("key_id = ?", keyID).
+		First(&result).Error
+	return result, err
+}
+
+// FetchAll ホールセラーの連携APIキーを複数件取得
+func (c *credentialRepository) FetchAll(wholesalerID int64) ([]credential.HtTmWholesalerAPICredentials, error) {
+	result := []credential.HtTmWholesalerAPICredentials{}
+	err := c.db.
+		Where("wholesaler_id = ?", wholesalerID).
+		Order("created_at DESC").
+		Find(&result).Error
+	return result, err
+}
+
+// Create 連携APIキーを1件作成
+func (c *credentialRepository) Create(cred *credential.HtTmWholesalerAPICredentials) error {
+	return c.db.Create(cred).Error
+}
+
+// Rotate 連携APIキーを1件作成し、既存の有効なキーの有効期限を設定
+// 既に期限が設定されているキーは、期限を延ばさない
+func (c *credentialRepository) Rotate(cred *credential.HtTmWholesalerAPICredentials, expiresAt time.Time) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&credential.HtTmWholesalerAPICredentials{}).
+			Where("wholesaler_id = ? AND revoked_at IS NULL", cred.WholesalerID).
+			Where("expires_at IS NULL OR expires_at > ?", expiresAt).
+			Updates(map[string]interface{}{
+				"expires_at": expiresAt,
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(cred).Error
+	})
+}
+
+// Revoke 連携APIキーを失効
+func (c *credentialRepository) Revoke(keyID string) error {
+	return c.db.Model(&credential.HtTmWholesalerAPICredentials{}).
+		Where("key_id = ? AND revoked_at IS NULL", keyID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// TouchLastUsed 最終利用日時を更新
+func (c *credentialRepository) TouchLastUsed(keyID string) error {
+	return c.db.Model(&credential.HtTmWholesalerAPICredentials{}).
+		Where("key_id = ?", keyID).
+		Update("last_used_at", time.Now()).Error
+}
+
+// CreateNonce ノンスを記録（既に記録済みの場合はfalse）
+func (c *credentialRepository) CreateNonce(nonce *credential.HtThWholesalerAPINonces) (bool, error) {
+	result := c.db.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(nonce)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteNoncesBefore 指定日時より前のノンスを削除
+func (c *credentialRepository) DeleteNoncesBefore(before time.Time) (int64, error) {
+	result := c.db.Where("created_at < ?", before).Delete(&credential.HtThWholesalerAPINonces{})
+	return result.RowsAffected, result.Error
+}
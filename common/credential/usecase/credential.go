@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential"
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type credentialUsecase struct {
+	CRepository credential.ICredentialRepository
+}
+
+// NewCredentialUsecase インスタンス生成
+func NewCredentialUsecase(db *gorm.DB) credential.ICredentialUsecase {
+	return &credentialUsecase{
+		CRepository: infra.NewCredentialRepository(db),
+	}
+}
+
+// Verify 署名を検証し、キーに紐づくホールセラーIDを返す
+// タイムスタンプが許容範囲外、もしくはノンスが処理済みの場合は拒否する
+func (c *credentialUsecase) Verify(request *credential.SignedRequest) (int64, error) {
+	if request.KeyID == "" || request.Nonce == "" || request.Signature == "" {
+		return 0, credential.ErrInvalidSignature
+	}
+	unix, err := strconv.ParseInt(request.Timestamp, 10, 64)
+	if err != nil {
+		return 0, credential.ErrInvalidSignature
+	}
+	now := time.Now()
+	requestedAt := time.Unix(unix, 0)
+	if requestedAt.Before(now.Add(-credential.SignatureTolerance)) || requestedAt.After(now.Add(credential.SignatureTolerance)) {
+		return 0, credential.ErrInvalidSignature
+	}
+
+	cred, err := c.CRepository.FetchOne(request.KeyID)
+	if err != nil {
+		return 0, credential.ErrInvalidSignature
+	}
+	if isActive(&cred, now) == false {
+		return 0, credential.ErrInvalidSignature
+	}
+	secret, err := utils.DecryptSecret(cred.SecretEnc)
+	if err != nil {
+		return 0, err
+	}
+	if utils.VerifyRequestSignature(secret, request.Method, request.Path, request.Timestamp, request.Nonce, request.Body, request.Signature) == false {
+		return 0, credential.ErrInvalidSignature
+	}
+
+	// 署名が正しいことを確認してからノンスを記録する（他人のノンスを先に消費させない）
+	created, err := c.CRepository.CreateNonce(&credential.HtThWholesalerAPINonces{
+		KeyID:     cred.KeyID,
+		Nonce:     request.Nonce,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if created == false {
+		return 0, credential.ErrReplayedRequest
+	}
+	if err := c.CRepository.TouchLastUsed(cred.KeyID); err != nil {
+		return 0, err
+	}
+	return cred.WholesalerID, nil
+}
+
+// Issue キーを新規発行（既存のキーはそのまま使える）
+func (c *credentialUsecase) Issue(wholesalerID int64) (*credential.IssueOutput, error) {
+	cred, secret, err := newCredential(wholesalerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CRepository.Create(cred); err != nil {
+		return nil, err
+	}
+	return &credential.IssueOutput{KeyID: cred.KeyID, Secret: secret, WholesalerID: wholesalerID}, nil
+}
+
+// Rotate キーを新規発行し、既存のキーは指定期間後に失効させる
+// 期間中は新旧どちらのキーでも署名できるため、連携先は停止せずにキーを切り替えられる
+func (c *credentialUsecase) Rotate(wholesalerID int64, overlap time.Duration) (*credential.IssueOutput, error) {
+	cred, secret, err := newCredential(wholesalerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CRepository.Rotate(cred, time.Now().Add(overlap)); err != nil {
+		return nil, err
+	}
+	return &credential.IssueOutput{KeyID: cred.KeyID, Secret: secret, WholesalerID: wholesalerID}, nil
+}
+
+// Revoke キーを即時失効
+func (c *credentialUsecase) Revoke(keyID string) error {
+	return c.CRepository.Revoke(keyID)
+}
+
+// List ホールセラーのキー一覧
+func (c *credentialUsecase) List(wholesalerID int64) ([]credential.HtTmWholesalerAPICredentials, error) {
+	return c.CRepository.FetchAll(wholesalerID)
+}
+
+// PurgeNonces 有効期限を過ぎたノンスを削除（RunNoncePurgeWorkerで定期実行）
+// タイムスタンプの許容範囲外のリクエストはノンスを見る前に拒否されるため、それより古いものは不要
+func (c *credentialUsecase) PurgeNonces() (int64, error) {
+	return c.CRepository.DeleteNoncesBefore(time.Now().Add(-2 * credential.SignatureTolerance))
+}
+
+// NoncePurgeInterval 有効期限を過ぎたノンスを削除する間隔
+// NONCE_PURGE_INTERVAL（例: 10m）で変更できる
+func NoncePurgeInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("NONCE_PURGE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return credential.DefaultNoncePurgeInterval
+	}
+	return interval
+}
+
+// RunNoncePurgeWorker ctxが終了するまで、interval毎に有効期限を過ぎたノンスを削除する
+func RunNoncePurgeWorker(ctx context.Context, cUsecase credential.ICredentialUsecase, interval time.Duration, logger echo.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := cUsecase.PurgeNonces(); err != nil {
+			logger.Error(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newCredential キーIDとシークレットを生成（シークレットは暗号化して保持する）
+func newCredential(wholesalerID int64) (*credential.HtTmWholesalerAPICredentials, string, error) {
+	keyID, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	secretEnc, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	return &credential.HtTmWholesalerAPICredentials{
+		KeyID:        "wk_" + keyID,
+		WholesalerID: wholesalerID,
+		SecretEnc:    secretEnc,
+		Times:        common.Times{CreatedAt: now, UpdatedAt: now},
+	}, secret, nil
+}
+
+// isActive 失効しておらず、有効期限内のキーか
+func isActive(cred *credential.HtTmWholesalerAPICredentials, now time.Time) bool {
+	if cred.RevokedAt.IsZero() == false {
+		return false
+	}
+	return cred.ExpiresAt.IsZero() || cred.ExpiresAt.After(now)
+}
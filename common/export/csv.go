@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// utf8BOM ExcelにUTF-8と判定させるためのBOM
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvWriter CSV出力（xlsxはすべて文字列のセルとして出力するため、数式のエスケープはCSVのみ行う）
+type csvWriter struct {
+	writer  *csv.Writer
+	encoder io.WriteCloser
+}
+
+// newCSVWriter インスタンス生成
+func newCSVWriter(w io.Writer, shiftJIS bool) (Writer, error) {
+	result := &csvWriter{}
+	if shiftJIS {
+		result.encoder = transform.NewWriter(w, encoding.ReplaceUnsupported(japanese.ShiftJIS.NewEncoder()))
+		w = result.encoder
+	} else if _, err := w.Write(utf8BOM); err != nil {
+		return nil, err
+	}
+	result.writer = csv.NewWriter(w)
+	result.writer.UseCRLF = true
+	return result, nil
+}
+
+// Write 1行出力
+// 宿泊者が入力した値がExcelで数式として実行されないよう、数式とみなされる文字で始まるセルは先頭に ' を付ける
+func (c *csvWriter) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, value := range record {
+		escaped[i] = escapeFormula(value)
+	}
+	return c.writer.Write(escaped)
+}
+
+// escapeFormula 数式とみなされる文字（= + - @ タブ 改行）で始まる値の先頭に ' を付ける
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Close バッファに残っている内容を出力して終了する
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return err
+	}
+	if c.encoder != nil {
+		return c.encoder.Close()
+	}
+	return nil
+}
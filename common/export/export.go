@@ -0,0 +1,73 @@
+package export
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// FormatCSV CSV（UTF-8 BOM付き。Excelで開いても文字化けしない）
+	FormatCSV = "csv"
+	// FormatCSVShiftJIS CSV（Shift_JIS。Shift_JISで表せない文字は「?」に置き換える）
+	FormatCSVShiftJIS = "csv_sjis"
+	// FormatXLSX Excel（xlsx）
+	FormatXLSX = "xlsx"
+	// FormatPDF PDF（A4横の表。印刷用）
+	FormatPDF = "pdf"
+)
+
+// Writer 表形式のデータを1行ずつ出力する
+type Writer interface {
+	// Write 1行出力
+	Write(record []string) error
+	// Close バッファに残っている内容を出力して終了する（出力先自体はCloseしない）
+	Close() error
+}
+
+// NewWriter 出力形式に応じたWriterを生成
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, false)
+	case FormatCSVShiftJIS:
+		return newCSVWriter(w, true)
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatPDF:
+		return newPDFWriter(w)
+	}
+	return nil, fmt.Errorf("Error: %s", "unsupported export format "+format)
+}
+
+// ContentType 出力形式に応じたContent-Type
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=UTF-8"
+	case FormatCSVShiftJIS:
+		return "text/csv; charset=Shift_JIS"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "application/octet-stream"
+}
+
+// Extension 出力形式に応じたファイルの拡張子
+func Extension(format string) string {
+	switch format {
+	case FormatXLSX:
+		return ".xlsx"
+	case FormatPDF:
+		return ".pdf"
+	}
+	return ".csv"
+}
+
+// SetTitle 見出しを表示できる形式（PDF）の場合、見出しを設定する
+func SetTitle(writer Writer, title string) {
+	if titled, ok := writer.(interface{ SetTitle(title string) }); ok {
+		titled.SetTitle(title)
+	}
+}
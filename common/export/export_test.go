@@ -0,0 +1,113 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func writeAll(t *testing.T, format string, records [][]string) []byte {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(format, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func Test_NewWriter(t *testing.T) {
+	records := [][]string{{"予約番号", "予約者名"}, {"1001", "山田 太郎"}}
+
+	t.Run("UTF-8のCSVはBOM付きで出力されることのテスト", func(t *testing.T) {
+		out := writeAll(t, FormatCSV, records)
+		if bytes.HasPrefix(out, utf8BOM) == false {
+			t.Fatalf("BOMがありません。%x", out[:3])
+		}
+		if string(out[3:]) != "予約番号,予約者名\r\n1001,山田 太郎\r\n" {
+			t.Fatalf("出力内容が一致しません。%s", out)
+		}
+	})
+
+	t.Run("CSVは数式とみなされる値の先頭に'を付けて出力されることのテスト", func(t *testing.T) {
+		out := writeAll(t, FormatCSV, [][]string{{"=HYPERLINK(\"http://example.com\")", "+81", "-1", "@SUM(A1)", "\tx", "山田=太郎"}})
+		want := "\"'=HYPERLINK(\"\"http://example.com\"\")\",'+81,'-1,'@SUM(A1),'\tx,山田=太郎\r\n"
+		if string(out[3:]) != want {
+			t.Fatalf("出力内容が一致しません。%q", out[3:])
+		}
+	})
+
+	t.Run("Shift_JISのCSVは変換できない文字を置き換えて出力されることのテスト", func(t *testing.T) {
+		out := writeAll(t, FormatCSVShiftJIS, [][]string{{"髙橋", "🏨"}})
+		decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(string(decoded), "髙橋,") == false || strings.Contains(string(decoded), "🏨") {
+			t.Fatalf("出力内容が一致しません。%s", decoded)
+		}
+	})
+
+	t.Run("xlsxはシートに全行がエスケープされて出力されることのテスト", func(t *testing.T) {
+		out := writeAll(t, FormatXLSX, append(records, []string{"<script>", "A&B"}))
+		z, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sheet string
+		for _, f := range z.File {
+			if f.Name != xlsxSheetName {
+				continue
+			}
+			r, _ := f.Open()
+			b, _ := ioutil.ReadAll(r)
+			sheet = string(b)
+		}
+		if strings.Count(sheet, "<row>") != 3 || strings.Contains(sheet, "&lt;script&gt;") == false || strings.Contains(sheet, "A&amp;B") == false {
+			t.Fatalf("シートの内容が一致しません。%s", sheet)
+		}
+	})
+
+	t.Run("PDFは見出し行を各ページに繰り返して出力されることのテスト", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w, err := NewWriter(FormatPDF, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetTitle(w, "到着予定一覧")
+		w.Write(records[0])
+		for i := 0; i < 100; i++ {
+			w.Write(records[1])
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if strings.HasPrefix(out, "%PDF-") == false || strings.HasSuffix(out, "%%EOF\n") == false {
+			t.Fatalf("PDFの形式になっていません。%s", out[:16])
+		}
+		pages := strings.Count(out, "/Type /Page ")
+		// 「予約番号」のUTF-16BE
+		headers := strings.Count(out, "<4E887D04756A53F7> Tj")
+		if pages < 2 || headers != pages {
+			t.Fatalf("ページ数と見出し行の数が一致しません。ページ数: %d, 見出し行: %d", pages, headers)
+		}
+	})
+
+	t.Run("未対応の形式はエラーになることのテスト", func(t *testing.T) {
+		if _, err := NewWriter("txt", &bytes.Buffer{}); err == nil {
+			t.Fatal("エラーになりません。")
+		}
+	})
+}
@@ -0,0 +1,300 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// pdfPageWidth ページの幅（A4横、pt）
+	pdfPageWidth = 842.0
+	// pdfPageHeight ページの高さ（A4横、pt）
+	pdfPageHeight = 595.0
+	// pdfMargin 余白
+	pdfMargin = 28.0
+	// pdfFontSize 表の文字の大きさ
+	pdfFontSize = 8.0
+	// pdfTitleFontSize 見出しの文字の大きさ
+	pdfTitleFontSize = 12.0
+	// pdfLineHeight 表の1行の高さ
+	pdfLineHeight = 10.0
+	// pdfCellPadding セル内の余白
+	pdfCellPadding = 3.0
+	// pdfMinColumnWidth 列の最小幅
+	pdfMinColumnWidth = 24.0
+	// pdfMaxColumnWidth 列の最大幅（超える内容は折り返す）
+	pdfMaxColumnWidth = 220.0
+)
+
+// pdfFontObjects 日本語を表示するためのフォント（埋め込まずに閲覧ソフトのフォントを使う）
+// 半角文字は UniJIS-UCS2-HW-H で半角のCIDに割り当て、幅を半分にする
+var pdfFontObjects = []string{
+	"<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiKakuGo-W5 /Encoding /UniJIS-UCS2-HW-H /DescendantFonts [4 0 R] >>",
+	"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiKakuGo-W5 " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> " +
+		"/FontDescriptor 5 0 R /DW 1000 /W [231 389 500] >>",
+	"<< /Type /FontDescriptor /FontName /HeiseiKakuGo-W5 /Flags 4 /FontBBox [-92 -250 1010 922] " +
+		"/ItalicAngle 0 /Ascent 752 /Descent -221 /CapHeight 737 /StemV 114 >>",
+}
+
+// pdfWriter PDF出力（印刷用の表）
+// 列幅とページ分けを内容から決めるため、全行を受け取ってからCloseで書き出す
+type pdfWriter struct {
+	w       io.Writer
+	title   string
+	records [][]string
+}
+
+// newPDFWriter インスタンス生成
+func newPDFWriter(w io.Writer) (Writer, error) {
+	return &pdfWriter{w: w}, nil
+}
+
+// SetTitle 各ページの先頭に表示する見出しを設定
+func (p *pdfWriter) SetTitle(title string) {
+	p.title = title
+}
+
+// Write 1行出力（1行目は見出し行として各ページの先頭に繰り返す）
+func (p *pdfWriter) Write(record []string) error {
+	p.records = append(p.records, append([]string{}, record...))
+	return nil
+}
+
+// Close 表をページに分けてPDFを書き出す
+func (p *pdfWriter) Close() error {
+	pages := p.paginate()
+	contents := make([][]byte, len(pages))
+	for i, page := range pages {
+		contents[i] = p.renderPage(page, i+1, len(pages))
+	}
+	return writePDF(p.w, contents)
+}
+
+// pdfRow 折り返し済みの1行
+type pdfRow struct {
+	cells  [][]string
+	height float64
+}
+
+// pdfLayout 列幅と、ページごとの行
+type pdfLayout struct {
+	widths []float64
+	header *pdfRow
+	rows   []pdfRow
+}
+
+// paginate 列幅を決めて各セルを折り返し、ページに収まる行ごとに分ける
+func (p *pdfWriter) paginate() []pdfLayout {
+	widths := p.columnWidths()
+	var header *pdfRow
+	records := p.records
+	if len(records) > 0 {
+		row := wrapRow(records[0], widths)
+		header = &row
+		records = records[1:]
+	}
+
+	top := pdfPageHeight - pdfMargin
+	if p.title != "" {
+		top -= pdfTitleFontSize + pdfLineHeight
+	}
+	available := top - pdfMargin - pdfLineHeight
+	if header != nil {
+		available -= header.height
+	}
+
+	pages := []pdfLayout{{widths: widths, header: header}}
+	used := 0.0
+	for _, record := range records {
+		row := wrapRow(record, widths)
+		current := &pages[len(pages)-1]
+		if used+row.height > available && len(current.rows) > 0 {
+			pages = append(pages, pdfLayout{widths: widths, header: header})
+			current = &pages[len(pages)-1]
+			used = 0
+		}
+		current.rows = append(current.rows, row)
+		used += row.height
+	}
+	return pages
+}
+
+// columnWidths 内容に合わせた列幅（ページに収まらない場合は比率を保って縮める）
+func (p *pdfWriter) columnWidths() []float64 {
+	widths := []float64{}
+	for _, record := range p.records {
+		for i, value := range record {
+			if i >= len(widths) {
+				widths = append(widths, pdfMinColumnWidth)
+			}
+			for _, line := range strings.Split(value, "\n") {
+				width := textWidth(line) + pdfCellPadding*2
+				if width > pdfMaxColumnWidth {
+					width = pdfMaxColumnWidth
+				}
+				if width > widths[i] {
+					widths[i] = width
+				}
+			}
+		}
+	}
+	total := 0.0
+	for _, width := range widths {
+		total += width
+	}
+	if limit := pdfPageWidth - pdfMargin*2; total > limit {
+		for i := range widths {
+			widths[i] = widths[i] * limit / total
+		}
+	}
+	return widths
+}
+
+// renderPage 1ページ分の描画命令
+func (p *pdfWriter) renderPage(page pdfLayout, pageNo int, pageCount int) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("0.5 w\n")
+	y := pdfPageHeight - pdfMargin
+	if p.title != "" {
+		writeText(buf, pdfMargin, y-pdfTitleFontSize, pdfTitleFontSize, p.title)
+		y -= pdfTitleFontSize + pdfLineHeight
+	}
+	if page.header != nil {
+		drawRow(buf, page.widths, *page.header, y, true)
+		y -= page.header.height
+	}
+	for _, row := range page.rows {
+		drawRow(buf, page.widths, row, y, false)
+		y -= row.height
+	}
+	footer := fmt.Sprintf("%d / %d", pageNo, pageCount)
+	writeText(buf, pdfPageWidth-pdfMargin-textWidth(footer), pdfMargin-pdfLineHeight, pdfFontSize, footer)
+	return buf.Bytes()
+}
+
+// drawRow 1行分の枠と文字を描画（見出し行は背景を塗る）
+func drawRow(buf *bytes.Buffer, widths []float64, row pdfRow, top float64, shaded bool) {
+	x := pdfMargin
+	for i, width := range widths {
+		if shaded {
+			fmt.Fprintf(buf, "0.9 g %.2f %.2f %.2f %.2f re f 0 g\n", x, top-row.height, width, row.height)
+		}
+		fmt.Fprintf(buf, "%.2f %.2f %.2f %.2f re S\n", x, top-row.height, width, row.height)
+		if i < len(row.cells) {
+			for n, line := range row.cells[i] {
+				writeText(buf, x+pdfCellPadding, top-pdfCellPadding-pdfFontSize-float64(n)*pdfLineHeight, pdfFontSize, line)
+			}
+		}
+		x += width
+	}
+}
+
+// writeText 文字列を描画（UTF-16BEの16進数で渡す）
+func writeText(buf *bytes.Buffer, x float64, y float64, size float64, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(buf, "BT /F1 %.1f Tf %.2f %.2f Td <", size, x, y)
+	for _, r := range text {
+		if r > 0xFFFF || r < 0x20 {
+			r = '?'
+		}
+		fmt.Fprintf(buf, "%04X", r)
+	}
+	buf.WriteString("> Tj ET\n")
+}
+
+// wrapRow 各セルを列幅に収まるよう折り返す
+func wrapRow(record []string, widths []float64) pdfRow {
+	row := pdfRow{cells: make([][]string, len(record))}
+	lines := 1
+	for i, value := range record {
+		if i >= len(widths) {
+			break
+		}
+		row.cells[i] = wrapText(value, widths[i]-pdfCellPadding*2)
+		if len(row.cells[i]) > lines {
+			lines = len(row.cells[i])
+		}
+	}
+	row.height = float64(lines)*pdfLineHeight + pdfCellPadding*2
+	return row
+}
+
+// wrapText 文字列を指定した幅ごとに折り返す（改行はそのまま改行として扱う）
+func wrapText(text string, width float64) []string {
+	result := []string{}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := []rune{}
+		lineWidth := 0.0
+		for _, r := range paragraph {
+			w := runeWidth(r)
+			if lineWidth+w > width && len(line) > 0 {
+				result = append(result, string(line))
+				line, lineWidth = []rune{}, 0
+			}
+			line = append(line, r)
+			lineWidth += w
+		}
+		result = append(result, string(line))
+	}
+	return result
+}
+
+// textWidth 表の文字の大きさで描画した時の幅
+func textWidth(text string) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth 1文字の幅（半角は全角の半分）
+func runeWidth(r rune) float64 {
+	if r < utf8.RuneSelf || (r >= 0xFF61 && r <= 0xFF9F) {
+		return pdfFontSize / 2
+	}
+	return pdfFontSize
+}
+
+// writePDF ページごとの描画命令からPDFファイルを組み立てる
+// オブジェクトは 1:カタログ 2:ページツリー 3〜5:フォント 6以降:ページと描画命令の組
+func writePDF(w io.Writer, contents [][]byte) error {
+	buf := &bytes.Buffer{}
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	kids := []string{}
+	for i := range contents {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+i*2))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(contents)))
+	for _, font := range pdfFontObjects {
+		object(font)
+	}
+	for i, content := range contents {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 7+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
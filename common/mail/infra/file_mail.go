@@ -0,0 +1,32 @@
+package infra
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+)
+
+type fileSender struct {
+	dir string
+}
+
+// NewFileSender 送信せずにファイルへ書き出すインスタンス生成（開発環境向け）
+func NewFileSender(dir string) mail.ISender {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &fileSender{dir: dir}
+}
+
+// Send メール1通を.emlファイルとして書き出す
+func (f *fileSender) Send(message *mail.Message) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.eml", time.Now().Format("20060102150405.000000000"))
+	return ioutil.WriteFile(filepath.Join(f.dir, name), buildMessage(os.Getenv("MAIL_FROM"), message), 0600)
+}
@@ -0,0 +1,46 @@
+package infra
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+)
+
+func Test_MailSender(t *testing.T) {
+	message := &mail.Message{
+		To:      []string{"hotel@example.com"},
+		Subject: "パスワード再設定のご案内",
+		Body:    "1行目\n2行目",
+	}
+
+	t.Run("メモリ上に送信順で保持されることのテスト", func(t *testing.T) {
+		sender := NewMemorySender()
+		sender.Send(message)
+		sender.Send(&mail.Message{To: []string{"other@example.com"}})
+		messages := sender.Messages()
+		if len(messages) != 2 || messages[0].Subject != message.Subject {
+			t.Fatalf("保持しているメールが一致しません。%v", messages)
+		}
+	})
+
+	t.Run("件名をエンコードしてファイルに書き出すことのテスト", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "mail")
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := NewFileSender(dir).Send(message); err != nil {
+			t.Fatalf(err.Error())
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if len(files) != 1 {
+			t.Fatalf("ファイルが書き出されていません。")
+		}
+		b, _ := ioutil.ReadFile(files[0])
+		if !strings.Contains(string(b), "Subject: =?UTF-8?b?") || !strings.Contains(string(b), "1行目\r\n2行目") {
+			t.Fatalf("メールの内容が不正です。%s", string(b))
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package infra
+
+import (
+	"sync"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+)
+
+// MemorySender 送信せずにメモリ上に保持する（テスト向け）
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+// NewMemorySender インスタンス生成
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send メール1通を保持する
+func (m *MemorySender) Send(message *mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *message)
+	return nil
+}
+
+// Messages 保持しているメールを送信順に返す
+func (m *MemorySender) Messages() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mail.Message{}, m.messages...)
+}
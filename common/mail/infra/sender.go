@@ -0,0 +1,20 @@
+package infra
+
+import (
+	"os"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+)
+
+// NewMailSender MAIL_DRIVERに応じた送信方法のインスタンス生成
+// smtp（既定）、file（MAIL_FILE_DIRに書き出す）、memory（メモリ上に保持する）
+func NewMailSender() mail.ISender {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "file":
+		return NewFileSender(os.Getenv("MAIL_FILE_DIR"))
+	case "memory":
+		return NewMemorySender()
+	default:
+		return NewSMTPSender()
+	}
+}
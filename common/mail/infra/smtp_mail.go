@@ -0,0 +1,58 @@
+package infra
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/mail"
+)
+
+type smtpSender struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPSender SMTPで送信するインスタンス生成
+func NewSMTPSender() mail.ISender {
+	return &smtpSender{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     os.Getenv("SMTP_PORT"),
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		from:     os.Getenv("MAIL_FROM"),
+	}
+}
+
+// Send メールを1通送信
+func (s *smtpSender) Send(message *mail.Message) error {
+	if s.host == "" || s.from == "" {
+		return fmt.Errorf("Error: %s", "SMTP_HOST and MAIL_FROM must be set")
+	}
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.host, s.port), auth, s.from, message.To, buildMessage(s.from, message))
+}
+
+// buildMessage ヘッダーを付けたメール本文（UTF-8）を組み立てる
+func buildMessage(from string, message *mail.Message) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(message.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", message.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,14 @@
+package mail
+
+// Message 送信するメール
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// ISender メール送信のインターフェース
+type ISender interface {
+	// Send メールを1通送信
+	Send(message *Message) error
+}
@@ -0,0 +1,135 @@
+package infra
+
+import (
+	"fmt"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"gorm.io/gorm"
+)
+
+// ownerTable リソースの施設IDを引くためのテーブルと主キー
+type ownerTable struct {
+	table  string
+	column string
+}
+
+// wholesalerTables ホールセラーごとにテーブルが分かれているリソース
+var wholesalerTables = map[string]map[int64]ownerTable{
+	ownership.KindPlan: {
+		utils.WholesalerIDTl:     {"ht_tm_plan_tls", "plan_id"},
+		utils.WholesalerIDTema:   {"ht_tm_plan_temas", "plan_tema_id"},
+		utils.WholesalerIDNeppan: {"ht_tm_plan_neppans", "plan_id"},
+		utils.WholesalerIDDirect: {"ht_tm_plan_directs", "plan_id"},
+		utils.WholesalerIDRaku2:  {"ht_tm_plan_raku2s", "plan_id"},
+	},
+	ownership.KindRoomType: {
+		utils.WholesalerIDTl:     {"ht_tm_room_type_tls", "room_type_id"},
+		utils.WholesalerIDTema:   {"ht_tm_room_type_temas", "room_type_id"},
+		utils.WholesalerIDNeppan: {"ht_tm_room_type_neppans", "room_type_id"},
+		utils.WholesalerIDDirect: {"ht_tm_room_type_directs", "room_type_id"},
+		utils.WholesalerIDRaku2:  {"ht_tm_room_type_raku2s", "room_type_id"},
+	},
+	ownership.KindImage: {
+		// Temaの画像はTLのテーブルを使っている
+		utils.WholesalerIDTl:     {"ht_tm_image_tls", "image_tl_id"},
+		utils.WholesalerIDTema:   {"ht_tm_image_tls", "image_tl_id"},
+		utils.WholesalerIDNeppan: {"ht_tm_image_neppans", "room_image_neppan_id"},
+		utils.WholesalerIDDirect: {"ht_tm_image_directs", "room_image_direct_id"},
+		utils.WholesalerIDRaku2:  {"ht_tm_image_raku2s", "room_image_raku2_id"},
+	},
+}
+
+// commonTables ホールセラー共通のテーブルで管理しているリソース
+var commonTables = map[string]ownerTable{
+	ownership.KindApplication:       {"ht_th_applications", "cm_application_id"},
+	ownership.KindSettlementAccount: {"ht_tm_settlement_accounts", "id"},
+	ownership.KindCancelPolicy:      {"ht_tm_plan_cancel_policies", "plan_cancel_policy_id"},
+}
+
+// TableOf リソースを管理しているテーブルと主キーの列名
+func TableOf(kind string, wholesalerID int64) (string, string, bool) {
+	if kind == ownership.KindProperty {
+		return "ht_tm_properties", "property_id", true
+	}
+	t, ok := commonTables[kind]
+	if !ok {
+		t, ok = wholesalerTables[kind][wholesalerID]
+	}
+	return t.table, t.column, ok
+}
+
+type ownershipRepository struct {
+	db *gorm.DB
+}
+
+// NewOwnershipRepository インスタンス生成
+func NewOwnershipRepository(db *gorm.DB) ownership.IOwnershipRepository {
+	return &ownershipRepository{
+		db: db,
+	}
+}
+
+// TxStart トランザクションスタート
+func (o *ownershipRepository) TxStart() (*gorm.DB, error) {
+	tx := o.db.Begin()
+	return tx, tx.Error
+}
+
+// TxCommit トランザクションコミット
+func (o *ownershipRepository) TxCommit(tx *gorm.DB) error {
+	return tx.Commit().Error
+}
+
+// TxRollback トランザクション ロールバック
+func (o *ownershipRepository) TxRollback(tx *gorm.DB) {
+	tx.Rollback()
+}
+
+// FetchPropertyIDs リソースIDごとの施設IDを取得（存在しないIDは含まれない）
+func (o *ownershipRepository) FetchPropertyIDs(kind string, wholesalerID int64, ids []int64) (map[int64]int64, error) {
+	rows := []struct {
+		ID         int64
+		PropertyID int64
+	}{}
+	query := o.db
+	switch kind {
+	case ownership.KindProperty:
+		query = query.Table("ht_tm_properties").
+			Select("property_id AS id, property_id").
+			Where("property_id IN ?", ids)
+	case ownership.KindSettlement:
+		// 精算書はHMアカウントに紐づくため、アカウントの施設を所有者とする
+		query = query.Table("ht_tm_hotel_settlements AS settlements").
+			Select("settlements.id, hm.property_id").
+			Joins("INNER JOIN ht_tm_hotel_managers AS hm ON settlements.hotel_manager_id = hm.hotel_manager_id").
+			Where("settlements.id IN ?", ids)
+	default:
+		table, column, ok := TableOf(kind, wholesalerID)
+		if !ok {
+			return nil, fmt.Errorf("Error: %s", "resource "+kind+" is not supported for this wholesaler")
+		}
+		query = query.Table(table).
+			Select(column+" AS id, property_id").
+			Where(column+" IN ?", ids)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := map[int64]int64{}
+	for _, row := range rows {
+		result[row.ID] = row.PropertyID
+	}
+	return result, nil
+}
+
+// FetchClientCompanyPropertyIDs 親アカウントと同じ会社に属する施設IDを取得
+func (o *ownershipRepository) FetchClientCompanyPropertyIDs(clientCompanyID int64) ([]int64, error) {
+	result := []int64{}
+	err := o.db.
+		Table("ht_tm_properties").
+		Where("client_company_id = ?", clientCompanyID).
+		Pluck("property_id", &result).Error
+	return result, err
+}
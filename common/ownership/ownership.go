@@ -0,0 +1,150 @@
+package ownership
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common"
+)
+
+// ErrForbidden 他施設のリソースへのアクセス
+var ErrForbidden = errors.New("Error: 他の施設のデータは操作できません。")
+
+const (
+	// KindProperty 施設
+	KindProperty = "property"
+	// KindPlan プラン
+	KindPlan = "plan"
+	// KindRoomType 部屋タイプ
+	KindRoomType = "room_type"
+	// KindImage 画像
+	KindImage = "image"
+	// KindApplication 予約
+	KindApplication = "cm_application"
+	// KindSettlement 精算書
+	KindSettlement = "settlement"
+	// KindSettlementAccount 精算口座情報
+	KindSettlementAccount = "settlement_account"
+	// KindCancelPolicy キャンセルポリシー
+	KindCancelPolicy = "cancel_policy"
+)
+
+// FieldKinds 入力構造体のフィールド名と、そのフィールドが指すリソースの種類
+// 新しい入力を追加する場合、リソースのIDを持つフィールドはここに登録されている名前にすること
+var FieldKinds = map[string]string{
+	"PropertyID":         KindProperty,
+	"PropertyIDs":        KindProperty,
+	"PlanID":             KindPlan,
+	"PlanIDs":            KindPlan,
+	"RoomTypeID":         KindRoomType,
+	"RoomTypeIDs":        KindRoomType,
+	"SelectedRooms":      KindRoomType,
+	"ImageID":            KindImage,
+	"CmApplicationID":    KindApplication,
+	"CmApplicationIDs":   KindApplication,
+	"SettlementID":       KindSettlement,
+	"AccountID":          KindSettlementAccount,
+	"PlanCancelPolicyID": KindCancelPolicy,
+	"PlanCancelPolicyId": KindCancelPolicy,
+}
+
+// Resource リクエストに含まれるリソース1件
+type Resource struct {
+	Kind         string
+	ID           int64
+	WholesalerID int64
+}
+
+// IOwnershipUsecase リソースの所有チェック関連のusecaseのインターフェース
+type IOwnershipUsecase interface {
+	// Authorize リクエストに含まれるリソースがすべてHMアカウントの施設のものか確認する
+	Authorize(hmUser account.HtTmHotelManager, request interface{}) error
+}
+
+// IOwnershipRepository リソースの所有チェック関連のrepositoryのインターフェース
+type IOwnershipRepository interface {
+	common.Repository
+	// FetchPropertyIDs リソースIDごとの施設IDを取得（存在しないIDは含まれない）
+	FetchPropertyIDs(kind string, wholesalerID int64, ids []int64) (map[int64]int64, error)
+	// FetchClientCompanyPropertyIDs 親アカウントと同じ会社に属する施設IDを取得
+	FetchClientCompanyPropertyIDs(clientCompanyID int64) ([]int64, error)
+}
+
+// ResourcesOf リクエストの構造体（ネスト・スライス含む）からリソースIDを抜き出す
+// 同じ構造体に WholesalerID があれば、そのリソースのホールセラーIDとして扱う
+func ResourcesOf(request interface{}) []Resource {
+	resources := []Resource{}
+	collectResources(reflect.ValueOf(request), 0, &resources)
+	return resources
+}
+
+// collectResources ResourcesOfの再帰処理
+func collectResources(v reflect.Value, wholesalerID int64, resources *[]Resource) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectResources(v.Elem(), wholesalerID, resources)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectResources(v.Index(i), wholesalerID, resources)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		if f, ok := t.FieldByName("WholesalerID"); ok && len(f.Index) == 1 {
+			if id, ok := intValue(v.FieldByIndex(f.Index)); ok && id != 0 {
+				wholesalerID = id
+			}
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			kind, ok := FieldKinds[field.Name]
+			if !ok {
+				collectResources(v.Field(i), wholesalerID, resources)
+				continue
+			}
+			for _, id := range intValues(v.Field(i)) {
+				if id == 0 {
+					continue
+				}
+				*resources = append(*resources, Resource{Kind: kind, ID: id, WholesalerID: wholesalerID})
+			}
+		}
+	}
+}
+
+// intValues 整数・整数のポインタ・整数のスライスから値を取り出す
+func intValues(v reflect.Value) []int64 {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return intValues(v.Elem())
+	case reflect.Slice, reflect.Array:
+		values := []int64{}
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, intValues(v.Index(i))...)
+		}
+		return values
+	}
+	if id, ok := intValue(v); ok {
+		return []int64{id}
+	}
+	return nil
+}
+
+// intValue 整数型の値をint64で取り出す
+func intValue(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
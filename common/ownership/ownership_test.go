@@ -0,0 +1,167 @@
+package ownership
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+	"github.com/Adventureinc/hotel-hm-api/src/facility"
+	"github.com/Adventureinc/hotel-hm-api/src/image"
+	"github.com/Adventureinc/hotel-hm-api/src/plan"
+	"github.com/Adventureinc/hotel-hm-api/src/price"
+	"github.com/Adventureinc/hotel-hm-api/src/room"
+	"github.com/Adventureinc/hotel-hm-api/src/settlement"
+	"github.com/Adventureinc/hotel-hm-api/src/stock"
+)
+
+// handlerInputs HMアカウントのトークンで呼び出すハンドラーの入力
+var handlerInputs = []interface{}{
+	&account.CheckConnectInput{},
+	&analytics.ReportInput{},
+	&analytics.PickupInput{},
+	&booking.SearchInput{},
+	&booking.DownloadInput{},
+	&booking.ExportInput{},
+	&booking.DetailInput{},
+	&booking.CancelInput{},
+	&booking.CancelFeeInput{},
+	&booking.PartialCancelInput{},
+	&booking.AmendInput{},
+	&booking.ManualBookingInput{},
+	&booking.NotificationSettingInput{},
+	&booking.SaveNotificationSettingInput{},
+	&booking.NotificationLogInput{},
+	&booking.RoomingListInput{},
+	&booking.NoShowInput{},
+	&booking.BulkNoShowInput{},
+	&booking.ContactInput{},
+	&booking.UpdateContactStatusInput{},
+	&booking.AppendMemoInput{},
+	&booking.UpdateMisuseInput{},
+	&cancelPolicy.ListInput{},
+	&cancelPolicy.CreateInput{},
+	&cancelPolicy.DetailInput{},
+	&cancelPolicy.UpdateInput{},
+	&cancelPolicy.DeleteInput{},
+	&cancelPolicy.PlanListInput{},
+	&facility.UpdateDispPriorityInput{},
+	&facility.BaseInfoInput{},
+	&facility.SaveBaseInfoInput{},
+	&facility.SaveDetailInput{},
+	&image.ListInput{},
+	&image.UpdateInput{},
+	&image.UpdateIsMainInput{},
+	&[]image.UpdateSortNumInput{},
+	&image.DeleteInput{},
+	&image.UploadInput{},
+	&image.MainImagesCountInput{},
+	&plan.ListInput{},
+	&plan.DetailInput{},
+	&plan.SaveInput{},
+	&plan.DeleteInput{},
+	&plan.StopSalesInput{},
+	&price.DetailInput{},
+	&[]price.SaveInput{},
+	&room.ListInput{},
+	&room.DetailInput{},
+	&room.SaveInput{},
+	&room.DeleteInput{},
+	&room.StopSalesInput{},
+	&settlement.ListInput{},
+	&settlement.UpdateInput{},
+	&settlement.DownloadInput{},
+	&settlement.InfoInput{},
+	&settlement.SaveInfoInput{},
+	&stock.CalendarInput{},
+	&stock.StopSalesInput{},
+	&stock.ListInput{},
+	&[]stock.SaveInput{},
+}
+
+// notResourceFields IDを名前に含むが、施設に紐づくリソースを指さないフィールド
+var notResourceFields = map[string]bool{
+	"WholesalerID":       true, // 別途チェックする
+	"PlanGroupID":        true, // プランIDとあわせて送られる
+	"PropertyAmenityID":  true, // マスタ
+	"RoomKindID":         true, // マスタ
+	"ChildRateID":        true, // プランIDとあわせて送られる
+	"ClientCompanyID":    true,
+	"ConnectID":          true, // ホールセラー側の連携ID
+	"ApplicationIDs":     true, // 予約検索の絞り込み条件。検索自体がproperty_idで絞られる
+	"HtThBookingRoomIDs": true, // 予約IDとあわせて送られ、予約の部屋かどうかはusecaseで確認する
+	"HtThBookingRoomID":  true, // 予約IDとあわせて送られ、予約の部屋かどうかはusecaseで確認する
+}
+
+func Test_ResourcesOf(t *testing.T) {
+	t.Run("ネストした構造体・スライス・ポインタからリソースを抜き出せることのテスト", func(t *testing.T) {
+		policyID := uint64(9)
+		request := &plan.SaveInput{
+			PlanTable:          plan.PlanTable{PlanID: 1, RoomTypeID: 2, PropertyID: 3},
+			SelectedRooms:      []int64{4, 5},
+			Images:             []image.PlanImagesInput{{ImageID: 6, PlanID: 1}},
+			PlanCancelPolicyId: &policyID,
+		}
+		got := map[Resource]bool{}
+		for _, resource := range ResourcesOf(request) {
+			got[resource] = true
+		}
+		want := []Resource{
+			{Kind: KindPlan, ID: 1},
+			{Kind: KindRoomType, ID: 2},
+			{Kind: KindProperty, ID: 3},
+			{Kind: KindRoomType, ID: 4},
+			{Kind: KindRoomType, ID: 5},
+			{Kind: KindImage, ID: 6},
+			{Kind: KindCancelPolicy, ID: 9},
+		}
+		for _, resource := range want {
+			if !got[resource] {
+				t.Fatalf("リソースが抜き出せていません。%+v", resource)
+			}
+		}
+	})
+
+	t.Run("同じ構造体のWholesalerIDがリソースに引き継がれることのテスト", func(t *testing.T) {
+		request := &booking.DetailInput{CmApplicationID: 10, PropertyID: 20, WholesalerID: 7}
+		for _, resource := range ResourcesOf(request) {
+			if resource.WholesalerID != 7 {
+				t.Fatalf("ホールセラーIDが引き継がれていません。%+v", resource)
+			}
+		}
+	})
+
+	t.Run("ハンドラーの入力のIDフィールドがすべて所有チェックの対象であることのテスト", func(t *testing.T) {
+		for _, input := range handlerInputs {
+			assertFieldsClassified(t, reflect.TypeOf(input), reflect.TypeOf(input).String())
+		}
+	})
+}
+
+// assertFieldsClassified IDを名前に含むフィールドがFieldKindsかnotResourceFieldsに登録されているか確認する
+func assertFieldsClassified(t *testing.T, typ reflect.Type, path string) {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		assertFieldsClassified(t, typ.Elem(), path)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if _, ok := FieldKinds[field.Name]; ok || notResourceFields[field.Name] {
+			continue
+		}
+		if strings.HasSuffix(field.Name, "ID") || strings.HasSuffix(field.Name, "IDs") || strings.HasSuffix(field.Name, "Id") {
+			t.Fatalf("所有チェックの対象か判断できないフィールドがあります。%s.%s", path, field.Name)
+		}
+		assertFieldsClassified(t, field.Type, path+"."+field.Name)
+	}
+}
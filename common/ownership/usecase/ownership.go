@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"gorm.io/gorm"
+)
+
+type ownershipUsecase struct {
+	ORepository ownership.IOwnershipRepository
+}
+
+// NewOwnershipUsecase インスタンス生成
+func NewOwnershipUsecase(db *gorm.DB) ownership.IOwnershipUsecase {
+	return &ownershipUsecase{
+		ORepository: infra.NewOwnershipRepository(db),
+	}
+}
+
+// Authorize リクエストに含まれるリソースがすべてHMアカウントの施設のものか確認する
+func (o *ownershipUsecase) Authorize(hmUser account.HtTmHotelManager, request interface{}) error {
+	resources := ownership.ResourcesOf(request)
+	if len(resources) == 0 {
+		return nil
+	}
+
+	isParent := hmUser.WholesalerID == utils.WholesalerIDParent
+	allowed := map[int64]bool{}
+	if isParent {
+		propertyIDs, err := o.ORepository.FetchClientCompanyPropertyIDs(hmUser.ClientCompanyID)
+		if err != nil {
+			return err
+		}
+		for _, propertyID := range propertyIDs {
+			allowed[propertyID] = true
+		}
+	} else {
+		allowed[hmUser.PropertyID] = true
+	}
+
+	// 種類・ホールセラーごとにまとめて施設IDを引く
+	type group struct {
+		kind         string
+		wholesalerID int64
+	}
+	groups := map[group][]int64{}
+	for _, resource := range resources {
+		wholesalerID := resource.WholesalerID
+		if !isParent {
+			// 子アカウントは自分のホールセラー以外を指定できない
+			if wholesalerID != 0 && wholesalerID != hmUser.WholesalerID {
+				return fmt.Errorf("%w: wholesaler_id %d", ownership.ErrForbidden, wholesalerID)
+			}
+			wholesalerID = hmUser.WholesalerID
+		}
+		if resource.Kind == ownership.KindProperty {
+			if !allowed[resource.ID] {
+				return fmt.Errorf("%w: property_id %d", ownership.ErrForbidden, resource.ID)
+			}
+			continue
+		}
+		g := group{kind: resource.Kind, wholesalerID: wholesalerID}
+		groups[g] = append(groups[g], resource.ID)
+	}
+
+	for g, ids := range groups {
+		propertyIDs, err := o.ORepository.FetchPropertyIDs(g.kind, g.wholesalerID, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			propertyID, ok := propertyIDs[id]
+			if !ok || !allowed[propertyID] {
+				return fmt.Errorf("%w: %s %d", ownership.ErrForbidden, g.kind, id)
+			}
+		}
+	}
+	return nil
+}
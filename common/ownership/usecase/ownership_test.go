@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/analytics"
+	"github.com/Adventureinc/hotel-hm-api/src/booking"
+	"github.com/Adventureinc/hotel-hm-api/src/cancelPolicy"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/Adventureinc/hotel-hm-api/src/facility"
+	"github.com/Adventureinc/hotel-hm-api/src/image"
+	"github.com/Adventureinc/hotel-hm-api/src/plan"
+	"github.com/Adventureinc/hotel-hm-api/src/price"
+	"github.com/Adventureinc/hotel-hm-api/src/room"
+	"github.com/Adventureinc/hotel-hm-api/src/settlement"
+	"github.com/Adventureinc/hotel-hm-api/src/stock"
+	"gorm.io/gorm"
+)
+
+const (
+	ownProperty   = int64(1)
+	otherProperty = int64(2)
+	// ownID 自施設のリソースID、otherID 他施設のリソースID（種類を問わず同じIDを使う）
+	ownID   = int64(100)
+	otherID = int64(200)
+)
+
+// mockOwnershipRepository リソースIDと施設IDの対応を固定で返すリポジトリ
+type mockOwnershipRepository struct{}
+
+func (m *mockOwnershipRepository) TxStart() (*gorm.DB, error) { return nil, nil }
+func (m *mockOwnershipRepository) TxCommit(tx *gorm.DB) error { return nil }
+func (m *mockOwnershipRepository) TxRollback(tx *gorm.DB)     {}
+
+func (m *mockOwnershipRepository) FetchPropertyIDs(kind string, wholesalerID int64, ids []int64) (map[int64]int64, error) {
+	owners := map[int64]int64{ownID: ownProperty, otherID: otherProperty}
+	result := map[int64]int64{}
+	for _, id := range ids {
+		if propertyID, ok := owners[id]; ok {
+			result[id] = propertyID
+		}
+	}
+	return result, nil
+}
+
+func (m *mockOwnershipRepository) FetchClientCompanyPropertyIDs(clientCompanyID int64) ([]int64, error) {
+	if clientCompanyID == 10 {
+		return []int64{ownProperty}, nil
+	}
+	return []int64{}, nil
+}
+
+// requestsFor 各ハンドラーの入力に、指定したIDを詰めたもの
+func requestsFor(propertyID int64, id int64) map[string]interface{} {
+	policyID := uint64(id)
+	return map[string]interface{}{
+		"booking.SearchInput":          &booking.SearchInput{PropertyID: propertyID, WholesalerID: utils.WholesalerIDDirect},
+		"booking.DownloadInput":        &booking.DownloadInput{PropertyID: ownProperty, CmApplicationIDs: []int64{ownID, id}},
+		"booking.ExportInput":          &booking.ExportInput{SearchInput: booking.SearchInput{PropertyID: propertyID, WholesalerID: utils.WholesalerIDDirect}},
+		"booking.DetailInput":          &booking.DetailInput{PropertyID: ownProperty, CmApplicationID: id},
+		"booking.CancelInput":          &booking.CancelInput{CmApplicationID: id},
+		"booking.CancelFeeInput":       &booking.CancelFeeInput{CmApplicationID: id},
+		"booking.PartialCancelInput":   &booking.PartialCancelInput{CmApplicationID: id},
+		"booking.AmendInput":           &booking.AmendInput{CmApplicationID: id},
+		"booking.ManualBookingInput":   &booking.ManualBookingInput{PropertyID: propertyID, WholesalerID: utils.WholesalerIDDirect},
+		"booking.ManualBooking.Rooms":  &booking.ManualBookingInput{PropertyID: ownProperty, WholesalerID: utils.WholesalerIDDirect, Rooms: []booking.BookingRoomInput{{PlanID: id}}},
+		"booking.NotificationSetting":  &booking.NotificationSettingInput{PropertyID: propertyID},
+		"booking.SaveNotification":     &booking.SaveNotificationSettingInput{PropertyID: propertyID},
+		"booking.NotificationLogs":     &booking.NotificationLogInput{PropertyID: propertyID},
+		"booking.RoomingList":          &booking.RoomingListInput{PropertyID: propertyID},
+		"booking.NoShowInput":          &booking.NoShowInput{CmApplicationID: id},
+		"booking.BulkNoShowInput":      &booking.BulkNoShowInput{PropertyID: ownProperty, CmApplicationIDs: []int64{ownID, id}},
+		"booking.ContactInput":         &booking.ContactInput{CmApplicationID: id},
+		"booking.UpdateContactStatus":  &booking.UpdateContactStatusInput{CmApplicationID: id},
+		"booking.AppendMemoInput":      &booking.AppendMemoInput{CmApplicationID: id},
+		"booking.UpdateMisuseInput":    &booking.UpdateMisuseInput{CmApplicationID: id},
+		"cancelPolicy.CreateInput":     &cancelPolicy.CreateInput{PropertyID: propertyID},
+		"cancelPolicy.DetailInput":     &cancelPolicy.DetailInput{PlanCancelPolicyID: &policyID},
+		"cancelPolicy.UpdateInput":     &cancelPolicy.UpdateInput{PlanCancelPolicyID: &policyID},
+		"cancelPolicy.DeleteInput":     &cancelPolicy.DeleteInput{PlanCancelPolicyID: policyID},
+		"facility.BaseInfoInput":       &facility.BaseInfoInput{PropertyID: propertyID},
+		"facility.SaveDetailInput":     &facility.SaveDetailInput{PropertyID: propertyID},
+		"image.ListInput":              &image.ListInput{PropertyID: propertyID},
+		"image.UpdateInput":            &image.UpdateInput{ImageID: id},
+		"image.UpdateSortNumInput":     &[]image.UpdateSortNumInput{{ImageID: ownID}, {ImageID: id}},
+		"image.DeleteInput":            &image.DeleteInput{ImageID: id},
+		"plan.DetailInput":             &plan.DetailInput{PropertyID: ownProperty, PlanID: id},
+		"plan.SaveInput":               &plan.SaveInput{PlanTable: plan.PlanTable{PlanID: ownID, PropertyID: ownProperty}, SelectedRooms: []int64{id}},
+		"plan.SaveInput.Images":        &plan.SaveInput{PlanTable: plan.PlanTable{PropertyID: ownProperty}, Images: []image.PlanImagesInput{{ImageID: id}}},
+		"plan.SaveInput.CancelPolicy":  &plan.SaveInput{PlanTable: plan.PlanTable{PropertyID: ownProperty}, PlanCancelPolicyId: &policyID},
+		"plan.DeleteInput":             &plan.DeleteInput{PlanID: id},
+		"price.DetailInput":            &price.DetailInput{PlanID: id},
+		"price.SaveInput":              &[]price.SaveInput{{PlanID: ownID}, {PlanID: id}},
+		"room.DetailInput":             &room.DetailInput{PropertyID: ownProperty, RoomTypeID: id},
+		"room.SaveInput":               &room.SaveInput{RoomTypeTable: room.RoomTypeTable{RoomTypeID: id, PropertyID: ownProperty}},
+		"room.DeleteInput":             &room.DeleteInput{RoomTypeID: id},
+		"settlement.ListInput":         &settlement.ListInput{PropertyID: propertyID, WholesalerID: utils.WholesalerIDDirect},
+		"settlement.UpdateInput":       &settlement.UpdateInput{SettlementID: id},
+		"settlement.DownloadInput":     &settlement.DownloadInput{SettlementID: id},
+		"settlement.SaveInfoInput":     &settlement.SaveInfoInput{AccountID: id, PropertyID: ownProperty, WholesalerID: utils.WholesalerIDDirect},
+		"stock.StopSalesInput":         &stock.StopSalesInput{RoomTypeIDs: []int64{ownID, id}},
+		"stock.ListInput":              &stock.ListInput{PropertyID: propertyID},
+		"stock.SaveInput":              &[]stock.SaveInput{{RoomTypeID: id}},
+		"account.CheckConnectInput":    &account.CheckConnectInput{PropertyID: propertyID, WholesalerID: utils.WholesalerIDDirect},
+		"analytics.ReportInput":        &analytics.ReportInput{PropertyID: propertyID},
+		"analytics.PickupInput":        &analytics.PickupInput{PropertyID: propertyID},
+		"facility.SaveBaseInfoInput":   &facility.SaveBaseInfoInput{PropertyID: propertyID},
+		"image.MainImagesCountInput":   &image.MainImagesCountInput{PropertyID: propertyID, WholesalerID: utils.WholesalerIDDirect},
+		"cancelPolicy.PlanListInput":   &cancelPolicy.PlanListInput{PlanCancelPolicyID: policyID},
+		"plan.StopSalesInput":          &plan.StopSalesInput{PlanID: id},
+		"room.StopSalesInput":          &room.StopSalesInput{RoomTypeID: id},
+		"settlement.InfoInput":         &settlement.InfoInput{PropertyID: propertyID},
+		"facility.UpdateDisp":          &facility.UpdateDispPriorityInput{PropertyID: propertyID},
+		"image.UpdateIsMainInput":      &image.UpdateIsMainInput{ImageID: id},
+		"booking.DownloadInput.Others": &booking.DownloadInput{PropertyID: propertyID, CmApplicationIDs: []int64{ownID}},
+	}
+}
+
+func Test_Authorize(t *testing.T) {
+	o := &ownershipUsecase{ORepository: &mockOwnershipRepository{}}
+	hmUser := account.HtTmHotelManager{HotelManagerID: 1, PropertyID: ownProperty, WholesalerID: utils.WholesalerIDDirect}
+
+	t.Run("自施設のリソースは操作できることのテスト", func(t *testing.T) {
+		for name, request := range requestsFor(ownProperty, ownID) {
+			if err := o.Authorize(hmUser, request); err != nil {
+				t.Fatalf("自施設のリソースがエラーになりました。入力: %s, エラー: %s", name, err)
+			}
+		}
+	})
+
+	t.Run("他施設のリソースはどのハンドラーの入力からも操作できないことのテスト", func(t *testing.T) {
+		for name, request := range requestsFor(otherProperty, otherID) {
+			if err := o.Authorize(hmUser, request); !errors.Is(err, ownership.ErrForbidden) {
+				t.Fatalf("他施設のリソースが拒否されませんでした。入力: %s, エラー: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("存在しないリソースは操作できないことのテスト", func(t *testing.T) {
+		if err := o.Authorize(hmUser, &plan.DeleteInput{PlanID: 999}); !errors.Is(err, ownership.ErrForbidden) {
+			t.Fatalf("存在しないリソースが拒否されませんでした。エラー: %v", err)
+		}
+	})
+
+	t.Run("自分以外のホールセラーを指定できないことのテスト", func(t *testing.T) {
+		request := &booking.SearchInput{PropertyID: ownProperty, WholesalerID: utils.WholesalerIDNeppan}
+		if err := o.Authorize(hmUser, request); !errors.Is(err, ownership.ErrForbidden) {
+			t.Fatalf("他のホールセラーが拒否されませんでした。エラー: %v", err)
+		}
+	})
+
+	t.Run("親アカウントは同じ会社の施設のみ操作できることのテスト", func(t *testing.T) {
+		parent := account.HtTmHotelManager{HotelManagerID: 2, ClientCompanyID: 10, WholesalerID: utils.WholesalerIDParent}
+		own := &facility.UpdateDispPriorityInput{PropertyID: ownProperty, WholesalerID: utils.WholesalerIDDirect}
+		if err := o.Authorize(parent, own); err != nil {
+			t.Fatalf("同じ会社の施設がエラーになりました。エラー: %s", err)
+		}
+		other := &facility.UpdateDispPriorityInput{PropertyID: otherProperty, WholesalerID: utils.WholesalerIDDirect}
+		if err := o.Authorize(parent, other); !errors.Is(err, ownership.ErrForbidden) {
+			t.Fatalf("他の会社の施設が拒否されませんでした。エラー: %v", err)
+		}
+	})
+}
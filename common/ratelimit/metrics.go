@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ReasonRateLimited リクエスト数の上限超過
+	ReasonRateLimited = "rate_limited"
+	// ReasonBodyTooLarge リクエストサイズの上限超過
+	ReasonBodyTooLarge = "body_too_large"
+	// ReasonTooManyItems 一括更新の件数の上限超過
+	ReasonTooManyItems = "too_many_items"
+)
+
+// rejections 拒否したリクエストの件数（理由・ルートもしくはエンドポイントごと、プロセス起動から累計）
+// アカウント・接続元IPごとに数えると際限なく増えるため、それらはログにのみ出力する
+var rejections = struct {
+	sync.Mutex
+	counts map[string]map[string]int64
+}{counts: map[string]map[string]int64{}}
+
+// recordRejection 拒否したリクエストを記録する
+// targetはルートもしくはエンドポイント、clientは制限の対象（アカウント・接続元IP、ない場合は空）
+func recordRejection(c echo.Context, reason string, target string, client string) {
+	rejections.Lock()
+	defer rejections.Unlock()
+	if rejections.counts[reason] == nil {
+		rejections.counts[reason] = map[string]int64{}
+	}
+	rejections.counts[reason][target]++
+	c.Echo().Logger.Warnf("request rejected: reason=%s target=%s client=%s path=%s", reason, target, client, c.Request().URL.Path)
+}
+
+// Rejections 拒否したリクエストの件数を理由・ルートもしくはエンドポイントごとに取得する
+func Rejections() map[string]map[string]int64 {
+	rejections.Lock()
+	defer rejections.Unlock()
+	result := map[string]map[string]int64{}
+	for reason, targets := range rejections.counts {
+		result[reason] = map[string]int64{}
+		for target, count := range targets {
+			result[reason][target] = count
+		}
+	}
+	return result
+}
+
+// Metrics 拒否したリクエストの件数（社内運用向け）
+// 応答したインスタンスの件数のみのため、全体の件数は各インスタンスのログ（request rejected）から集計する
+func Metrics(c echo.Context) error {
+	return c.JSON(http.StatusOK, Rejections())
+}
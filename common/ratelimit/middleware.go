@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Adventureinc/hotel-hm-api/src/common/credential"
+	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// EndpointPlanBulk プランの一括更新
+	EndpointPlanBulk = "plan_bulk"
+	// EndpointRoomBulk 部屋の一括更新
+	EndpointRoomBulk = "room_bulk"
+	// EndpointPriceBulk 料金の一括更新
+	EndpointPriceBulk = "price_bulk"
+	// EndpointStockBulk 在庫の一括更新
+	EndpointStockBulk = "stock_bulk"
+
+	// defaultBulkMaxItems 一括更新1リクエストあたりの件数上限の既定値
+	defaultBulkMaxItems = 10000
+	// defaultHMMaxBodyBytes HM APIのリクエストサイズ上限の既定値
+	defaultHMMaxBodyBytes = 10 << 20
+	// defaultBulkMaxBodyBytes 連携APIのリクエストサイズ上限の既定値
+	defaultBulkMaxBodyBytes = 32 << 20
+)
+
+// Wholesaler 連携APIキーごとのリクエスト数を制限するmiddleware
+// RATE_LIMIT_WHOLESALER_RPS（既定 10）、RATE_LIMIT_WHOLESALER_BURST（既定 50）で設定する
+// auth.InternalWithDB の後に適用する
+func Wholesaler() echo.MiddlewareFunc {
+	limiter := NewLimiter(envFloat("RATE_LIMIT_WHOLESALER_RPS", 10), envInt("RATE_LIMIT_WHOLESALER_BURST", 50))
+	return limit(limiter, func(c echo.Context) string {
+		return "wholesaler_key:" + c.Request().Header.Get(credential.HeaderKeyID)
+	})
+}
+
+// HM HMアカウントごとのリクエスト数を制限するmiddleware
+// RATE_LIMIT_HM_RPS（既定 5）、RATE_LIMIT_HM_BURST（既定 30）で設定する
+// auth.HM の後に適用する
+func HM() echo.MiddlewareFunc {
+	limiter := NewLimiter(envFloat("RATE_LIMIT_HM_RPS", 5), envInt("RATE_LIMIT_HM_BURST", 30))
+	return limit(limiter, func(c echo.Context) string {
+		claimParam, err := utils.GetHmUser(c)
+		if err != nil {
+			return "ip:" + c.RealIP()
+		}
+		return "hotel_manager:" + strconv.FormatInt(claimParam.HotelManagerID, 10)
+	})
+}
+
+// limit キーごとにトークンを消費し、上限を超えた場合は Retry-After を付けて429を返す
+func limit(limiter *Limiter, keyOf func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := keyOf(c)
+			allowed, retryAfter := limiter.Allow(key, time.Now())
+			if !allowed {
+				recordRejection(c, ReasonRateLimited, c.Path(), key)
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+// HMBodyLimit HM APIのリクエストサイズを制限するmiddleware（HM_MAX_BODY_BYTES、既定 10MB）
+func HMBodyLimit() echo.MiddlewareFunc {
+	return BodyLimit(int64(envInt("HM_MAX_BODY_BYTES", defaultHMMaxBodyBytes)))
+}
+
+// BulkBodyLimit 連携APIのリクエストサイズを制限するmiddleware（BULK_MAX_BODY_BYTES、既定 32MB）
+// 署名の検証で本文を読むため、auth.InternalWithDB より前に適用する
+func BulkBodyLimit() echo.MiddlewareFunc {
+	return BodyLimit(int64(envInt("BULK_MAX_BODY_BYTES", defaultBulkMaxBodyBytes)))
+}
+
+// BodyLimit リクエストサイズを制限するmiddleware。上限を超えた場合は413を返す
+func BodyLimit(maxBytes int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			if request.ContentLength > maxBytes {
+				recordRejection(c, ReasonBodyTooLarge, c.Path(), c.RealIP())
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			if request.Body == nil {
+				return next(c)
+			}
+			// Content-Length のないリクエストもあるため、上限+1バイトまで読んで確認する
+			body, err := ioutil.ReadAll(io.LimitReader(request.Body, maxBytes+1))
+			if err != nil {
+				c.Echo().Logger.Error(err)
+				return echo.ErrBadRequest
+			}
+			if int64(len(body)) > maxBytes {
+				recordRejection(c, ReasonBodyTooLarge, c.Path(), c.RealIP())
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			request.Body = ioutil.NopCloser(bytes.NewReader(body))
+			return next(c)
+		}
+	}
+}
+
+// CheckItemCount 一括更新の件数が上限以内か確認する。上限を超えた場合は413のエラーを返す
+// 上限は BULK_MAX_ITEMS_<ENDPOINT>（例: BULK_MAX_ITEMS_STOCK_BULK）、未設定の場合は BULK_MAX_ITEMS（既定 10000）
+func CheckItemCount(c echo.Context, endpoint string, count int) error {
+	maxItems := envInt("BULK_MAX_ITEMS_"+strings.ToUpper(endpoint), envInt("BULK_MAX_ITEMS", defaultBulkMaxItems))
+	if count <= maxItems {
+		return nil
+	}
+	recordRejection(c, ReasonTooManyItems, endpoint, c.RealIP())
+	return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many items: %d (max %d)", count, maxItems))
+}
+
+// envInt 環境変数を整数で取得（未設定・不正な値の場合は既定値）
+func envInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// envFloat 環境変数を小数で取得（未設定・不正な値の場合は既定値）
+func envFloat(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,77 @@
+// Package ratelimit リクエスト数・リクエストサイズ・一括更新の件数の制限
+//
+// リクエスト数の制限（Limiter）と拒否件数（Rejections）は共有ストアを使わず、プロセス（インスタンス）ごとに保持する。
+// 複数台で動かす場合、利用者ごとの上限は実質「台数×設定値」になり、再起動で残量・件数はリセットされる。
+// 全体の上限を合わせたい場合は、RATE_LIMIT_*_RPS・RATE_LIMIT_*_BURST に台数で割った値を設定する
+//
+// middlewareはルーティング（app.Route）で次の順に適用する
+//
+//	hm := e.Group("", ratelimit.HMBodyLimit(), auth.HM(db), ratelimit.HM())
+//	bulk := e.Group("/bulk", ratelimit.BulkBodyLimit(), auth.InternalWithDB(db), ratelimit.Wholesaler())
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets これを超えて利用者が増えた場合、満タンに戻ったバケットを破棄する
+const maxIdleBuckets = 10000
+
+// bucket 利用者1件分のトークンバケット
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter トークンバケット方式のリクエスト数制限（キーごと・プロセス内。他のインスタンスとは共有しない）
+// rate は1秒あたりに補充するトークン数、burst はバケットの容量
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+// NewLimiter インスタンス生成
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow キーのトークンを1つ消費できるか。できない場合は次に消費できるまでの時間を返す
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		l.evict(now)
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / l.rate
+	return false, time.Duration(math.Ceil(wait*1000)) * time.Millisecond
+}
+
+// evict 満タンに戻ったバケットを破棄する（満タンのバケットは新規作成と同じ状態のため）
+func (l *Limiter) evict(now time.Time) {
+	if len(l.buckets) < maxIdleBuckets {
+		return
+	}
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
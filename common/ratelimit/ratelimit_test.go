@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Test_Limiter(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	t.Run("容量までは許可し、超えたら補充までの時間を返すことのテスト", func(t *testing.T) {
+		limiter := NewLimiter(2, 3)
+		for i := 0; i < 3; i++ {
+			if ok, _ := limiter.Allow("a", now); !ok {
+				t.Fatalf("%d回目が拒否されました。", i+1)
+			}
+		}
+		ok, retryAfter := limiter.Allow("a", now)
+		if ok || retryAfter != 500*time.Millisecond {
+			t.Fatalf("4回目の結果が不正です。ok=%v retryAfter=%v", ok, retryAfter)
+		}
+		if ok, _ := limiter.Allow("a", now.Add(500*time.Millisecond)); !ok {
+			t.Fatalf("補充後に拒否されました。")
+		}
+	})
+
+	t.Run("キーごとに独立して制限することのテスト", func(t *testing.T) {
+		limiter := NewLimiter(1, 1)
+		limiter.Allow("a", now)
+		if ok, _ := limiter.Allow("b", now); !ok {
+			t.Fatalf("別のキーが拒否されました。")
+		}
+	})
+}
+
+func Test_recordRejection(t *testing.T) {
+	t.Run("拒否件数はアカウント・接続元IPごとではなくルートごとに数えることのテスト", func(t *testing.T) {
+		e := echo.New()
+		for _, client := range []string{"hotel_manager:1", "hotel_manager:2", "ip:192.0.2.1"} {
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/plan", nil), httptest.NewRecorder())
+			c.SetPath("/plan")
+			recordRejection(c, ReasonRateLimited, c.Path(), client)
+		}
+		targets := Rejections()[ReasonRateLimited]
+		if len(targets) != 1 || targets["/plan"] < 3 {
+			t.Fatalf("拒否件数が一致しません。%v", targets)
+		}
+	})
+}
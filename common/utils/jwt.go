@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// defaultAccessTokenMinutes アクセストークンの有効期限（分）の既定値
+	defaultAccessTokenMinutes = 15
+	// defaultRefreshTokenDays リフレッシュトークンの有効期限（日）の既定値
+	defaultRefreshTokenDays = 30
+)
+
+// jwtKey 署名鍵1件分の情報
+type jwtKey struct {
+	method     jwt.SigningMethod
+	signKey    interface{}
+	verifyKey  interface{}
+	canSigning bool
+}
+
+// jwtKeyRing kidごとの署名鍵
+type jwtKeyRing struct {
+	currentKid string
+	keys       map[string]jwtKey
+}
+
+var (
+	keyRing     *jwtKeyRing
+	keyRingErr  error
+	keyRingOnce sync.Once
+)
+
+// loadJWTKeyRing 環境変数から署名鍵を読み込む
+// JWT_KEY_IDS にカンマ区切りでkidを列挙し、kidごとに以下を設定する
+//
+//	JWT_KEY_<kid>_ALG         HS256 もしくは RS256
+//	JWT_KEY_<kid>_SECRET      HS256の共通鍵
+//	JWT_KEY_<kid>_PRIVATE_KEY RS256の秘密鍵（PEM）。検証専用の旧鍵であれば省略可
+//	JWT_KEY_<kid>_PUBLIC_KEY  RS256の公開鍵（PEM）
+//
+// 署名には JWT_SIGNING_KID の鍵を使い、それ以外の鍵はローテーション期間中の検証にのみ使う
+// JWT_KEY_IDS が未設定の場合は、従来どおり JWT_SECRET のHS256鍵（kidなし）だけを使う
+func loadJWTKeyRing() (*jwtKeyRing, error) {
+	ring := &jwtKeyRing{keys: map[string]jwtKey{}}
+	kids := strings.TrimSpace(os.Getenv("JWT_KEY_IDS"))
+	if kids == "" {
+		secret := []byte(os.Getenv("JWT_SECRET"))
+		ring.keys[""] = jwtKey{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret, canSigning: true}
+		return ring, nil
+	}
+
+	for _, kid := range strings.Split(kids, ",") {
+		kid = strings.TrimSpace(kid)
+		if kid == "" {
+			continue
+		}
+		prefix := "JWT_KEY_" + strings.ToUpper(kid) + "_"
+		switch os.Getenv(prefix + "ALG") {
+		case "RS256":
+			verifyKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(os.Getenv(prefix + "PUBLIC_KEY")))
+			if err != nil {
+				return nil, fmt.Errorf("kid %s: %s", kid, err)
+			}
+			key := jwtKey{method: jwt.SigningMethodRS256, verifyKey: verifyKey}
+			if privateKey := os.Getenv(prefix + "PRIVATE_KEY"); privateKey != "" {
+				signKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKey))
+				if err != nil {
+					return nil, fmt.Errorf("kid %s: %s", kid, err)
+				}
+				key.signKey = signKey
+				key.canSigning = true
+			}
+			ring.keys[kid] = key
+		case "HS256", "":
+			secret := []byte(os.Getenv(prefix + "SECRET"))
+			if len(secret) == 0 {
+				return nil, fmt.Errorf("kid %s: secret is empty", kid)
+			}
+			ring.keys[kid] = jwtKey{method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret, canSigning: true}
+		default:
+			return nil, fmt.Errorf("kid %s: unsupported alg %s", kid, os.Getenv(prefix+"ALG"))
+		}
+	}
+
+	// kid導入前に発行されたトークンは、有効期限が切れるまで JWT_SECRET で検証する
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		if _, ok := ring.keys[""]; !ok {
+			ring.keys[""] = jwtKey{method: jwt.SigningMethodHS256, verifyKey: []byte(secret)}
+		}
+	}
+
+	ring.currentKid = os.Getenv("JWT_SIGNING_KID")
+	if key, ok := ring.keys[ring.currentKid]; !ok || !key.canSigning {
+		return nil, fmt.Errorf("signing kid %s is not available", ring.currentKid)
+	}
+	return ring, nil
+}
+
+// getJWTKeyRing 署名鍵を取得（初回のみ環境変数から読み込む）
+func getJWTKeyRing() (*jwtKeyRing, error) {
+	keyRingOnce.Do(func() {
+		keyRing, keyRingErr = loadJWTKeyRing()
+	})
+	return keyRing, keyRingErr
+}
+
+// AccessTokenTTL アクセストークンの有効期限
+func AccessTokenTTL() time.Duration {
+	return durationFromEnv("JWT_ACCESS_TOKEN_MINUTES", defaultAccessTokenMinutes, time.Minute)
+}
+
+// RefreshTokenTTL リフレッシュトークンの有効期限
+func RefreshTokenTTL() time.Duration {
+	return durationFromEnv("JWT_REFRESH_TOKEN_DAYS", defaultRefreshTokenDays, 24*time.Hour)
+}
+
+// durationFromEnv 環境変数の数値に単位を掛けた期間を返す。未設定・不正値の場合は既定値を使う
+func durationFromEnv(name string, defaultValue int, unit time.Duration) time.Duration {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		value = defaultValue
+	}
+	return time.Duration(value) * unit
+}
+
+// ParseToken 署名鍵のkidに応じてトークンを検証する
+func ParseToken(tokenString string) (*jwt.Token, error) {
+	ring, err := getJWTKeyRing()
+	if err != nil {
+		return nil, err
+	}
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ring.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %s", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verifyKey, nil
+	})
+}
+
+// GenerateRandomToken 推測不可能なランダム文字列を生成
+func GenerateRandomToken(byteLength int) (string, error) {
+	b := make([]byte, byteLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken リフレッシュトークン等をDBに保存するためのハッシュ値
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
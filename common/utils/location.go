@@ -0,0 +1,35 @@
+package utils
+
+import "time"
+
+// DefaultLocationName 施設のタイムゾーンが分からない場合のタイムゾーン
+const DefaultLocationName = "Asia/Tokyo"
+
+// countryLocations 施設の国コードとタイムゾーン（複数のタイムゾーンを持つ国は登録しない）
+var countryLocations = map[string]string{
+	"JP": "Asia/Tokyo",
+	"KR": "Asia/Seoul",
+	"TW": "Asia/Taipei",
+	"HK": "Asia/Hong_Kong",
+	"MO": "Asia/Macau",
+	"CN": "Asia/Shanghai",
+	"TH": "Asia/Bangkok",
+	"VN": "Asia/Ho_Chi_Minh",
+	"SG": "Asia/Singapore",
+	"MY": "Asia/Kuala_Lumpur",
+	"PH": "Asia/Manila",
+	"GU": "Pacific/Guam",
+}
+
+// PropertyLocation 施設の国コードからタイムゾーンを返す。分からない場合は日本時間
+func PropertyLocation(countryCode string) *time.Location {
+	name, ok := countryLocations[countryCode]
+	if !ok {
+		name = DefaultLocationName
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone(DefaultLocationName, 9*60*60)
+	}
+	return loc
+}
@@ -32,21 +32,47 @@ func RequestLog(c echo.Context, request interface{}) {
 	c.Echo().Logger.Info("request >>> " + string(request_log))
 }
 
-// GenerateToken token発行スクリプト
-func GenerateToken(hotelManagerID int64) (string, error) {
+// GenerateToken アクセストークン発行スクリプト
+// sessionIDはリフレッシュトークンと対になるセッションのID
+func GenerateToken(hotelManagerID int64, sessionID string) (string, error) {
+	return GenerateTokenUntil(hotelManagerID, sessionID, time.Time{})
+}
+
+// GenerateTokenUntil untilより後に失効しないアクセストークンを生成する（untilがゼロ値の場合は通常の有効期限）
+func GenerateTokenUntil(hotelManagerID int64, sessionID string, until time.Time) (string, error) {
+	ring, err := getJWTKeyRing()
+	if err != nil {
+		return "", err
+	}
+	key := ring.keys[ring.currentKid]
+
 	// Create token
-	token := jwt.New(jwt.SigningMethodHS256)
+	token := jwt.New(key.method)
+	if ring.currentKid != "" {
+		token.Header["kid"] = ring.currentKid
+	}
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
 
 	// Set claims
+	now := time.Now()
 	claims := token.Claims.(jwt.MapClaims)
 	claims["name"] = os.Getenv("JWT_CLAIMS_NAME")
 	claims["str"] = os.Getenv("JWT_CLAIMS_STR")
 	claims["hotelManagerID"] = hotelManagerID
-	claims["iat"] = time.Now().Unix()
-	claims["exp"] = time.Now().Add(time.Hour * 168).Unix()
+	claims["sid"] = sessionID
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	expiresAt := now.Add(AccessTokenTTL())
+	if !until.IsZero() && until.Before(expiresAt) {
+		expiresAt = until
+	}
+	claims["exp"] = expiresAt.Unix()
 
 	// Generate encoded token and send it as response
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return token.SignedString(key.signKey)
 }
 
 // GetExtensionFromContentType ContentTypeから拡張子を取得
@@ -88,7 +114,14 @@ func GetHmUser(c echo.Context) (*account.ClaimParam, error) {
 	if claims["name"].(string) != os.Getenv("JWT_CLAIMS_NAME") || claims["str"].(string) != os.Getenv("JWT_CLAIMS_STR") {
 		return &account.ClaimParam{}, fmt.Errorf("Error: %s", "tokenが不正です")
 	}
-	return &account.ClaimParam{HotelManagerID: int64(claims["hotelManagerID"].(float64)), APIToken: user.Raw}, nil
+	claimParam := &account.ClaimParam{HotelManagerID: int64(claims["hotelManagerID"].(float64)), APIToken: user.Raw}
+	// セッション導入前に発行されたトークンにはsid, jtiが含まれない
+	claimParam.SessionID, _ = claims["sid"].(string)
+	claimParam.TokenID, _ = claims["jti"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		claimParam.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return claimParam, nil
 }
 
 // PublicHoliday 休日用の構造体
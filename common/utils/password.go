@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// PasswordMinLength パスワードの最小文字数
+	PasswordMinLength = 8
+	// PasswordMaxLength パスワードの最大文字数（bcryptは72byteまでしか扱えない）
+	PasswordMaxLength = 72
+	// PasswordHistoryCount 再利用を禁止する過去パスワードの世代数
+	PasswordHistoryCount = 5
+	// passwordHashCost bcryptのコスト
+	passwordHashCost = 12
+)
+
+// HashPassword パスワードをbcryptでハッシュ化する
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// ComparePassword ハッシュ化済みのパスワードと平文のパスワードが一致するか
+func ComparePassword(hash string, password string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// ValidatePasswordPolicy パスワードポリシー（文字数・文字種）を満たしているか確認する
+func ValidatePasswordPolicy(password string) error {
+	if utf8.RuneCountInString(password) < PasswordMinLength {
+		return fmt.Errorf("Error: パスワードは%d文字以上で入力してください。", PasswordMinLength)
+	}
+	if len(password) > PasswordMaxLength {
+		return fmt.Errorf("Error: パスワードは%d文字以内で入力してください。", PasswordMaxLength)
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			hasLetter = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return fmt.Errorf("Error: %s", "パスワードは英字と数字を組み合わせてください。")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"testing"
+)
+
+func Test_Password(t *testing.T) {
+	t.Run("ハッシュ化したパスワードと照合できることのテスト", func(t *testing.T) {
+		hash, err := HashPassword("akano1234")
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if hash == "akano1234" {
+			t.Fatalf("ハッシュ化されていません。")
+		}
+		if ComparePassword(hash, "akano1234") == false {
+			t.Fatalf("正しいパスワードで照合できませんでした。")
+		}
+		if ComparePassword(hash, "akano12345") {
+			t.Fatalf("誤ったパスワードで照合できてしまいました。")
+		}
+		if ComparePassword("", "") {
+			t.Fatalf("空のハッシュで照合できてしまいました。")
+		}
+	})
+
+	t.Run("パスワードポリシーのテスト", func(t *testing.T) {
+		test := map[string]bool{
+			"akano123":   true,
+			"Tomohiro1":  true,
+			"akano12":    false,
+			"akanoakano": false,
+			"12345678":   false,
+			"":           false,
+		}
+
+		for password, valid := range test {
+			err := ValidatePasswordPolicy(password)
+			if valid && err != nil {
+				t.Fatalf("ポリシーを満たすパスワードがエラーになりました。パスワード: %s, エラー: %s", password, err)
+			}
+			if !valid && err == nil {
+				t.Fatalf("ポリシーを満たさないパスワードがエラーになりませんでした。パスワード: %s", password)
+			}
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// RequestSignature 連携APIリクエストの署名（HMAC-SHA256、16進数）
+// 署名対象は メソッド・パス（クエリ含む）・タイムスタンプ・ノンス・本文のSHA-256 を改行でつないだもの
+func RequestSignature(secret string, method string, path string, timestamp string, nonce string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	message := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodySum[:]),
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequestSignature 連携APIリクエストの署名が正しいか（比較は定数時間で行う）
+func VerifyRequestSignature(secret string, method string, path string, timestamp string, nonce string, body []byte, signature string) bool {
+	expected := RequestSignature(secret, method, path, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+)
+
+func Test_RequestSignature(t *testing.T) {
+	secret := "secret"
+	body := []byte(`{"plan_id":1}`)
+	signature := RequestSignature(secret, "post", "/bulk/plan?x=1", "1600000000", "nonce", body)
+
+	t.Run("同じ内容の署名は検証に成功することのテスト", func(t *testing.T) {
+		if !VerifyRequestSignature(secret, "POST", "/bulk/plan?x=1", "1600000000", "nonce", body, signature) {
+			t.Fatalf("署名の検証に失敗しました。")
+		}
+	})
+
+	t.Run("署名対象が1つでも異なれば検証に失敗することのテスト", func(t *testing.T) {
+		test := map[string]bool{
+			"secret":    VerifyRequestSignature("other", "POST", "/bulk/plan?x=1", "1600000000", "nonce", body, signature),
+			"method":    VerifyRequestSignature(secret, "PUT", "/bulk/plan?x=1", "1600000000", "nonce", body, signature),
+			"path":      VerifyRequestSignature(secret, "POST", "/bulk/plan?x=2", "1600000000", "nonce", body, signature),
+			"timestamp": VerifyRequestSignature(secret, "POST", "/bulk/plan?x=1", "1600000001", "nonce", body, signature),
+			"nonce":     VerifyRequestSignature(secret, "POST", "/bulk/plan?x=1", "1600000000", "other", body, signature),
+			"body":      VerifyRequestSignature(secret, "POST", "/bulk/plan?x=1", "1600000000", "nonce", []byte(`{"plan_id":2}`), signature),
+		}
+		for name, ok := range test {
+			if ok {
+				t.Fatalf("%sが異なる署名の検証に成功しました。", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_TOTP(t *testing.T) {
+	// RFC 6238 のテストベクトル（SHA1）の下6桁
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	test := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	t.Run("RFC 6238 のテストベクトルと一致することのテスト", func(t *testing.T) {
+		for unix, want := range test {
+			got, err := TOTPCode(secret, time.Unix(unix, 0))
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if got != want {
+				t.Fatalf("ワンタイムパスワードが一致しません。時刻: %d, 期待値: %s, 結果: %s", unix, want, got)
+			}
+		}
+	})
+
+	t.Run("前後1ステップまで許容し、それ以上ずれたコードは拒否することのテスト", func(t *testing.T) {
+		now := time.Unix(1111111111, 0)
+		code, _ := TOTPCode(secret, now)
+		if _, ok := ValidateTOTP(secret, code, now.Add(totpPeriod*time.Second)); ok == false {
+			t.Fatalf("1ステップ後のコードが拒否されました。")
+		}
+		if _, ok := ValidateTOTP(secret, code, now.Add(3*totpPeriod*time.Second)); ok {
+			t.Fatalf("3ステップ後のコードが許可されました。")
+		}
+		if _, ok := ValidateTOTP(secret, "12345", now); ok {
+			t.Fatalf("桁数の異なるコードが許可されました。")
+		}
+	})
+
+	t.Run("プロビジョニングURIにシークレットが含まれることのテスト", func(t *testing.T) {
+		uri := TOTPProvisioningURI("akano", "ABCDEF")
+		if !strings.HasPrefix(uri, "otpauth://totp/") || !strings.Contains(uri, "secret=ABCDEF") {
+			t.Fatalf("プロビジョニングURIが不正です。%s", uri)
+		}
+	})
+
+	t.Run("シークレットを暗号化して復号できることのテスト", func(t *testing.T) {
+		os.Setenv("SECRET_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+		defer os.Unsetenv("SECRET_ENCRYPTION_KEY")
+		encrypted, err := EncryptSecret(secret)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		decrypted, err := DecryptSecret(encrypted)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if decrypted != secret {
+			t.Fatalf("復号した値が一致しません。")
+		}
+	})
+}
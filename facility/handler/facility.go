@@ -5,6 +5,11 @@ import (
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/facility"
 	"github.com/Adventureinc/hotel-hm-api/src/facility/usecase"
@@ -21,6 +26,8 @@ type FacilityHandler struct {
 	FDirectUsecase facility.IFacilityUsecase
 	FRaku2Usecase  facility.IFacilityRaku2Usecase
 	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
+	AuditUsecase   audit.IAuditUsecase
 }
 
 // NewFacilityHandler インスタンス生成
@@ -33,6 +40,8 @@ func NewFacilityHandler(db *gorm.DB) *FacilityHandler {
 		FDirectUsecase: usecase.NewFacilityDirectUsecase(db),
 		FRaku2Usecase:  usecase.NewFacilityRaku2Usecase(db),
 		AUsecase:       aUsecase.NewAccountUsecase(db),
+		OUsecase:       oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:   auditUsecase.NewAuditUsecase(db),
 	}
 }
 
@@ -92,7 +101,7 @@ func (f *FacilityHandler) FetchAll(c echo.Context) error {
 
 // UpdateDispPriority 施設のサイト公開フラグを更新
 func (f *FacilityHandler) UpdateDispPriority(c echo.Context) error {
-	_, err := f.getHmUser(c)
+	hmUser, err := f.getHmUser(c)
 	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -109,6 +118,21 @@ func (f *FacilityHandler) UpdateDispPriority(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, f.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := f.AuditUsecase.Begin(hmUser, audit.EntityProperty, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, f.AuditUsecase, trail)
+
 	switch request.WholesalerID {
 	case utils.WholesalerIDTl:
 		if err := f.FTlUsecase.UpdateDispPriority(request); err != nil {
@@ -163,6 +187,11 @@ func (f *FacilityHandler) FetchBaseInfo(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		res, _ := f.FTlUsecase.FetchBaseInfo(&hmUser, claimParam, request)
@@ -205,6 +234,11 @@ func (f *FacilityHandler) FetchDetail(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		res, _ := f.FTlUsecase.FetchDetail(request)
@@ -247,6 +281,21 @@ func (f *FacilityHandler) SaveBaseInfo(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, f.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := f.AuditUsecase.Begin(hmUser, audit.EntityProperty, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, f.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		if err := f.FTlUsecase.SaveBaseInfo(&hmUser, claimParam, request); err != nil {
@@ -331,6 +380,21 @@ func (f *FacilityHandler) SaveDetail(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := f.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, f.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := f.AuditUsecase.Begin(hmUser, audit.EntityProperty, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, f.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTl:
 		if err := f.FTlUsecase.SaveDetail(request); err != nil {
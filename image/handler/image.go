@@ -5,6 +5,11 @@ import (
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/image"
 	"github.com/Adventureinc/hotel-hm-api/src/image/usecase"
@@ -19,6 +24,8 @@ type ImageHandler struct {
 	IDirectUsecase image.IImageUsecase
 	IRaku2Usecase  image.IImageUsecase
 	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
+	AuditUsecase   audit.IAuditUsecase
 }
 
 // NewImageHandler インスタンス生成
@@ -29,6 +36,8 @@ func NewImageHandler(db *gorm.DB) *ImageHandler {
 		IDirectUsecase: usecase.NewImageDirectUsecase(db),
 		IRaku2Usecase:  usecase.NewImageRaku2Usecase(db),
 		AUsecase:       aUsecase.NewAccountUsecase(db),
+		OUsecase:       oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:   auditUsecase.NewAuditUsecase(db),
 	}
 }
 
@@ -51,6 +60,11 @@ func (i *ImageHandler) FetchAll(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTema:
 		fallthrough
@@ -93,6 +107,21 @@ func (i *ImageHandler) Update(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, i.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := i.AuditUsecase.Begin(hmUser, audit.EntityImage, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, i.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTema:
 		fallthrough
@@ -143,6 +172,21 @@ func (i *ImageHandler) UpdateIsMain(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, i.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := i.AuditUsecase.Begin(hmUser, audit.EntityImage, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, i.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTema:
 		fallthrough
@@ -195,6 +239,21 @@ func (i *ImageHandler) UpdateSortNum(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, i.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := i.AuditUsecase.Begin(hmUser, audit.EntityImage, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, i.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTema:
 		fallthrough
@@ -245,6 +304,21 @@ func (i *ImageHandler) Delete(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, i.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := i.AuditUsecase.Begin(hmUser, audit.EntityImage, audit.ActionDelete, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, i.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDTema:
 		fallthrough
@@ -301,6 +375,21 @@ func (i *ImageHandler) Create(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, i.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := i.AuditUsecase.Begin(hmUser, audit.EntityImage, audit.ActionCreate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, i.AuditUsecase, trail)
+
 	request.Caption = utils.RemoveDoubleQuotation(request.Caption)
 	request.ContentType = utils.RemoveDoubleQuotation(request.ContentType)
 	request.CategoryCd = utils.RemoveDoubleQuotation(request.CategoryCd)
@@ -338,7 +427,7 @@ func (i *ImageHandler) Create(c echo.Context) error {
 
 // CountMainImages メイン画像設定数取得
 func (i *ImageHandler) CountMainImages(c echo.Context) error {
-	_, err := i.getHmUser(c)
+	hmUser, err := i.getHmUser(c)
 	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -354,6 +443,11 @@ func (i *ImageHandler) CountMainImages(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 	utils.RequestLog(c, request)
+
+	if err := i.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
 	switch request.WholesalerID {
 	case utils.WholesalerIDTema:
 		fallthrough
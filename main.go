@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"time"
 	_ "time/tzdata"
 
+	anUsecase "github.com/Adventureinc/hotel-hm-api/src/analytics/usecase"
+	bUsecase "github.com/Adventureinc/hotel-hm-api/src/booking/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/app"
+	cUsecase "github.com/Adventureinc/hotel-hm-api/src/common/credential/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/infra"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -43,6 +47,7 @@ func main() {
 
 	e.Validator = &customValidator{validator: validator.New()}
 	e.Use(middleware.Recover())
+	e.Use(middleware.RequestID())
 	e.Use(middleware.Logger())
 
 	// debug用cors設定
@@ -59,5 +64,14 @@ func main() {
 	// ルーティング
 	app.Route(e, hotelDB)
 
+	// adminへのキャンセル依頼の送信
+	go bUsecase.RunCancelWorker(context.Background(), bUsecase.NewBookingUsecase(hotelDB), bUsecase.CancelWorkerInterval(), e.Logger)
+	// 施設への予約通知の送信
+	go bUsecase.RunNotificationWorker(context.Background(), bUsecase.NewBookingUsecase(hotelDB), bUsecase.NotificationWorkerInterval(), e.Logger)
+	// 稼働率・ADR・RevPARの集計結果の更新
+	go anUsecase.RunAnalyticsWorker(context.Background(), anUsecase.NewAnalyticsUsecase(hotelDB), anUsecase.AnalyticsWorkerInterval(), e.Logger)
+	// 連携APIの有効期限を過ぎたノンスの削除
+	go cUsecase.RunNoncePurgeWorker(context.Background(), cUsecase.NewCredentialUsecase(hotelDB), cUsecase.NoncePurgeInterval(), e.Logger)
+
 	e.Logger.Fatal(e.Start(":1323"))
 }
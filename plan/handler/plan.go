@@ -10,6 +10,12 @@ import (
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ratelimit"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/plan"
 	"github.com/Adventureinc/hotel-hm-api/src/plan/usecase"
@@ -25,6 +31,8 @@ type PlanHandler struct {
 	PDirectUsecase   plan.IPlanUsecase
 	PRaku2Usecase    plan.IPlanUsecase
 	AUsecase         account.IAccountUsecase
+	OUsecase         ownership.IOwnershipUsecase
+	AuditUsecase     audit.IAuditUsecase
 	RLogRepository   log.ILogRepository
 }
 
@@ -40,6 +48,8 @@ func NewPlanHandler(db *gorm.DB) *PlanHandler {
 		PDirectUsecase:   usecase.NewPlanDirectUsecase(db),
 		PRaku2Usecase:    usecase.NewPlanRaku2Usecase(db),
 		AUsecase:         aUsecase.NewAccountUsecase(db),
+		OUsecase:         oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:     auditUsecase.NewAuditUsecase(db),
 		RLogRepository:   infra.NewLogRepository(db),
 	}
 }
@@ -62,6 +72,11 @@ func (p *PlanHandler) List(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		list, _ := p.PNeppanUsecase.FetchList(request)
@@ -101,6 +116,11 @@ func (p *PlanHandler) Detail(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		detail, err := p.PNeppanUsecase.Detail(request)
@@ -160,6 +180,21 @@ func (p *PlanHandler) Create(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, p.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := p.AuditUsecase.Begin(hmUser, audit.EntityPlan, audit.ActionCreate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, p.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := p.PNeppanUsecase.Create(request); err != nil {
@@ -208,6 +243,21 @@ func (p *PlanHandler) Update(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, p.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := p.AuditUsecase.Begin(hmUser, audit.EntityPlan, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, p.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := p.PNeppanUsecase.Update(request); err != nil {
@@ -253,10 +303,25 @@ func (p *PlanHandler) Delete(c echo.Context) error {
 	utils.RequestLog(c, request)
 
 	if request.PlanID == 0 {
-		c.Echo().Logger.Error("room_type_id がありません。")
+		c.Echo().Logger.Error("plan_id がありません。")
 		return echo.ErrBadRequest
 	}
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, p.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := p.AuditUsecase.Begin(hmUser, audit.EntityPlan, audit.ActionDelete, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, p.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := p.PNeppanUsecase.Delete(request.PlanID); err != nil {
@@ -299,6 +364,21 @@ func (p *PlanHandler) UpdateStopSales(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, p.AUsecase, &hmUser, account.PermissionInventoryEdit); err != nil {
+		return err
+	}
+
+	trail, err := p.AuditUsecase.Begin(hmUser, audit.EntityStopSale, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, p.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := p.PNeppanUsecase.UpdateStopSales(request); err != nil {
@@ -341,6 +421,9 @@ func (p *PlanHandler) CreateOrUpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointPlanBulk, len(requestDataTl)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestDataTl)
@@ -358,6 +441,9 @@ func (p *PlanHandler) CreateOrUpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointPlanBulk, len(requestDataTema)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestDataTema)
@@ -3,8 +3,14 @@ package handler
 import (
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
 	"github.com/Adventureinc/hotel-hm-api/src/common/log"
 	"github.com/Adventureinc/hotel-hm-api/src/common/log/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ratelimit"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/price"
 	"github.com/Adventureinc/hotel-hm-api/src/price/usecase"
@@ -21,6 +27,8 @@ type PriceHandler struct {
 	PTlUsecase     price.IPriceBulkTlUsecase
 	PTemaUsecase   price.IPriceBulkTemaUsecase
 	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
+	AuditUsecase   audit.IAuditUsecase
 	RLogRepository log.ILogRepository
 }
 
@@ -34,6 +42,8 @@ func NewPriceHandler(db *gorm.DB) *PriceHandler {
 		PTlUsecase:     usecase.NewPriceTlUsecase(db),
 		PTemaUsecase:   usecase.NewPriceTemaUsecase(db),
 		AUsecase:       aUsecase.NewAccountUsecase(db),
+		OUsecase:       oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:   auditUsecase.NewAuditUsecase(db),
 		RLogRepository: infra.NewLogRepository(db),
 	}
 }
@@ -56,6 +66,11 @@ func (p *PriceHandler) Detail(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDDirect:
 		detail, err := p.PDirectUsecase.FetchDetail(request)
@@ -88,6 +103,21 @@ func (p *PriceHandler) Save(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := p.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, p.AUsecase, &hmUser, account.PermissionInventoryEdit); err != nil {
+		return err
+	}
+
+	trail, err := p.AuditUsecase.Begin(hmUser, audit.EntityPrice, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, p.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDDirect:
 		if err := p.PDirectUsecase.Save(request); err != nil {
@@ -109,6 +139,9 @@ func (p *PriceHandler) UpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointPriceBulk, len(requestTl)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestTl)
@@ -126,6 +159,9 @@ func (p *PriceHandler) UpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointPriceBulk, len(requestTema)); err != nil {
+			return err
+		}
 		// validate request data
 		errorMessages := utils.Validate(c, requestTema)
 		if len(errorMessages) > 0 {
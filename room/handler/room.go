@@ -3,8 +3,14 @@ package handler
 import (
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
 	"github.com/Adventureinc/hotel-hm-api/src/common/log"
 	"github.com/Adventureinc/hotel-hm-api/src/common/log/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ratelimit"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/room"
 	rInfra "github.com/Adventureinc/hotel-hm-api/src/room/infra"
@@ -24,6 +30,8 @@ type RoomHandler struct {
 	RDirectUsecase  room.IRoomUsecase
 	RRaku2Usecase   room.IRoomUsecase
 	AUsecase        account.IAccountUsecase
+	OUsecase        ownership.IOwnershipUsecase
+	AuditUsecase    audit.IAuditUsecase
 	RLogRepository  log.ILogRepository
 	RTlRepository   room.IRoomTlRepository
 	RTemaUsecase    room.IRoomTemaUseCase
@@ -42,6 +50,8 @@ func NewRoomHandler(db *gorm.DB) *RoomHandler {
 		RDirectUsecase:  usecase.NewRoomDirectUsecase(db),
 		RRaku2Usecase:   usecase.NewRoomRaku2Usecase(db),
 		AUsecase:        aUsecase.NewAccountUsecase(db),
+		OUsecase:        oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:    auditUsecase.NewAuditUsecase(db),
 		RLogRepository:  infra.NewLogRepository(db),
 		RTlRepository:   rInfra.NewRoomTlRepository(db),
 		RTemaUsecase:    usecase.NewRoomTemaUseCase(db),
@@ -67,6 +77,11 @@ func (r *RoomHandler) List(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := r.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		list, _ := r.RNeppanUsecase.FetchList(request)
@@ -160,6 +175,11 @@ func (r *RoomHandler) Detail(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := r.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		detail, err := r.RNeppanUsecase.FetchDetail(request)
@@ -221,6 +241,21 @@ func (r *RoomHandler) Create(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := r.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, r.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := r.AuditUsecase.Begin(hmUser, audit.EntityRoomType, audit.ActionCreate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, r.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := r.RNeppanUsecase.Create(request); err != nil {
@@ -255,6 +290,9 @@ func (r *RoomHandler) CreateOrUpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointRoomBulk, len(requestDataTl)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestDataTl)
@@ -272,6 +310,9 @@ func (r *RoomHandler) CreateOrUpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointRoomBulk, len(requestDataTema)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestDataTema)
@@ -364,6 +405,21 @@ func (r *RoomHandler) Update(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
+	if err := r.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, r.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := r.AuditUsecase.Begin(hmUser, audit.EntityRoomType, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, r.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := r.RNeppanUsecase.Update(request); err != nil {
@@ -413,6 +469,21 @@ func (r *RoomHandler) Delete(c echo.Context) error {
 		return echo.ErrBadRequest
 	}
 
+	if err := r.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, r.AUsecase, &hmUser, account.PermissionContentEdit); err != nil {
+		return err
+	}
+
+	trail, err := r.AuditUsecase.Begin(hmUser, audit.EntityRoomType, audit.ActionDelete, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, r.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := r.RNeppanUsecase.Delete(request.RoomTypeID); err != nil {
@@ -456,6 +527,21 @@ func (r *RoomHandler) UpdateStopSales(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := r.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, r.AUsecase, &hmUser, account.PermissionInventoryEdit); err != nil {
+		return err
+	}
+
+	trail, err := r.AuditUsecase.Begin(hmUser, audit.EntityStopSale, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, r.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := r.RNeppanUsecase.UpdateStopSales(request); err != nil {
@@ -5,6 +5,11 @@ import (
 
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/settlement"
 	"github.com/Adventureinc/hotel-hm-api/src/settlement/usecase"
@@ -14,21 +19,25 @@ import (
 
 // SettlementHandler 請求関連の振り分け
 type SettlementHandler struct {
-	SUsecase settlement.ISettlementUsecase
-	AUsecase account.IAccountUsecase
+	SUsecase     settlement.ISettlementUsecase
+	AUsecase     account.IAccountUsecase
+	OUsecase     ownership.IOwnershipUsecase
+	AuditUsecase audit.IAuditUsecase
 }
 
 // NewSettlementHandler インスタンス生成
 func NewSettlementHandler(db *gorm.DB) *SettlementHandler {
 	return &SettlementHandler{
-		SUsecase: usecase.NewSettlementUsecase(db),
-		AUsecase: aUsecase.NewAccountUsecase(db),
+		SUsecase:     usecase.NewSettlementUsecase(db),
+		AUsecase:     aUsecase.NewAccountUsecase(db),
+		OUsecase:     oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase: auditUsecase.NewAuditUsecase(db),
 	}
 }
 
 // List 請求書一覧
 func (s *SettlementHandler) List(c echo.Context) error {
-	_, err := s.getHmUser(c)
+	hmUser, err := s.getHmUser(c)
 	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -44,13 +53,18 @@ func (s *SettlementHandler) List(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	list, _ := s.SUsecase.FetchAll(*request)
 	return c.JSON(http.StatusOK, list)
 }
 
 // Approve 請求書の承認
 func (s *SettlementHandler) Approve(c echo.Context) error {
-	_, err := s.getHmUser(c)
+	hmUser, err := s.getHmUser(c)
 	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -66,6 +80,21 @@ func (s *SettlementHandler) Approve(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, s.AUsecase, &hmUser, account.PermissionSettlementApprove); err != nil {
+		return err
+	}
+
+	trail, err := s.AuditUsecase.Begin(hmUser, audit.EntitySettlement, audit.ActionApprove, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, s.AuditUsecase, trail)
+
 	if err := s.SUsecase.Approve(*request); err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.ErrInternalServerError
@@ -75,7 +104,7 @@ func (s *SettlementHandler) Approve(c echo.Context) error {
 
 // Download 請求書ダウンロード
 func (s *SettlementHandler) Download(c echo.Context) error {
-	_, err := s.getHmUser(c)
+	hmUser, err := s.getHmUser(c)
 	if err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -91,6 +120,11 @@ func (s *SettlementHandler) Download(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	tempFileName, downloadFileName, dErr := s.SUsecase.Download(request)
 	if dErr != nil {
 		c.Echo().Logger.Error(dErr)
@@ -106,7 +140,7 @@ func (s *SettlementHandler) FetchInfo(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
 
-	_, hmErr := s.AUsecase.FetchHMUserByToken(claimParam)
+	hmUser, hmErr := s.AUsecase.FetchHMUserByToken(claimParam)
 	if hmErr != nil {
 		c.Echo().Logger.Error(hmErr)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -123,6 +157,11 @@ func (s *SettlementHandler) FetchInfo(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	info, _ := s.SUsecase.FetchInfo(request, claimParam)
 	return c.JSON(http.StatusOK, info)
 }
@@ -134,7 +173,7 @@ func (s *SettlementHandler) SaveInfo(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
 	}
 
-	_, hmErr := s.AUsecase.FetchHMUserByToken(claimParam)
+	hmUser, hmErr := s.AUsecase.FetchHMUserByToken(claimParam)
 	if hmErr != nil {
 		c.Echo().Logger.Error(hmErr)
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
@@ -151,6 +190,21 @@ func (s *SettlementHandler) SaveInfo(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, s.AUsecase, &hmUser, account.PermissionSettlementAccountEdit); err != nil {
+		return err
+	}
+
+	trail, err := s.AuditUsecase.Begin(hmUser, audit.EntitySettlementAccount, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, s.AuditUsecase, trail)
+
 	if err := s.SUsecase.SaveInfo(request, claimParam); err != nil {
 		c.Echo().Logger.Error(err)
 		return echo.ErrInternalServerError
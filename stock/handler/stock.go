@@ -3,8 +3,14 @@ package handler
 import (
 	"github.com/Adventureinc/hotel-hm-api/src/account"
 	aUsecase "github.com/Adventureinc/hotel-hm-api/src/account/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/audit"
+	auditUsecase "github.com/Adventureinc/hotel-hm-api/src/common/audit/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/auth"
 	"github.com/Adventureinc/hotel-hm-api/src/common/log"
 	"github.com/Adventureinc/hotel-hm-api/src/common/log/infra"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ownership"
+	oUsecase "github.com/Adventureinc/hotel-hm-api/src/common/ownership/usecase"
+	"github.com/Adventureinc/hotel-hm-api/src/common/ratelimit"
 	"github.com/Adventureinc/hotel-hm-api/src/common/utils"
 	"github.com/Adventureinc/hotel-hm-api/src/stock"
 	"github.com/Adventureinc/hotel-hm-api/src/stock/usecase"
@@ -23,6 +29,8 @@ type StockHandler struct {
 	SDirectUsecase stock.IStockUsecase
 	SRaku2Usecase  stock.IStockUsecase
 	AUsecase       account.IAccountUsecase
+	OUsecase       ownership.IOwnershipUsecase
+	AuditUsecase   audit.IAuditUsecase
 	RLogRepository log.ILogRepository
 }
 
@@ -38,6 +46,8 @@ func NewStockHandler(db *gorm.DB) *StockHandler {
 		SDirectUsecase: usecase.NewStockDirectUsecase(db),
 		SRaku2Usecase:  usecase.NewStockRaku2Usecase(db),
 		AUsecase:       aUsecase.NewAccountUsecase(db),
+		OUsecase:       oUsecase.NewOwnershipUsecase(db),
+		AuditUsecase:   auditUsecase.NewAuditUsecase(db),
 		RLogRepository: infra.NewLogRepository(db),
 	}
 }
@@ -60,6 +70,11 @@ func (s *StockHandler) Calendar(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		cal, _ := s.SNeppanUsecase.FetchCalendar(hmUser, *request)
@@ -98,6 +113,21 @@ func (s *StockHandler) UpdateStopSales(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, s.AUsecase, &hmUser, account.PermissionInventoryEdit); err != nil {
+		return err
+	}
+
+	trail, err := s.AuditUsecase.Begin(hmUser, audit.EntityStopSale, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, s.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDNeppan:
 		if err := s.SNeppanUsecase.UpdateStopSales(request); err != nil {
@@ -139,6 +169,11 @@ func (s *StockHandler) FetchAll(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDDirect:
 		detail, _ := s.SDirectUsecase.FetchAll(request)
@@ -167,6 +202,21 @@ func (s *StockHandler) Save(c echo.Context) error {
 	}
 	utils.RequestLog(c, request)
 
+	if err := s.OUsecase.Authorize(hmUser, request); err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrForbidden
+	}
+	if err := auth.RequirePermission(c, s.AUsecase, &hmUser, account.PermissionInventoryEdit); err != nil {
+		return err
+	}
+
+	trail, err := s.AuditUsecase.Begin(hmUser, audit.EntityStock, audit.ActionUpdate, request)
+	if err != nil {
+		c.Echo().Logger.Error(err)
+		return echo.ErrInternalServerError
+	}
+	defer audit.RecordOnSuccess(c, s.AuditUsecase, trail)
+
 	switch hmUser.WholesalerID {
 	case utils.WholesalerIDDirect:
 		if err := s.SDirectUsecase.Save(request); err != nil {
@@ -188,6 +238,9 @@ func (s *StockHandler) UpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointStockBulk, len(requestStockTl)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestStockTl)
@@ -205,6 +258,9 @@ func (s *StockHandler) UpdateBulk(c echo.Context) error {
 			c.Echo().Logger.Error(err)
 			return echo.ErrBadRequest
 		}
+		if err := ratelimit.CheckItemCount(c, ratelimit.EndpointStockBulk, len(requestStockTema)); err != nil {
+			return err
+		}
 
 		// validate request data
 		errorMessages := utils.Validate(c, requestStockTema)
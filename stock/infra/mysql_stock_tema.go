@@ -118,6 +118,7 @@ func (s *stockTemaRepository) FetchAllBookingsByPlanIDList(planIDList []int64, s
 		Table("ht_th_booking_prices as b").
 		Joins("INNER JOIN ht_th_applications as a ON a.cm_application_id = b.cm_application_id").
 		Where("a.cancel_flg = 0").
+		Where("b.cancel_flg = 0").
 		Where("a.wholesaler_id = ?", utils.WholesalerIDTema).
 		Where("b.plan_id IN ?", planIDList).
 		Where("b.use_date BETWEEN ? AND ?", startDate, endDate).
@@ -55,6 +55,7 @@ func (s *stockTlRepository) FetchAllBookingsByPlanIDList(planIDList []int64, sta
 		Table("ht_th_booking_prices as b").
 		Joins("INNER JOIN ht_th_applications as a ON a.cm_application_id = b.cm_application_id").
 		Where("a.cancel_flg = 0").
+		Where("b.cancel_flg = 0").
 		Where("a.wholesaler_id = ?", utils.WholesalerIDTl).
 		Where("b.plan_id IN ?", planIDList).
 		Where("b.use_date BETWEEN ? AND ?", startDate, endDate).
@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Adventureinc/hotel-hm-api/src/account"
+	"github.com/Adventureinc/hotel-hm-api/src/settlement"
+	"github.com/Adventureinc/hotel-hm-api/src/settlement/handler"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccountUsecase ロールだけを持つHMアカウントを返すmock
+type MockAccountUsecase struct {
+	account.IAccountUsecase
+	roles []string
+}
+
+// FetchHMUserByToken mock
+func (m *MockAccountUsecase) FetchHMUserByToken(claimParam *account.ClaimParam) (account.HtTmHotelManager, error) {
+	return account.HtTmHotelManager{HotelManagerID: claimParam.HotelManagerID, PropertyID: 1208010}, nil
+}
+
+// HasPermission mock
+func (m *MockAccountUsecase) HasPermission(hmUser *account.HtTmHotelManager, permission string) (bool, error) {
+	for _, p := range account.PermissionsOf(m.roles) {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MockOwnershipUsecase 所有確認を常に通すmock
+type MockOwnershipUsecase struct{}
+
+// Authorize mock
+func (m *MockOwnershipUsecase) Authorize(hmUser account.HtTmHotelManager, request interface{}) error {
+	return nil
+}
+
+// MockSettlementUsecase mock implementation
+type MockSettlementUsecase struct {
+	settlement.ISettlementUsecase
+	mock.Mock
+}
+
+// Approve mock
+func (m *MockSettlementUsecase) Approve(req settlement.UpdateInput) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+type nopValidator struct{}
+
+func (v *nopValidator) Validate(i interface{}) error {
+	return nil
+}
+
+// TestSettlementHandlerApproveViewerForbidden 閲覧のみのロールでは請求書を承認できない
+func TestSettlementHandlerApproveViewerForbidden(t *testing.T) {
+	os.Setenv("JWT_CLAIMS_NAME", "test")
+	os.Setenv("JWT_CLAIMS_STR", "test")
+	mockUseCase := new(MockSettlementUsecase)
+	h := &handler.SettlementHandler{
+		SUsecase: mockUseCase,
+		AUsecase: &MockAccountUsecase{roles: []string{account.RoleViewer}},
+		OUsecase: &MockOwnershipUsecase{},
+	}
+
+	e := echo.New()
+	e.Validator = &nopValidator{}
+	req := httptest.NewRequest(http.MethodPut, "/settlement/approve", strings.NewReader(`{"settlement_id":1,"is_approve":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"name": "test", "str": "test", "hotelManagerID": float64(1)}})
+
+	err := h.Approve(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	mockUseCase.AssertNotCalled(t, "Approve", mock.Anything)
+}